@@ -0,0 +1,136 @@
+// Go TCP Accept Saturation Benchmark
+// The listener hot path under a connection storm: each iteration starts
+// one goroutine per client, all dialing the same listener at once, and
+// ends when the server has accepted every one of them. The server's
+// accept loop does nothing but Accept and hand the connection on, so the
+// case measures how fast accepts drain a full queue.
+//
+// Each client sends the time its Dial returned - the handshake is done and
+// the connection sits in the accept queue from then on - and the server
+// records, per connection, how long after that its Accept returned: the
+// accept queue latency, in the histogram. A connection accepted before its
+// client saw the handshake complete waited for nothing and records zero.
+//
+// Metrics: accepts_per_sec, from the start of the storm to the last accept.
+//
+// Clients: connected at unix ns u64, then close
+
+package main
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"time"
+)
+
+// acceptSample is one accepted connection as the server saw it
+type acceptSample struct {
+	accepted time.Time
+	queued   time.Duration
+	err      error
+}
+
+var acceptStorm struct {
+	ln      net.Listener
+	samples chan acceptSample
+	done    chan struct{}
+}
+
+func setupTcpAcceptStorm(b *B) error {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	acceptStorm.ln = ln
+	acceptStorm.samples = make(chan acceptSample, b.IntParam("clients"))
+	acceptStorm.done = make(chan struct{})
+	go acceptLoop(ln)
+	return nil
+}
+
+// acceptLoop accepts until ln is closed, reading each client's timestamp
+// off the accept path
+func acceptLoop(ln net.Listener) {
+	defer close(acceptStorm.done)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		accepted := time.Now()
+		go func() {
+			defer conn.Close()
+			var buf [8]byte
+			if _, err := io.ReadFull(conn, buf[:]); err != nil {
+				acceptStorm.samples <- acceptSample{err: err}
+				return
+			}
+			connected := time.Unix(0, int64(binary.LittleEndian.Uint64(buf[:])))
+			acceptStorm.samples <- acceptSample{accepted: accepted, queued: max(accepted.Sub(connected), 0)}
+		}()
+	}
+}
+
+func teardownTcpAcceptStorm() {
+	if acceptStorm.ln != nil {
+		acceptStorm.ln.Close()
+		<-acceptStorm.done
+	}
+	acceptStorm.ln = nil
+}
+
+// benchTcpAcceptStorm dials the listener from every client at once
+func benchTcpAcceptStorm(b *B) {
+	clients := b.IntParam("clients")
+	addr := acceptStorm.ln.Addr().String()
+	dialErrs := make(chan error, clients)
+	start := time.Now()
+	for i := 0; i < clients; i++ {
+		go func() {
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				dialErrs <- err
+				return
+			}
+			dialErrs <- nil
+			defer conn.Close()
+			var buf [8]byte
+			binary.LittleEndian.PutUint64(buf[:], uint64(time.Now().UnixNano()))
+			// A failed write shows up as the server's read error
+			conn.Write(buf[:])
+		}()
+	}
+
+	// A client that failed to connect was never accepted
+	connected := clients
+	for i := 0; i < clients; i++ {
+		if err := <-dialErrs; err != nil {
+			b.Fatal(err)
+			connected--
+		}
+	}
+	last := start
+	for i := 0; i < connected; i++ {
+		s := <-acceptStorm.samples
+		if s.err != nil {
+			b.Fatal(s.err)
+			continue
+		}
+		b.Histogram().Record(s.queued)
+		if s.accepted.After(last) {
+			last = s.accepted
+		}
+	}
+	b.ReportMetric("accepts_per_sec", float64(connected)/last.Sub(start).Seconds())
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "TcpAcceptStorm", Category: "tcp", Tags: []string{"net"},
+		Iterations: 20,
+		Axes:       []Axis{{Name: "clients", Values: Ints(100, 1000)}},
+		Setup:      setupTcpAcceptStorm, Teardown: teardownTcpAcceptStorm,
+		Fn: benchTcpAcceptStorm,
+	})
+}
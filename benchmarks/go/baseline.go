@@ -0,0 +1,113 @@
+// Baseline Storage - Go
+//
+// Persists a result set under a name and, on later runs, prints
+// per-benchmark deltas against it, flagging regressions above a threshold.
+//
+// -compare-baseline exits 1 when it flags a regression, so it can gate CI.
+//
+// Run with: go run . -save-baseline main
+//      then: go run . -compare-baseline main [-regression-threshold 10]
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// baselineDir is where named baselines are stored, relative to the
+// working directory
+const baselineDir = "baselines"
+
+// Delta is the change of one benchmark between a baseline and a new run
+type Delta struct {
+	Name       string
+	BaselineUs float64
+	CurrentUs  float64
+	// Percent is the change in time per iteration; positive is slower
+	Percent    float64
+	Regression bool
+}
+
+// baselinePath returns the file a named baseline is stored in
+func baselinePath(name string) string {
+	return filepath.Join(baselineDir, name+".json")
+}
+
+// ValidateBaselineName rejects names that would not stay a single file in
+// baselineDir
+func ValidateBaselineName(name string) error {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return fmt.Errorf("invalid baseline name %q: it must not be empty or contain path separators or ..", name)
+	}
+	return nil
+}
+
+// SaveBaseline stores a result set under name
+func SaveBaseline(name string, set ResultSet) error {
+	if err := ValidateBaselineName(name); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(baselineDir, 0o755); err != nil {
+		return err
+	}
+	return WriteResultSet(baselinePath(name), set)
+}
+
+// LoadBaseline reads the result set stored under name
+func LoadBaseline(name string) (ResultSet, error) {
+	if err := ValidateBaselineName(name); err != nil {
+		return ResultSet{}, err
+	}
+	return LoadResultSet(baselinePath(name))
+}
+
+// CompareResults matches current results to the baseline by name; benchmarks
+// missing from either side, or that failed on either side, are skipped
+func CompareResults(baseline, current []BenchmarkResult, thresholdPercent float64) []Delta {
+	byName := make(map[string]BenchmarkResult, len(baseline))
+	for _, r := range baseline {
+		if r.Error == "" {
+			byName[r.Name] = r
+		}
+	}
+
+	var deltas []Delta
+	for _, r := range current {
+		old, ok := byName[r.Name]
+		if !ok || old.TimeUs <= 0 || r.Error != "" {
+			continue
+		}
+		percent := (r.TimeUs - old.TimeUs) / old.TimeUs * 100
+		deltas = append(deltas, Delta{
+			Name:       r.Name,
+			BaselineUs: old.TimeUs,
+			CurrentUs:  r.TimeUs,
+			Percent:    percent,
+			Regression: percent > thresholdPercent,
+		})
+	}
+	return deltas
+}
+
+// PrintDeltas prints a comparison table and returns the regression count
+func PrintDeltas(baselineName string, deltas []Delta, thresholdPercent float64) int {
+	fmt.Printf("\n=== Comparison against baseline %q (threshold %.1f%%) ===\n\n", baselineName, thresholdPercent)
+	fmt.Printf("%-40s %14s %14s %10s\n", "Benchmark", "Baseline", "Current", "Delta")
+	fmt.Println(strings.Repeat("-", 93))
+
+	regressions := 0
+	for _, d := range deltas {
+		flag := ""
+		if d.Regression {
+			flag = "  REGRESSION"
+			regressions++
+		}
+		fmt.Printf("%-40s %11.2f us %11.2f us %+9.1f%%%s\n", d.Name, d.BaselineUs, d.CurrentUs, d.Percent, flag)
+	}
+	fmt.Println(strings.Repeat("-", 93))
+	fmt.Printf("%d benchmarks compared, %d regressions\n", len(deltas), regressions)
+	return regressions
+}
@@ -0,0 +1,37 @@
+// Baseline Storage Tests - Go
+//
+// Run with: go test -run Baseline
+
+package main
+
+import "testing"
+
+func TestValidateBaselineName(t *testing.T) {
+	for _, name := range []string{"main", "v0.4.0", "pr-123"} {
+		if err := ValidateBaselineName(name); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "../x", "a/b", `a\b`, ".."} {
+		if ValidateBaselineName(name) == nil {
+			t.Errorf("%q accepted", name)
+		}
+	}
+}
+
+func TestCompareResultsSkipsErrors(t *testing.T) {
+	baseline := []BenchmarkResult{
+		{Name: "Ok", TimeUs: 10},
+		{Name: "FailedBefore", TimeUs: 1, Error: "setup: refused"},
+		{Name: "FailsNow", TimeUs: 10},
+	}
+	current := []BenchmarkResult{
+		{Name: "Ok", TimeUs: 12},
+		{Name: "FailedBefore", TimeUs: 10},
+		{Name: "FailsNow", TimeUs: 100, Error: "timeout"},
+	}
+	deltas := CompareResults(baseline, current, 10)
+	if len(deltas) != 1 || deltas[0].Name != "Ok" || !deltas[0].Regression {
+		t.Errorf("deltas = %+v, want only Ok, flagged", deltas)
+	}
+}
@@ -0,0 +1,250 @@
+// Bounded-Memory Batch Processing Benchmark - Go
+//
+// Streams 10GB of synthetic 16-byte records (key, value) through a windowed
+// group-by-sum under a fixed memory budget. When the in-memory aggregation
+// table reaches the budget it is spilled to disk as a sorted run; at the end
+// of each window the runs are merged back. Reports stream throughput and
+// peak RSS, matching TML's bounded-memory streaming comparison.
+
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+const (
+	batchStreamBytes   = 10 << 30
+	batchRecordSize    = 16
+	batchWindowRecords = 64 << 20
+	batchDistinctKeys  = 4 << 20
+	// batchEntryBytes approximates one map entry including bucket overhead
+	batchEntryBytes = 48
+)
+
+// recordStream generates deterministic (key, value) records
+type recordStream struct {
+	remaining int64
+	state     uint64
+}
+
+func newRecordStream(size int64) *recordStream {
+	return &recordStream{remaining: size, state: 0x9E3779B97F4A7C15}
+}
+
+// Read fills p with whole records
+func (s *recordStream) Read(p []byte) (int, error) {
+	if s.remaining == 0 {
+		return 0, io.EOF
+	}
+	n := len(p) / batchRecordSize * batchRecordSize
+	if int64(n) > s.remaining {
+		n = int(s.remaining)
+	}
+	for off := 0; off < n; off += batchRecordSize {
+		// xorshift64*
+		s.state ^= s.state >> 12
+		s.state ^= s.state << 25
+		s.state ^= s.state >> 27
+		v := s.state * 2685821657736338717
+		binary.LittleEndian.PutUint64(p[off:], v%batchDistinctKeys)
+		binary.LittleEndian.PutUint64(p[off+8:], v>>40)
+	}
+	s.remaining -= int64(n)
+	return n, nil
+}
+
+// windowAggregator sums values per key within a window, spilling sorted
+// runs to dir whenever the table reaches maxEntries
+type windowAggregator struct {
+	dir        string
+	maxEntries int
+	table      map[uint64]uint64
+	runs       []string
+	spilled    int64
+}
+
+func (a *windowAggregator) add(key, value uint64) error {
+	if _, ok := a.table[key]; !ok && len(a.table) >= a.maxEntries {
+		if err := a.spill(); err != nil {
+			return err
+		}
+	}
+	a.table[key] += value
+	return nil
+}
+
+// spill writes the table as a run sorted by key and clears it
+func (a *windowAggregator) spill() error {
+	keys := make([]uint64, 0, len(a.table))
+	for k := range a.table {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	path := filepath.Join(a.dir, "run-"+strconv.Itoa(len(a.runs)))
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriterSize(f, 1<<20)
+	var rec [batchRecordSize]byte
+	for _, k := range keys {
+		binary.LittleEndian.PutUint64(rec[:], k)
+		binary.LittleEndian.PutUint64(rec[8:], a.table[k])
+		w.Write(rec[:])
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	a.spilled += int64(len(keys) * batchRecordSize)
+	a.runs = append(a.runs, path)
+	clear(a.table)
+	return f.Close()
+}
+
+// runReader is one sorted spill run being merged
+type runReader struct {
+	r        *bufio.Reader
+	f        *os.File
+	key, sum uint64
+	rec      [batchRecordSize]byte
+}
+
+func (rr *runReader) next() bool {
+	if _, err := io.ReadFull(rr.r, rr.rec[:]); err != nil {
+		return false
+	}
+	rr.key = binary.LittleEndian.Uint64(rr.rec[:])
+	rr.sum = binary.LittleEndian.Uint64(rr.rec[8:])
+	return true
+}
+
+type runHeap []*runReader
+
+func (h runHeap) Len() int            { return len(h) }
+func (h runHeap) Less(i, j int) bool  { return h[i].key < h[j].key }
+func (h runHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(*runReader)) }
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// flush closes the window, returning the number of distinct keys and a
+// checksum over all per-key sums
+func (a *windowAggregator) flush() (keys int, checksum uint64, err error) {
+	if len(a.runs) == 0 {
+		for k, v := range a.table {
+			checksum += k ^ v
+		}
+		keys = len(a.table)
+		clear(a.table)
+		return keys, checksum, nil
+	}
+	if len(a.table) > 0 {
+		if err := a.spill(); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	h := runHeap{}
+	for _, path := range a.runs {
+		f, err := os.Open(path)
+		if err != nil {
+			return 0, 0, err
+		}
+		rr := &runReader{r: bufio.NewReaderSize(f, 256<<10), f: f}
+		if rr.next() {
+			h = append(h, rr)
+		} else {
+			f.Close()
+		}
+	}
+	heap.Init(&h)
+	for h.Len() > 0 {
+		key, sum := h[0].key, uint64(0)
+		for h.Len() > 0 && h[0].key == key {
+			sum += h[0].sum
+			if h[0].next() {
+				heap.Fix(&h, 0)
+			} else {
+				h[0].f.Close()
+				heap.Pop(&h)
+			}
+		}
+		keys++
+		checksum += key ^ sum
+	}
+	for _, path := range a.runs {
+		os.Remove(path)
+	}
+	a.runs = a.runs[:0]
+	return keys, checksum, nil
+}
+
+// batchSink keeps results observable so work is not optimized away
+var batchSink uint64
+
+func init() {
+	Register(Benchmark{
+		Name: "BatchBoundedMemory", Category: "batch", Tags: []string{"cpu", "alloc", "large"},
+		Iterations: 1, DataSize: batchStreamBytes,
+		Axes: []Axis{{Name: "budget_mb", Values: Ints(16, 64, 256)}},
+		Fn: func(b *B) {
+			dir, err := os.MkdirTemp("", "tml-batch-")
+			if err != nil {
+				b.Fatal(err)
+				return
+			}
+			defer os.RemoveAll(dir)
+
+			budget := b.IntParam("budget_mb") << 20
+			agg := &windowAggregator{
+				dir:        dir,
+				maxEntries: budget / batchEntryBytes,
+				table:      make(map[uint64]uint64),
+			}
+			resetPeakRSS()
+
+			r := bufio.NewReaderSize(newRecordStream(batchStreamBytes), 1<<20)
+			var rec [batchRecordSize]byte
+			inWindow := 0
+			for {
+				if _, err := io.ReadFull(r, rec[:]); err != nil {
+					break
+				}
+				if err := agg.add(binary.LittleEndian.Uint64(rec[:]), binary.LittleEndian.Uint64(rec[8:])); err != nil {
+					b.Fatal(err)
+					return
+				}
+				if inWindow++; inWindow == batchWindowRecords {
+					_, sum, err := agg.flush()
+					if err != nil {
+						b.Fatal(err)
+						return
+					}
+					batchSink += sum
+					inWindow = 0
+				}
+			}
+			if _, sum, err := agg.flush(); err != nil {
+				b.Fatal(err)
+			} else {
+				batchSink += sum
+			}
+
+			b.ReportMetric("peak_rss_mb", float64(peakRSS())/(1<<20))
+			b.ReportMetric("spilled_mb", float64(agg.spilled)/(1<<20))
+		},
+	})
+}
@@ -0,0 +1,227 @@
+// Regression Bisect - Go
+//
+// Finds the commit that made a benchmark slower. Given a known-good and a
+// known-bad commit, bisect checks each candidate out into a temporary git
+// worktree, builds the Go suite there and runs just the named benchmark
+// (with that commit's own configuration and golden fixture), then binary
+// searches the first-parent history between the two. A commit is bad when
+// any case of the benchmark is more than -threshold percent slower than on
+// the good commit, as -compare-baseline would flag it.
+//
+// Each step rebuilds and reruns the benchmark, so pick a benchmark and
+// threshold that separate noise from the regression; -runs takes the best
+// of several runs per commit to steady the decision.
+//
+// Run with: go run . bisect -bench TcpRequestSweep -good v0.4.0 [-bad HEAD] [-threshold 10] [-runs 1]
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// runBisect implements the bisect subcommand
+func runBisect(args []string) int {
+	fs := flag.NewFlagSet("bisect", flag.ExitOnError)
+	bench := fs.String("bench", "", "benchmark to bisect, matched by name as compare matches names")
+	good := fs.String("good", "", "commit on which the benchmark was fast")
+	bad := fs.String("bad", "HEAD", "commit on which the benchmark is slow")
+	threshold := fs.Float64("threshold", 10, "percent slowdown from the good commit that makes a commit bad")
+	runs := fs.Int("runs", 1, "runs per commit, keeping each case's fastest")
+	fs.Parse(args)
+	if *bench == "" || *good == "" || *runs < 1 {
+		fmt.Fprintln(os.Stderr, "usage: bisect -bench name -good commit [-bad commit] [-threshold 10] [-runs 1]")
+		return 2
+	}
+
+	top, err := git("", "rev-parse", "--show-toplevel")
+	if err == nil {
+		var prefix string
+		if prefix, err = git("", "rev-parse", "--show-prefix"); err == nil {
+			err = bisectRange(bisector{repo: top, suiteDir: prefix, bench: *bench, threshold: *threshold, runs: *runs}, *good, *bad)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// bisector measures the benchmark at commits of one repository
+type bisector struct {
+	repo      string
+	suiteDir  string // the Go suite's directory, relative to repo
+	bench     string
+	threshold float64
+	runs      int
+}
+
+// bisectRange finds and prints the first bad commit between good and bad
+func bisectRange(s bisector, good, bad string) error {
+	list, err := git(s.repo, "rev-list", "--reverse", "--first-parent", good+".."+bad)
+	if err != nil {
+		return err
+	}
+	commits := strings.Fields(list)
+	if len(commits) == 0 {
+		return fmt.Errorf("no commits between %s and %s", good, bad)
+	}
+
+	fmt.Fprintf(os.Stderr, "bisect: measuring good commit %s\n", good)
+	baseline, err := s.measure(good)
+	if err != nil {
+		return fmt.Errorf("good commit %s: %w", good, err)
+	}
+	if len(baseline) == 0 {
+		return fmt.Errorf("good commit %s has no benchmark matching %q", good, s.bench)
+	}
+
+	step := 0
+	regressed := func(i int) (bool, error) {
+		step++
+		fmt.Fprintf(os.Stderr, "bisect: step %d, commit %.12s\n", step, commits[i])
+		results, err := s.measure(commits[i])
+		if err != nil {
+			return false, fmt.Errorf("commit %.12s: %w", commits[i], err)
+		}
+		deltas := CompareResults(baseline, results, s.threshold)
+		if len(deltas) == 0 {
+			return false, fmt.Errorf("commit %.12s has no benchmark matching %q", commits[i], s.bench)
+		}
+		bad := false
+		for _, d := range deltas {
+			flag := ""
+			if d.Regression {
+				flag, bad = "  REGRESSION", true
+			}
+			fmt.Fprintf(os.Stderr, "  %-40s %11.2f us %+9.1f%%%s\n", d.Name, d.CurrentUs, d.Percent, flag)
+		}
+		return bad, nil
+	}
+	first, err := firstRegression(len(commits), regressed)
+	if err != nil {
+		return err
+	}
+	if first < 0 {
+		fmt.Printf("%s is not more than %.0f%% slower at %s than at %s\n", s.bench, s.threshold, bad, good)
+		return nil
+	}
+	subject, err := git(s.repo, "log", "-1", "--format=%h %s", commits[first])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("first bad commit: %s\n", subject)
+	return nil
+}
+
+// firstRegression binary searches commits 0..n-1, each newer than the last
+// and all newer than the good commit, for the first one regressed reports.
+// The last commit is tested first; when it has not regressed the result is
+// -1. Assumes a regression, once introduced, stays.
+func firstRegression(n int, regressed func(i int) (bool, error)) (int, error) {
+	ok, err := regressed(n - 1)
+	if err != nil || !ok {
+		return -1, err
+	}
+	// Invariant: lo is good (or the good commit at -1), hi is bad
+	lo, hi := -1, n-1
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		ok, err := regressed(mid)
+		if err != nil {
+			return -1, err
+		}
+		if ok {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi, nil
+}
+
+// measure builds the suite at commit in a temporary worktree and returns
+// the benchmark's results, each case at its fastest over s.runs
+func (s bisector) measure(commit string) ([]BenchmarkResult, error) {
+	dir, err := os.MkdirTemp("", "tml-bisect-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tree := filepath.Join(dir, "tree")
+	if _, err := git(s.repo, "worktree", "add", "--detach", tree, commit); err != nil {
+		return nil, err
+	}
+	defer git(s.repo, "worktree", "remove", "--force", tree)
+
+	suite := filepath.Join(tree, s.suiteDir)
+	bin := filepath.Join(dir, "bench")
+	build := exec.Command("go", "build", "-o", bin, ".")
+	build.Dir = suite
+	build.Stdout, build.Stderr = io.Discard, os.Stderr
+	if err := build.Run(); err != nil {
+		return nil, fmt.Errorf("build: %w", err)
+	}
+
+	var best []BenchmarkResult
+	for run := 0; run < s.runs; run++ {
+		resultsPath := filepath.Join(dir, fmt.Sprintf("results-%d.json", run))
+		cmd := exec.Command(bin, "-run", benchNamePattern(s.bench), "-o", resultsPath, "-quiet")
+		cmd.Dir = suite
+		cmd.Stdout, cmd.Stderr = io.Discard, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return nil, err
+		}
+		set, err := LoadResultSet(resultsPath)
+		if err != nil {
+			return nil, err
+		}
+		best = fastestResults(best, set.Results)
+	}
+	return best, nil
+}
+
+// fastestResults merges results into best, keeping each case's lowest time
+func fastestResults(best, results []BenchmarkResult) []BenchmarkResult {
+	if best == nil {
+		return results
+	}
+	index := make(map[string]int, len(best))
+	for i, r := range best {
+		index[r.Name] = i
+	}
+	for _, r := range results {
+		i, ok := index[r.Name]
+		if !ok {
+			continue
+		}
+		if r.TimeUs < best[i].TimeUs {
+			best[i] = r
+		}
+	}
+	return best
+}
+
+// git runs a git command in dir (the working directory when empty) and
+// returns its trimmed output
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) && len(exit.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(exit.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
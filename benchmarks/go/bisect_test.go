@@ -0,0 +1,38 @@
+// Bisect Tests - Go
+//
+// Run with: go test -run 'FirstRegression|FastestResults'
+
+package main
+
+import "testing"
+
+func TestFirstRegression(t *testing.T) {
+	for _, tc := range []struct {
+		n, first int
+	}{
+		{1, 0}, {1, -1}, {2, 0}, {2, 1}, {7, 0}, {7, 3}, {7, 6}, {7, -1}, {64, 37},
+	} {
+		tested := map[int]bool{}
+		got, err := firstRegression(tc.n, func(i int) (bool, error) {
+			if tested[i] {
+				t.Errorf("n=%d: commit %d tested twice", tc.n, i)
+			}
+			tested[i] = true
+			return tc.first >= 0 && i >= tc.first, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.first {
+			t.Errorf("n=%d: first regression %d, want %d", tc.n, got, tc.first)
+		}
+	}
+}
+
+func TestFastestResults(t *testing.T) {
+	best := fastestResults(nil, []BenchmarkResult{{Name: "a", TimeUs: 5}, {Name: "b", TimeUs: 2}})
+	best = fastestResults(best, []BenchmarkResult{{Name: "a", TimeUs: 3}, {Name: "b", TimeUs: 4}, {Name: "c", TimeUs: 1}})
+	if len(best) != 2 || best[0].TimeUs != 3 || best[1].TimeUs != 2 {
+		t.Errorf("fastest results %+v, want a=3 b=2", best)
+	}
+}
@@ -0,0 +1,238 @@
+// Bit Manipulation Benchmarks - Go
+//
+// Baseline for TML's bit intrinsics, using math/bits over a 512M-bit set:
+//
+//   - popcount of the whole set, with bits.OnesCount64 (POPCNT where the
+//     CPU has it) against the portable SWAR reduction
+//   - bit reversal of every word
+//   - iterating the set bits of a sparse set forward with trailing-zero
+//     counts and backward with leading-zero counts
+//   - Morton (Z-order) encoding and decoding of 10M coordinate pairs, with
+//     magic-mask bit spreading against a bit-by-bit loop
+
+package main
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+const (
+	bitsetWords  = 1 << 23
+	mortonPoints = 10_000_000
+)
+
+// popCountSWAR counts set bits without a hardware instruction (Hacker's
+// Delight 5-2)
+func popCountSWAR(x uint64) int {
+	x -= (x >> 1) & 0x5555555555555555
+	x = (x & 0x3333333333333333) + ((x >> 2) & 0x3333333333333333)
+	x = (x + (x >> 4)) & 0x0f0f0f0f0f0f0f0f
+	return int((x * 0x0101010101010101) >> 56)
+}
+
+// popCountBitset counts the set bits of words
+func popCountBitset(words []uint64, swar bool) int {
+	n := 0
+	if swar {
+		for _, w := range words {
+			n += popCountSWAR(w)
+		}
+		return n
+	}
+	for _, w := range words {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// scanForward visits every set bit in ascending order and returns how many
+// there are and a position-weighted checksum of the visiting order
+func scanForward(words []uint64) (count, checksum int) {
+	for wi, w := range words {
+		for w != 0 {
+			count++
+			checksum += count * (wi*64 + bits.TrailingZeros64(w))
+			w &= w - 1
+		}
+	}
+	return count, checksum
+}
+
+// scanBackward visits every set bit in descending order; its checksum
+// weights the positions from the end, so it matches scanForward's
+func scanBackward(words []uint64, total int) (count, checksum int) {
+	for wi := len(words) - 1; wi >= 0; wi-- {
+		w := words[wi]
+		for w != 0 {
+			top := 63 - bits.LeadingZeros64(w)
+			checksum += (total - count) * (wi*64 + top)
+			count++
+			w &^= 1 << top
+		}
+	}
+	return count, checksum
+}
+
+// mortonEncode interleaves x and y into a Z-order code, x in the even bits
+func mortonEncode(x, y uint32) uint64 {
+	return spreadBits(uint64(x)) | spreadBits(uint64(y))<<1
+}
+
+// mortonDecode inverts mortonEncode
+func mortonDecode(code uint64) (x, y uint32) {
+	return uint32(squashBits(code)), uint32(squashBits(code >> 1))
+}
+
+// mortonEncodeLoop is mortonEncode one bit at a time
+func mortonEncodeLoop(x, y uint32) uint64 {
+	var code uint64
+	for i := 0; i < 32; i++ {
+		code |= uint64(x>>i&1)<<(2*i) | uint64(y>>i&1)<<(2*i+1)
+	}
+	return code
+}
+
+// mortonDecodeLoop is mortonDecode one bit at a time
+func mortonDecodeLoop(code uint64) (x, y uint32) {
+	for i := 0; i < 32; i++ {
+		x |= uint32(code>>(2*i)&1) << i
+		y |= uint32(code>>(2*i+1)&1) << i
+	}
+	return x, y
+}
+
+// bitsState holds the fixtures of whichever bit benchmark is running
+var bitsState struct {
+	words    []uint64
+	out      []uint64
+	xs, ys   []uint32
+	codes    []uint64
+	setBits  int
+	checksum int
+}
+
+var bitsSink uint64
+
+func setupDenseBitset(b *B) error {
+	rng := newRand(1532)
+	bitsState.words = make([]uint64, bitsetWords)
+	for i := range bitsState.words {
+		bitsState.words[i] = rng.Uint64()
+	}
+	bitsState.out = make([]uint64, bitsetWords)
+	return nil
+}
+
+// setupSparseBitset sets about one bit in 64, so scans visit ~8M bits
+func setupSparseBitset(b *B) error {
+	rng := newRand(15322)
+	s := &bitsState
+	s.words = make([]uint64, bitsetWords)
+	for i := range s.words {
+		s.words[i] = rng.Uint64() & rng.Uint64() & rng.Uint64() & rng.Uint64() & rng.Uint64() & rng.Uint64()
+	}
+	s.setBits, s.checksum = scanForward(s.words)
+	return nil
+}
+
+func setupMorton(b *B) error {
+	rng := newRand(15323)
+	s := &bitsState
+	s.xs = make([]uint32, mortonPoints)
+	s.ys = make([]uint32, mortonPoints)
+	s.codes = make([]uint64, mortonPoints)
+	for i := range s.xs {
+		s.xs[i], s.ys[i] = rng.Uint32(), rng.Uint32()
+		s.codes[i] = mortonEncode(s.xs[i], s.ys[i])
+	}
+	return nil
+}
+
+func teardownBits() {
+	s := &bitsState
+	s.words, s.out, s.xs, s.ys, s.codes = nil, nil, nil, nil, nil
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "BitsPopCount", Category: "bits", Tags: []string{"cpu"},
+		Iterations: 20, DataSize: 8 * bitsetWords,
+		Axes:  []Axis{{Name: "impl", Values: Strings("intrinsic", "swar")}},
+		Setup: setupDenseBitset, Teardown: teardownBits,
+		Fn: func(b *B) {
+			bitsSink = uint64(popCountBitset(bitsState.words, b.StringParam("impl") == "swar"))
+		},
+	})
+	Register(Benchmark{
+		Name: "BitsReverse", Category: "bits", Tags: []string{"cpu"},
+		Iterations: 20, DataSize: 8 * bitsetWords,
+		Setup: setupDenseBitset, Teardown: teardownBits,
+		Fn: func(b *B) {
+			s := &bitsState
+			for i, w := range s.words {
+				s.out[i] = bits.Reverse64(w)
+			}
+			bitsSink = s.out[len(s.out)-1]
+		},
+	})
+	Register(Benchmark{
+		Name: "BitsScan", Category: "bits", Tags: []string{"cpu"},
+		Iterations: 20, DataSize: 8 * bitsetWords,
+		Axes:  []Axis{{Name: "direction", Values: Strings("forward", "backward")}},
+		Setup: setupSparseBitset, Teardown: teardownBits,
+		Fn: func(b *B) {
+			s := &bitsState
+			var count, checksum int
+			if b.StringParam("direction") == "forward" {
+				count, checksum = scanForward(s.words)
+			} else {
+				count, checksum = scanBackward(s.words, s.setBits)
+			}
+			if count != s.setBits || checksum != s.checksum {
+				b.Fatal(fmt.Errorf("visited %d set bits (checksum %d), want %d (%d)", count, checksum, s.setBits, s.checksum))
+				return
+			}
+			bitsSink = uint64(checksum)
+			b.ReportMetric("set_bits", float64(count))
+		},
+	})
+	Register(Benchmark{
+		Name: "MortonEncode", Category: "bits", Tags: []string{"cpu"},
+		Iterations: 20, DataSize: 8 * mortonPoints,
+		Axes:  []Axis{{Name: "method", Values: Strings("magic", "loop")}},
+		Setup: setupMorton, Teardown: teardownBits,
+		Fn: func(b *B) {
+			s := &bitsState
+			if b.StringParam("method") == "magic" {
+				for i := range s.codes {
+					s.codes[i] = mortonEncode(s.xs[i], s.ys[i])
+				}
+			} else {
+				for i := range s.codes {
+					s.codes[i] = mortonEncodeLoop(s.xs[i], s.ys[i])
+				}
+			}
+			bitsSink = s.codes[len(s.codes)-1]
+		},
+	})
+	Register(Benchmark{
+		Name: "MortonDecode", Category: "bits", Tags: []string{"cpu"},
+		Iterations: 20, DataSize: 8 * mortonPoints,
+		Axes:  []Axis{{Name: "method", Values: Strings("magic", "loop")}},
+		Setup: setupMorton, Teardown: teardownBits,
+		Fn: func(b *B) {
+			s := &bitsState
+			if b.StringParam("method") == "magic" {
+				for i, code := range s.codes {
+					s.xs[i], s.ys[i] = mortonDecode(code)
+				}
+			} else {
+				for i, code := range s.codes {
+					s.xs[i], s.ys[i] = mortonDecodeLoop(code)
+				}
+			}
+			bitsSink = uint64(s.xs[len(s.xs)-1])
+		},
+	})
+}
@@ -0,0 +1,58 @@
+// Bit Manipulation Tests - Go
+//
+// Run with: go test -run 'PopCount|Scan|Morton'
+
+package main
+
+import (
+	"math/bits"
+	"testing"
+)
+
+func TestPopCountSWAR(t *testing.T) {
+	rng := newRand(1)
+	for _, x := range []uint64{0, 1, 1 << 63, ^uint64(0), 0x5555555555555555} {
+		if got, want := popCountSWAR(x), bits.OnesCount64(x); got != want {
+			t.Errorf("popCountSWAR(%#x) = %d, want %d", x, got, want)
+		}
+	}
+	words := make([]uint64, 1000)
+	for i := range words {
+		words[i] = rng.Uint64()
+	}
+	if got, want := popCountBitset(words, true), popCountBitset(words, false); got != want {
+		t.Errorf("SWAR popcount %d, intrinsic %d", got, want)
+	}
+}
+
+func TestScanDirectionsAgree(t *testing.T) {
+	words := []uint64{0b1011, 0, 1 << 63, 1}
+	count, checksum := scanForward(words)
+	// Set bits 0, 1, 3, 191, 192 weighted by their 1-based rank
+	if want := 1*0 + 2*1 + 3*3 + 4*191 + 5*192; count != 5 || checksum != want {
+		t.Errorf("scanForward = %d, %d, want 5, %d", count, checksum, want)
+	}
+	if bc, bs := scanBackward(words, count); bc != count || bs != checksum {
+		t.Errorf("scanBackward = %d, %d, want %d, %d", bc, bs, count, checksum)
+	}
+}
+
+func TestMortonCodes(t *testing.T) {
+	if got := mortonEncode(0b11, 0b01); got != 0b0111 {
+		t.Errorf("mortonEncode(3, 1) = %#b, want 0b111", got)
+	}
+	rng := newRand(2)
+	for i := 0; i < 1000; i++ {
+		x, y := rng.Uint32(), rng.Uint32()
+		code := mortonEncode(x, y)
+		if loop := mortonEncodeLoop(x, y); loop != code {
+			t.Fatalf("mortonEncodeLoop(%d, %d) = %#x, want %#x", x, y, loop, code)
+		}
+		if dx, dy := mortonDecode(code); dx != x || dy != y {
+			t.Fatalf("mortonDecode(%#x) = %d, %d, want %d, %d", code, dx, dy, x, y)
+		}
+		if dx, dy := mortonDecodeLoop(code); dx != x || dy != y {
+			t.Fatalf("mortonDecodeLoop(%#x) = %d, %d, want %d, %d", code, dx, dy, x, y)
+		}
+	}
+}
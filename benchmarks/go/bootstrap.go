@@ -0,0 +1,62 @@
+// Bootstrap Confidence Intervals - Go
+//
+// Percentile bootstrap of the ratio between the mean iteration times of two
+// sets of raw samples (see rawsamples.go): both sets are resampled with
+// replacement bootstrapResamples times, and the middle 95% of the resampled
+// ratios is the confidence interval. A ratio whose interval excludes 1 is
+// significant; one whose interval straddles 1 is within the noise of the
+// runs, however large the point estimate looks.
+
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const (
+	bootstrapResamples  = 2000
+	bootstrapConfidence = 0.95
+)
+
+// RatioCI is a confidence interval for a ratio of mean times
+type RatioCI struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+// Significant reports whether the interval excludes a ratio of 1
+func (ci RatioCI) Significant() bool {
+	return ci.Low > 1 || ci.High < 1
+}
+
+// resampledMean returns the mean of len(samples) draws with replacement
+func resampledMean(samples []time.Duration, rng *rand.Rand) float64 {
+	var sum float64
+	for range samples {
+		sum += float64(samples[rng.Intn(len(samples))])
+	}
+	return sum / float64(len(samples))
+}
+
+// bootstrapRatioCI estimates the interval of mean(num) / mean(den); ok is
+// false if either side has no samples or a resampled mean of den is zero
+func bootstrapRatioCI(num, den []time.Duration, rng *rand.Rand) (ci RatioCI, ok bool) {
+	if len(num) == 0 || len(den) == 0 {
+		return RatioCI{}, false
+	}
+	ratios := make([]float64, bootstrapResamples)
+	for i := range ratios {
+		d := resampledMean(den, rng)
+		if d == 0 {
+			return RatioCI{}, false
+		}
+		ratios[i] = resampledMean(num, rng) / d
+	}
+	sort.Float64s(ratios)
+	tail := (1 - bootstrapConfidence) / 2
+	low := int(tail * float64(len(ratios)))
+	high := min(int((1-tail)*float64(len(ratios))), len(ratios)-1)
+	return RatioCI{Low: ratios[low], High: ratios[high]}, true
+}
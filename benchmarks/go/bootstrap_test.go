@@ -0,0 +1,66 @@
+// Bootstrap Confidence Interval Tests - Go
+//
+// Run with: go test -run 'Bootstrap|Significance'
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// noisySamples returns n samples around mean with +-10% uniform noise
+func noisySamples(n int, mean time.Duration, seed int64) []time.Duration {
+	rng := newRand(seed)
+	out := make([]time.Duration, n)
+	for i := range out {
+		out[i] = mean + time.Duration((rng.Float64()-0.5)*0.2*float64(mean))
+	}
+	return out
+}
+
+func TestBootstrapRatioCI(t *testing.T) {
+	base := noisySamples(200, time.Millisecond, 1)
+	same := noisySamples(200, time.Millisecond, 2)
+	double := noisySamples(200, 2*time.Millisecond, 3)
+
+	ci, ok := bootstrapRatioCI(same, base, newRand(4))
+	if !ok || ci.Significant() || ci.Low > 1 || ci.High < 1 {
+		t.Errorf("same distribution: %+v ok %v, want an interval around 1", ci, ok)
+	}
+	ci, ok = bootstrapRatioCI(double, base, newRand(4))
+	if !ok || !ci.Significant() || ci.Low < 1.9 || ci.High > 2.1 {
+		t.Errorf("double: %+v ok %v, want a significant interval around 2", ci, ok)
+	}
+	if _, ok := bootstrapRatioCI(nil, base, newRand(4)); ok {
+		t.Error("no samples should give no interval")
+	}
+}
+
+func TestAnnotateSignificance(t *testing.T) {
+	comparisons := []Comparison{
+		{Name: "Noise", Ratio: 1.3, Winner: "go"},
+		{Name: "Real", Ratio: 2, Winner: "go"},
+		{Name: "NoSamples", Ratio: 3, Winner: "go"},
+	}
+	// Few, very noisy samples: a 1.3x point estimate that isn't significant
+	noisy := []time.Duration{100, 2000, 300, 1500}
+	goSamples := map[string][]time.Duration{
+		"Noise": noisy,
+		"Real":  noisySamples(100, time.Millisecond, 1),
+	}
+	tmlSamples := map[string][]time.Duration{
+		"noise": {130, 2600, 390, 1950},
+		"real":  noisySamples(100, 2*time.Millisecond, 2),
+	}
+	AnnotateSignificance(comparisons, goSamples, tmlSamples)
+	if c := comparisons[0]; c.CI == nil || c.CI.Significant() || c.Winner != "" {
+		t.Errorf("Noise: CI %v winner %q, want an insignificant interval and no winner", c.CI, c.Winner)
+	}
+	if c := comparisons[1]; c.CI == nil || !c.CI.Significant() || c.Winner != "go" {
+		t.Errorf("Real: CI %v winner %q, want a significant Go win", c.CI, c.Winner)
+	}
+	if c := comparisons[2]; c.CI != nil || c.Winner != "go" {
+		t.Errorf("NoSamples: CI %v winner %q, want it left alone", c.CI, c.Winner)
+	}
+}
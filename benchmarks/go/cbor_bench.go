@@ -0,0 +1,615 @@
+// CBOR Benchmarks - Go
+//
+// CBOR (RFC 8949) encoding and decoding of the JSON fixtures, the Go
+// reference number for TML's CBOR wire format: CborParse<Size> and
+// CborMarshal<Size> decode and encode the small, medium and large
+// fixtures from and into the same structs as the typed JSON benchmarks
+// (json_typed_bench.go), each document a map keyed by the JSON keys.
+//
+// The suite has no third-party dependencies, so as with protobuf
+// (proto_bench.go) the codec is written by hand the way a code generator
+// would. The encoder produces the core deterministic encoding (RFC 8949
+// section 4.2.1) every suite can reproduce byte for byte: shortest-form
+// heads, definite lengths, and map keys in bytewise order of their
+// encodings - shorter keys first. Zero ages are left out as the JSON
+// fixtures leave them out. The decoder accepts any well-formed CBOR for
+// the same data: keys in any order, indefinite lengths, longer heads, and
+// unknown keys, whose values it skips.
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf8"
+)
+
+// CBOR major types
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+)
+
+const (
+	cborFalse = 0xf4
+	cborTrue  = 0xf5
+	cborBreak = 0xff
+	// cborMaxDepth bounds the nesting of skipped items
+	cborMaxDepth = 32
+)
+
+var errCBORTruncated = errors.New("cbor: truncated item")
+
+// appendCBORHead appends the shortest head for major type major and
+// argument n
+func appendCBORHead(dst []byte, major byte, n uint64) []byte {
+	m := major << 5
+	switch {
+	case n < 24:
+		return append(dst, m|byte(n))
+	case n <= math.MaxUint8:
+		return append(dst, m|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(dst, m|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(dst, m|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(dst, m|27), n)
+	}
+}
+
+func appendCBORText(dst []byte, s string) []byte {
+	return append(appendCBORHead(dst, cborText, uint64(len(s))), s...)
+}
+
+func appendCBORInt(dst []byte, v int) []byte {
+	if v < 0 {
+		return appendCBORHead(dst, cborNegInt, uint64(-1-v))
+	}
+	return appendCBORHead(dst, cborUint, uint64(v))
+}
+
+func appendCBORBool(dst []byte, v bool) []byte {
+	if v {
+		return append(dst, cborTrue)
+	}
+	return append(dst, cborFalse)
+}
+
+// cborDecoder reads items from an encoded document
+type cborDecoder struct {
+	b []byte
+}
+
+// head reads an item's head: its major type, its argument and whether its
+// length is indefinite
+func (d *cborDecoder) head() (major byte, arg uint64, indefinite bool, err error) {
+	if len(d.b) == 0 {
+		return 0, 0, false, errCBORTruncated
+	}
+	major, info := d.b[0]>>5, d.b[0]&0x1f
+	d.b = d.b[1:]
+	var n int
+	switch {
+	case info < 24:
+		return major, uint64(info), false, nil
+	case info == 31:
+		if major < cborBytes || major == cborTag || major == cborSimple {
+			// A break outside an indefinite container included
+			return 0, 0, false, fmt.Errorf("cbor: major type %d cannot be indefinite", major)
+		}
+		return major, 0, true, nil
+	case info > 27:
+		return 0, 0, false, fmt.Errorf("cbor: reserved additional information %d", info)
+	default:
+		n = 1 << (info - 24)
+	}
+	if len(d.b) < n {
+		return 0, 0, false, errCBORTruncated
+	}
+	for _, c := range d.b[:n] {
+		arg = arg<<8 | uint64(c)
+	}
+	d.b = d.b[n:]
+	return major, arg, false, nil
+}
+
+// cborContainer counts down the entries of an array or map
+type cborContainer struct {
+	n          uint64
+	indefinite bool
+}
+
+// next reports whether c has another entry, consuming the break that ends
+// an indefinite container
+func (d *cborDecoder) next(c *cborContainer) bool {
+	if c.indefinite {
+		if len(d.b) > 0 && d.b[0] == cborBreak {
+			d.b = d.b[1:]
+			return false
+		}
+		return true
+	}
+	if c.n == 0 {
+		return false
+	}
+	c.n--
+	return true
+}
+
+// container reads the head of an array or map
+func (d *cborDecoder) container(major byte) (cborContainer, error) {
+	m, n, indefinite, err := d.head()
+	if err != nil {
+		return cborContainer{}, err
+	}
+	if m != major {
+		return cborContainer{}, fmt.Errorf("cbor: want major type %d, got %d", major, m)
+	}
+	if !indefinite && n > uint64(len(d.b)) {
+		// Every entry takes at least a byte
+		return cborContainer{}, errCBORTruncated
+	}
+	return cborContainer{n: n, indefinite: indefinite}, nil
+}
+
+// textBytes reads a text string, aliasing the document unless it is
+// split into indefinite-length chunks
+func (d *cborDecoder) textBytes() ([]byte, error) {
+	major, n, indefinite, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if major != cborText {
+		return nil, fmt.Errorf("cbor: want a text string, got major type %d", major)
+	}
+	if indefinite {
+		var text []byte
+		for len(d.b) == 0 || d.b[0] != cborBreak {
+			chunk, err := d.definiteText()
+			if err != nil {
+				return nil, err
+			}
+			text = append(text, chunk...)
+		}
+		d.b = d.b[1:]
+		return text, nil
+	}
+	return d.take(n)
+}
+
+// definiteText reads a definite-length text string, as the chunks of an
+// indefinite one must be
+func (d *cborDecoder) definiteText() ([]byte, error) {
+	major, n, indefinite, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if major != cborText || indefinite {
+		return nil, errors.New("cbor: indefinite text string chunk is not a definite text string")
+	}
+	return d.take(n)
+}
+
+func (d *cborDecoder) take(n uint64) ([]byte, error) {
+	if n > uint64(len(d.b)) {
+		return nil, errCBORTruncated
+	}
+	v := d.b[:n]
+	d.b = d.b[n:]
+	return v, nil
+}
+
+func (d *cborDecoder) text() (string, error) {
+	b, err := d.textBytes()
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(b) {
+		return "", errors.New("cbor: text string is not valid UTF-8")
+	}
+	return string(b), nil
+}
+
+func (d *cborDecoder) int() (int, error) {
+	major, n, _, err := d.head()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case major == cborUint && n <= math.MaxInt64:
+		return int(n), nil
+	case major == cborNegInt && n <= math.MaxInt64:
+		return -1 - int(n), nil
+	case major == cborUint || major == cborNegInt:
+		return 0, errors.New("cbor: integer overflows int64")
+	}
+	return 0, fmt.Errorf("cbor: want an integer, got major type %d", major)
+}
+
+func (d *cborDecoder) bool() (bool, error) {
+	if len(d.b) == 0 {
+		return false, errCBORTruncated
+	}
+	c := d.b[0]
+	if c != cborFalse && c != cborTrue {
+		return false, fmt.Errorf("cbor: want a boolean, got %#x", c)
+	}
+	d.b = d.b[1:]
+	return c == cborTrue, nil
+}
+
+// skip skips one item of any type, such as the value of an unknown key
+func (d *cborDecoder) skip() error {
+	return d.skipDepth(0)
+}
+
+func (d *cborDecoder) skipDepth(depth int) error {
+	if depth > cborMaxDepth {
+		return errors.New("cbor: items nested too deeply")
+	}
+	major, n, indefinite, err := d.head()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case cborBytes, cborText:
+		if indefinite {
+			for len(d.b) == 0 || d.b[0] != cborBreak {
+				m, n, indefinite, err := d.head()
+				if err != nil {
+					return err
+				}
+				if m != major || indefinite {
+					return errors.New("cbor: indefinite string chunk is not a definite string of its type")
+				}
+				if _, err := d.take(n); err != nil {
+					return err
+				}
+			}
+			d.b = d.b[1:]
+			return nil
+		}
+		_, err = d.take(n)
+		return err
+	case cborArray, cborMap:
+		if major == cborMap && !indefinite {
+			if n > math.MaxUint64/2 {
+				return errCBORTruncated
+			}
+			n *= 2
+		}
+		c := cborContainer{n: n, indefinite: indefinite}
+		for d.next(&c) {
+			if err := d.skipDepth(depth + 1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case cborTag:
+		return d.skipDepth(depth + 1)
+	}
+	// Integers, simple values and floats are all head
+	return nil
+}
+
+// Address
+
+func (a *jsonAddress) appendCBOR(dst []byte) []byte {
+	dst = appendCBORHead(dst, cborMap, 3)
+	dst = appendCBORText(appendCBORText(dst, "zip"), a.Zip)
+	dst = appendCBORText(appendCBORText(dst, "city"), a.City)
+	return appendCBORText(appendCBORText(dst, "street"), a.Street)
+}
+
+func (a *jsonAddress) unmarshalCBOR(d *cborDecoder) error {
+	m, err := d.container(cborMap)
+	for err == nil && d.next(&m) {
+		var key []byte
+		if key, err = d.textBytes(); err != nil {
+			break
+		}
+		switch string(key) {
+		case "street":
+			a.Street, err = d.text()
+		case "city":
+			a.City, err = d.text()
+		case "zip":
+			a.Zip, err = d.text()
+		default:
+			err = d.skip()
+		}
+	}
+	return err
+}
+
+// Small
+
+func (s *jsonSmall) appendCBOR(dst []byte) []byte {
+	dst = appendCBORHead(dst, cborMap, 6)
+	dst = appendCBORInt(appendCBORText(dst, "age"), s.Age)
+	dst = appendCBORText(appendCBORText(dst, "name"), s.Name)
+	dst = appendCBORText(appendCBORText(dst, "email"), s.Email)
+	dst = appendCBORBool(appendCBORText(dst, "active"), s.Active)
+	dst = appendCBORHead(appendCBORText(dst, "scores"), cborArray, uint64(len(s.Scores)))
+	for _, v := range s.Scores {
+		dst = appendCBORInt(dst, v)
+	}
+	return s.Address.appendCBOR(appendCBORText(dst, "address"))
+}
+
+func (s *jsonSmall) unmarshalCBOR(d *cborDecoder) error {
+	m, err := d.container(cborMap)
+	for err == nil && d.next(&m) {
+		var key []byte
+		if key, err = d.textBytes(); err != nil {
+			break
+		}
+		switch string(key) {
+		case "name":
+			s.Name, err = d.text()
+		case "age":
+			s.Age, err = d.int()
+		case "active":
+			s.Active, err = d.bool()
+		case "email":
+			s.Email, err = d.text()
+		case "scores":
+			err = s.unmarshalCBORScores(d)
+		case "address":
+			err = s.Address.unmarshalCBOR(d)
+		default:
+			err = d.skip()
+		}
+	}
+	return err
+}
+
+func (s *jsonSmall) unmarshalCBORScores(d *cborDecoder) error {
+	a, err := d.container(cborArray)
+	if err != nil {
+		return err
+	}
+	if !a.indefinite {
+		s.Scores = make([]int, 0, a.n)
+	}
+	for d.next(&a) {
+		v, err := d.int()
+		if err != nil {
+			return err
+		}
+		s.Scores = append(s.Scores, v)
+	}
+	return nil
+}
+
+// User
+
+func (u *jsonUser) appendCBOR(dst []byte) []byte {
+	n := uint64(4)
+	if u.Age != 0 {
+		n++
+	}
+	dst = appendCBORHead(dst, cborMap, n)
+	dst = appendCBORInt(appendCBORText(dst, "id"), u.ID)
+	if u.Age != 0 {
+		dst = appendCBORInt(appendCBORText(dst, "age"), u.Age)
+	}
+	dst = appendCBORText(appendCBORText(dst, "name"), u.Name)
+	dst = appendCBORText(appendCBORText(dst, "email"), u.Email)
+	return appendCBORBool(appendCBORText(dst, "active"), u.Active)
+}
+
+func (u *jsonUser) unmarshalCBOR(d *cborDecoder) error {
+	m, err := d.container(cborMap)
+	for err == nil && d.next(&m) {
+		var key []byte
+		if key, err = d.textBytes(); err != nil {
+			break
+		}
+		switch string(key) {
+		case "id":
+			u.ID, err = d.int()
+		case "name":
+			u.Name, err = d.text()
+		case "email":
+			u.Email, err = d.text()
+		case "active":
+			u.Active, err = d.bool()
+		case "age":
+			u.Age, err = d.int()
+		default:
+			err = d.skip()
+		}
+	}
+	return err
+}
+
+func appendCBORUsers(dst []byte, users []jsonUser) []byte {
+	dst = appendCBORHead(dst, cborArray, uint64(len(users)))
+	for i := range users {
+		dst = users[i].appendCBOR(dst)
+	}
+	return dst
+}
+
+func unmarshalCBORUsers(d *cborDecoder) ([]jsonUser, error) {
+	a, err := d.container(cborArray)
+	if err != nil {
+		return nil, err
+	}
+	var users []jsonUser
+	if !a.indefinite {
+		users = make([]jsonUser, 0, a.n)
+	}
+	for d.next(&a) {
+		users = append(users, jsonUser{})
+		if err := users[len(users)-1].unmarshalCBOR(d); err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
+}
+
+// Medium
+
+func (m *jsonMedium) appendCBOR(dst []byte) []byte {
+	md := &m.Metadata
+	dst = appendCBORHead(dst, cborMap, 2)
+	dst = appendCBORUsers(appendCBORText(dst, "users"), m.Users)
+	dst = appendCBORHead(appendCBORText(dst, "metadata"), cborMap, 4)
+	dst = appendCBORInt(appendCBORText(dst, "page"), md.Page)
+	dst = appendCBORInt(appendCBORText(dst, "total"), md.Total)
+	dst = appendCBORBool(appendCBORText(dst, "has_more"), md.HasMore)
+	return appendCBORInt(appendCBORText(dst, "per_page"), md.PerPage)
+}
+
+func (m *jsonMedium) unmarshalCBOR(d *cborDecoder) error {
+	doc, err := d.container(cborMap)
+	for err == nil && d.next(&doc) {
+		var key []byte
+		if key, err = d.textBytes(); err != nil {
+			break
+		}
+		switch string(key) {
+		case "users":
+			m.Users, err = unmarshalCBORUsers(d)
+		case "metadata":
+			err = m.unmarshalCBORMetadata(d)
+		default:
+			err = d.skip()
+		}
+	}
+	return err
+}
+
+func (m *jsonMedium) unmarshalCBORMetadata(d *cborDecoder) error {
+	md := &m.Metadata
+	meta, err := d.container(cborMap)
+	for err == nil && d.next(&meta) {
+		var key []byte
+		if key, err = d.textBytes(); err != nil {
+			break
+		}
+		switch string(key) {
+		case "total":
+			md.Total, err = d.int()
+		case "page":
+			md.Page, err = d.int()
+		case "per_page":
+			md.PerPage, err = d.int()
+		case "has_more":
+			md.HasMore, err = d.bool()
+		default:
+			err = d.skip()
+		}
+	}
+	return err
+}
+
+// Large
+
+func (l *jsonLarge) appendCBOR(dst []byte) []byte {
+	dst = appendCBORHead(dst, cborMap, 2)
+	dst = appendCBORUsers(appendCBORText(dst, "users"), l.Users)
+	dst = appendCBORHead(appendCBORText(dst, "metadata"), cborMap, 1)
+	return appendCBORInt(appendCBORText(dst, "count"), l.Metadata.Count)
+}
+
+func (l *jsonLarge) unmarshalCBOR(d *cborDecoder) error {
+	doc, err := d.container(cborMap)
+	for err == nil && d.next(&doc) {
+		var key []byte
+		if key, err = d.textBytes(); err != nil {
+			break
+		}
+		switch string(key) {
+		case "users":
+			l.Users, err = unmarshalCBORUsers(d)
+		case "metadata":
+			err = l.unmarshalCBORMetadata(d)
+		default:
+			err = d.skip()
+		}
+	}
+	return err
+}
+
+func (l *jsonLarge) unmarshalCBORMetadata(d *cborDecoder) error {
+	meta, err := d.container(cborMap)
+	for err == nil && d.next(&meta) {
+		var key []byte
+		if key, err = d.textBytes(); err != nil {
+			break
+		}
+		switch string(key) {
+		case "count":
+			l.Metadata.Count, err = d.int()
+		default:
+			err = d.skip()
+		}
+	}
+	return err
+}
+
+// cborMessage is a fixture struct with a hand-written CBOR codec
+type cborMessage[T any] interface {
+	*T
+	appendCBOR(dst []byte) []byte
+	unmarshalCBOR(d *cborDecoder) error
+}
+
+// unmarshalCBOR decodes data, which must hold exactly one item, into m
+func unmarshalCBOR[T any, P cborMessage[T]](data []byte, m P) error {
+	d := cborDecoder{data}
+	if err := m.unmarshalCBOR(&d); err != nil {
+		return err
+	}
+	if len(d.b) > 0 {
+		return fmt.Errorf("cbor: %d bytes after the item", len(d.b))
+	}
+	return nil
+}
+
+// registerCBOR registers the Parse and Marshal benchmarks of one fixture:
+// the JSON fixture decoded into T, encoded as CBOR
+func registerCBOR[T any, P cborMessage[T]](size string, fixture []byte, iterations int64) {
+	var value T
+	if err := decodeJSONStrict(fixture, &value); err != nil {
+		panic("json fixture " + size + ": " + err.Error())
+	}
+	encoded := P(&value).appendCBOR(nil)
+	Register(Benchmark{
+		Name: "CborParse" + size, Category: "cbor", Tags: []string{"serde", "alloc"},
+		Iterations: iterations, DataSize: int64(len(encoded)),
+		Fn: func(b *B) {
+			var obj T
+			if err := unmarshalCBOR[T, P](encoded, &obj); err != nil {
+				b.Fatal(err)
+			}
+		},
+	})
+	Register(Benchmark{
+		Name: "CborMarshal" + size, Category: "cbor", Tags: []string{"serde", "alloc"},
+		Iterations: iterations, DataSize: int64(len(encoded)),
+		Fn: func(b *B) {
+			// Encoders grow their output as they go; start at the
+			// size of the last document, as a pooled buffer would
+			P(&value).appendCBOR(make([]byte, 0, len(encoded)))
+		},
+	})
+}
+
+func init() {
+	registerCBOR[jsonSmall]("Small", GenerateSmallJSON(), 10000)
+	registerCBOR[jsonMedium]("Medium", GenerateMediumJSON(), 5000)
+	registerCBOR[jsonLarge]("Large", GenerateLargeJSON(), 100)
+}
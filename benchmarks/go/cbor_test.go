@@ -0,0 +1,89 @@
+// CBOR Tests - Go
+//
+// Run with: go test -run Cbor
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCborFixturesRoundTrip(t *testing.T) {
+	var small, smallBack jsonSmall
+	var medium, mediumBack jsonMedium
+	var large, largeBack jsonLarge
+	decodeJSONStrict(GenerateSmallJSON(), &small)
+	decodeJSONStrict(GenerateMediumJSON(), &medium)
+	decodeJSONStrict(GenerateLargeJSON(), &large)
+
+	if err := unmarshalCBOR(small.appendCBOR(nil), &smallBack); err != nil || !reflect.DeepEqual(smallBack, small) {
+		t.Errorf("small decoded as %+v, %v", smallBack, err)
+	}
+	if err := unmarshalCBOR(medium.appendCBOR(nil), &mediumBack); err != nil || !reflect.DeepEqual(mediumBack, medium) {
+		t.Errorf("medium decoded as %+v, %v", mediumBack, err)
+	}
+	if err := unmarshalCBOR(large.appendCBOR(nil), &largeBack); err != nil || !reflect.DeepEqual(largeBack, large) {
+		t.Errorf("large decoded as %+v, %v", largeBack, err)
+	}
+}
+
+func TestCborDeterministicEncoding(t *testing.T) {
+	// Keys shortest first, then bytewise; -1 as major type 1, 300 in a
+	// two-byte head
+	s := jsonSmall{Name: "a", Age: -1, Scores: []int{1, 300}, Address: jsonAddress{Zip: "z"}}
+	want := []byte{
+		0xa6,
+		0x63, 'a', 'g', 'e', 0x20,
+		0x64, 'n', 'a', 'm', 'e', 0x61, 'a',
+		0x65, 'e', 'm', 'a', 'i', 'l', 0x60,
+		0x66, 'a', 'c', 't', 'i', 'v', 'e', 0xf4,
+		0x66, 's', 'c', 'o', 'r', 'e', 's', 0x82, 0x01, 0x19, 0x01, 0x2c,
+		0x67, 'a', 'd', 'd', 'r', 'e', 's', 's', 0xa3,
+		0x63, 'z', 'i', 'p', 0x61, 'z',
+		0x64, 'c', 'i', 't', 'y', 0x60,
+		0x66, 's', 't', 'r', 'e', 'e', 't', 0x60,
+	}
+	if got := s.appendCBOR(nil); !bytes.Equal(got, want) {
+		t.Errorf("encoded as % x, want % x", got, want)
+	}
+}
+
+func TestCborParseAcceptsNonDeterministic(t *testing.T) {
+	doc := []byte{
+		// An indefinite map
+		0xbf,
+		0x66, 's', 'c', 'o', 'r', 'e', 's', 0x9f, 0x07, 0x18, 0x08, 0xff, // scores: [7, 8], indefinite, 8 in a long head
+		0x61, 'x', 0xc1, 0xa1, 0x61, 'k', 0x5f, 0x41, 0x00, 0xff, // x: 1({"k": h'00'}), unknown
+		0x7f, 0x61, 'a', 0x62, 'g', 'e', 0xff, 0x18, 0x1e, // age: 30, key in chunks
+		0xff,
+	}
+	var s jsonSmall
+	if err := unmarshalCBOR(doc, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Age != 30 || !reflect.DeepEqual(s.Scores, []int{7, 8}) {
+		t.Errorf("decoded as %+v", s)
+	}
+}
+
+func TestCborParseRejectsMalformed(t *testing.T) {
+	for name, doc := range map[string][]byte{
+		"truncated string":   {0xa1, 0x64, 'n', 'a', 'm', 'e', 0x65, 'a'},
+		"truncated map":      {0xa2, 0x63, 'a', 'g', 'e', 0x01},
+		"wrong type":         {0xa1, 0x63, 'a', 'g', 'e', 0x61, '1'},
+		"invalid UTF-8":      {0xa1, 0x64, 'n', 'a', 'm', 'e', 0x61, 0xff},
+		"reserved info":      {0xa1, 0x63, 'a', 'g', 'e', 0x1c},
+		"stray break":        {0xa1, 0x61, 'x', 0xff},
+		"mixed chunks":       {0xa1, 0x61, 'x', 0x7f, 0x41, 0x00, 0xff},
+		"trailing bytes":     {0xa0, 0x00},
+		"not a map":          {0x80},
+		"unterminated array": {0xa1, 0x66, 's', 'c', 'o', 'r', 'e', 's', 0x9f, 0x01},
+	} {
+		var s jsonSmall
+		if err := unmarshalCBOR(doc, &s); err == nil {
+			t.Errorf("%s: decoded without error", name)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Clocks - Go
+//
+// Timer-driven code - the pacer's token bucket, heartbeat session expiry -
+// takes its time from a Clock instead of the time package, so the same
+// code runs against the wall clock in the network benchmarks and against
+// a virtualClock in the virtual-time ones (virtualtime_bench.go). Virtual
+// time only moves when the benchmark advances it: a simulated second costs
+// nothing, timers fire at exactly their deadline and every run is the
+// same, leaving only the cost of the data structures to measure.
+
+package main
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// Clock is a source of time and timers
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	// AfterFunc calls f once d has elapsed
+	AfterFunc(d time.Duration, f func()) ClockTimer
+}
+
+// ClockTimer is a timer from AfterFunc; *time.Timer is one
+type ClockTimer interface {
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// realClock is the wall clock
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+func (realClock) AfterFunc(d time.Duration, f func()) ClockTimer { return time.AfterFunc(d, f) }
+
+// virtualClock is a clock that only moves when advanced. Sleep advances it
+// too, so a single goroutine driving the clock never blocks. Timer
+// callbacks run on the advancing goroutine, in deadline order and, for
+// equal deadlines, in the order they were set.
+type virtualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers virtualTimerHeap
+	seq    uint64
+}
+
+// virtualEpoch is where virtual clocks start, so runs are reproducible
+var virtualEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newVirtualClock() *virtualClock {
+	return &virtualClock{now: virtualEpoch}
+}
+
+func (c *virtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *virtualClock) Sleep(d time.Duration) {
+	c.Advance(d)
+}
+
+func (c *virtualClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+	t := &virtualTimer{clock: c, f: f, index: -1}
+	c.mu.Lock()
+	c.schedule(t, d)
+	c.mu.Unlock()
+	return t
+}
+
+// Advance moves the clock forward by d, firing every timer due by then
+func (c *virtualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for len(c.timers) > 0 && !c.timers[0].when.After(end) {
+		t := heap.Pop(&c.timers).(*virtualTimer)
+		c.now = t.when
+		// Unlocked, so the callback may use the clock and its timers
+		c.mu.Unlock()
+		t.f()
+		c.mu.Lock()
+	}
+	c.now = end
+	c.mu.Unlock()
+}
+
+// Pending returns the number of armed timers
+func (c *virtualClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// schedule arms t to fire d from now; c.mu must be held
+func (c *virtualClock) schedule(t *virtualTimer, d time.Duration) {
+	c.seq++
+	t.when, t.seq = c.now.Add(d), c.seq
+	heap.Push(&c.timers, t)
+}
+
+// virtualTimer is a timer of a virtualClock
+type virtualTimer struct {
+	clock *virtualClock
+	f     func()
+	when  time.Time
+	seq   uint64
+	index int // position in the heap, or -1 when not armed
+}
+
+func (t *virtualTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	if t.index < 0 {
+		return false
+	}
+	heap.Remove(&t.clock.timers, t.index)
+	return true
+}
+
+func (t *virtualTimer) Reset(d time.Duration) bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	armed := t.index >= 0
+	if armed {
+		heap.Remove(&c.timers, t.index)
+	}
+	c.schedule(t, d)
+	return armed
+}
+
+// virtualTimerHeap orders timers by deadline, then by when they were set
+type virtualTimerHeap []*virtualTimer
+
+func (h virtualTimerHeap) Len() int { return len(h) }
+
+func (h virtualTimerHeap) Less(i, j int) bool {
+	if !h[i].when.Equal(h[j].when) {
+		return h[i].when.Before(h[j].when)
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h virtualTimerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *virtualTimerHeap) Push(x any) {
+	t := x.(*virtualTimer)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *virtualTimerHeap) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	t.index = -1
+	*h = old[:len(old)-1]
+	return t
+}
@@ -0,0 +1,95 @@
+// Clock Tests - Go
+//
+// Run with: go test -run Clock
+
+package main
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestVirtualClockFiresInOrder(t *testing.T) {
+	c := newVirtualClock()
+	var fired []string
+	var at []time.Duration
+	record := func(name string) func() {
+		return func() {
+			fired = append(fired, name)
+			at = append(at, c.Now().Sub(virtualEpoch))
+		}
+	}
+	c.AfterFunc(30*time.Millisecond, record("c"))
+	c.AfterFunc(10*time.Millisecond, record("a"))
+	c.AfterFunc(10*time.Millisecond, record("b"))
+	stopped := c.AfterFunc(20*time.Millisecond, record("stopped"))
+	if !stopped.Stop() {
+		t.Fatal("Stop of an armed timer returned false")
+	}
+
+	c.Advance(15 * time.Millisecond)
+	if len(fired) != 2 || fired[0] != "a" || fired[1] != "b" {
+		t.Fatalf("after 15ms fired %v, want [a b]", fired)
+	}
+	c.Advance(time.Second)
+	want := []time.Duration{10 * time.Millisecond, 10 * time.Millisecond, 30 * time.Millisecond}
+	if len(fired) != 3 || fired[2] != "c" {
+		t.Fatalf("fired %v, want [a b c]", fired)
+	}
+	for i := range want {
+		if at[i] != want[i] {
+			t.Errorf("%s fired at %v, want %v", fired[i], at[i], want[i])
+		}
+	}
+	if now := c.Now().Sub(virtualEpoch); now != time.Second+15*time.Millisecond {
+		t.Errorf("clock at %v after advancing 1.015s", now)
+	}
+	if c.Pending() != 0 {
+		t.Errorf("%d timers still pending", c.Pending())
+	}
+}
+
+func TestVirtualClockResetAndRearm(t *testing.T) {
+	c := newVirtualClock()
+	ticks := 0
+	var tick ClockTimer
+	tick = c.AfterFunc(10*time.Millisecond, func() {
+		ticks++
+		tick.Reset(10 * time.Millisecond)
+	})
+	c.Sleep(55 * time.Millisecond)
+	if ticks != 5 {
+		t.Errorf("periodic timer ticked %d times in 55ms, want 5", ticks)
+	}
+
+	fired := false
+	timer := c.AfterFunc(10*time.Millisecond, func() { fired = true })
+	c.Advance(5 * time.Millisecond)
+	if !timer.Reset(10 * time.Millisecond) {
+		t.Error("Reset of an armed timer returned false")
+	}
+	c.Advance(9 * time.Millisecond)
+	if fired {
+		t.Error("reset timer fired at its old deadline")
+	}
+	c.Advance(time.Millisecond)
+	if !fired {
+		t.Error("reset timer did not fire at its new deadline")
+	}
+}
+
+func TestPacedWriterVirtualRate(t *testing.T) {
+	// 1MB at 8MB/s takes exactly 125ms of virtual time
+	c := newVirtualClock()
+	w := newPacedWriter(io.Discard, 8<<20, c)
+	block := make([]byte, 64*1024)
+	for i := 0; i < 16; i++ {
+		if n, err := w.Write(block); err != nil || n != len(block) {
+			t.Fatalf("write %d: n %d err %v", i, n, err)
+		}
+	}
+	if elapsed := c.Now().Sub(virtualEpoch); elapsed < 124*time.Millisecond || elapsed > 126*time.Millisecond {
+		t.Errorf("1MB at 8MB/s took %v of virtual time, want 125ms", elapsed)
+	}
+}
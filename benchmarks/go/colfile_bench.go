@@ -0,0 +1,297 @@
+// Columnar File Read Benchmark - Go
+//
+// Reads a generated columnar file laid out like an Arrow IPC stream: a
+// schema header followed by record batches, each holding every column as
+// one contiguous little-endian buffer padded to 8 bytes. The reader streams
+// batch by batch through a reused buffer and runs the columnar aggregation
+// query over each, so the reported MB/s is file decode plus scan.
+//
+// The decode axis compares copying values out with encoding/binary against
+// reinterpreting the batch buffer in place, the zero-copy path Arrow
+// readers take.
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"unsafe"
+)
+
+const (
+	colFileRows      = 50_000_000
+	colFileBatchRows = 1 << 20
+	colFileMagic     = "TMLCOL1\x00"
+)
+
+// colFileSchema lists the columns of the sales table and their widths
+var colFileSchema = []struct {
+	name  string
+	width int
+}{
+	{"timestamp", 8},
+	{"amount", 8},
+	{"quantity", 4},
+	{"region", 2},
+	{"status", 1},
+}
+
+// colFileState is the file generated in setup and its directory
+var colFileState struct {
+	dir  string
+	path string
+	size int64
+}
+
+// padTo8 rounds a column buffer length up so the next column stays aligned
+func padTo8(n int) int {
+	return (n + 7) &^ 7
+}
+
+// writeColumnarFile writes the deterministic sales table as a columnar file
+func writeColumnarFile(path string) (int64, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriterSize(f, 1<<20)
+	w.WriteString(colFileMagic)
+	binary.Write(w, binary.LittleEndian, uint32(len(colFileSchema)))
+	for _, col := range colFileSchema {
+		w.WriteByte(byte(col.width))
+		w.WriteByte(byte(len(col.name)))
+		w.WriteString(col.name)
+	}
+
+	rng := newRand(1521)
+	cols := make([][]byte, len(colFileSchema))
+	for i, col := range colFileSchema {
+		cols[i] = make([]byte, padTo8(colFileBatchRows*col.width))
+	}
+	for start := 0; start < colFileRows; start += colFileBatchRows {
+		n := min(colFileBatchRows, colFileRows-start)
+		for i := 0; i < n; i++ {
+			v := rng.Uint64()
+			binary.LittleEndian.PutUint64(cols[0][i*8:], uint64(1_700_000_000+start+i))
+			binary.LittleEndian.PutUint64(cols[1][i*8:], math.Float64bits(float64(v%100_000)/100))
+			binary.LittleEndian.PutUint32(cols[2][i*4:], uint32(v>>20%50))
+			binary.LittleEndian.PutUint16(cols[3][i*2:], uint16(v>>32%columnarRegions))
+			cols[4][i] = uint8(v >> 40 % 4)
+		}
+		binary.Write(w, binary.LittleEndian, uint32(n))
+		for i, col := range colFileSchema {
+			w.Write(cols[i][:padTo8(n*col.width)])
+		}
+	}
+	binary.Write(w, binary.LittleEndian, uint32(0))
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+	return info.Size(), f.Close()
+}
+
+// columnarFileReader streams record batches from a columnar file
+type columnarFileReader struct {
+	r   *bufio.Reader
+	f   *os.File
+	buf []byte
+	// decoded holds the copy-decoded batch, reused across batches
+	decoded salesColumns
+}
+
+func openColumnarFile(path string) (*columnarFileReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	cr := &columnarFileReader{r: bufio.NewReaderSize(f, 1<<20), f: f}
+	if err := cr.readSchema(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	batchBytes := 0
+	for _, col := range colFileSchema {
+		batchBytes += padTo8(colFileBatchRows * col.width)
+	}
+	// Allocating as []uint64 guarantees the 8-byte alignment zero-copy needs
+	words := make([]uint64, batchBytes/8)
+	cr.buf = unsafe.Slice((*byte)(unsafe.Pointer(&words[0])), batchBytes)
+	return cr, nil
+}
+
+func (cr *columnarFileReader) readSchema() error {
+	magic := make([]byte, len(colFileMagic))
+	if _, err := io.ReadFull(cr.r, magic); err != nil {
+		return err
+	}
+	if string(magic) != colFileMagic {
+		return errors.New("not a columnar file")
+	}
+	var ncols uint32
+	if err := binary.Read(cr.r, binary.LittleEndian, &ncols); err != nil {
+		return err
+	}
+	if int(ncols) != len(colFileSchema) {
+		return fmt.Errorf("columnar file has %d columns, want %d", ncols, len(colFileSchema))
+	}
+	for _, col := range colFileSchema {
+		var hdr [2]byte
+		if _, err := io.ReadFull(cr.r, hdr[:]); err != nil {
+			return err
+		}
+		name := make([]byte, hdr[1])
+		if _, err := io.ReadFull(cr.r, name); err != nil {
+			return err
+		}
+		if int(hdr[0]) != col.width || string(name) != col.name {
+			return fmt.Errorf("unexpected column %q (width %d)", name, hdr[0])
+		}
+	}
+	return nil
+}
+
+// next reads the following batch into the shared buffer and returns its
+// per-column byte slices; it returns io.EOF after the last batch
+func (cr *columnarFileReader) next() ([][]byte, int, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(cr.r, hdr[:]); err != nil {
+		return nil, 0, err
+	}
+	n := int(binary.LittleEndian.Uint32(hdr[:]))
+	if n == 0 {
+		return nil, 0, io.EOF
+	}
+	if n > colFileBatchRows {
+		return nil, 0, fmt.Errorf("batch of %d rows exceeds %d", n, colFileBatchRows)
+	}
+	cols := make([][]byte, len(colFileSchema))
+	off := 0
+	for i, col := range colFileSchema {
+		size := padTo8(n * col.width)
+		cols[i] = cr.buf[off : off+n*col.width]
+		if _, err := io.ReadFull(cr.r, cr.buf[off:off+size]); err != nil {
+			return nil, 0, err
+		}
+		off += size
+	}
+	return cols, n, nil
+}
+
+// decodeCopy decodes a batch value by value into the reused typed slices
+func (cr *columnarFileReader) decodeCopy(cols [][]byte, n int) *salesColumns {
+	d := &cr.decoded
+	if cap(d.Timestamp) < n {
+		d.Timestamp = make([]int64, n)
+		d.Amount = make([]float64, n)
+		d.Quantity = make([]int32, n)
+		d.Region = make([]uint16, n)
+		d.Status = make([]uint8, n)
+	}
+	d.Timestamp, d.Amount, d.Quantity = d.Timestamp[:n], d.Amount[:n], d.Quantity[:n]
+	d.Region, d.Status = d.Region[:n], d.Status[:n]
+	for i := 0; i < n; i++ {
+		d.Timestamp[i] = int64(binary.LittleEndian.Uint64(cols[0][i*8:]))
+		d.Amount[i] = math.Float64frombits(binary.LittleEndian.Uint64(cols[1][i*8:]))
+		d.Quantity[i] = int32(binary.LittleEndian.Uint32(cols[2][i*4:]))
+		d.Region[i] = binary.LittleEndian.Uint16(cols[3][i*2:])
+	}
+	copy(d.Status, cols[4])
+	return d
+}
+
+// decodeZeroCopy reinterprets the batch buffer as typed slices; valid only
+// on little-endian hosts and until the next batch is read
+func decodeZeroCopy(cols [][]byte, n int) *salesColumns {
+	return &salesColumns{
+		Timestamp: unsafe.Slice((*int64)(unsafe.Pointer(unsafe.SliceData(cols[0]))), n),
+		Amount:    unsafe.Slice((*float64)(unsafe.Pointer(unsafe.SliceData(cols[1]))), n),
+		Quantity:  unsafe.Slice((*int32)(unsafe.Pointer(unsafe.SliceData(cols[2]))), n),
+		Region:    unsafe.Slice((*uint16)(unsafe.Pointer(unsafe.SliceData(cols[3]))), n),
+		Status:    cols[4],
+	}
+}
+
+func (cr *columnarFileReader) close() error {
+	return cr.f.Close()
+}
+
+// aggregateColumnarFile runs the group-by query over every batch of the file
+func aggregateColumnarFile(path string, zeroCopy bool) ([columnarRegions]float64, error) {
+	var sums [columnarRegions]float64
+	cr, err := openColumnarFile(path)
+	if err != nil {
+		return sums, err
+	}
+	defer cr.close()
+	for {
+		cols, n, err := cr.next()
+		if err == io.EOF {
+			return sums, nil
+		}
+		if err != nil {
+			return sums, err
+		}
+		var batch *salesColumns
+		if zeroCopy {
+			batch = decodeZeroCopy(cols, n)
+		} else {
+			batch = cr.decodeCopy(cols, n)
+		}
+		part := aggregateColumns(batch)
+		for i, s := range part {
+			sums[i] += s
+		}
+	}
+}
+
+func setupColumnarFile(b *B) error {
+	dir, err := os.MkdirTemp("", "tml-colfile-")
+	if err != nil {
+		return err
+	}
+	colFileState.dir = dir
+	colFileState.path = filepath.Join(dir, "sales.col")
+	colFileState.size, err = writeColumnarFile(colFileState.path)
+	return err
+}
+
+func teardownColumnarFile() {
+	if colFileState.dir != "" {
+		os.RemoveAll(colFileState.dir)
+	}
+	colFileState.dir, colFileState.path, colFileState.size = "", "", 0
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "ColumnarFileRead", Category: "columnar", Tags: []string{"cpu", "large"},
+		Iterations: 5,
+		Axes:       []Axis{{Name: "decode", Values: Strings("copy", "zerocopy")}},
+		Setup:      setupColumnarFile, Teardown: teardownColumnarFile,
+		Fn: func(b *B) {
+			b.SetBytes(colFileState.size)
+			sums, err := aggregateColumnarFile(colFileState.path, b.StringParam("decode") == "zerocopy")
+			if err != nil {
+				b.Fatal(err)
+				return
+			}
+			total := 0.0
+			for _, s := range sums {
+				total += s
+			}
+			columnarSink = total
+		},
+	})
+}
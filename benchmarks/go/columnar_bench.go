@@ -0,0 +1,146 @@
+// Columnar Aggregation Benchmarks - Go
+//
+// Runs the same filter + group-by + sum query over 100M rows stored as an
+// array of row structs and as separate typed column slices:
+//
+//	SELECT region, SUM(amount) WHERE status = 1 AND quantity > 10 GROUP BY region
+//
+// The query touches four of five fields, so the comparison isolates the
+// effect of data layout (cache lines carrying unused fields, vectorizable
+// column scans) that features in the TML docs.
+
+package main
+
+const (
+	columnarRows    = 100_000_000
+	columnarRegions = 64
+	// columnarQueriedBytes is the size of the four queried fields of a row
+	// (status, quantity, region, amount), the logical data per row both
+	// layouts' throughput is measured against; a salesRow also carries its
+	// timestamp and padding
+	columnarQueriedBytes = 1 + 4 + 2 + 8
+)
+
+// salesRow is the row-oriented layout
+type salesRow struct {
+	Timestamp int64
+	Amount    float64
+	Quantity  int32
+	Region    uint16
+	Status    uint8
+}
+
+// salesColumns is the column-oriented layout of the same table
+type salesColumns struct {
+	Timestamp []int64
+	Amount    []float64
+	Quantity  []int32
+	Region    []uint16
+	Status    []uint8
+}
+
+// columnarFixture holds only the layout under test so both tables are
+// never resident together
+var columnarFixture struct {
+	layout  string
+	rows    []salesRow
+	columns *salesColumns
+}
+
+// generateSales calls emit for every row of the deterministic table
+func generateSales(emit func(i int, r salesRow)) {
+	rng := newRand(1520)
+	for i := 0; i < columnarRows; i++ {
+		v := rng.Uint64()
+		emit(i, salesRow{
+			Timestamp: int64(1_700_000_000 + i),
+			Amount:    float64(v%100_000) / 100,
+			Quantity:  int32(v >> 20 % 50),
+			Region:    uint16(v >> 32 % columnarRegions),
+			Status:    uint8(v >> 40 % 4),
+		})
+	}
+}
+
+func setupColumnar(b *B) error {
+	columnarFixture.layout = b.StringParam("layout")
+	switch columnarFixture.layout {
+	case "rows":
+		rows := make([]salesRow, columnarRows)
+		generateSales(func(i int, r salesRow) { rows[i] = r })
+		columnarFixture.rows = rows
+	case "columns":
+		c := &salesColumns{
+			Timestamp: make([]int64, columnarRows),
+			Amount:    make([]float64, columnarRows),
+			Quantity:  make([]int32, columnarRows),
+			Region:    make([]uint16, columnarRows),
+			Status:    make([]uint8, columnarRows),
+		}
+		generateSales(func(i int, r salesRow) {
+			c.Timestamp[i] = r.Timestamp
+			c.Amount[i] = r.Amount
+			c.Quantity[i] = r.Quantity
+			c.Region[i] = r.Region
+			c.Status[i] = r.Status
+		})
+		columnarFixture.columns = c
+	}
+	return nil
+}
+
+func teardownColumnar() {
+	columnarFixture.rows = nil
+	columnarFixture.columns = nil
+}
+
+func aggregateRows(rows []salesRow) [columnarRegions]float64 {
+	var sums [columnarRegions]float64
+	for i := range rows {
+		r := &rows[i]
+		if r.Status == 1 && r.Quantity > 10 {
+			sums[r.Region] += r.Amount
+		}
+	}
+	return sums
+}
+
+func aggregateColumns(c *salesColumns) [columnarRegions]float64 {
+	var sums [columnarRegions]float64
+	status, quantity, region, amount := c.Status, c.Quantity, c.Region, c.Amount
+	// Reslicing to a common length lets the compiler drop bounds checks
+	n := len(status)
+	quantity, region, amount = quantity[:n], region[:n], amount[:n]
+	for i := 0; i < n; i++ {
+		if status[i] == 1 && quantity[i] > 10 {
+			sums[region[i]] += amount[i]
+		}
+	}
+	return sums
+}
+
+// columnarSink keeps results observable so the scan is not optimized away
+var columnarSink float64
+
+func init() {
+	Register(Benchmark{
+		Name: "ColumnarAggregate", Category: "columnar", Tags: []string{"cpu", "large"},
+		Iterations: 5,
+		Axes:       []Axis{{Name: "layout", Values: Strings("rows", "columns")}},
+		Setup:      setupColumnar, Teardown: teardownColumnar,
+		Fn: func(b *B) {
+			b.SetBytes(columnarRows * columnarQueriedBytes)
+			var sums [columnarRegions]float64
+			if columnarFixture.layout == "rows" {
+				sums = aggregateRows(columnarFixture.rows)
+			} else {
+				sums = aggregateColumns(columnarFixture.columns)
+			}
+			total := 0.0
+			for _, s := range sums {
+				total += s
+			}
+			columnarSink = total
+		},
+	})
+}
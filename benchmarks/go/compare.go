@@ -0,0 +1,225 @@
+// Go vs TML Comparison - Go
+//
+// Prints the per-benchmark speedup between a Go result file and a TML
+// result file, flagging benchmarks where either side is more than
+// -threshold percent faster, followed by the geometric mean ratio. Names
+// are matched ignoring case, spaces, underscores and dashes, since the
+// suites do not share one naming convention.
+//
+// Given raw samples for both sides (-go-samples, -tml-samples; see
+// rawsamples.go), each ratio also gets a bootstrapped 95% confidence
+// interval, marked * when it excludes 1, and a side is only flagged as the
+// winner when the difference is significant as well as above the
+// threshold.
+//
+// -notify-url posts the benchmarks where TML is slower to a webhook (see
+// notify.go).
+//
+// Run with: go run . compare [-threshold 20] [-notify-url url] [-go-samples go.bin -tml-samples tml.bin] go.json tml.json
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Comparison is one benchmark present in both result files
+type Comparison struct {
+	Name   string
+	GoUs   float64
+	TMLUs  float64
+	Ratio  float64 // TML time / Go time; > 1 means Go is faster
+	Winner string  // "go", "tml" or "" when within the threshold
+	// CI is the bootstrapped interval of Ratio, when both sides have raw
+	// samples
+	CI *RatioCI
+}
+
+// runCompare implements the compare subcommand
+func runCompare(args []string) int {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	threshold := fs.Float64("threshold", 20, "percent faster before a side is flagged as the winner")
+	goSamplesPath := fs.String("go-samples", "", "raw samples of the Go run, for confidence intervals")
+	tmlSamplesPath := fs.String("tml-samples", "", "raw samples of the TML run, for confidence intervals")
+	notifyURL := fs.String("notify-url", "", "POST a JSON summary to this webhook when TML is more than -threshold slower somewhere")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 || (*goSamplesPath == "") != (*tmlSamplesPath == "") {
+		fmt.Fprintln(os.Stderr, "usage: compare [-threshold 20] [-notify-url url] [-go-samples go.bin -tml-samples tml.bin] go.json tml.json")
+		return 2
+	}
+	goSet, err := LoadResultSet(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	tmlSet, err := LoadResultSet(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	comparisons, unmatched := CompareGoTML(goSet.Results, tmlSet.Results, *threshold)
+	if *goSamplesPath != "" {
+		goSamples, err := readRawSamples(*goSamplesPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", *goSamplesPath, err)
+			return 1
+		}
+		tmlSamples, err := readRawSamples(*tmlSamplesPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", *tmlSamplesPath, err)
+			return 1
+		}
+		AnnotateSignificance(comparisons, goSamples, tmlSamples)
+	}
+	PrintComparisons(comparisons, *threshold)
+	if unmatched > 0 {
+		fmt.Printf("%d benchmarks appear in only one file\n", unmatched)
+	}
+	if *notifyURL != "" {
+		n := NewNotification("Go ("+fs.Arg(0)+")", *threshold, tmlChanges(comparisons))
+		if sent, err := Notify(*notifyURL, n); err != nil {
+			fmt.Fprintf(os.Stderr, "error: notifying: %v\n", err)
+			return 1
+		} else if sent {
+			fmt.Println("Regressions posted to -notify-url")
+		}
+	}
+	return 0
+}
+
+// tmlChanges expresses comparisons as notification entries: how much
+// slower TML is than Go, or, negative, how much faster, so that the
+// threshold applies to both the way Winner applies it. Comparisons whose
+// winner was withdrawn as not significant count as unchanged.
+func tmlChanges(comparisons []Comparison) []NotifyEntry {
+	changes := make([]NotifyEntry, len(comparisons))
+	for i, c := range comparisons {
+		changes[i].Name = c.Name
+		switch {
+		case c.CI != nil && c.Winner == "":
+		case c.Ratio >= 1:
+			changes[i].Percent = (c.Ratio - 1) * 100
+		default:
+			changes[i].Percent = -(1/c.Ratio - 1) * 100
+		}
+	}
+	return changes
+}
+
+// normalizeBenchName folds a name for cross-suite matching
+func normalizeBenchName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '_' || r == '-' {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+}
+
+// CompareGoTML pairs results by normalized name and returns the pairs,
+// sorted by name, and the number of results without a counterpart
+func CompareGoTML(goResults, tmlResults []BenchmarkResult, threshold float64) ([]Comparison, int) {
+	tml := map[string]BenchmarkResult{}
+	for _, r := range tmlResults {
+		tml[normalizeBenchName(r.Name)] = r
+	}
+	var out []Comparison
+	matched := 0
+	for _, g := range goResults {
+		t, ok := tml[normalizeBenchName(g.Name)]
+		if !ok || g.TimeUs <= 0 || t.TimeUs <= 0 || g.Error != "" || t.Error != "" {
+			continue
+		}
+		matched++
+		c := Comparison{Name: g.Name, GoUs: g.TimeUs, TMLUs: t.TimeUs, Ratio: t.TimeUs / g.TimeUs}
+		limit := 1 + threshold/100
+		switch {
+		case c.Ratio > limit:
+			c.Winner = "go"
+		case 1/c.Ratio > limit:
+			c.Winner = "tml"
+		}
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, len(goResults) + len(tmlResults) - 2*matched
+}
+
+// AnnotateSignificance attaches a bootstrapped confidence interval to every
+// comparison with raw samples on both sides, and withdraws the winner of
+// any whose interval includes 1
+func AnnotateSignificance(comparisons []Comparison, goSamples, tmlSamples map[string][]time.Duration) {
+	normalized := func(samples map[string][]time.Duration) map[string][]time.Duration {
+		out := make(map[string][]time.Duration, len(samples))
+		for name, s := range samples {
+			out[normalizeBenchName(name)] = s
+		}
+		return out
+	}
+	goByName, tmlByName := normalized(goSamples), normalized(tmlSamples)
+	rng := newRand(1540)
+	for i := range comparisons {
+		c := &comparisons[i]
+		key := normalizeBenchName(c.Name)
+		ci, ok := bootstrapRatioCI(tmlByName[key], goByName[key], rng)
+		if !ok {
+			continue
+		}
+		c.CI = &ci
+		if !ci.Significant() {
+			c.Winner = ""
+		}
+	}
+}
+
+// PrintComparisons prints the comparison table and summary
+func PrintComparisons(comparisons []Comparison, threshold float64) {
+	fmt.Printf("%-40s %15s %15s %10s\n", "Benchmark", "Go", "TML", "TML/Go")
+	fmt.Println(strings.Repeat("-", 100))
+	goWins, tmlWins := 0, 0
+	withCI, significant := 0, 0
+	logSum := 0.0
+	for _, c := range comparisons {
+		verdict := ""
+		switch c.Winner {
+		case "go":
+			verdict = fmt.Sprintf("<< Go %.2fx faster", c.Ratio)
+			goWins++
+		case "tml":
+			verdict = fmt.Sprintf(">> TML %.2fx faster", 1/c.Ratio)
+			tmlWins++
+		}
+		interval := ""
+		if c.CI != nil {
+			withCI++
+			mark := " "
+			if c.CI.Significant() {
+				mark = "*"
+				significant++
+			}
+			interval = fmt.Sprintf(" [%.2f, %.2f]%s", c.CI.Low, c.CI.High, mark)
+		}
+		line := fmt.Sprintf("%-40s %12.3f us %12.3f us %9.2fx%s  %s", c.Name, c.GoUs, c.TMLUs, c.Ratio, interval, verdict)
+		fmt.Println(strings.TrimRight(line, " "))
+		logSum += math.Log(c.Ratio)
+	}
+	fmt.Println(strings.Repeat("-", 100))
+	if len(comparisons) == 0 {
+		fmt.Println("No benchmarks in common")
+		return
+	}
+	fmt.Printf("%d compared: Go >%.0f%% faster on %d, TML >%.0f%% faster on %d, geometric mean TML/Go %.2fx\n",
+		len(comparisons), threshold, goWins, threshold, tmlWins, math.Exp(logSum/float64(len(comparisons))))
+	if withCI > 0 {
+		fmt.Printf("%d of %d with raw samples differ significantly (95%% bootstrap CI excludes 1, marked *)\n", significant, withCI)
+	}
+}
@@ -0,0 +1,333 @@
+// Shared Configuration - Go
+//
+// benchmarks/config.yaml holds the parameters the language suites must
+// agree on: iteration counts, payload sizes, durations, echo server ports,
+// latency SLO thresholds and the RNG seed. The harness reads it before
+// selecting benchmarks. Only the Go suite loads it so far; the other suites
+// keep their own copies of these values, which have to be kept in step.
+//
+// Only the YAML subset the file needs is supported: nested block maps,
+// scalars, inline [a, b] lists and # comments. No YAML library is vendored,
+// and the subset keeps the file readable by the simpler parsers of the
+// other suites.
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultConfigPath locates the shared configuration from benchmarks/go;
+// elsewhere the built-in defaults apply unless -config names the file
+// (defaultFileFlag)
+const defaultConfigPath = "../config.yaml"
+
+// BenchConfig is the shared cross-language configuration
+type BenchConfig struct {
+	// Seed is added to every benchmark's fixed RNG seed
+	Seed int64
+	// Iterations overrides registered iteration counts by benchmark name
+	Iterations   map[string]int64
+	PayloadSizes struct {
+		Request int
+	}
+	Durations struct {
+		// Stream is how long each streaming throughput case runs
+		Stream time.Duration
+	}
+	Ports struct {
+		TCPEcho int
+		UDPEcho int
+	}
+	SLO struct {
+		// Thresholds are the round-trip times the request benchmarks count
+		// requests over (jitter.go)
+		Thresholds []time.Duration
+	}
+	// Binaries are the suite executables the orchestrate subcommand runs,
+	// as written in the file (relative to it)
+	Binaries struct {
+		Go  string
+		TML string
+	}
+}
+
+// benchConfig is the configuration in effect; the defaults match the
+// values the benchmarks were registered with
+var benchConfig = defaultConfig()
+
+func defaultConfig() BenchConfig {
+	var c BenchConfig
+	c.PayloadSizes.Request = 64
+	c.Durations.Stream = 5 * time.Second
+	c.SLO.Thresholds = []time.Duration{time.Millisecond, 10 * time.Millisecond}
+	return c
+}
+
+// newRand returns a generator for a benchmark's fixed seed, offset by the
+// configured seed
+func newRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(benchConfig.Seed + seed))
+}
+
+// LoadConfig reads the configuration at path; keys it does not set keep
+// their defaults and keys the Go suite does not use are ignored
+func LoadConfig(path string) (BenchConfig, error) {
+	c := defaultConfig()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, fmt.Errorf("loading config: %w", err)
+	}
+	doc, err := parseYAMLSubset(string(data))
+	if err != nil {
+		return c, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := configInt(doc, "seed", &c.Seed); err != nil {
+		return c, err
+	}
+	if iters, ok := doc["iterations"].(map[string]interface{}); ok {
+		c.Iterations = make(map[string]int64, len(iters))
+		for name := range iters {
+			var n int64
+			if err := configInt(iters, name, &n); err != nil {
+				return c, fmt.Errorf("iterations: %w", err)
+			}
+			if n <= 0 {
+				return c, fmt.Errorf("iterations: %s must be positive", name)
+			}
+			c.Iterations[name] = n
+		}
+	}
+	var n int64
+	if sizes, ok := doc["payload_sizes"].(map[string]interface{}); ok {
+		n = int64(c.PayloadSizes.Request)
+		if err := configInt(sizes, "request", &n); err != nil {
+			return c, fmt.Errorf("payload_sizes: %w", err)
+		}
+		// Request payloads carry an 8-byte sequence number in open-loop mode
+		if n < 8 || n > 65507 {
+			return c, fmt.Errorf("payload_sizes: request must be between 8 and 65507 bytes")
+		}
+		c.PayloadSizes.Request = int(n)
+	}
+	if durations, ok := doc["durations"].(map[string]interface{}); ok {
+		n = int64(c.Durations.Stream / time.Second)
+		if err := configInt(durations, "stream_seconds", &n); err != nil {
+			return c, fmt.Errorf("durations: %w", err)
+		}
+		if n <= 0 {
+			return c, fmt.Errorf("durations: stream_seconds must be positive")
+		}
+		c.Durations.Stream = time.Duration(n) * time.Second
+	}
+	if ports, ok := doc["ports"].(map[string]interface{}); ok {
+		for key, dst := range map[string]*int{"tcp_echo": &c.Ports.TCPEcho, "udp_echo": &c.Ports.UDPEcho} {
+			n = int64(*dst)
+			if err := configInt(ports, key, &n); err != nil {
+				return c, fmt.Errorf("ports: %w", err)
+			}
+			if n < 0 || n > 65535 {
+				return c, fmt.Errorf("ports: %s out of range", key)
+			}
+			*dst = int(n)
+		}
+	}
+	if slo, ok := doc["slo"].(map[string]interface{}); ok && slo["thresholds_us"] != nil {
+		list, ok := slo["thresholds_us"].([]interface{})
+		if !ok {
+			return c, fmt.Errorf("slo: thresholds_us: want a list, got %v", slo["thresholds_us"])
+		}
+		c.SLO.Thresholds = nil
+		for _, v := range list {
+			us, ok := v.(int64)
+			if !ok || us <= 0 {
+				return c, fmt.Errorf("slo: thresholds_us: want positive integers, got %v", v)
+			}
+			c.SLO.Thresholds = append(c.SLO.Thresholds, time.Duration(us)*time.Microsecond)
+		}
+	}
+	if bins, ok := doc["binaries"].(map[string]interface{}); ok {
+		for key, dst := range map[string]*string{"go": &c.Binaries.Go, "tml": &c.Binaries.TML} {
+			if err := configString(bins, key, dst); err != nil {
+				return c, fmt.Errorf("binaries: %w", err)
+			}
+		}
+	}
+	return c, nil
+}
+
+// configString stores m[key] into dst when present
+func configString(m map[string]interface{}, key string, dst *string) error {
+	v, ok := m[key]
+	if !ok || v == nil {
+		return nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("%s: want a string, got %v", key, v)
+	}
+	*dst = s
+	return nil
+}
+
+// configInt stores m[key] into dst when present
+func configInt(m map[string]interface{}, key string, dst *int64) error {
+	v, ok := m[key]
+	if !ok {
+		return nil
+	}
+	n, ok := v.(int64)
+	if !ok {
+		return fmt.Errorf("%s: want an integer, got %v", key, v)
+	}
+	*dst = n
+	return nil
+}
+
+// Apply makes c the configuration in effect and applies its iteration
+// overrides to the registry
+func (c BenchConfig) Apply() {
+	benchConfig = c
+	for i := range registry {
+		if n, ok := c.Iterations[registry[i].Name]; ok {
+			registry[i].Iterations = n
+		}
+	}
+}
+
+// yamlLine is one significant line of a YAML document
+type yamlLine struct {
+	num    int
+	indent int
+	key    string
+	value  string
+}
+
+// parseYAMLSubset parses block maps of scalars and inline lists
+func parseYAMLSubset(src string) (map[string]interface{}, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(src, "\n") {
+		text := stripYAMLComment(strings.TrimRight(raw, " \t\r"))
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			return nil, fmt.Errorf("line %d: block lists are not supported, use [a, b]", i+1)
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok || (value != "" && value[0] != ' ') {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", i+1)
+		}
+		lines = append(lines, yamlLine{
+			num: i + 1, indent: len(text) - len(trimmed),
+			key: strings.TrimSpace(key), value: strings.TrimSpace(value),
+		})
+	}
+	m, next, err := parseYAMLBlock(lines, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if next < len(lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", lines[next].num)
+	}
+	return m, nil
+}
+
+// parseYAMLBlock parses the map whose keys start at lines[i] with the
+// given indentation and returns the index of the first line after it
+func parseYAMLBlock(lines []yamlLine, i, indent int) (map[string]interface{}, int, error) {
+	m := make(map[string]interface{})
+	for i < len(lines) && lines[i].indent == indent {
+		l := lines[i]
+		if _, dup := m[l.key]; dup {
+			return nil, i, fmt.Errorf("line %d: duplicate key %q", l.num, l.key)
+		}
+		i++
+		if l.value != "" {
+			v, err := parseYAMLScalar(l.value)
+			if err != nil {
+				return nil, i, fmt.Errorf("line %d: %w", l.num, err)
+			}
+			m[l.key] = v
+			continue
+		}
+		if i < len(lines) && lines[i].indent > indent {
+			child, next, err := parseYAMLBlock(lines, i, lines[i].indent)
+			if err != nil {
+				return nil, next, err
+			}
+			m[l.key], i = child, next
+			continue
+		}
+		m[l.key] = nil
+	}
+	if i < len(lines) && lines[i].indent > indent {
+		return nil, i, fmt.Errorf("line %d: unexpected indentation", lines[i].num)
+	}
+	return m, i, nil
+}
+
+// parseYAMLScalar parses an int, float, bool, string or inline list
+func parseYAMLScalar(s string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("unterminated list %q", s)
+		}
+		inner := strings.TrimSpace(s[1 : len(s)-1])
+		if inner == "" {
+			return []interface{}{}, nil
+		}
+		var list []interface{}
+		for _, item := range strings.Split(inner, ",") {
+			v, err := parseYAMLScalar(strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case len(s) >= 2 && (s[0] == '"' || s[0] == '\''):
+		if s[len(s)-1] != s[0] {
+			return nil, fmt.Errorf("unterminated string %s", s)
+		}
+		return s[1 : len(s)-1], nil
+	case s == "true" || s == "false":
+		return s == "true", nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	return s, nil
+}
+
+// stripYAMLComment removes a trailing # comment outside quotes
+func stripYAMLComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' '):
+			return s[:i]
+		}
+	}
+	return s
+}
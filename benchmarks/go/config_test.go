@@ -0,0 +1,78 @@
+// Shared Configuration Tests - Go
+//
+// Run with: go test -run 'YAML|Config'
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseYAMLSubset(t *testing.T) {
+	doc, err := parseYAMLSubset(`
+# comment
+seed: 7
+name: "a # not a comment"
+iterations:
+  TcpEcho: 100   # trailing comment
+  nested:
+    deep: 1.5
+sizes: [64, 1024]
+empty:
+flag: true
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"seed": int64(7),
+		"name": "a # not a comment",
+		"iterations": map[string]interface{}{
+			"TcpEcho": int64(100),
+			"nested":  map[string]interface{}{"deep": 1.5},
+		},
+		"sizes": []interface{}{int64(64), int64(1024)},
+		"empty": nil,
+		"flag":  true,
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("parsed %#v\nwant %#v", doc, want)
+	}
+}
+
+func TestParseYAMLSubsetErrors(t *testing.T) {
+	for _, src := range []string{
+		"a: 1\n  b: 2",
+		"a:\n  - 1",
+		"a: 1\na: 2",
+		"novalue",
+		"a: [1, 2",
+		"a:b",
+	} {
+		if _, err := parseYAMLSubset(src); err == nil {
+			t.Errorf("parseYAMLSubset(%q) succeeded, want error", src)
+		}
+	}
+}
+
+func TestLoadSharedConfig(t *testing.T) {
+	c, err := LoadConfig(defaultConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.PayloadSizes.Request != 64 || c.Iterations["TcpReusedRequest"] != 10000 || c.Durations.Stream != 5*time.Second {
+		t.Errorf("unexpected shared config %+v", c)
+	}
+}
+
+func TestConfigSLOThresholds(t *testing.T) {
+	c, err := LoadConfig(defaultConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Duration{time.Millisecond, 10 * time.Millisecond}; !reflect.DeepEqual(c.SLO.Thresholds, want) {
+		t.Errorf("SLO thresholds %v, want %v", c.SLO.Thresholds, want)
+	}
+}
@@ -0,0 +1,142 @@
+// Count-Min Sketch Benchmark - Go
+//
+// Frequency estimation with a count-min sketch: depth rows of width
+// counters, each key incrementing one counter per row, and the estimate
+// taking the minimum across rows. Estimates never undercount and exceed
+// the true count by at most e*N/width with probability 1 - e^-depth. The
+// stream is Zipfian, like word or URL frequencies, so a few heavy hitters
+// dominate. Reports updates/sec and the estimate error. Pairs with the
+// HyperLogLog benchmarks.
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"time"
+)
+
+const (
+	countMinStream = 10_000_000
+	countMinKeys   = 1_000_000
+	countMinDepth  = 4
+)
+
+// CountMinSketch estimates key frequencies in sublinear space
+type CountMinSketch struct {
+	width  uint64 // a power of two
+	depth  int
+	counts []uint32
+}
+
+// NewCountMinSketch returns a sketch with depth rows of width counters;
+// width is rounded up to a power of two
+func NewCountMinSketch(width, depth int) *CountMinSketch {
+	w := uint64(1)
+	for w < uint64(width) {
+		w <<= 1
+	}
+	return &CountMinSketch{width: w, depth: depth, counts: make([]uint32, int(w)*depth)}
+}
+
+// Add counts one occurrence of key
+func (s *CountMinSketch) Add(key uint64) {
+	// Row indexes come from two halves of one hash (Kirsch-Mitzenmacher)
+	h := mix64(key)
+	h1, h2 := h, h>>32|1
+	for i := 0; i < s.depth; i++ {
+		s.counts[uint64(i)*s.width+(h1+uint64(i)*h2)&(s.width-1)]++
+	}
+}
+
+// Estimate returns an upper bound on key's count
+func (s *CountMinSketch) Estimate(key uint64) uint32 {
+	h := mix64(key)
+	h1, h2 := h, h>>32|1
+	est := uint32(math.MaxUint32)
+	for i := 0; i < s.depth; i++ {
+		est = min(est, s.counts[uint64(i)*s.width+(h1+uint64(i)*h2)&(s.width-1)])
+	}
+	return est
+}
+
+// countMinFixture is the Zipfian key stream and the exact count of every
+// key, built in setup
+var countMinFixture struct {
+	stream []uint64
+	exact  []uint32
+	// heavy are the 100 most frequent keys
+	heavy []uint64
+}
+
+func setupCountMin(b *B) error {
+	rng := newRand(1527)
+	zipf := rand.NewZipf(rng, 1.1, 1, countMinKeys-1)
+	stream := make([]uint64, countMinStream)
+	exact := make([]uint32, countMinKeys)
+	for i := range stream {
+		k := zipf.Uint64()
+		stream[i] = k
+		exact[k]++
+	}
+	keys := make([]uint64, countMinKeys)
+	for i := range keys {
+		keys[i] = uint64(i)
+	}
+	slices.SortFunc(keys, func(a, b uint64) int { return int(exact[b]) - int(exact[a]) })
+	countMinFixture.stream, countMinFixture.exact, countMinFixture.heavy = stream, exact, keys[:100]
+	return nil
+}
+
+func teardownCountMin() {
+	countMinFixture.stream, countMinFixture.exact, countMinFixture.heavy = nil, nil, nil
+}
+
+// reportCountMinError reports the mean overcount across every key seen,
+// the mean relative error of the heavy hitters and the share of keys whose
+// estimate is within the e*N/width guarantee
+func reportCountMinError(b *B, s *CountMinSketch) {
+	f := &countMinFixture
+	bound := math.E * countMinStream / float64(s.width)
+	var over float64
+	seen, within := 0, 0
+	for k, c := range f.exact {
+		if c == 0 {
+			continue
+		}
+		diff := float64(s.Estimate(uint64(k)) - c)
+		over += diff
+		seen++
+		if diff <= bound {
+			within++
+		}
+	}
+	heavyErr := 0.0
+	for _, k := range f.heavy {
+		heavyErr += float64(s.Estimate(k)-f.exact[k]) / float64(f.exact[k])
+	}
+	b.ReportMetric("mean_overcount", over/float64(seen))
+	b.ReportMetric("heavy_err_pct", heavyErr/float64(len(f.heavy))*100)
+	b.ReportMetric("within_bound_pct", float64(within)/float64(seen)*100)
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "CountMinSketch", Category: "probabilistic", Tags: []string{"cpu"},
+		Iterations: 5,
+		Axes:       []Axis{{Name: "width", Values: Ints(2048, 65536)}},
+		Setup:      setupCountMin, Teardown: teardownCountMin,
+		Fn: func(b *B) {
+			s := NewCountMinSketch(b.IntParam("width"), countMinDepth)
+			start := time.Now()
+			for _, k := range countMinFixture.stream {
+				s.Add(k)
+			}
+			b.ReportMetric("updates_per_sec", countMinStream/time.Since(start).Seconds())
+			b.StopTimer()
+			reportCountMinError(b, s)
+			b.StartTimer()
+		},
+	})
+}
@@ -0,0 +1,23 @@
+//go:build unix
+
+// Process CPU Time - Go
+//
+// User plus system CPU time consumed by the whole process, for benchmarks
+// that report the CPU cost of work dominated by waiting. Platforms without
+// getrusage report zero (cputime_other.go).
+
+package main
+
+import (
+	"syscall"
+	"time"
+)
+
+// processCPUTime returns the CPU time the process has used so far
+func processCPUTime() time.Duration {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0
+	}
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano())
+}
@@ -0,0 +1,84 @@
+// Go TCP Deadline Overhead Benchmark
+// The reused-connection echo round trip of TcpReusedRequest, with the read
+// and/or write deadline set before every request, the way servers bound
+// each operation. Go arms deadlines on the runtime poller's timers, other
+// runtimes with a timer per operation or a poll timeout, so the cost
+// differs widely between them:
+//
+//   - none: no deadlines, TcpReusedRequest's round trip
+//   - read, write: SetReadDeadline or SetWriteDeadline per request
+//   - both: both per request
+//
+// Besides the round-trip latency, deadline_ns reports the mean time spent
+// computing and setting the deadlines of one request.
+
+package main
+
+import (
+	"time"
+)
+
+// tcpDeadlineTimeout is how far ahead each deadline is set; no request
+// comes near it, so only arming and disarming is measured
+const tcpDeadlineTimeout = 5 * time.Second
+
+// deadlineCost accumulates the time spent setting deadlines in one run of
+// a case; a new B starts over
+var deadlineCost struct {
+	b     *B
+	calls int64
+	total time.Duration
+}
+
+func setupTcpDeadlineRequest(b *B) error {
+	return tcpRequest.dial(tcpTransport{}, benchConfig.PayloadSizes.Request)
+}
+
+// benchDeadlineRequest sets the case's deadlines and performs one round
+// trip over the connection opened in setup
+func benchDeadlineRequest(b *B) {
+	c := &tcpRequest
+	mode := b.StringParam("deadlines")
+	b.SetBytes(int64(len(c.payload)))
+	start := time.Now()
+	if mode == "read" || mode == "both" {
+		if err := c.conn.SetReadDeadline(start.Add(tcpDeadlineTimeout)); err != nil {
+			b.Fatal(err)
+			return
+		}
+	}
+	if mode == "write" || mode == "both" {
+		if err := c.conn.SetWriteDeadline(start.Add(tcpDeadlineTimeout)); err != nil {
+			b.Fatal(err)
+			return
+		}
+	}
+	set := time.Since(start)
+	if err := c.transport.RoundTrip(c.conn, c.payload, c.reply); err != nil {
+		b.Fatal(err)
+		return
+	}
+	rtt := time.Since(start)
+	b.Histogram().Record(rtt)
+	tcpRTT.record(b, rtt)
+
+	d := &deadlineCost
+	if d.b != b {
+		d.b, d.calls, d.total = b, 0, 0
+	}
+	d.calls++
+	d.total += set
+	if mode != "none" {
+		b.ReportMetric("deadline_ns", float64(d.total)/float64(d.calls))
+	}
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "TcpDeadlineRequest", Category: "tcp", Tags: []string{"net", "remote"},
+		Iterations: 10000,
+		Axes:       []Axis{{Name: "deadlines", Values: Strings("none", "read", "write", "both")}},
+		Setup:      setupTcpDeadlineRequest, Teardown: tcpRequest.close,
+		Fn: benchDeadlineRequest,
+	})
+}
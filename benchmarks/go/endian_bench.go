@@ -0,0 +1,105 @@
+// Endianness Conversion Benchmarks - Go
+//
+// Decodes and encodes 64MB of 64-bit words the ways wire-format decoders
+// do: binary.LittleEndian, binary.BigEndian (a byte swap on little-endian
+// hosts) and a raw unsafe load or store in host order. The offset axis
+// shifts every access off 8-byte alignment by one byte, which x86 and
+// arm64 tolerate at some cost; the unsafe unaligned path would fault on
+// strict-alignment architectures and is only meaningful on those two.
+
+package main
+
+import (
+	"encoding/binary"
+	"unsafe"
+)
+
+const endianWords = 8 << 20
+
+// endianState is the buffer, allocated as words so offset 0 is aligned,
+// with one spare word for the misaligned cases
+var endianState struct {
+	buf []byte
+}
+
+var endianSink uint64
+
+func setupEndian(b *B) error {
+	words := make([]uint64, endianWords+1)
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(&words[0])), 8*len(words))
+	rng := newRand(1533)
+	for i := range words {
+		words[i] = rng.Uint64()
+	}
+	endianState.buf = buf
+	return nil
+}
+
+func teardownEndian() {
+	endianState.buf = nil
+}
+
+// sumWords decodes endianWords words starting at buf[0] with method
+func sumWords(buf []byte, method string) uint64 {
+	var sum uint64
+	switch method {
+	case "little":
+		for i := 0; i < endianWords; i++ {
+			sum += binary.LittleEndian.Uint64(buf[8*i:])
+		}
+	case "big":
+		for i := 0; i < endianWords; i++ {
+			sum += binary.BigEndian.Uint64(buf[8*i:])
+		}
+	case "unsafe":
+		_ = buf[8*endianWords-1]
+		p := unsafe.Pointer(unsafe.SliceData(buf))
+		for i := 0; i < endianWords; i++ {
+			sum += *(*uint64)(unsafe.Add(p, 8*i))
+		}
+	}
+	return sum
+}
+
+// fillWords encodes the counter values 0..endianWords-1 into buf with method
+func fillWords(buf []byte, method string) {
+	switch method {
+	case "little":
+		for i := 0; i < endianWords; i++ {
+			binary.LittleEndian.PutUint64(buf[8*i:], uint64(i))
+		}
+	case "big":
+		for i := 0; i < endianWords; i++ {
+			binary.BigEndian.PutUint64(buf[8*i:], uint64(i))
+		}
+	case "unsafe":
+		_ = buf[8*endianWords-1]
+		p := unsafe.Pointer(unsafe.SliceData(buf))
+		for i := 0; i < endianWords; i++ {
+			*(*uint64)(unsafe.Add(p, 8*i)) = uint64(i)
+		}
+	}
+}
+
+func init() {
+	axes := []Axis{
+		{Name: "method", Values: Strings("little", "big", "unsafe")},
+		{Name: "offset", Values: Ints(0, 1)},
+	}
+	Register(Benchmark{
+		Name: "EndianDecode", Category: "endian", Tags: []string{"cpu", "serde"},
+		Iterations: 50, DataSize: 8 * endianWords, Axes: axes,
+		Setup: setupEndian, Teardown: teardownEndian,
+		Fn: func(b *B) {
+			endianSink = sumWords(endianState.buf[b.IntParam("offset"):], b.StringParam("method"))
+		},
+	})
+	Register(Benchmark{
+		Name: "EndianEncode", Category: "endian", Tags: []string{"cpu", "serde"},
+		Iterations: 50, DataSize: 8 * endianWords, Axes: axes,
+		Setup: setupEndian, Teardown: teardownEndian,
+		Fn: func(b *B) {
+			fillWords(endianState.buf[b.IntParam("offset"):], b.StringParam("method"))
+		},
+	})
+}
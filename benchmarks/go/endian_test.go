@@ -0,0 +1,27 @@
+// Endianness Tests - Go
+//
+// Run with: go test -run Endian
+
+package main
+
+import "testing"
+
+func TestEndianMethodsRoundTrip(t *testing.T) {
+	setupEndian(nil)
+	defer teardownEndian()
+	for _, offset := range []int{0, 1} {
+		buf := endianState.buf[offset:]
+		for _, method := range []string{"little", "big", "unsafe"} {
+			fillWords(buf, method)
+			// Decoding in the order written recovers the values 0..n-1
+			want := uint64(endianWords) * (endianWords - 1) / 2
+			if got := sumWords(buf, method); got != want {
+				t.Errorf("%s at offset %d: sum %d, want %d", method, offset, got, want)
+			}
+		}
+		fillWords(buf, "big")
+		if sumWords(buf, "little") == sumWords(buf, "big") {
+			t.Errorf("offset %d: little- and big-endian decodes agree on big-endian data", offset)
+		}
+	}
+}
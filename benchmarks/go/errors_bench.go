@@ -0,0 +1,129 @@
+// Error Handling Benchmarks - Go
+//
+// Measures the cost of Go's error model: explicit error returns through deep
+// call chains, errors.Is/As over wrapped chains, sentinel comparisons and
+// panic/recover used as non-local control flow. Error-model overhead is a
+// recurring point in the TML comparison.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+var errBenchSentinel = errors.New("sentinel failure")
+
+// benchError is a typed error located with errors.As
+type benchError struct {
+	Code int
+}
+
+func (e *benchError) Error() string {
+	return fmt.Sprintf("bench error %d", e.Code)
+}
+
+// failAtDepth returns an error from the bottom of a depth-deep call chain
+func failAtDepth(depth int) error {
+	if depth == 0 {
+		return errBenchSentinel
+	}
+	if err := failAtDepth(depth - 1); err != nil {
+		return err
+	}
+	return nil
+}
+
+// panicAtDepth panics from the bottom of a depth-deep call chain
+func panicAtDepth(depth int) {
+	if depth == 0 {
+		panic(errBenchSentinel)
+	}
+	panicAtDepth(depth - 1)
+}
+
+// recoverFrom runs panicAtDepth and converts the panic back into an error
+func recoverFrom(depth int) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = r.(error)
+		}
+	}()
+	panicAtDepth(depth)
+	return nil
+}
+
+// wrapChain wraps base depth times with fmt.Errorf("%w")
+func wrapChain(base error, depth int) error {
+	err := base
+	for i := 0; i < depth; i++ {
+		err = fmt.Errorf("layer %d: %w", i, err)
+	}
+	return err
+}
+
+// errorSink keeps results observable so calls are not optimized away
+var errorSink bool
+
+// wrappedErr is the chain the current ErrWrappedIs or ErrWrappedAs case
+// searches; it is built in setup, so only the lookup is timed
+var wrappedErr error
+
+func init() {
+	depths := []Axis{{Name: "depth", Values: Ints(1, 10, 100)}}
+
+	Register(Benchmark{
+		Name: "ErrReturnDeep", Category: "errors", Tags: []string{"cpu"},
+		Iterations: 1_000_000, Axes: depths,
+		Fn: func(b *B) {
+			errorSink = failAtDepth(b.IntParam("depth")) != nil
+		},
+	})
+	Register(Benchmark{
+		Name: "ErrPanicRecover", Category: "errors", Tags: []string{"cpu"},
+		Iterations: 1_000_000, Axes: depths,
+		Fn: func(b *B) {
+			errorSink = recoverFrom(b.IntParam("depth")) != nil
+		},
+	})
+	Register(Benchmark{
+		Name: "ErrSentinelCompare", Category: "errors", Tags: []string{"cpu"},
+		Iterations: 10_000_000,
+		Fn: func(b *B) {
+			errorSink = failAtDepth(0) == errBenchSentinel
+		},
+	})
+
+	Register(Benchmark{
+		Name: "ErrWrappedIs", Category: "errors", Tags: []string{"cpu"},
+		Iterations: 1_000_000, Axes: depths,
+		Setup: func(b *B) error {
+			wrappedErr = wrapChain(errBenchSentinel, b.IntParam("depth"))
+			return nil
+		},
+		Teardown: func() { wrappedErr = nil },
+		Fn: func(b *B) {
+			errorSink = errors.Is(wrappedErr, errBenchSentinel)
+		},
+	})
+	Register(Benchmark{
+		Name: "ErrWrappedAs", Category: "errors", Tags: []string{"cpu", "alloc"},
+		Iterations: 1_000_000, Axes: depths,
+		Setup: func(b *B) error {
+			wrappedErr = wrapChain(&benchError{Code: 42}, b.IntParam("depth"))
+			return nil
+		},
+		Teardown: func() { wrappedErr = nil },
+		Fn: func(b *B) {
+			var target *benchError
+			errorSink = errors.As(wrappedErr, &target)
+		},
+	})
+	Register(Benchmark{
+		Name: "ErrWrapCreate", Category: "errors", Tags: []string{"cpu", "alloc"},
+		Iterations: 100_000, Axes: depths,
+		Fn: func(b *B) {
+			errorSink = wrapChain(errBenchSentinel, b.IntParam("depth")) != nil
+		},
+	})
+}
@@ -0,0 +1,141 @@
+// Interface Boxing and Escape Analysis - Go
+//
+// Pairs of functions doing the same work, where the first form forces a heap
+// escape (interface conversion, fmt-style variadics, pointers outliving the
+// frame) and the second is the tuned, non-escaping equivalent. The
+// harness's allocs/op shows which form escapes: 1 or more for the first,
+// 0 for the second.
+//
+// Inspect the compiler's decisions with: go build -gcflags=-m
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// shape is the interface used for the dynamic-dispatch pair
+type shape interface {
+	area() int
+}
+
+type rect struct {
+	w, h int
+}
+
+func (r rect) area() int { return r.w * r.h }
+
+// boxedSink and intSink outlive every call, forcing stores into them to escape
+var (
+	boxedSink interface{}
+	intSink   int
+	strSink   string
+	byteSink  []byte
+)
+
+// boxInt converts an int to interface{} and stores it: one allocation for
+// values outside the runtime's small-integer cache
+func boxInt(v int) {
+	boxedSink = v
+}
+
+// storeInt is the non-boxing equivalent of boxInt
+func storeInt(v int) {
+	intSink = v
+}
+
+// areaViaInterface stores a rect behind an interface that escapes
+func areaViaInterface(w, h int) int {
+	var s shape = &rect{w, h}
+	boxedSink = s
+	return s.area()
+}
+
+// areaConcrete calls the same method on a stack value
+func areaConcrete(w, h int) int {
+	r := rect{w, h}
+	return r.area()
+}
+
+// formatSprintf formats with fmt: the variadic ...interface{} boxes v and
+// the result string is heap allocated
+func formatSprintf(v int) string {
+	return fmt.Sprintf("id-%d", v)
+}
+
+// formatAppend formats into a caller-provided buffer without allocating
+func formatAppend(buf []byte, v int) []byte {
+	buf = append(buf[:0], "id-"...)
+	return strconv.AppendInt(buf, int64(v), 10)
+}
+
+// newRectPtr returns a pointer to a local, which therefore escapes
+//
+//go:noinline
+func newRectPtr(w, h int) *rect {
+	return &rect{w, h}
+}
+
+// newRectValue returns the same data by value
+//
+//go:noinline
+func newRectValue(w, h int) rect {
+	return rect{w, h}
+}
+
+// sumDynamicSlice allocates a buffer whose size is unknown at compile time
+func sumDynamicSlice(n int) int {
+	buf := make([]int, n)
+	for i := range buf {
+		buf[i] = i
+	}
+	total := 0
+	for _, v := range buf {
+		total += v
+	}
+	return total
+}
+
+// sumFixedSlice uses a constant-size buffer that stays on the stack
+func sumFixedSlice() int {
+	var buf [64]int
+	for i := range buf {
+		buf[i] = i
+	}
+	total := 0
+	for _, v := range buf {
+		total += v
+	}
+	return total
+}
+
+// escapeN varies the inputs between iterations so no call can be folded
+// into a constant
+var escapeN int
+
+func init() {
+	register := func(name string, fn func()) {
+		Register(Benchmark{
+			Name: name, Category: "escape", Tags: []string{"cpu", "alloc"},
+			Iterations: 1_000_000,
+			Fn: func(b *B) {
+				escapeN++
+				fn()
+			},
+		})
+	}
+
+	// Past the runtime's cache of small integers, which box without
+	// allocating
+	register("EscapeBoxInt", func() { boxInt(escapeN + 1000) })
+	register("EscapeStoreInt", func() { storeInt(escapeN + 1000) })
+	register("EscapeInterfaceCall", func() { intSink = areaViaInterface(escapeN, 3) })
+	register("EscapeConcreteCall", func() { intSink = areaConcrete(escapeN, 3) })
+	register("EscapeSprintf", func() { strSink = formatSprintf(escapeN) })
+	register("EscapeAppendInt", func() { byteSink = formatAppend(byteSink, escapeN) })
+	register("EscapeReturnPointer", func() { intSink = newRectPtr(escapeN, 3).area() })
+	register("EscapeReturnValue", func() { intSink = newRectValue(escapeN, 3).area() })
+	register("EscapeDynamicSlice", func() { intSink = sumDynamicSlice(64 + escapeN%2) })
+	register("EscapeFixedSlice", func() { intSink = sumFixedSlice() })
+}
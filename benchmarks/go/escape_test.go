@@ -0,0 +1,33 @@
+// Interface Boxing and Escape Analysis Tests - Go
+//
+// Run with: go test -run Escape
+
+package main
+
+import "testing"
+
+func TestEscapePairs(t *testing.T) {
+	// Inputs vary per call, so the compiler cannot box constants statically
+	// or size the dynamic slice at compile time
+	buf := make([]byte, 0, 32)
+	n := 1000
+	next := func() int { n++; return n }
+	pairs := []struct {
+		name            string
+		escaping, tuned func()
+	}{
+		{"BoxInt", func() { boxInt(next()) }, func() { storeInt(next()) }},
+		{"InterfaceCall", func() { intSink = areaViaInterface(4, 3) }, func() { intSink = areaConcrete(4, 3) }},
+		{"Sprintf", func() { strSink = formatSprintf(1000) }, func() { buf = formatAppend(buf, 1000) }},
+		{"ReturnPointer", func() { intSink = newRectPtr(4, 3).area() }, func() { intSink = newRectValue(4, 3).area() }},
+		{"DynamicSlice", func() { intSink = sumDynamicSlice(64 + next()%2) }, func() { intSink = sumFixedSlice() }},
+	}
+	for _, p := range pairs {
+		if n := testing.AllocsPerRun(100, p.escaping); n < 1 {
+			t.Errorf("%s: escaping form made %v allocs/op, want at least 1", p.name, n)
+		}
+		if n := testing.AllocsPerRun(100, p.tuned); n != 0 {
+			t.Errorf("%s: tuned form made %v allocs/op, want 0", p.name, n)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// External Merge Sort Benchmark - Go
+//
+// Sorts a 5GB file of random 64-bit keys under a memory budget: the input
+// is cut into sorted runs of run_mb each, written to temporary files, then
+// k-way merged into the output using whatever is left of the budget for
+// read buffers. Reports sort MB/s and the temporary I/O volume, for the
+// large-data chapter of the TML comparison.
+
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+const extSortBytes = 5 << 30
+
+// extSortState is the input file generated in setup and its directory
+var extSortState struct {
+	dir   string
+	input string
+}
+
+func setupExtSort(b *B) error {
+	dir, err := os.MkdirTemp("", "tml-extsort-")
+	if err != nil {
+		return err
+	}
+	extSortState.dir = dir
+	extSortState.input = filepath.Join(dir, "input")
+
+	f, err := os.Create(extSortState.input)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriterSize(f, 1<<20)
+	rng := newRand(1519)
+	var buf [8]byte
+	for i := 0; i < extSortBytes/8; i++ {
+		binary.LittleEndian.PutUint64(buf[:], rng.Uint64())
+		w.Write(buf[:])
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func teardownExtSort() {
+	if extSortState.dir != "" {
+		os.RemoveAll(extSortState.dir)
+	}
+	extSortState.dir, extSortState.input = "", ""
+}
+
+// sortedRun is one run file being consumed by the merge
+type sortedRun struct {
+	r   *bufio.Reader
+	f   *os.File
+	key uint64
+	buf [8]byte
+}
+
+func (s *sortedRun) next() bool {
+	if _, err := io.ReadFull(s.r, s.buf[:]); err != nil {
+		return false
+	}
+	s.key = binary.LittleEndian.Uint64(s.buf[:])
+	return true
+}
+
+type sortedRunHeap []*sortedRun
+
+func (h sortedRunHeap) Len() int            { return len(h) }
+func (h sortedRunHeap) Less(i, j int) bool  { return h[i].key < h[j].key }
+func (h sortedRunHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *sortedRunHeap) Push(x interface{}) { *h = append(*h, x.(*sortedRun)) }
+func (h *sortedRunHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// externalSort sorts input into output, returning the bytes written to and
+// read back from temporary run files
+func externalSort(input, output, tmpDir string, budget, runSize int) (tempWritten, tempRead int64, runs int, err error) {
+	in, err := os.Open(input)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer in.Close()
+
+	// Phase 1: sorted runs
+	keys := make([]uint64, runSize/8)
+	raw := make([]byte, runSize)
+	var runPaths []string
+	for {
+		n, err := io.ReadFull(in, raw)
+		if n == 0 {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return 0, 0, 0, err
+		}
+		chunk := keys[:n/8]
+		for i := range chunk {
+			chunk[i] = binary.LittleEndian.Uint64(raw[i*8:])
+		}
+		slices.Sort(chunk)
+		for i, k := range chunk {
+			binary.LittleEndian.PutUint64(raw[i*8:], k)
+		}
+		path := filepath.Join(tmpDir, fmt.Sprintf("run-%d", len(runPaths)))
+		if err := os.WriteFile(path, raw[:n], 0o644); err != nil {
+			return 0, 0, 0, err
+		}
+		runPaths = append(runPaths, path)
+		tempWritten += int64(n)
+	}
+	keys, raw = nil, nil
+
+	// Phase 2: k-way merge, splitting the budget across read buffers
+	readBuf := budget / (len(runPaths) + 1)
+	if readBuf < 64<<10 {
+		readBuf = 64 << 10
+	}
+	h := sortedRunHeap{}
+	defer func() {
+		for _, s := range h {
+			s.f.Close()
+		}
+	}()
+	for _, path := range runPaths {
+		f, err := os.Open(path)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		s := &sortedRun{r: bufio.NewReaderSize(f, readBuf), f: f}
+		if s.next() {
+			h = append(h, s)
+		} else {
+			f.Close()
+		}
+	}
+	heap.Init(&h)
+
+	out, err := os.Create(output)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer out.Close()
+	w := bufio.NewWriterSize(out, readBuf)
+	var buf [8]byte
+	prev := uint64(0)
+	for h.Len() > 0 {
+		s := h[0]
+		if s.key < prev {
+			return 0, 0, 0, fmt.Errorf("merge produced %d after %d", s.key, prev)
+		}
+		prev = s.key
+		binary.LittleEndian.PutUint64(buf[:], s.key)
+		w.Write(buf[:])
+		tempRead += 8
+		if s.next() {
+			heap.Fix(&h, 0)
+		} else {
+			s.f.Close()
+			heap.Pop(&h)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return 0, 0, 0, err
+	}
+	for _, path := range runPaths {
+		os.Remove(path)
+	}
+	return tempWritten, tempRead, len(runPaths), nil
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "ExternalSort", Category: "extsort", Tags: []string{"cpu", "alloc", "large"},
+		Iterations: 1, DataSize: extSortBytes,
+		Axes: []Axis{
+			{Name: "budget_mb", Values: Ints(256, 1024)},
+			{Name: "run_mb", Values: Ints(32, 128)},
+		},
+		Setup: setupExtSort, Teardown: teardownExtSort,
+		Fn: func(b *B) {
+			budget := b.IntParam("budget_mb") << 20
+			runSize := b.IntParam("run_mb") << 20
+			if runSize > budget {
+				runSize = budget
+			}
+			output := filepath.Join(extSortState.dir, "output")
+			written, read, runs, err := externalSort(extSortState.input, output, extSortState.dir, budget, runSize)
+			if err != nil {
+				b.Fatal(err)
+				return
+			}
+			os.Remove(output)
+			b.ReportMetric("runs", float64(runs))
+			b.ReportMetric("temp_write_mb", float64(written)/(1<<20))
+			b.ReportMetric("temp_read_mb", float64(read)/(1<<20))
+		},
+	})
+}
@@ -0,0 +1,380 @@
+// FlatBuffers Benchmarks - Go
+//
+// The zero-copy end of the serialization comparison: the user list of the
+// large fixture as a FlatBuffers buffer (../common/fixtures.fbs), which is
+// read in place instead of decoded:
+//
+//   - FlatbufBuild builds the buffer from the users, reusing the builder as
+//     real code does, and reports its size as wire_bytes, vs_json_pct and
+//     vs_proto_pct against the JSON fixture and the protobuf encoding
+//   - FlatbufAccess reads fields out of a built buffer with no decode step:
+//     access=one reads one user's name and age, access=all every field of
+//     every user, the work a decode would do up front
+//
+// The suite has no third-party dependencies, so as with protobuf
+// (proto_bench.go) this holds what the flatbuffers runtime and flatc's
+// generated code would: a builder writing back to front as the runtime's
+// does (aligned scalars, defaults left out, vtables shared between tables
+// of the same layout) and accessors looking fields up through the vtable.
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Field slots of the schema's tables, in declaration order
+const (
+	fbUserID = iota
+	fbUserName
+	fbUserEmail
+	fbUserActive
+	fbUserAge
+	fbUserFields
+)
+
+const (
+	fbLargeUsers = iota
+	fbLargeMetadata
+	fbLargeFields
+)
+
+// fbBuilder builds a buffer back to front: objects are prepended, so
+// children are written before the tables referring to them, and offsets
+// are sizes of the buffer so far, which stay valid as it grows
+type fbBuilder struct {
+	buf      []byte
+	head     int
+	minAlign int
+	// vtables are the offsets of the vtables written so far
+	vtables []int
+	// fields are the offsets of the current table's fields, 0 if absent
+	fields    []int
+	tableSize int
+}
+
+func newFbBuilder(capacity int) *fbBuilder {
+	b := &fbBuilder{buf: make([]byte, capacity)}
+	b.Reset()
+	return b
+}
+
+// Reset empties b, keeping its memory
+func (b *fbBuilder) Reset() {
+	b.head = len(b.buf)
+	b.minAlign = 1
+	b.vtables = b.vtables[:0]
+}
+
+func (b *fbBuilder) size() int {
+	return len(b.buf) - b.head
+}
+
+// grow makes room for n more bytes, moving the contents to the back of a
+// larger buffer
+func (b *fbBuilder) grow(n int) {
+	if b.head >= n {
+		return
+	}
+	buf := make([]byte, 2*len(b.buf)+n)
+	copy(buf[len(buf)-b.size():], b.buf[b.head:])
+	b.head = len(buf) - b.size()
+	b.buf = buf
+}
+
+// prep pads so that, after extra more bytes, a value of align bytes can
+// be prepended aligned
+func (b *fbBuilder) prep(align, extra int) {
+	b.minAlign = max(b.minAlign, align)
+	pad := -(b.size() + extra) & (align - 1)
+	b.grow(pad + extra + align)
+	for range pad {
+		b.head--
+		b.buf[b.head] = 0
+	}
+}
+
+func (b *fbBuilder) placeUint32(v uint32) {
+	b.head -= 4
+	binary.LittleEndian.PutUint32(b.buf[b.head:], v)
+}
+
+func (b *fbBuilder) prependInt32(v int32) {
+	b.prep(4, 0)
+	b.placeUint32(uint32(v))
+}
+
+func (b *fbBuilder) prependUint16(v uint16) {
+	b.prep(2, 0)
+	b.head -= 2
+	binary.LittleEndian.PutUint16(b.buf[b.head:], v)
+}
+
+func (b *fbBuilder) prependBool(v bool) {
+	b.prep(1, 0)
+	b.head--
+	b.buf[b.head] = 0
+	if v {
+		b.buf[b.head] = 1
+	}
+}
+
+// prependOffset prepends a reference to the object at off
+func (b *fbBuilder) prependOffset(off int) {
+	b.prep(4, 0)
+	b.placeUint32(uint32(b.size() - off + 4))
+}
+
+// createString writes s, NUL-terminated, and returns its offset
+func (b *fbBuilder) createString(s string) int {
+	b.prep(4, len(s)+1)
+	b.head--
+	b.buf[b.head] = 0
+	b.head -= len(s)
+	copy(b.buf[b.head:], s)
+	b.placeUint32(uint32(len(s)))
+	return b.size()
+}
+
+// createOffsets writes a vector of references to the objects at offs and
+// returns its offset
+func (b *fbBuilder) createOffsets(offs []int) int {
+	b.prep(4, 4*len(offs))
+	for i := len(offs) - 1; i >= 0; i-- {
+		b.prependOffset(offs[i])
+	}
+	b.placeUint32(uint32(len(offs)))
+	return b.size()
+}
+
+func (b *fbBuilder) startTable(fields int) {
+	b.fields = append(b.fields[:0], make([]int, fields)...)
+	b.tableSize = b.size()
+}
+
+func (b *fbBuilder) addInt32(slot int, v int32) {
+	if v != 0 {
+		b.prependInt32(v)
+		b.fields[slot] = b.size()
+	}
+}
+
+func (b *fbBuilder) addBool(slot int, v bool) {
+	if v {
+		b.prependBool(v)
+		b.fields[slot] = b.size()
+	}
+}
+
+func (b *fbBuilder) addOffset(slot, off int) {
+	if off != 0 {
+		b.prependOffset(off)
+		b.fields[slot] = b.size()
+	}
+}
+
+// addStruct records the struct just prepended inline as slot's field
+func (b *fbBuilder) addStruct(slot int) {
+	b.fields[slot] = b.size()
+}
+
+// endTable writes the table's vtable, or points it at an identical one
+// written before, and returns the table's offset
+func (b *fbBuilder) endTable() int {
+	b.prependInt32(0)
+	table := b.size()
+	n := len(b.fields)
+	for n > 0 && b.fields[n-1] == 0 {
+		n--
+	}
+	for i := n - 1; i >= 0; i-- {
+		var off uint16
+		if b.fields[i] != 0 {
+			off = uint16(table - b.fields[i])
+		}
+		b.prependUint16(off)
+	}
+	b.prependUint16(uint16(table - b.tableSize))
+	b.prependUint16(uint16(2 * (n + 2)))
+
+	vtable := b.size()
+	vt := b.buf[b.head : b.head+2*(n+2)]
+	for _, prev := range b.vtables {
+		p := len(b.buf) - prev
+		if int(binary.LittleEndian.Uint16(b.buf[p:])) == len(vt) && string(b.buf[p:p+len(vt)]) == string(vt) {
+			vtable = prev
+			b.head += len(vt)
+			break
+		}
+	}
+	if vtable == b.size() {
+		b.vtables = append(b.vtables, vtable)
+	}
+	binary.LittleEndian.PutUint32(b.buf[len(b.buf)-table:], uint32(int32(vtable-table)))
+	return table
+}
+
+// Finish writes the reference to the root table and returns the buffer
+func (b *fbBuilder) Finish(root int) []byte {
+	b.prep(b.minAlign, 4)
+	b.prependOffset(root)
+	return b.buf[b.head:]
+}
+
+// buildFbLarge builds the large fixture's buffer
+func buildFbLarge(b *fbBuilder, l *jsonLarge) []byte {
+	b.Reset()
+	users := make([]int, len(l.Users))
+	for i := range l.Users {
+		u := &l.Users[i]
+		name, email := b.createString(u.Name), b.createString(u.Email)
+		b.startTable(fbUserFields)
+		b.addInt32(fbUserID, int32(u.ID))
+		b.addOffset(fbUserName, name)
+		b.addOffset(fbUserEmail, email)
+		b.addBool(fbUserActive, u.Active)
+		b.addInt32(fbUserAge, int32(u.Age))
+		users[i] = b.endTable()
+	}
+	vec := b.createOffsets(users)
+	b.startTable(fbLargeFields)
+	b.addOffset(fbLargeUsers, vec)
+	b.prependInt32(int32(l.Metadata.Count))
+	b.addStruct(fbLargeMetadata)
+	return b.Finish(b.endTable())
+}
+
+// fbTable is a table read in place, as generated accessors wrap it
+type fbTable struct {
+	buf []byte
+	pos int
+}
+
+func (t fbTable) uint32(pos int) int {
+	return int(binary.LittleEndian.Uint32(t.buf[pos:]))
+}
+
+// field returns the position of slot's field, or 0 if the table leaves it
+// out
+func (t fbTable) field(slot int) int {
+	vtable := t.pos - int(int32(binary.LittleEndian.Uint32(t.buf[t.pos:])))
+	entry := 4 + 2*slot
+	if entry >= int(binary.LittleEndian.Uint16(t.buf[vtable:])) {
+		return 0
+	}
+	if off := int(binary.LittleEndian.Uint16(t.buf[vtable+entry:])); off != 0 {
+		return t.pos + off
+	}
+	return 0
+}
+
+func (t fbTable) int32(slot int) int32 {
+	if p := t.field(slot); p != 0 {
+		return int32(binary.LittleEndian.Uint32(t.buf[p:]))
+	}
+	return 0
+}
+
+func (t fbTable) bool(slot int) bool {
+	if p := t.field(slot); p != 0 {
+		return t.buf[p] != 0
+	}
+	return false
+}
+
+// bytes returns slot's string, aliasing the buffer
+func (t fbTable) bytes(slot int) []byte {
+	p := t.field(slot)
+	if p == 0 {
+		return nil
+	}
+	p += t.uint32(p)
+	return t.buf[p+4 : p+4+t.uint32(p)]
+}
+
+// fbLarge is the root table of a large fixture buffer
+type fbLarge struct{ fbTable }
+
+func fbLargeRoot(buf []byte) fbLarge {
+	return fbLarge{fbTable{buf, int(binary.LittleEndian.Uint32(buf))}}
+}
+
+func (l fbLarge) UsersLength() int {
+	p := l.field(fbLargeUsers)
+	if p == 0 {
+		return 0
+	}
+	return l.uint32(p + l.uint32(p))
+}
+
+func (l fbLarge) User(i int) fbUser {
+	p := l.field(fbLargeUsers)
+	p += l.uint32(p) + 4 + 4*i
+	return fbUser{fbTable{l.buf, p + l.uint32(p)}}
+}
+
+func (l fbLarge) Count() int32 {
+	return l.int32(fbLargeMetadata)
+}
+
+// fbUser is one user table
+type fbUser struct{ fbTable }
+
+func (u fbUser) ID() int32     { return u.int32(fbUserID) }
+func (u fbUser) Name() []byte  { return u.bytes(fbUserName) }
+func (u fbUser) Email() []byte { return u.bytes(fbUserEmail) }
+func (u fbUser) Active() bool  { return u.bool(fbUserActive) }
+func (u fbUser) Age() int32    { return u.int32(fbUserAge) }
+
+// fbAccessSink keeps the reads of FlatbufAccess from being optimized away
+var fbAccessSink int
+
+func init() {
+	fixture := GenerateLargeJSON()
+	var large jsonLarge
+	if err := decodeJSONStrict(fixture, &large); err != nil {
+		panic("json fixture Large: " + err.Error())
+	}
+	builder := newFbBuilder(1024)
+	encoded := append([]byte(nil), buildFbLarge(builder, &large)...)
+	protoSize := float64(len(marshalProto[jsonLarge](&large)))
+
+	Register(Benchmark{
+		Name: "FlatbufBuild", Category: "flatbuffers", Tags: []string{"serde"},
+		Iterations: 1000, DataSize: int64(len(encoded)),
+		Fn: func(b *B) {
+			buf := buildFbLarge(builder, &large)
+			b.ReportMetric("wire_bytes", float64(len(buf)))
+			b.ReportMetric("vs_json_pct", percentChange(float64(len(fixture)), float64(len(buf))))
+			b.ReportMetric("vs_proto_pct", percentChange(protoSize, float64(len(buf))))
+		},
+	})
+	Register(Benchmark{
+		Name: "FlatbufAccess", Category: "flatbuffers", Tags: []string{"serde"},
+		Iterations: 10000, DataSize: int64(len(encoded)),
+		Axes: []Axis{{Name: "access", Values: Strings("one", "all")}},
+		Fn: func(b *B) {
+			root := fbLargeRoot(encoded)
+			n := root.UsersLength()
+			if n != len(large.Users) {
+				b.Fatal(fmt.Errorf("buffer has %d users, want %d", n, len(large.Users)))
+				return
+			}
+			if b.StringParam("access") == "one" {
+				u := root.User(n / 2)
+				fbAccessSink = len(u.Name()) + int(u.Age())
+				return
+			}
+			sum := int(root.Count())
+			for i := range n {
+				u := root.User(i)
+				sum += int(u.ID()) + len(u.Name()) + len(u.Email()) + int(u.Age())
+				if u.Active() {
+					sum++
+				}
+			}
+			fbAccessSink = sum
+		},
+	})
+}
@@ -0,0 +1,68 @@
+// FlatBuffers Tests - Go
+//
+// Run with: go test -run Flatbuf
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFlatbufRoundTrip(t *testing.T) {
+	var large jsonLarge
+	if err := decodeJSONStrict(GenerateLargeJSON(), &large); err != nil {
+		t.Fatal(err)
+	}
+	// Defaults left out must read back as defaults
+	large.Users[1].Age = 0
+	buf := buildFbLarge(newFbBuilder(1024), &large)
+
+	root := fbLargeRoot(buf)
+	if n := root.UsersLength(); n != len(large.Users) {
+		t.Fatalf("buffer has %d users, want %d", n, len(large.Users))
+	}
+	if int(root.Count()) != large.Metadata.Count {
+		t.Errorf("count %d, want %d", root.Count(), large.Metadata.Count)
+	}
+	for i, want := range large.Users {
+		u := root.User(i)
+		got := jsonUser{ID: int(u.ID()), Name: string(u.Name()), Email: string(u.Email()), Active: u.Active(), Age: int(u.Age())}
+		if got != want {
+			t.Errorf("user %d read as %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestFlatbufBuilderGrowsAndResets(t *testing.T) {
+	var large jsonLarge
+	if err := decodeJSONStrict(GenerateLargeJSON(), &large); err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte(nil), buildFbLarge(newFbBuilder(1<<16), &large)...)
+	b := newFbBuilder(1)
+	for range 2 {
+		if got := buildFbLarge(b, &large); !bytes.Equal(got, want) {
+			t.Fatalf("built % x, want % x", got, want)
+		}
+	}
+	if len(want)%4 != 0 {
+		t.Errorf("buffer of %d bytes is not aligned", len(want))
+	}
+}
+
+func TestFlatbufSharesVtables(t *testing.T) {
+	same := jsonUser{ID: 1, Name: "a", Email: "b", Active: true, Age: 2}
+	other := jsonUser{ID: 2, Name: "c", Email: "d"}
+	root := fbLargeRoot(buildFbLarge(newFbBuilder(64), &jsonLarge{Users: []jsonUser{same, same, other}}))
+	vtable := func(i int) int {
+		u := root.User(i)
+		return u.pos - int(int32(u.uint32(u.pos)))
+	}
+	if vtable(0) != vtable(1) {
+		t.Errorf("tables of the same layout use vtables at %d and %d", vtable(0), vtable(1))
+	}
+	if vtable(0) == vtable(2) {
+		t.Errorf("tables of different layouts share the vtable at %d", vtable(0))
+	}
+}
@@ -0,0 +1,200 @@
+// Frame Decoder Robustness Benchmark - Go
+//
+// Feeds malformed input to a length-prefixed frame decoder, mirroring
+// TML's robustness benchmark: how fast hostile or corrupt frames are
+// rejected, and that the decoder neither panics nor allocates according
+// to the lengths an attacker declares. Each iteration decodes a corpus of
+// framingCorpusSize independent inputs, one kind per case:
+//
+//   - valid: well-formed frames, the baseline
+//   - truncated: valid frames cut short anywhere, header included
+//   - oversize: headers declaring up to 4GB payloads
+//   - bad_header: unknown frame types or reserved flag bits set
+//   - garbage: random bytes
+//
+// A panic or a heap allocation during decoding fails the case; frames are
+// decoded in place, so the decoder should never allocate at all.
+//
+// Frame: type u8 | flags u8 | payload length u32 (little endian) | payload
+//
+// The decoder is also a native fuzz target: go test -fuzz FuzzDecodeFrame
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+const (
+	frameHeaderSize = 6
+	frameMaxPayload = 1 << 20
+	// frameTypeMax is the highest known frame type; types start at 1
+	frameTypeMax = 4
+	// frameFlagsKnown are the flag bits with a meaning; the rest are
+	// reserved and must be zero
+	frameFlagsKnown = 0x03
+
+	framingCorpusSize = 100_000
+)
+
+var (
+	errFrameShort    = errors.New("frame: incomplete")
+	errFrameTooLarge = errors.New("frame: payload too large")
+	errFrameType     = errors.New("frame: unknown type")
+	errFrameFlags    = errors.New("frame: reserved flags set")
+)
+
+// frame is a decoded frame; payload aliases the input
+type frame struct {
+	typ, flags byte
+	payload    []byte
+}
+
+// appendFrame encodes f
+func appendFrame(dst []byte, f frame) []byte {
+	dst = append(dst, f.typ, f.flags)
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(len(f.payload)))
+	return append(dst, f.payload...)
+}
+
+// decodeFrame decodes the frame at the start of buf, returning it and the
+// bytes it occupies. The header is validated before the declared length
+// is trusted, and errFrameShort means more input is needed.
+func decodeFrame(buf []byte) (frame, int, error) {
+	if len(buf) < frameHeaderSize {
+		return frame{}, 0, errFrameShort
+	}
+	f := frame{typ: buf[0], flags: buf[1]}
+	if f.typ == 0 || f.typ > frameTypeMax {
+		return frame{}, 0, errFrameType
+	}
+	if f.flags&^frameFlagsKnown != 0 {
+		return frame{}, 0, errFrameFlags
+	}
+	n := binary.LittleEndian.Uint32(buf[2:])
+	if n > frameMaxPayload {
+		return frame{}, 0, errFrameTooLarge
+	}
+	end := frameHeaderSize + int(n)
+	if len(buf) < end {
+		return frame{}, 0, errFrameShort
+	}
+	f.payload = buf[frameHeaderSize:end]
+	return f, end, nil
+}
+
+// framingCorpus generates count inputs of the given kind
+func framingCorpus(kind string, count int, seed int64) ([][]byte, error) {
+	rng := newRand(seed)
+	validFrame := func() []byte {
+		payload := make([]byte, rng.Intn(512))
+		rng.Read(payload)
+		return appendFrame(nil, frame{
+			typ:     byte(1 + rng.Intn(frameTypeMax)),
+			flags:   byte(rng.Intn(frameFlagsKnown + 1)),
+			payload: payload,
+		})
+	}
+	corpus := make([][]byte, count)
+	for i := range corpus {
+		switch kind {
+		case "valid":
+			corpus[i] = validFrame()
+		case "truncated":
+			buf := validFrame()
+			corpus[i] = buf[:rng.Intn(len(buf))]
+		case "oversize":
+			buf := validFrame()
+			binary.LittleEndian.PutUint32(buf[2:], frameMaxPayload+1+uint32(rng.Int63n(1<<32-frameMaxPayload-1)))
+			corpus[i] = buf
+		case "bad_header":
+			buf := validFrame()
+			if rng.Intn(2) == 0 {
+				buf[0] = byte(frameTypeMax + 1 + rng.Intn(256-frameTypeMax-1))
+			} else {
+				buf[1] |= byte(1 << (2 + rng.Intn(6)))
+			}
+			corpus[i] = buf
+		case "garbage":
+			buf := make([]byte, rng.Intn(64))
+			rng.Read(buf)
+			corpus[i] = buf
+		default:
+			return nil, fmt.Errorf("unknown input kind %q", kind)
+		}
+	}
+	return corpus, nil
+}
+
+var framing struct {
+	corpus [][]byte
+	bytes  int64
+}
+
+var framingSink int
+
+func setupFrameDecodeMalformed(b *B) error {
+	corpus, err := framingCorpus(b.StringParam("input"), framingCorpusSize, 1538)
+	if err != nil {
+		return err
+	}
+	framing.corpus, framing.bytes = corpus, 0
+	for _, in := range corpus {
+		framing.bytes += int64(len(in))
+	}
+	return nil
+}
+
+// decodeFramingCorpus decodes every input, returning how many were
+// rejected; a decoder panic is returned as an error
+func decodeFramingCorpus(corpus [][]byte) (rejected int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("decoder panicked: %v", r)
+		}
+	}()
+	for _, in := range corpus {
+		f, n, err := decodeFrame(in)
+		if err != nil {
+			rejected++
+			continue
+		}
+		framingSink += n + len(f.payload)
+	}
+	return rejected, nil
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "FrameDecodeMalformed", Category: "framing", Tags: []string{"cpu", "alloc"},
+		Iterations: 20,
+		Axes:       []Axis{{Name: "input", Values: Strings("valid", "truncated", "oversize", "bad_header", "garbage")}},
+		Setup:      setupFrameDecodeMalformed,
+		Teardown:   func() { framing.corpus = nil },
+		Fn: func(b *B) {
+			b.SetBytes(framing.bytes)
+			var before, after runtime.MemStats
+			b.StopTimer()
+			runtime.ReadMemStats(&before)
+			b.StartTimer()
+			rejected, err := decodeFramingCorpus(framing.corpus)
+			b.StopTimer()
+			defer b.StartTimer()
+			runtime.ReadMemStats(&after)
+			if err != nil {
+				b.Fatal(err)
+				return
+			}
+			// Other goroutines may allocate a little; decoding by declared
+			// lengths would allocate far more than a frame's worth
+			if grown := after.TotalAlloc - before.TotalAlloc; grown > frameMaxPayload {
+				b.Fatal(fmt.Errorf("decoding allocated %d bytes", grown))
+				return
+			}
+			b.ReportMetric("rejected_pct", 100*float64(rejected)/float64(len(framing.corpus)))
+		},
+	})
+}
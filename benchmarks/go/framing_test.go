@@ -0,0 +1,83 @@
+// Frame Decoder Tests - Go
+//
+// Run with: go test -run Frame
+// Fuzz with: go test -run '^$' -fuzz FuzzDecodeFrame
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeFrameRejects(t *testing.T) {
+	valid := appendFrame(nil, frame{typ: 1, flags: 1, payload: []byte("hello")})
+	cases := []struct {
+		name string
+		in   []byte
+		want error
+	}{
+		{"empty", nil, errFrameShort},
+		{"header only", valid[:frameHeaderSize], errFrameShort},
+		{"short payload", valid[:len(valid)-1], errFrameShort},
+		{"type zero", append([]byte{0}, valid[1:]...), errFrameType},
+		{"unknown type", append([]byte{frameTypeMax + 1}, valid[1:]...), errFrameType},
+		{"reserved flag", append([]byte{1, 0x80}, valid[2:]...), errFrameFlags},
+		{"oversize", []byte{1, 0, 0xff, 0xff, 0xff, 0xff}, errFrameTooLarge},
+	}
+	for _, c := range cases {
+		if _, _, err := decodeFrame(c.in); err != c.want {
+			t.Errorf("%s: err %v, want %v", c.name, err, c.want)
+		}
+	}
+	f, n, err := decodeFrame(append(valid, "next"...))
+	if err != nil || n != len(valid) || string(f.payload) != "hello" {
+		t.Errorf("valid frame: %+v, %d, %v", f, n, err)
+	}
+}
+
+func TestFramingCorpusKinds(t *testing.T) {
+	for _, kind := range []string{"valid", "truncated", "oversize", "bad_header", "garbage"} {
+		corpus, err := framingCorpus(kind, 1000, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rejected, err := decodeFramingCorpus(corpus)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch kind {
+		case "valid":
+			if rejected != 0 {
+				t.Errorf("%s: %d rejected, want none", kind, rejected)
+			}
+		case "garbage":
+			// Random bytes occasionally form a valid frame
+		default:
+			if rejected != len(corpus) {
+				t.Errorf("%s: %d of %d rejected, want all", kind, rejected, len(corpus))
+			}
+		}
+	}
+}
+
+func FuzzDecodeFrame(f *testing.F) {
+	f.Add(appendFrame(nil, frame{typ: 1, payload: []byte("hello")}))
+	f.Add([]byte{1, 0, 0xff, 0xff, 0xff, 0xff})
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, in []byte) {
+		fr, n, err := decodeFrame(in)
+		if err != nil {
+			if n != 0 {
+				t.Fatalf("rejected input consumed %d bytes", n)
+			}
+			return
+		}
+		if n > len(in) || n != frameHeaderSize+len(fr.payload) {
+			t.Fatalf("consumed %d of %d bytes for a %d-byte payload", n, len(in), len(fr.payload))
+		}
+		if enc := appendFrame(nil, fr); !bytes.Equal(enc, in[:n]) {
+			t.Fatalf("re-encoded %x, want %x", enc, in[:n])
+		}
+	})
+}
@@ -0,0 +1,287 @@
+// Finite State Machine Benchmarks - Go
+//
+// Drives an HTTP/1.x request-line recognizer ("GET /path HTTP/1.1\r\n")
+// through 100M transitions, one per input byte, with two dispatch
+// strategies over the same states:
+//
+//   - switch: a switch on the current state, with the character tests
+//     written inline as a hand-written lexer would
+//   - table: a dense [state][byte] transition table, as generated lexers
+//     emit, built by running the switch machine over every (state, byte)
+//
+// About 1% of the request lines are corrupted so the reject path is taken
+// too. This mirrors TML's FSM codegen comparison.
+
+package main
+
+import "fmt"
+
+const (
+	fsmStreamBytes = 10 << 20
+	fsmCorruptRate = 100
+)
+
+// fsmState is a request-line recognizer state
+type fsmState uint8
+
+const (
+	fsmStart    fsmState = iota // expecting the first method letter
+	fsmMethod                   // in the method token
+	fsmURIStart                 // after the method's space
+	fsmURI                      // in the request target
+	fsmH                        // "HTTP/" version prefix, one state per byte
+	fsmHT
+	fsmHTT
+	fsmHTTP
+	fsmSlash
+	fsmMajor // version digits
+	fsmDot
+	fsmMinor
+	fsmCR    // expecting "\r"
+	fsmLF    // expecting "\n"
+	fsmError // skipping a bad line up to its "\n"
+	// fsmAccept and fsmReject behave like fsmStart; entering them counts a
+	// recognized or rejected line
+	fsmAccept
+	fsmReject
+	fsmStates
+)
+
+// fsmCounts tallies accepted and rejected lines
+type fsmCounts struct {
+	accepted, rejected int
+}
+
+// fsmFail is where an unexpected byte leads
+func fsmFail(c byte) fsmState {
+	if c == '\n' {
+		return fsmReject
+	}
+	return fsmError
+}
+
+// runSwitchFSM feeds buf to the machine in state s using switch dispatch
+// and returns the final state
+func runSwitchFSM(s fsmState, buf []byte, counts *fsmCounts) fsmState {
+	for _, c := range buf {
+		switch s {
+		case fsmStart, fsmAccept, fsmReject:
+			if c >= 'A' && c <= 'Z' {
+				s = fsmMethod
+			} else {
+				s = fsmFail(c)
+			}
+		case fsmMethod:
+			if c >= 'A' && c <= 'Z' {
+				s = fsmMethod
+			} else if c == ' ' {
+				s = fsmURIStart
+			} else {
+				s = fsmFail(c)
+			}
+		case fsmURIStart:
+			if c == '/' || c == '*' {
+				s = fsmURI
+			} else {
+				s = fsmFail(c)
+			}
+		case fsmURI:
+			if c > ' ' && c < 0x7f {
+				s = fsmURI
+			} else if c == ' ' {
+				s = fsmH
+			} else {
+				s = fsmFail(c)
+			}
+		case fsmH:
+			if c == 'H' {
+				s = fsmHT
+			} else {
+				s = fsmFail(c)
+			}
+		case fsmHT:
+			if c == 'T' {
+				s = fsmHTT
+			} else {
+				s = fsmFail(c)
+			}
+		case fsmHTT:
+			if c == 'T' {
+				s = fsmHTTP
+			} else {
+				s = fsmFail(c)
+			}
+		case fsmHTTP:
+			if c == 'P' {
+				s = fsmSlash
+			} else {
+				s = fsmFail(c)
+			}
+		case fsmSlash:
+			if c == '/' {
+				s = fsmMajor
+			} else {
+				s = fsmFail(c)
+			}
+		case fsmMajor:
+			if c >= '0' && c <= '9' {
+				s = fsmDot
+			} else {
+				s = fsmFail(c)
+			}
+		case fsmDot:
+			if c == '.' {
+				s = fsmMinor
+			} else {
+				s = fsmFail(c)
+			}
+		case fsmMinor:
+			if c >= '0' && c <= '9' {
+				s = fsmCR
+			} else {
+				s = fsmFail(c)
+			}
+		case fsmCR:
+			if c == '\r' {
+				s = fsmLF
+			} else {
+				s = fsmFail(c)
+			}
+		case fsmLF:
+			if c == '\n' {
+				s = fsmAccept
+			} else {
+				s = fsmFail(c)
+			}
+		case fsmError:
+			if c == '\n' {
+				s = fsmReject
+			}
+		}
+		if s >= fsmAccept {
+			if s == fsmAccept {
+				counts.accepted++
+			} else {
+				counts.rejected++
+			}
+		}
+	}
+	return s
+}
+
+// fsmTable is a dense transition table indexed by state, then input byte
+type fsmTable [fsmStates][256]fsmState
+
+// newFSMTable derives the table from the switch machine so both strategies
+// recognize exactly the same language
+func newFSMTable() *fsmTable {
+	var t fsmTable
+	var discard fsmCounts
+	for s := fsmState(0); s < fsmStates; s++ {
+		for c := 0; c < 256; c++ {
+			t[s][c] = runSwitchFSM(s, []byte{byte(c)}, &discard)
+		}
+	}
+	return &t
+}
+
+// run feeds buf to the machine in state s using table lookups and returns
+// the final state
+func (t *fsmTable) run(s fsmState, buf []byte, counts *fsmCounts) fsmState {
+	for _, c := range buf {
+		s = t[s][c]
+		if s >= fsmAccept {
+			if s == fsmAccept {
+				counts.accepted++
+			} else {
+				counts.rejected++
+			}
+		}
+	}
+	return s
+}
+
+// generateRequestLines builds a stream of request lines of about size
+// bytes, corrupting one byte in roughly every fsmCorruptRate lines
+func generateRequestLines(size int, seed int64) []byte {
+	methods := []string{"GET", "GET", "GET", "POST", "PUT", "DELETE", "HEAD", "OPTIONS"}
+	segments := []string{"api", "v1", "v2", "users", "orders", "items", "search", "static", "img", "index.html"}
+	rng := newRand(seed)
+	buf := make([]byte, 0, size+256)
+	for len(buf) < size {
+		start := len(buf)
+		buf = append(buf, methods[rng.Intn(len(methods))]...)
+		buf = append(buf, ' ')
+		for i := 0; i < 1+rng.Intn(4); i++ {
+			buf = append(buf, '/')
+			buf = append(buf, segments[rng.Intn(len(segments))]...)
+		}
+		if rng.Intn(3) == 0 {
+			buf = fmt.Appendf(buf, "/%d?page=%d", rng.Intn(100_000), rng.Intn(50))
+		}
+		buf = fmt.Appendf(buf, " HTTP/1.%d\r\n", rng.Intn(2))
+		if rng.Intn(fsmCorruptRate) == 0 {
+			// Any byte but the terminator, so line boundaries stay put
+			pos := start + rng.Intn(len(buf)-start-1)
+			buf[pos] = byte(rng.Intn(256))
+			if buf[pos] == '\n' {
+				buf[pos] = 0
+			}
+		}
+	}
+	return buf
+}
+
+// fsmFixture is the request stream, the transition table and the expected
+// tallies both strategies must reproduce
+var fsmFixture struct {
+	stream []byte
+	table  *fsmTable
+	want   fsmCounts
+}
+
+var fsmSink fsmCounts
+
+func setupFSM(b *B) error {
+	f := &fsmFixture
+	f.stream = generateRequestLines(fsmStreamBytes, 1530)
+	f.table = newFSMTable()
+	f.want = fsmCounts{}
+	runSwitchFSM(fsmStart, f.stream, &f.want)
+	var got fsmCounts
+	f.table.run(fsmStart, f.stream, &got)
+	if got != f.want {
+		return fmt.Errorf("table FSM counted %+v, switch FSM %+v", got, f.want)
+	}
+	return nil
+}
+
+func teardownFSM() {
+	fsmFixture.stream, fsmFixture.table = nil, nil
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "RequestLineFSM", Category: "fsm", Tags: []string{"cpu"},
+		Iterations: 10,
+		Axes:       []Axis{{Name: "dispatch", Values: Strings("switch", "table")}},
+		Setup:      setupFSM, Teardown: teardownFSM,
+		Fn: func(b *B) {
+			f := &fsmFixture
+			b.SetBytes(int64(len(f.stream)))
+			var counts fsmCounts
+			if b.StringParam("dispatch") == "table" {
+				f.table.run(fsmStart, f.stream, &counts)
+			} else {
+				runSwitchFSM(fsmStart, f.stream, &counts)
+			}
+			if counts != f.want {
+				b.Fatal(fmt.Errorf("counted %+v, want %+v", counts, f.want))
+				return
+			}
+			fsmSink = counts
+			b.ReportMetric("transitions_per_op", float64(len(f.stream)))
+			b.ReportMetric("rejected_lines", float64(counts.rejected))
+		},
+	})
+}
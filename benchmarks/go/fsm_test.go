@@ -0,0 +1,46 @@
+// FSM Tests - Go
+//
+// Run with: go test -run FSM
+
+package main
+
+import "testing"
+
+func TestFSMRecognizesRequestLines(t *testing.T) {
+	input := "GET /index.html HTTP/1.1\r\n" +
+		"OPTIONS * HTTP/1.0\r\n" +
+		"get /lowercase HTTP/1.1\r\n" +
+		"POST /missing-version\r\n" +
+		"PUT /x HTTP/1.1\n" +
+		"\n" +
+		"DELETE /api/v1/users/7?force=1 HTTP/2.0\r\n"
+	want := fsmCounts{accepted: 3, rejected: 4}
+	table := newFSMTable()
+	var viaSwitch, viaTable fsmCounts
+	runSwitchFSM(fsmStart, []byte(input), &viaSwitch)
+	table.run(fsmStart, []byte(input), &viaTable)
+	if viaSwitch != want {
+		t.Errorf("switch FSM counted %+v, want %+v", viaSwitch, want)
+	}
+	if viaTable != want {
+		t.Errorf("table FSM counted %+v, want %+v", viaTable, want)
+	}
+}
+
+func TestFSMGeneratedStreamCounts(t *testing.T) {
+	stream := generateRequestLines(1<<16, 1)
+	lines := 0
+	for _, c := range stream {
+		if c == '\n' {
+			lines++
+		}
+	}
+	var counts fsmCounts
+	newFSMTable().run(fsmStart, stream, &counts)
+	if counts.accepted+counts.rejected != lines {
+		t.Errorf("counted %+v over %d lines", counts, lines)
+	}
+	if counts.rejected == 0 || counts.rejected > lines/20 {
+		t.Errorf("rejected %d of %d lines, want about 1%%", counts.rejected, lines)
+	}
+}
@@ -0,0 +1,166 @@
+// Future/Promise Completion Patterns - Go
+//
+// Ways of delivering an asynchronous result from the goroutine that
+// produces it to the one waiting on it: a single-use channel per future, a
+// reused channel, a mutex+condition variable and callback registration.
+// Each helper performs one complete operation and returns the completion
+// latency, measured from the moment the result is resolved to the moment
+// the consumer observes it. Relevant to TML's future/promise comparison.
+//
+// Harness benchmarks (latency percentiles) are registered below; allocs/op
+// come from the testing.B versions in future_test.go.
+
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// futureResolver runs resolve tasks on a dedicated goroutine, standing in
+// for the I/O or worker thread that completes a future
+type futureResolver struct {
+	tasks chan func()
+	done  chan struct{}
+}
+
+func newFutureResolver() *futureResolver {
+	r := &futureResolver{tasks: make(chan func()), done: make(chan struct{})}
+	go func() {
+		defer close(r.done)
+		for task := range r.tasks {
+			task()
+		}
+	}()
+	return r
+}
+
+// close stops the resolver goroutine and waits for it to exit
+func (r *futureResolver) close() {
+	close(r.tasks)
+	<-r.done
+}
+
+// completeChanFuture delivers the result through a freshly made channel
+func completeChanFuture(r *futureResolver) time.Duration {
+	ch := make(chan time.Time, 1)
+	r.tasks <- func() { ch <- time.Now() }
+	return time.Since(<-ch)
+}
+
+// completeChanReused delivers the result through a long-lived channel
+func completeChanReused(r *futureResolver, ch chan time.Time, resolve func()) time.Duration {
+	r.tasks <- resolve
+	return time.Since(<-ch)
+}
+
+// condFuture is a future built from a mutex and condition variable
+type condFuture struct {
+	mu   sync.Mutex
+	cond sync.Cond
+	done bool
+	at   time.Time
+}
+
+// completeCondFuture blocks on a condition variable until resolved
+func completeCondFuture(r *futureResolver) time.Duration {
+	f := &condFuture{}
+	f.cond.L = &f.mu
+	r.tasks <- func() {
+		f.mu.Lock()
+		f.at = time.Now()
+		f.done = true
+		f.mu.Unlock()
+		f.cond.Signal()
+	}
+	f.mu.Lock()
+	for !f.done {
+		f.cond.Wait()
+	}
+	at := f.at
+	f.mu.Unlock()
+	return time.Since(at)
+}
+
+// callbackFuture runs registered callbacks on the resolving goroutine
+type callbackFuture struct {
+	mu       sync.Mutex
+	resolved bool
+	at       time.Time
+	callback func(at time.Time)
+}
+
+// then registers cb, running it immediately if already resolved
+func (f *callbackFuture) then(cb func(at time.Time)) {
+	f.mu.Lock()
+	if f.resolved {
+		f.mu.Unlock()
+		cb(f.at)
+		return
+	}
+	f.callback = cb
+	f.mu.Unlock()
+}
+
+func (f *callbackFuture) resolve(at time.Time) {
+	f.mu.Lock()
+	f.resolved = true
+	f.at = at
+	cb := f.callback
+	f.mu.Unlock()
+	if cb != nil {
+		cb(at)
+	}
+}
+
+// completeCallback registers a callback that measures completion latency;
+// the caller then waits on a reused channel only so operations don't
+// overlap
+func completeCallback(r *futureResolver, finished chan time.Duration) time.Duration {
+	f := &callbackFuture{}
+	f.then(func(at time.Time) { finished <- time.Since(at) })
+	r.tasks <- func() { f.resolve(time.Now()) }
+	return <-finished
+}
+
+var futureState struct {
+	resolver *futureResolver
+	reused   chan time.Time
+	resolve  func()
+	finished chan time.Duration
+}
+
+func init() {
+	setup := func(b *B) error {
+		s := &futureState
+		s.resolver = newFutureResolver()
+		s.reused = make(chan time.Time, 1)
+		s.resolve = func() { s.reused <- time.Now() }
+		s.finished = make(chan time.Duration, 1)
+		return nil
+	}
+	teardown := func() {
+		futureState.resolver.close()
+		futureState.resolver = nil
+	}
+	register := func(name string, complete func() time.Duration) {
+		Register(Benchmark{
+			Name: name, Category: "future", Tags: []string{"cpu", "alloc"},
+			Iterations: 100_000, Setup: setup, Teardown: teardown,
+			Fn: func(b *B) { b.Histogram().Record(complete()) },
+		})
+	}
+
+	register("FutureChanSingleUse", func() time.Duration {
+		return completeChanFuture(futureState.resolver)
+	})
+	register("FutureChanReused", func() time.Duration {
+		return completeChanReused(futureState.resolver, futureState.reused, futureState.resolve)
+	})
+	register("FutureCondVar", func() time.Duration {
+		return completeCondFuture(futureState.resolver)
+	})
+	register("FutureCallback", func() time.Duration {
+		return completeCallback(futureState.resolver, futureState.finished)
+	})
+}
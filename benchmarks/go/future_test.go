@@ -0,0 +1,49 @@
+// Future/Promise Completion Benchmarks - Go
+//
+// Run with: go test -bench=Future -benchmem
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func BenchmarkFutureChanSingleUse(b *testing.B) {
+	r := newFutureResolver()
+	defer r.close()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		completeChanFuture(r)
+	}
+}
+
+func BenchmarkFutureChanReused(b *testing.B) {
+	r := newFutureResolver()
+	defer r.close()
+	ch := make(chan time.Time, 1)
+	resolve := func() { ch <- time.Now() }
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		completeChanReused(r, ch, resolve)
+	}
+}
+
+func BenchmarkFutureCondVar(b *testing.B) {
+	r := newFutureResolver()
+	defer r.close()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		completeCondFuture(r)
+	}
+}
+
+func BenchmarkFutureCallback(b *testing.B) {
+	r := newFutureResolver()
+	defer r.close()
+	finished := make(chan time.Duration, 1)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		completeCallback(r, finished)
+	}
+}
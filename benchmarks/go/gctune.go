@@ -0,0 +1,100 @@
+// GC Tuning - Go
+//
+// -gogc and -memlimit apply debug.SetGCPercent and debug.SetMemoryLimit
+// before any benchmark runs, overriding the GOGC and GOMEMLIMIT environment
+// variables, so memory-pressure-sensitive benchmarks (batch, extsort,
+// columnar) can be compared under different collector configurations. The
+// effective settings are recorded in the result metadata.
+//
+// Example: go run . -run Batch -gogc off -memlimit 1GiB
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// byteSizeUnits are the suffixes accepted by parseByteSize, in the
+// GOMEMLIMIT format
+var byteSizeUnits = []struct {
+	suffix string
+	scale  int64
+}{
+	{"TiB", 1 << 40},
+	{"GiB", 1 << 30},
+	{"MiB", 1 << 20},
+	{"KiB", 1 << 10},
+	{"B", 1},
+}
+
+// parseByteSize parses sizes like "512MiB" or "2GiB"; a bare number is bytes
+// and "off" means no limit
+func parseByteSize(s string) (int64, error) {
+	if s == "off" {
+		return math.MaxInt64, nil
+	}
+	scale := int64(1)
+	num := s
+	for _, u := range byteSizeUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			num, scale = strings.TrimSuffix(s, u.suffix), u.scale
+			break
+		}
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	if n > math.MaxInt64/scale {
+		return 0, fmt.Errorf("size %q overflows", s)
+	}
+	return n * scale, nil
+}
+
+// applyGCSettings applies the -gogc and -memlimit flags; empty values keep
+// the runtime's current setting
+func applyGCSettings(gogc, memLimit string) error {
+	if gogc != "" {
+		percent := -1
+		if gogc != "off" {
+			p, err := strconv.Atoi(gogc)
+			if err != nil || p < 0 {
+				return fmt.Errorf("invalid -gogc %q (want a percent or \"off\")", gogc)
+			}
+			percent = p
+		}
+		debug.SetGCPercent(percent)
+	}
+	if memLimit != "" {
+		limit, err := parseByteSize(memLimit)
+		if err != nil {
+			return fmt.Errorf("invalid -memlimit: %w", err)
+		}
+		debug.SetMemoryLimit(limit)
+	}
+	return nil
+}
+
+// memoryLimit reports the effective soft memory limit ("off" when unset)
+func memoryLimit() string {
+	limit := debug.SetMemoryLimit(-1)
+	if limit == math.MaxInt64 {
+		return "off"
+	}
+	return formatByteSize(limit)
+}
+
+// formatByteSize formats n in the largest unit that divides it, the way
+// parseByteSize reads it back
+func formatByteSize(n int64) string {
+	for _, u := range byteSizeUnits {
+		if n%u.scale == 0 {
+			return fmt.Sprintf("%d%s", n/u.scale, u.suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", n)
+}
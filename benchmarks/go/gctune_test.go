@@ -0,0 +1,37 @@
+// GC Tuning Tests - Go
+//
+// Run with: go test -run ByteSize
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int64
+	}{
+		{"0", 0},
+		{"4096", 4096},
+		{"512B", 512},
+		{"64KiB", 64 << 10},
+		{"512MiB", 512 << 20},
+		{"2GiB", 2 << 30},
+		{"1TiB", 1 << 40},
+		{"off", math.MaxInt64},
+	}
+	for _, c := range cases {
+		got, err := parseByteSize(c.in)
+		if err != nil || got != c.want {
+			t.Errorf("parseByteSize(%q) = %d, %v; want %d", c.in, got, err, c.want)
+		}
+	}
+	for _, bad := range []string{"", "GiB", "-1MiB", "1.5GiB", "2GB", "9999999TiB"} {
+		if _, err := parseByteSize(bad); err == nil {
+			t.Errorf("parseByteSize(%q) succeeded, want error", bad)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// Geospatial Benchmarks - Go
+//
+// Haversine distance, point-in-polygon and geohash encode/decode over 10M
+// coordinates, each iteration processing every point, for the geo row of
+// the TML comparison matrix.
+//
+// Geohashes are computed by quantizing latitude and longitude to 30 bits
+// each and interleaving them, which yields the same cells as the usual
+// bisection loop in a fraction of the branches.
+
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	geoPoints        = 10_000_000
+	geohashPrecision = 12
+	earthRadiusKm    = 6371.0088
+)
+
+// geoPoint is a coordinate in degrees
+type geoPoint struct {
+	Lat, Lon float64
+}
+
+// haversineKm returns the great-circle distance between a and b
+func haversineKm(a, b geoPoint) float64 {
+	const rad = math.Pi / 180
+	dLat := (b.Lat - a.Lat) * rad
+	dLon := (b.Lon - a.Lon) * rad
+	s := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(a.Lat*rad)*math.Cos(b.Lat*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(s))
+}
+
+// pointInPolygon reports whether p lies inside the polygon by counting
+// edge crossings of a ray cast east from p
+func pointInPolygon(p geoPoint, polygon []geoPoint) bool {
+	inside := false
+	j := len(polygon) - 1
+	for i := range polygon {
+		a, b := polygon[i], polygon[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+		j = i
+	}
+	return inside
+}
+
+// starPolygon returns a star-shaped polygon with n vertices alternating
+// between two radii around center, concave like real boundaries
+func starPolygon(center geoPoint, n int, outer, inner float64) []geoPoint {
+	poly := make([]geoPoint, n)
+	for i := range poly {
+		r := outer
+		if i%2 == 1 {
+			r = inner
+		}
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		poly[i] = geoPoint{Lat: center.Lat + r*math.Sin(angle), Lon: center.Lon + r*math.Cos(angle)}
+	}
+	return poly
+}
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// geohashDecodeTable maps geohash characters back to their 5-bit values
+var geohashDecodeTable = func() (t [256]int8) {
+	for i := range t {
+		t[i] = -1
+	}
+	for i := 0; i < len(geohashAlphabet); i++ {
+		t[geohashAlphabet[i]] = int8(i)
+	}
+	return t
+}()
+
+// spreadBits moves bit i of x to bit 2i
+func spreadBits(x uint64) uint64 {
+	x &= 0xffffffff
+	x = (x | x<<16) & 0x0000ffff0000ffff
+	x = (x | x<<8) & 0x00ff00ff00ff00ff
+	x = (x | x<<4) & 0x0f0f0f0f0f0f0f0f
+	x = (x | x<<2) & 0x3333333333333333
+	x = (x | x<<1) & 0x5555555555555555
+	return x
+}
+
+// squashBits is the inverse of spreadBits
+func squashBits(x uint64) uint64 {
+	x &= 0x5555555555555555
+	x = (x | x>>1) & 0x3333333333333333
+	x = (x | x>>2) & 0x0f0f0f0f0f0f0f0f
+	x = (x | x>>4) & 0x00ff00ff00ff00ff
+	x = (x | x>>8) & 0x0000ffff0000ffff
+	x = (x | x>>16) & 0x00000000ffffffff
+	return x
+}
+
+// quantize maps v in [min, min+span) to a 30-bit cell index
+func quantize(v, min, span float64) uint64 {
+	q := (v - min) / span * (1 << 30)
+	return uint64(math.Max(0, math.Min(q, 1<<30-1)))
+}
+
+// geohashEncode writes the 12-character geohash of p into dst
+func geohashEncode(dst *[geohashPrecision]byte, p geoPoint) {
+	// Longitude takes the first (odd) bit of every pair
+	bits := spreadBits(quantize(p.Lon, -180, 360))<<1 | spreadBits(quantize(p.Lat, -90, 180))
+	for i := range dst {
+		dst[i] = geohashAlphabet[bits>>(55-5*i)&31]
+	}
+}
+
+// geohashDecode returns the center of the cell a geohash of up to 12
+// characters names
+func geohashDecode(hash []byte) (geoPoint, error) {
+	if len(hash) > geohashPrecision {
+		return geoPoint{}, fmt.Errorf("geohash %q longer than %d characters", hash, geohashPrecision)
+	}
+	var bits uint64
+	for _, c := range hash {
+		v := geohashDecodeTable[c]
+		if v < 0 {
+			return geoPoint{}, fmt.Errorf("invalid geohash character %q", c)
+		}
+		bits = bits<<5 | uint64(v)
+	}
+	n := 5 * len(hash)
+	bits <<= 60 - n
+	// Longitude gets the extra bit when n is odd
+	lonBits, latBits := (n+1)/2, n/2
+	lon := float64(squashBits(bits>>1))/(1<<30)*360 - 180 + math.Ldexp(180, -lonBits)
+	lat := float64(squashBits(bits))/(1<<30)*180 - 90 + math.Ldexp(90, -latBits)
+	return geoPoint{Lat: lat, Lon: lon}, nil
+}
+
+// geoFixture holds the coordinates and, for decoding, their geohashes
+var geoFixture struct {
+	points []geoPoint
+	hashes [][geohashPrecision]byte
+}
+
+func setupGeo(b *B) error {
+	rng := newRand(1528)
+	points := make([]geoPoint, geoPoints)
+	for i := range points {
+		points[i] = geoPoint{Lat: rng.Float64()*140 - 70, Lon: rng.Float64()*360 - 180}
+	}
+	geoFixture.points = points
+	return nil
+}
+
+func setupGeohashDecode(b *B) error {
+	setupGeo(b)
+	geoFixture.hashes = make([][geohashPrecision]byte, geoPoints)
+	for i, p := range geoFixture.points {
+		geohashEncode(&geoFixture.hashes[i], p)
+	}
+	return nil
+}
+
+func teardownGeo() {
+	geoFixture.points, geoFixture.hashes = nil, nil
+}
+
+// geoSink keeps results observable so the loops are not optimized away
+var geoSink float64
+
+func init() {
+	Register(Benchmark{
+		Name: "GeoHaversine", Category: "geo", Tags: []string{"cpu"},
+		Iterations: 5, Setup: setupGeo, Teardown: teardownGeo,
+		Fn: func(b *B) {
+			pts := geoFixture.points
+			total := 0.0
+			for i := 1; i < len(pts); i++ {
+				total += haversineKm(pts[i-1], pts[i])
+			}
+			geoSink = total
+		},
+	})
+	Register(Benchmark{
+		Name: "GeoPointInPolygon", Category: "geo", Tags: []string{"cpu"},
+		Iterations: 3, Setup: setupGeo, Teardown: teardownGeo,
+		Axes: []Axis{{Name: "vertices", Values: Ints(8, 64, 256)}},
+		Fn: func(b *B) {
+			poly := starPolygon(geoPoint{Lat: 10, Lon: 20}, b.IntParam("vertices"), 60, 30)
+			inside := 0
+			for _, p := range geoFixture.points {
+				if pointInPolygon(p, poly) {
+					inside++
+				}
+			}
+			b.ReportMetric("inside_pct", float64(inside)/geoPoints*100)
+		},
+	})
+	Register(Benchmark{
+		Name: "GeohashEncode", Category: "geo", Tags: []string{"cpu"},
+		Iterations: 5, Setup: setupGeo, Teardown: teardownGeo,
+		Fn: func(b *B) {
+			var buf [geohashPrecision]byte
+			var check byte
+			for _, p := range geoFixture.points {
+				geohashEncode(&buf, p)
+				check ^= buf[geohashPrecision-1]
+			}
+			geoSink = float64(check)
+		},
+	})
+	Register(Benchmark{
+		Name: "GeohashDecode", Category: "geo", Tags: []string{"cpu"},
+		Iterations: 5, Setup: setupGeohashDecode, Teardown: teardownGeo,
+		Fn: func(b *B) {
+			total := 0.0
+			for i := range geoFixture.hashes {
+				p, err := geohashDecode(geoFixture.hashes[i][:])
+				if err != nil {
+					b.Fatal(err)
+					return
+				}
+				total += p.Lat
+			}
+			geoSink = total
+		},
+	})
+}
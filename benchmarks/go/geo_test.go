@@ -0,0 +1,60 @@
+// Geospatial Tests - Go
+//
+// Run with: go test -run 'Geo|Haversine|Polygon'
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGeohashKnownValues(t *testing.T) {
+	cases := []struct {
+		p    geoPoint
+		hash string
+	}{
+		{geoPoint{Lat: 57.64911, Lon: 10.40744}, "u4pruydqqvj"},
+		{geoPoint{Lat: 42.605, Lon: -5.603}, "ezs42"},
+		{geoPoint{Lat: -25.382708, Lon: -49.265506}, "6gkzwgjzn820"},
+	}
+	for _, c := range cases {
+		var buf [geohashPrecision]byte
+		geohashEncode(&buf, c.p)
+		if got := string(buf[:len(c.hash)]); got != c.hash {
+			t.Errorf("encode %v = %s, want %s", c.p, got, c.hash)
+		}
+		// The decoded center lies within half a cell of the point
+		p, err := geohashDecode([]byte(c.hash))
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 5 * len(c.hash)
+		if math.Abs(p.Lat-c.p.Lat) > math.Ldexp(90, -n/2) || math.Abs(p.Lon-c.p.Lon) > math.Ldexp(180, -(n+1)/2) {
+			t.Errorf("decode %s = %v, want near %v", c.hash, p, c.p)
+		}
+	}
+	if _, err := geohashDecode([]byte("ezs4a")); err == nil {
+		t.Error("decoding a hash with 'a' succeeded")
+	}
+}
+
+func TestHaversine(t *testing.T) {
+	// Paris to London is about 343.5 km
+	d := haversineKm(geoPoint{48.8566, 2.3522}, geoPoint{51.5074, -0.1278})
+	if math.Abs(d-343.5) > 1 {
+		t.Errorf("Paris-London = %.1f km", d)
+	}
+}
+
+func TestPointInPolygon(t *testing.T) {
+	square := []geoPoint{{0, 0}, {0, 10}, {10, 10}, {10, 0}}
+	if !pointInPolygon(geoPoint{5, 5}, square) || pointInPolygon(geoPoint{15, 5}, square) {
+		t.Error("square containment wrong")
+	}
+	star := starPolygon(geoPoint{}, 8, 10, 2)
+	// Between two points of the star, beyond the inner radius
+	if pointInPolygon(geoPoint{Lat: 4 * math.Sin(math.Pi/4), Lon: 4 * math.Cos(math.Pi/4)}, star) {
+		t.Error("point in a star's notch reported inside")
+	}
+}
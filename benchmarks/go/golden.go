@@ -0,0 +1,167 @@
+// Golden Outputs - Go
+//
+// benchmarks/common/golden.json lists expected algorithm outputs and echo
+// payload checksums, computed independently of any suite's implementation.
+// The Go suite checks them before timing anything. The TML, Rust, C++ and
+// Python suites do not load the fixture yet, so until they do a mismatch
+// between their outputs and Go's still goes undetected.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+)
+
+// defaultGoldenPath locates the fixture from benchmarks/go; elsewhere the
+// check is skipped unless -golden names the file (defaultFileFlag)
+const defaultGoldenPath = "../common/golden.json"
+
+// GoldenFile is the shared fixture of expected outputs
+type GoldenFile struct {
+	Factorial []struct {
+		N     int32 `json:"n"`
+		Value int32 `json:"value"`
+	} `json:"factorial"`
+	Fibonacci []struct {
+		N     int32 `json:"n"`
+		Value int32 `json:"value"`
+	} `json:"fibonacci"`
+	GCD []struct {
+		A     int32 `json:"a"`
+		B     int32 `json:"b"`
+		Value int32 `json:"value"`
+	} `json:"gcd"`
+	Power []struct {
+		Base  int32 `json:"base"`
+		Exp   int32 `json:"exp"`
+		Value int32 `json:"value"`
+	} `json:"power"`
+	PrimeCounts []struct {
+		Limit int32 `json:"limit"`
+		Value int32 `json:"value"`
+	} `json:"prime_counts"`
+	CollatzSteps []struct {
+		N     int32 `json:"n"`
+		Value int32 `json:"value"`
+	} `json:"collatz_steps"`
+	SumRange []struct {
+		Start int32 `json:"start"`
+		End   int32 `json:"end"`
+		Value int32 `json:"value"`
+	} `json:"sum_range"`
+	EchoPayloads []struct {
+		Size    int    `json:"size"`
+		FNV1a64 string `json:"fnv1a64"`
+	} `json:"echo_payloads"`
+}
+
+// LoadGolden reads the golden fixture at path
+func LoadGolden(path string) (*GoldenFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading golden outputs: %w", err)
+	}
+	var g GoldenFile
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &g, nil
+}
+
+// Checks returns one verification check per fixture section
+func (g *GoldenFile) Checks() []verifyCheck {
+	return []verifyCheck{
+		{"Golden factorial", func() error {
+			for _, c := range g.Factorial {
+				if err := expectInt32s(c.Value, factorialRecursive(c.N), factorialIterative(c.N)); err != nil {
+					return fmt.Errorf("factorial(%d): %w", c.N, err)
+				}
+			}
+			return nil
+		}},
+		{"Golden fibonacci", func() error {
+			for _, c := range g.Fibonacci {
+				if err := expectInt32s(c.Value, fibonacciRecursive(c.N), fibonacciIterative(c.N)); err != nil {
+					return fmt.Errorf("fibonacci(%d): %w", c.N, err)
+				}
+			}
+			return nil
+		}},
+		{"Golden gcd", func() error {
+			for _, c := range g.GCD {
+				if err := expectInt32s(c.Value, gcdRecursive(c.A, c.B), gcdIterative(c.A, c.B)); err != nil {
+					return fmt.Errorf("gcd(%d, %d): %w", c.A, c.B, err)
+				}
+			}
+			return nil
+		}},
+		{"Golden power", func() error {
+			for _, c := range g.Power {
+				if err := expectInt32s(c.Value, powerNaive(c.Base, c.Exp), powerFast(c.Base, c.Exp)); err != nil {
+					return fmt.Errorf("power(%d, %d): %w", c.Base, c.Exp, err)
+				}
+			}
+			return nil
+		}},
+		{"Golden prime counts", func() error {
+			for _, c := range g.PrimeCounts {
+				if err := expectInt32s(c.Value, countPrimes(c.Limit)); err != nil {
+					return fmt.Errorf("primes up to %d: %w", c.Limit, err)
+				}
+			}
+			return nil
+		}},
+		{"Golden collatz steps", func() error {
+			for _, c := range g.CollatzSteps {
+				if err := expectInt32s(c.Value, collatzSteps(c.N)); err != nil {
+					return fmt.Errorf("collatz(%d): %w", c.N, err)
+				}
+			}
+			return nil
+		}},
+		{"Golden sum range", func() error {
+			for _, c := range g.SumRange {
+				if err := expectInt32s(c.Value, sumRange(c.Start, c.End)); err != nil {
+					return fmt.Errorf("sum(%d..%d): %w", c.Start, c.End, err)
+				}
+			}
+			return nil
+		}},
+		{"Golden echo payloads", func() error {
+			for _, c := range g.EchoPayloads {
+				payload := echoPattern(c.Size)
+				if got := fnv1a64Hex(payload); got != c.FNV1a64 {
+					return fmt.Errorf("%dB payload checksum %s, want %s", c.Size, got, c.FNV1a64)
+				}
+				reply, err := tcpEchoRoundTrip(payload)
+				if err != nil {
+					return fmt.Errorf("%dB echo: %w", c.Size, err)
+				}
+				if got := fnv1a64Hex(reply); got != c.FNV1a64 {
+					return fmt.Errorf("%dB echo checksum %s, want %s", c.Size, got, c.FNV1a64)
+				}
+			}
+			return nil
+		}},
+	}
+}
+
+// Verify runs every check and returns the failures
+func (g *GoldenFile) Verify() []error {
+	var failures []error
+	for _, check := range g.Checks() {
+		if err := check.Run(); err != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", check.Name, err))
+		}
+	}
+	return failures
+}
+
+func fnv1a64Hex(data []byte) string {
+	h := fnv.New64a()
+	h.Write(data)
+	return fmt.Sprintf("%016x", h.Sum64())
+}
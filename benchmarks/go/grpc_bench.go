@@ -0,0 +1,323 @@
+// Go gRPC Wire Benchmark
+// A small gRPC echo service, measured as a unary call and as a
+// bidirectional stream:
+//
+//   - GrpcWireUnary: one Echo call per iteration, a fresh HTTP/2 stream
+//     each time, recorded in the latency histogram
+//   - GrpcWireStream: grpcStreamMessages messages per iteration sent and
+//     echoed over one long-lived bidirectional stream, the sender running
+//     ahead of the reader; reported as messages_per_sec
+//
+// These are not grpc-go: to keep the default build free of third-party
+// dependencies they speak the gRPC wire protocol on net/http's HTTP/2
+// (cleartext, prior knowledge): POST /tml.bench.Echo/<method> with
+// content-type application/grpc, messages framed as compressed-flag u8 |
+// length u32 (big endian) | protobuf, and the status in the grpc-status
+// trailer. The message is the protobuf `message EchoMessage { bytes
+// payload = 1; }`, encoded by hand. Any gRPC client can call the service,
+// but the numbers measure this file's framing over net/http, not the
+// gRPC stack users deploy; GrpcUnary and GrpcStream run the same service
+// on grpc-go (grpc_go.go, built with -tags grpcgo).
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	grpcContentType    = "application/grpc"
+	grpcUnaryPath      = "/tml.bench.Echo/Unary"
+	grpcStreamPath     = "/tml.bench.Echo/Stream"
+	grpcFrameHeader    = 5
+	grpcMaxMessage     = 4 << 20
+	grpcStreamMessages = 1000
+)
+
+// appendEchoMessage appends a gRPC frame holding an EchoMessage with
+// payload
+func appendEchoMessage(dst, payload []byte) []byte {
+	var field [binary.MaxVarintLen64 + 1]byte
+	field[0] = 1<<3 | 2 // field 1, length-delimited
+	n := 1 + binary.PutUvarint(field[1:], uint64(len(payload)))
+	dst = append(dst, 0)
+	dst = binary.BigEndian.AppendUint32(dst, uint32(n+len(payload)))
+	dst = append(dst, field[:n]...)
+	return append(dst, payload...)
+}
+
+// readEchoMessage reads one gRPC frame into buf, growing it as needed, and
+// returns the EchoMessage payload it holds, aliasing buf
+func readEchoMessage(r io.Reader, buf *[]byte) ([]byte, error) {
+	var hdr [grpcFrameHeader]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	if hdr[0] != 0 {
+		return nil, errors.New("grpc: compressed messages are not supported")
+	}
+	n := binary.BigEndian.Uint32(hdr[1:])
+	if n > grpcMaxMessage {
+		return nil, fmt.Errorf("grpc: %d-byte message exceeds the limit", n)
+	}
+	if cap(*buf) < int(n) {
+		*buf = make([]byte, n)
+	}
+	msg := (*buf)[:n]
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return parseEchoMessage(msg)
+}
+
+// parseEchoMessage returns the payload of an encoded EchoMessage, aliasing
+// msg
+func parseEchoMessage(msg []byte) ([]byte, error) {
+	// An empty message has no fields; otherwise expect field 1 only
+	if len(msg) == 0 {
+		return msg, nil
+	}
+	if msg[0] != 1<<3|2 {
+		return nil, fmt.Errorf("grpc: unexpected field tag %#x", msg[0])
+	}
+	size, k := binary.Uvarint(msg[1:])
+	if k <= 0 || uint64(len(msg)-1-k) != size {
+		return nil, errors.New("grpc: malformed EchoMessage")
+	}
+	return msg[1+k:], nil
+}
+
+// grpcEchoHandler serves the Echo service
+func grpcEchoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor != 2 || r.Method != http.MethodPost || r.Header.Get("Content-Type") != grpcContentType {
+		http.Error(w, "gRPC requests only", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", grpcContentType)
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	status := func(code int, msg string) {
+		w.Header().Set("Grpc-Status", fmt.Sprint(code))
+		if msg != "" {
+			w.Header().Set("Grpc-Message", msg)
+		}
+	}
+	switch r.URL.Path {
+	case grpcUnaryPath, grpcStreamPath:
+	default:
+		status(12, "unknown method") // UNIMPLEMENTED
+		return
+	}
+	// Send the headers now: a streaming client waits for them before
+	// sending its first message
+	w.WriteHeader(http.StatusOK)
+	flusher := w.(http.Flusher)
+	flusher.Flush()
+
+	var in, out []byte
+	for {
+		payload, err := readEchoMessage(r.Body, &in)
+		if err == io.EOF {
+			status(0, "")
+			return
+		}
+		if err != nil {
+			status(13, err.Error()) // INTERNAL
+			return
+		}
+		out = appendEchoMessage(out[:0], payload)
+		if _, err := w.Write(out); err != nil {
+			return
+		}
+		flusher.Flush()
+		if r.URL.Path == grpcUnaryPath {
+			status(0, "")
+			return
+		}
+	}
+}
+
+// checkGRPCStatus reads the rest of a response and checks its status
+func checkGRPCStatus(resp *http.Response) error {
+	io.Copy(io.Discard, resp.Body)
+	if s := resp.Trailer.Get("Grpc-Status"); s != "0" {
+		return fmt.Errorf("grpc status %q: %s", s, resp.Trailer.Get("Grpc-Message"))
+	}
+	return nil
+}
+
+// grpcStream is an open bidirectional Stream call
+type grpcStream struct {
+	send *io.PipeWriter
+	resp *http.Response
+	recv *bufio.Reader
+}
+
+var grpcBench struct {
+	server  *http.Server
+	client  *http.Client
+	base    string
+	payload []byte
+	request []byte
+	buf     []byte
+	stream  *grpcStream
+}
+
+func setupGrpc(b *B) error {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	grpcBench.server = &http.Server{Handler: http.HandlerFunc(grpcEchoHandler), Protocols: &protocols}
+	go grpcBench.server.Serve(ln)
+	grpcBench.client = &http.Client{Transport: &http.Transport{
+		Protocols:       &protocols,
+		MaxConnsPerHost: 1,
+		DialContext: func(_ context.Context, network, addr string) (net.Conn, error) {
+			return dialTCP(addr)
+		},
+	}}
+	grpcBench.base = "http://" + ln.Addr().String()
+	grpcBench.payload = make([]byte, benchConfig.PayloadSizes.Request)
+	grpcBench.request = appendEchoMessage(nil, grpcBench.payload)
+	return nil
+}
+
+func setupGrpcStream(b *B) error {
+	if err := setupGrpc(b); err != nil {
+		return err
+	}
+	grpcBench.payload = make([]byte, b.IntParam("payload"))
+	grpcBench.request = appendEchoMessage(nil, grpcBench.payload)
+	pr, pw := io.Pipe()
+	req, err := newGRPCRequest(grpcStreamPath, pr)
+	if err != nil {
+		teardownGrpc()
+		return err
+	}
+	resp, err := grpcBench.client.Do(req)
+	if err != nil {
+		teardownGrpc()
+		return err
+	}
+	grpcBench.stream = &grpcStream{send: pw, resp: resp, recv: bufio.NewReader(resp.Body)}
+	return nil
+}
+
+func teardownGrpc() {
+	if s := grpcBench.stream; s != nil {
+		// Half-close so the server ends the call, then drain it
+		s.send.Close()
+		checkGRPCStatus(s.resp)
+		s.resp.Body.Close()
+	}
+	if grpcBench.client != nil {
+		grpcBench.client.CloseIdleConnections()
+	}
+	if grpcBench.server != nil {
+		grpcBench.server.Close()
+	}
+	grpcBench.server, grpcBench.client, grpcBench.stream = nil, nil, nil
+}
+
+func newGRPCRequest(path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodPost, grpcBench.base+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", grpcContentType)
+	req.Header.Set("Te", "trailers")
+	return req, nil
+}
+
+// benchGrpcUnary performs one unary Echo call
+func benchGrpcUnary(b *B) {
+	b.SetBytes(int64(len(grpcBench.payload)))
+	start := time.Now()
+	req, err := newGRPCRequest(grpcUnaryPath, bytes.NewReader(grpcBench.request))
+	if err != nil {
+		b.Fatal(err)
+		return
+	}
+	resp, err := grpcBench.client.Do(req)
+	if err != nil {
+		b.Fatal(err)
+		return
+	}
+	payload, err := readEchoMessage(resp.Body, &grpcBench.buf)
+	if err == nil && len(payload) != len(grpcBench.payload) {
+		err = fmt.Errorf("echoed %d bytes, want %d", len(payload), len(grpcBench.payload))
+	}
+	if err == nil {
+		err = checkGRPCStatus(resp)
+	}
+	resp.Body.Close()
+	if err != nil {
+		b.Fatal(err)
+		return
+	}
+	b.Histogram().Record(time.Since(start))
+}
+
+// benchGrpcStream sends a batch of messages on the open stream while
+// reading their echoes
+func benchGrpcStream(b *B) {
+	s := grpcBench.stream
+	b.SetBytes(int64(grpcStreamMessages * len(grpcBench.payload)))
+	start := time.Now()
+	sent := make(chan error, 1)
+	go func() {
+		for i := 0; i < grpcStreamMessages; i++ {
+			if _, err := s.send.Write(grpcBench.request); err != nil {
+				sent <- err
+				return
+			}
+		}
+		sent <- nil
+	}()
+	var readErr error
+	for i := 0; i < grpcStreamMessages && readErr == nil; i++ {
+		var payload []byte
+		if payload, readErr = readEchoMessage(s.recv, &grpcBench.buf); readErr == nil && len(payload) != len(grpcBench.payload) {
+			readErr = fmt.Errorf("echoed %d bytes, want %d", len(payload), len(grpcBench.payload))
+		}
+	}
+	if readErr != nil {
+		// Unblock the sender before waiting for it
+		s.send.CloseWithError(readErr)
+		<-sent
+		b.Fatal(readErr)
+		return
+	}
+	if err := <-sent; err != nil {
+		b.Fatal(err)
+		return
+	}
+	b.AddRate("messages_per_sec", grpcStreamMessages, time.Since(start))
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "GrpcWireUnary", Category: "rpc", Tags: []string{"net"},
+		Iterations: 5000,
+		Setup:      setupGrpc, Teardown: teardownGrpc,
+		Fn: benchGrpcUnary,
+	})
+	Register(Benchmark{
+		Name: "GrpcWireStream", Category: "rpc", Tags: []string{"net"},
+		Iterations: 50,
+		Axes:       []Axis{{Name: "payload", Values: Ints(64, 16*1024)}},
+		Setup:      setupGrpcStream, Teardown: teardownGrpc,
+		Fn: benchGrpcStream,
+	})
+}
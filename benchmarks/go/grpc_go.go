@@ -0,0 +1,225 @@
+//go:build grpcgo
+
+// The Echo service of grpc_bench.go on grpc-go, client and server, so the
+// numbers are those of the gRPC stack users deploy rather than of the
+// hand-rolled framing of GrpcWireUnary and GrpcWireStream:
+//
+//   - GrpcUnary: one Echo call per iteration, recorded in the latency
+//     histogram
+//   - GrpcStream: grpcStreamMessages messages per iteration sent and echoed
+//     over one long-lived bidirectional stream; reported as messages_per_sec
+//
+// The service is registered from a hand-written grpc.ServiceDesc with a
+// codec for EchoMessage (echoCodec), so no protoc output is needed; the
+// messages on the wire are the same protobuf either way.
+//
+// Run with: go get google.golang.org/grpc && go run -tags grpcgo . -run Grpc
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// echoPayload is the payload of an EchoMessage
+type echoPayload []byte
+
+// echoCodec encodes echoPayload as an EchoMessage
+type echoCodec struct{}
+
+func (echoCodec) Marshal(v any) ([]byte, error) {
+	p, ok := v.(*echoPayload)
+	if !ok {
+		return nil, fmt.Errorf("grpc: cannot marshal %T", v)
+	}
+	return appendEchoMessage(nil, *p)[grpcFrameHeader:], nil
+}
+
+func (echoCodec) Unmarshal(data []byte, v any) error {
+	p, ok := v.(*echoPayload)
+	if !ok {
+		return fmt.Errorf("grpc: cannot unmarshal into %T", v)
+	}
+	payload, err := parseEchoMessage(data)
+	if err != nil {
+		return err
+	}
+	// data is not the codec's to keep
+	*p = append((*p)[:0], payload...)
+	return nil
+}
+
+func (echoCodec) Name() string { return "proto" }
+
+// echoServiceDesc is the Echo service of grpc_bench.go
+var echoServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tml.bench.Echo",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Unary",
+		Handler: func(_ any, _ context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+			var m echoPayload
+			if err := dec(&m); err != nil {
+				return nil, err
+			}
+			return &m, nil
+		},
+	}},
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Stream",
+		ServerStreams: true,
+		ClientStreams: true,
+		Handler: func(_ any, stream grpc.ServerStream) error {
+			var m echoPayload
+			for {
+				if err := stream.RecvMsg(&m); err == io.EOF {
+					return nil
+				} else if err != nil {
+					return err
+				}
+				if err := stream.SendMsg(&m); err != nil {
+					return err
+				}
+			}
+		},
+	}},
+}
+
+var grpcGoBench struct {
+	server  *grpc.Server
+	conn    *grpc.ClientConn
+	payload echoPayload
+	reply   echoPayload
+	stream  grpc.ClientStream
+	cancel  context.CancelFunc
+}
+
+func setupGrpcGo(b *B) error {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	grpcGoBench.server = grpc.NewServer(grpc.ForceServerCodec(echoCodec{}))
+	grpcGoBench.server.RegisterService(&echoServiceDesc, struct{}{})
+	go grpcGoBench.server.Serve(ln)
+	grpcGoBench.conn, err = grpc.NewClient("passthrough:///"+ln.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(_ context.Context, addr string) (net.Conn, error) {
+			return dialTCP(addr)
+		}),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(echoCodec{})),
+	)
+	if err != nil {
+		teardownGrpcGo()
+		return err
+	}
+	grpcGoBench.payload = make(echoPayload, benchConfig.PayloadSizes.Request)
+	return nil
+}
+
+func setupGrpcGoStream(b *B) error {
+	if err := setupGrpcGo(b); err != nil {
+		return err
+	}
+	grpcGoBench.payload = make(echoPayload, b.IntParam("payload"))
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := grpcGoBench.conn.NewStream(ctx, &echoServiceDesc.Streams[0], grpcStreamPath)
+	if err != nil {
+		cancel()
+		teardownGrpcGo()
+		return err
+	}
+	grpcGoBench.stream, grpcGoBench.cancel = stream, cancel
+	return nil
+}
+
+func teardownGrpcGo() {
+	if s := grpcGoBench.stream; s != nil {
+		// Half-close so the server ends the call, then drain it
+		s.CloseSend()
+		for s.RecvMsg(&grpcGoBench.reply) == nil {
+		}
+		grpcGoBench.cancel()
+	}
+	if grpcGoBench.conn != nil {
+		grpcGoBench.conn.Close()
+	}
+	if grpcGoBench.server != nil {
+		grpcGoBench.server.Stop()
+	}
+	grpcGoBench.server, grpcGoBench.conn, grpcGoBench.stream = nil, nil, nil
+}
+
+// benchGrpcGoUnary performs one unary Echo call
+func benchGrpcGoUnary(b *B) {
+	b.SetBytes(int64(len(grpcGoBench.payload)))
+	start := time.Now()
+	err := grpcGoBench.conn.Invoke(context.Background(), grpcUnaryPath, &grpcGoBench.payload, &grpcGoBench.reply)
+	if err == nil && len(grpcGoBench.reply) != len(grpcGoBench.payload) {
+		err = fmt.Errorf("echoed %d bytes, want %d", len(grpcGoBench.reply), len(grpcGoBench.payload))
+	}
+	if err != nil {
+		b.Fatal(err)
+		return
+	}
+	b.Histogram().Record(time.Since(start))
+}
+
+// benchGrpcGoStream sends a batch of messages on the open stream while
+// reading their echoes
+func benchGrpcGoStream(b *B) {
+	s := grpcGoBench.stream
+	b.SetBytes(int64(grpcStreamMessages * len(grpcGoBench.payload)))
+	start := time.Now()
+	sent := make(chan error, 1)
+	go func() {
+		for i := 0; i < grpcStreamMessages; i++ {
+			if err := s.SendMsg(&grpcGoBench.payload); err != nil {
+				sent <- err
+				return
+			}
+		}
+		sent <- nil
+	}()
+	var readErr error
+	for i := 0; i < grpcStreamMessages && readErr == nil; i++ {
+		if readErr = s.RecvMsg(&grpcGoBench.reply); readErr == nil && len(grpcGoBench.reply) != len(grpcGoBench.payload) {
+			readErr = fmt.Errorf("echoed %d bytes, want %d", len(grpcGoBench.reply), len(grpcGoBench.payload))
+		}
+	}
+	if readErr != nil {
+		// Unblock the sender before waiting for it
+		grpcGoBench.cancel()
+		<-sent
+		b.Fatal(readErr)
+		return
+	}
+	if err := <-sent; err != nil {
+		b.Fatal(err)
+		return
+	}
+	b.AddRate("messages_per_sec", grpcStreamMessages, time.Since(start))
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "GrpcUnary", Category: "rpc", Tags: []string{"net"},
+		Iterations: 5000,
+		Setup:      setupGrpcGo, Teardown: teardownGrpcGo,
+		Fn: benchGrpcGoUnary,
+	})
+	Register(Benchmark{
+		Name: "GrpcStream", Category: "rpc", Tags: []string{"net"},
+		Iterations: 50,
+		Axes:       []Axis{{Name: "payload", Values: Ints(64, 16*1024)}},
+		Setup:      setupGrpcGoStream, Teardown: teardownGrpcGo,
+		Fn: benchGrpcGoStream,
+	})
+}
@@ -0,0 +1,61 @@
+// gRPC Echo Tests - Go
+//
+// Run with: go test -run Grpc
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGrpcEchoMessageRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, 127, 128, 70000} {
+		payload := bytes.Repeat([]byte{7}, size)
+		frame := appendEchoMessage(nil, payload)
+		var buf []byte
+		got, err := readEchoMessage(bytes.NewReader(frame), &buf)
+		if err != nil || !bytes.Equal(got, payload) {
+			t.Errorf("size %d: got %d bytes, err %v", size, len(got), err)
+		}
+	}
+	var buf []byte
+	if _, err := readEchoMessage(bytes.NewReader([]byte{1, 0, 0, 0, 0}), &buf); err == nil {
+		t.Error("compressed frame accepted")
+	}
+	if _, err := readEchoMessage(bytes.NewReader([]byte{0, 0, 0, 0, 3, 0x0a, 5, 1}), &buf); err == nil {
+		t.Error("payload length beyond the message accepted")
+	}
+}
+
+func TestGrpcEchoHandlerStatus(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(grpcEchoHandler))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	call := func(path string, body []byte) (*http.Response, []byte) {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", grpcContentType)
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp, data
+	}
+
+	frame := appendEchoMessage(nil, []byte("hello"))
+	resp, data := call(grpcUnaryPath, frame)
+	if !bytes.Equal(data, frame) || resp.Trailer.Get("Grpc-Status") != "0" {
+		t.Errorf("unary: body %q status %q", data, resp.Trailer.Get("Grpc-Status"))
+	}
+	resp, _ = call("/tml.bench.Echo/Missing", frame)
+	if resp.Trailer.Get("Grpc-Status") != "12" {
+		t.Errorf("unknown method: status %q, want 12", resp.Trailer.Get("Grpc-Status"))
+	}
+}
@@ -0,0 +1,458 @@
+// Benchmark Harness - Go
+//
+// Shared timing and result types used by every benchmark suite.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// BenchmarkResult holds the results of a benchmark
+type BenchmarkResult struct {
+	Name          string             `json:"name"`
+	Category      string             `json:"category,omitempty"`
+	TimeUs        float64            `json:"time_us"`
+	Iterations    int64              `json:"iterations"`
+	ThroughputMBs float64            `json:"throughput_mbs,omitempty"`
+	AllocsPerOp   int64              `json:"allocs_per_op"`
+	BytesPerOp    int64              `json:"bytes_per_op"`
+	Params        Params             `json:"params,omitempty"`
+	GC            *GCStats           `json:"gc,omitempty"`
+	Latency       *LatencySummary    `json:"latency,omitempty"`
+	TCPInfo       *TCPInfoStats      `json:"tcp_info,omitempty"`
+	Metrics       map[string]float64 `json:"metrics,omitempty"`
+	Soak          []SoakSnapshot     `json:"soak,omitempty"`
+	Leaks         *LeakReport        `json:"leaks,omitempty"`
+	Error         string             `json:"error,omitempty"`
+}
+
+// GCStats summarizes garbage collection during a benchmark's measured
+// iterations
+type GCStats struct {
+	NumGC        uint32  `json:"num_gc"`
+	PauseTotalUs float64 `json:"pause_total_us"`
+	PauseMaxUs   float64 `json:"pause_max_us"`
+}
+
+// gcStatsBetween computes GC activity between two MemStats snapshots
+func gcStatsBetween(before, after *runtime.MemStats) *GCStats {
+	stats := &GCStats{
+		NumGC:        after.NumGC - before.NumGC,
+		PauseTotalUs: float64(after.PauseTotalNs-before.PauseTotalNs) / 1e3,
+	}
+	// PauseNs is a circular buffer of the last 256 pauses
+	cycles := stats.NumGC
+	if cycles > uint32(len(after.PauseNs)) {
+		cycles = uint32(len(after.PauseNs))
+	}
+	for i := uint32(0); i < cycles; i++ {
+		pause := after.PauseNs[(after.NumGC-i+255)%256]
+		if us := float64(pause) / 1e3; us > stats.PauseMaxUs {
+			stats.PauseMaxUs = us
+		}
+	}
+	return stats
+}
+
+// ResultSet is the document written for a complete harness run
+type ResultSet struct {
+	// SchemaVersion is the result file format version; see schema.go
+	SchemaVersion int               `json:"schema_version"`
+	Language      string            `json:"language"`
+	Metadata      Metadata          `json:"metadata"`
+	Results       []BenchmarkResult `json:"results"`
+}
+
+// B is passed to every benchmark iteration and controls the timer, in the
+// style of testing.B, so per-iteration setup can be excluded from the
+// measurement
+type B struct {
+	// N is the number of measured iterations
+	N int64
+
+	params  Params
+	bytes   int64
+	hist    *Histogram
+	metrics map[string]float64
+	rates   map[string]*rateTotal
+	err     error
+	timerOn bool
+	start   time.Time
+	elapsed time.Duration
+
+	// Memory is accounted like time, over the intervals the timer ran, so
+	// allocations made while it is stopped stay out of the per-op counts
+	memStart   runtime.MemStats
+	memStop    runtime.MemStats
+	allocs     uint64
+	allocBytes uint64
+	gc         GCStats
+}
+
+// StartTimer resumes timing; it is called automatically before the first
+// measured iteration
+func (b *B) StartTimer() {
+	if !b.timerOn {
+		runtime.ReadMemStats(&b.memStart)
+		b.start = time.Now()
+		b.timerOn = true
+	}
+}
+
+// StopTimer pauses timing, e.g. while regenerating inputs
+func (b *B) StopTimer() {
+	if b.timerOn {
+		b.elapsed += time.Since(b.start)
+		runtime.ReadMemStats(&b.memStop)
+		b.allocs += b.memStop.Mallocs - b.memStart.Mallocs
+		b.allocBytes += b.memStop.TotalAlloc - b.memStart.TotalAlloc
+		gc := gcStatsBetween(&b.memStart, &b.memStop)
+		b.gc.NumGC += gc.NumGC
+		b.gc.PauseTotalUs += gc.PauseTotalUs
+		b.gc.PauseMaxUs = max(b.gc.PauseMaxUs, gc.PauseMaxUs)
+		b.timerOn = false
+	}
+}
+
+// timed returns the time accumulated so far, including the running
+// interval if the timer is on
+func (b *B) timed() time.Duration {
+	if b.timerOn {
+		return b.elapsed + time.Since(b.start)
+	}
+	return b.elapsed
+}
+
+// ResetTimer zeroes the elapsed time and memory counts without changing
+// whether the timer is running
+func (b *B) ResetTimer() {
+	if b.timerOn {
+		runtime.ReadMemStats(&b.memStart)
+		b.start = time.Now()
+	}
+	b.elapsed = 0
+	b.allocs, b.allocBytes = 0, 0
+	b.gc = GCStats{}
+}
+
+// SetBytes records the number of bytes processed per iteration, overriding
+// the benchmark's static DataSize for throughput reporting
+func (b *B) SetBytes(n int64) {
+	b.bytes = n
+}
+
+// Histogram returns the latency histogram for this run; benchmarks that
+// record per-operation latencies get the full percentile spectrum in their
+// result
+func (b *B) Histogram() *Histogram {
+	if b.hist == nil {
+		b.hist = NewHistogram()
+	}
+	return b.hist
+}
+
+// ReportMetric attaches a named benchmark-specific measurement (achieved
+// rate, loss count, ...) to the result, like testing.B.ReportMetric
+func (b *B) ReportMetric(name string, value float64) {
+	if b.metrics == nil {
+		b.metrics = map[string]float64{}
+	}
+	b.metrics[name] = value
+}
+
+// rateTotal is the work and time behind one AddRate metric
+type rateTotal struct {
+	ops     float64
+	elapsed time.Duration
+}
+
+// AddRate adds ops done in elapsed to the named rate metric, which is
+// reported as the total ops per second over all iterations of the run, not
+// the rate of whichever iteration reported last
+func (b *B) AddRate(name string, ops float64, elapsed time.Duration) {
+	if b.rates == nil {
+		b.rates = map[string]*rateTotal{}
+	}
+	t := b.rates[name]
+	if t == nil {
+		t = &rateTotal{}
+		b.rates[name] = t
+	}
+	t.ops += ops
+	t.elapsed += elapsed
+}
+
+// resultMetrics returns the reported metrics with the accumulated rates
+func (b *B) resultMetrics() map[string]float64 {
+	for name, t := range b.rates {
+		if t.elapsed > 0 {
+			b.ReportMetric(name, t.ops/t.elapsed.Seconds())
+		}
+	}
+	return b.metrics
+}
+
+// Fatal records err as the benchmark's failure and stops it after the
+// current iteration
+func (b *B) Fatal(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// Param returns the value of a matrix parameter for the current case
+func (b *B) Param(name string) interface{} {
+	v, ok := b.params[name]
+	if !ok {
+		panic(fmt.Sprintf("benchmark has no parameter %q", name))
+	}
+	return v
+}
+
+// IntParam returns an integer matrix parameter
+func (b *B) IntParam(name string) int {
+	return b.Param(name).(int)
+}
+
+// StringParam returns a string matrix parameter
+func (b *B) StringParam(name string) string {
+	return b.Param(name).(string)
+}
+
+// MeasureHook is notified around the measured iterations of every case,
+// outside the timed region; profilers and tracers attach here. Before
+// returns the function to call once measurement ends.
+type MeasureHook func(c Case) (after func(), err error)
+
+var measureHooks []MeasureHook
+
+// AddMeasureHook registers a hook for all subsequent runs
+func AddMeasureHook(h MeasureHook) {
+	measureHooks = append(measureHooks, h)
+}
+
+// beforeMeasure runs every hook, returning a function that completes them
+// in reverse order
+func beforeMeasure(c Case) (func(), error) {
+	var afters []func()
+	done := func() {
+		for i := len(afters) - 1; i >= 0; i-- {
+			afters[i]()
+		}
+	}
+	for _, h := range measureHooks {
+		after, err := h(c)
+		if err != nil {
+			done()
+			return nil, err
+		}
+		afters = append(afters, after)
+	}
+	return done, nil
+}
+
+// RunBenchmark executes a benchmark function once per iteration and returns
+// the time accumulated while the timer was running
+func RunBenchmark(name string, iterations int64, dataSize int64, fn func(b *B)) BenchmarkResult {
+	bench := &Benchmark{Name: name, Iterations: iterations, DataSize: dataSize, Fn: fn}
+	return RunCase(Case{Bench: bench, Name: name})
+}
+
+// RunCase runs one expanded case of a registered benchmark, wrapped in its
+// Setup and Teardown hooks, and reports what it leaked (see leak.go)
+func RunCase(c Case) BenchmarkResult {
+	if !leakCheck {
+		return runCase(c)
+	}
+	before := takeLeakSnapshot()
+	r := runCase(c)
+	r.Leaks = checkLeaks(before)
+	return r
+}
+
+// runCase is RunCase without the leak check
+func runCase(c Case) BenchmarkResult {
+	bench := c.Bench
+	iterations := bench.Iterations
+	resetTrackedTCPConns()
+	defer resetTrackedTCPConns()
+	// Deferred before Teardown, so connections closed there are included
+	defer startRecording(c)()
+
+	if bench.Setup != nil {
+		if err := bench.Setup(&B{params: c.Params}); err != nil {
+			// Release whatever the failed Setup got as far as creating
+			if bench.Teardown != nil {
+				bench.Teardown()
+			}
+			return BenchmarkResult{
+				Name: c.Name, Category: bench.Category, Iterations: iterations,
+				Params: c.Params, Error: fmt.Sprintf("setup: %v", err),
+			}
+		}
+	}
+	if bench.Teardown != nil {
+		defer bench.Teardown()
+	}
+
+	// Warmup
+	warmup := iterations / 10
+	if warmup > 10 {
+		warmup = 10
+	}
+	var tcpStats *TCPInfoStats
+	measured := false
+	b := &B{N: warmup, params: c.Params}
+	for i := int64(0); i < warmup && b.err == nil; i++ {
+		bench.Fn(b)
+	}
+
+	// Benchmark
+	if b.err == nil {
+		// The histogram is allocated up front rather than on first use, so
+		// its allocation is not counted against the measured iterations
+		b = &B{N: iterations, params: c.Params, hist: NewHistogram()}
+		after, err := beforeMeasure(c)
+		if err != nil {
+			b.Fatal(err)
+		} else {
+			samples := rawSamplesFor(c)
+			b.StartTimer()
+			for i := int64(0); i < iterations && b.err == nil; i++ {
+				if samples == nil {
+					bench.Fn(b)
+					continue
+				}
+				before := b.timed()
+				bench.Fn(b)
+				samples = append(samples, b.timed()-before)
+			}
+			b.StopTimer()
+			measured = true
+			after()
+			tcpStats = collectTCPInfo()
+			if samples != nil {
+				if err := rawSamples.write(c.Name, samples); err != nil {
+					b.Fatal(fmt.Errorf("writing raw samples: %w", err))
+				}
+			}
+		}
+	}
+
+	dataSize := bench.DataSize
+	if b.bytes > 0 {
+		dataSize = b.bytes
+	}
+	r := newResult(c.Name, iterations, dataSize, b.elapsed)
+	r.Category = bench.Category
+	r.Params = c.Params
+	// Like go test -benchmem, counts cover only the time the timer ran and
+	// include allocations made by other goroutines (e.g. echo servers)
+	// meanwhile
+	if iterations > 0 && measured {
+		r.AllocsPerOp = int64(b.allocs) / iterations
+		r.BytesPerOp = int64(b.allocBytes) / iterations
+		gc := b.gc
+		r.GC = &gc
+	}
+	if b.hist != nil && b.hist.Count() > 0 {
+		r.Latency = b.hist.Summary()
+	}
+	r.Metrics = b.resultMetrics()
+	r.TCPInfo = tcpStats
+	if b.err != nil {
+		r.Error = b.err.Error()
+	}
+	return r
+}
+
+// newResult converts a measured duration into a BenchmarkResult
+func newResult(name string, iterations int64, dataSize int64, elapsed time.Duration) BenchmarkResult {
+	totalUs := elapsed.Microseconds()
+	avgUs := float64(totalUs) / float64(iterations)
+	throughput := 0.0
+	if dataSize > 0 && totalUs > 0 {
+		throughput = float64(dataSize*iterations) / (float64(totalUs) / 1e6) / (1024 * 1024)
+	}
+
+	return BenchmarkResult{
+		Name:          name,
+		TimeUs:        avgUs,
+		Iterations:    iterations,
+		ThroughputMBs: throughput,
+	}
+}
+
+// PrintResult prints a benchmark result
+func PrintResult(r BenchmarkResult) {
+	if r.Error != "" {
+		fmt.Printf("%-40s FAILED: %s\n", r.Name, r.Error)
+		return
+	}
+	fmt.Printf("%-40s %12.2f us %12d iters %10d B/op %8d allocs/op",
+		r.Name, r.TimeUs, r.Iterations, r.BytesPerOp, r.AllocsPerOp)
+	if r.ThroughputMBs > 0 {
+		fmt.Printf(" %12.2f MB/s", r.ThroughputMBs)
+	}
+	fmt.Println()
+	if gc := r.GC; gc != nil && gc.NumGC > 0 {
+		fmt.Printf("    gc: %d cycles, pause total %.2f us, max %.2f us\n", gc.NumGC, gc.PauseTotalUs, gc.PauseMaxUs)
+	}
+	if l := r.Latency; l != nil {
+		fmt.Printf("    latency us: p50 %.2f  p90 %.2f  p99 %.2f  p99.9 %.2f  p99.99 %.2f  max %.2f\n",
+			l.P50Us, l.P90Us, l.P99Us, l.P999Us, l.P9999Us, l.MaxUs)
+	}
+	if r.TCPInfo != nil {
+		fmt.Printf("    tcp: %s\n", r.TCPInfo)
+	}
+	if r.Leaks != nil {
+		fmt.Printf("    LEAKED: %s\n", r.Leaks)
+	}
+	if len(r.Metrics) > 0 {
+		keys := make([]string, 0, len(r.Metrics))
+		for k := range r.Metrics {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fmt.Print("    metrics:")
+		for _, k := range keys {
+			fmt.Printf("  %s %.6g", k, r.Metrics[k])
+		}
+		fmt.Println()
+	}
+}
+
+// WriteResultSet writes a result set as indented JSON, stamped with the
+// current schema version
+func WriteResultSet(path string, set ResultSet) error {
+	set.SchemaVersion = resultSchemaVersion
+	data, err := json.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// LoadResultSet reads a result set written by WriteResultSet, by an older
+// version of the harness or by one of the other language suites, migrating
+// it to the current schema version
+func LoadResultSet(path string) (ResultSet, error) {
+	var set ResultSet
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return set, err
+	}
+	if data, err = migrateResultSet(data); err != nil {
+		return set, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return set, fmt.Errorf("%s: %w", path, err)
+	}
+	return set, nil
+}
@@ -0,0 +1,67 @@
+// Benchmark Harness Tests - Go
+//
+// Run with: go test -run RunCase
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var harnessSink []byte
+
+func TestRunCaseExcludesStoppedAllocations(t *testing.T) {
+	bench := &Benchmark{Name: "StoppedAllocs", Iterations: 100, Fn: func(b *B) {
+		b.StopTimer()
+		harnessSink = make([]byte, 64<<10)
+		b.StartTimer()
+		harnessSink = harnessSink[:1]
+	}}
+	r := RunCase(Case{Name: "StoppedAllocs", Bench: bench})
+	if r.Error != "" {
+		t.Fatal(r.Error)
+	}
+	// Other goroutines may allocate a little meanwhile, but nowhere near
+	// the 64KiB made while the timer was stopped
+	if r.BytesPerOp >= 64<<10 {
+		t.Errorf("bytes/op = %d, want the stopped-timer allocations excluded", r.BytesPerOp)
+	}
+}
+
+func TestRunCaseTearsDownFailedSetup(t *testing.T) {
+	tornDown := false
+	bench := &Benchmark{
+		Name: "FailedSetup", Iterations: 1,
+		Setup:    func(b *B) error { return errors.New("no fixture") },
+		Teardown: func() { tornDown = true },
+		Fn:       func(b *B) { t.Error("ran after a failed setup") },
+	}
+	r := RunCase(Case{Name: "FailedSetup", Bench: bench})
+	if r.Error != "setup: no fixture" {
+		t.Errorf("error = %q", r.Error)
+	}
+	if !tornDown {
+		t.Error("Teardown not run after the failed setup")
+	}
+}
+
+func TestRunCaseAccumulatesRates(t *testing.T) {
+	calls := 0
+	bench := &Benchmark{Name: "Rates", Iterations: 10, Fn: func(b *B) {
+		// Only the last measured iteration is slow; a per-iteration rate
+		// would report it alone
+		calls++
+		ops, elapsed := 10.0, time.Second
+		if calls == 11 {
+			ops, elapsed = 1, 10*time.Second
+		}
+		b.AddRate("ops_per_sec", ops, elapsed)
+	}}
+	r := RunCase(Case{Name: "Rates", Bench: bench})
+	// One warmup iteration, then 9 x 10 ops in 1s and 1 op in 10s
+	if got, want := r.Metrics["ops_per_sec"], 91.0/19; got != want {
+		t.Errorf("ops_per_sec = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,347 @@
+// Go UDP Heartbeat Benchmark
+// Keeps thousands of idle UDP "sessions" alive with periodic heartbeats,
+// the workload of TML's session-keepalive layer. All sessions share one
+// client socket and identify themselves by an 8-byte session id; the
+// server answers each heartbeat and tracks sessions in a map, expiring any
+// not heard from within heartbeatTimeout. One session in
+// heartbeatSilentEvery registers and then falls silent, so every run also
+// exercises timeout detection.
+//
+// An iteration is one heartbeat round over every live session, sent in
+// windows of heartbeatWindow; rounds start heartbeatInterval apart, the
+// wait between them untimed. The detection axis compares two ways of
+// finding expired sessions:
+//
+//   - timer: one timer per session, reset by every heartbeat
+//   - sweep: a periodic timer scanning the whole map every heartbeatSweep
+//
+// Metrics: ns_per_heartbeat (map and timer work plus the UDP round trip),
+// bytes_per_session (heap growth when registering), expired against
+// silent sessions, false_expiries of live ones and the mean detection lag
+// past the timeout.
+//
+// The server keeps time with a Clock; SessionExpiry (virtualtime_bench.go)
+// runs the same session tracking without sockets on a virtual one.
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const (
+	heartbeatInterval    = 100 * time.Millisecond
+	heartbeatTimeout     = 3 * heartbeatInterval
+	heartbeatSweep       = heartbeatTimeout / 4
+	heartbeatWindow      = 128
+	heartbeatSilentEvery = 100
+)
+
+// heartbeatSession is the server's state for one session
+type heartbeatSession struct {
+	lastSeen time.Time
+	timer    ClockTimer
+}
+
+// heartbeatExpiry records one session the server expired and how long
+// after its deadline that happened
+type heartbeatExpiry struct {
+	id  uint64
+	lag time.Duration
+}
+
+// heartbeatServer answers heartbeats and expires silent sessions
+type heartbeatServer struct {
+	pc        net.PacketConn
+	clock     Clock
+	useTimers bool
+
+	mu       sync.Mutex
+	sessions map[uint64]*heartbeatSession
+	expired  []heartbeatExpiry
+	sweeper  ClockTimer
+	closed   bool
+}
+
+// newHeartbeatServer creates a server's session tracking, without a
+// socket; sessions are registered and kept alive with touch
+func newHeartbeatServer(detection string, clock Clock) (*heartbeatServer, error) {
+	if detection != "timer" && detection != "sweep" {
+		return nil, fmt.Errorf("unknown detection %q", detection)
+	}
+	s := &heartbeatServer{
+		clock:     clock,
+		useTimers: detection == "timer",
+		sessions:  map[uint64]*heartbeatSession{},
+	}
+	if !s.useTimers {
+		s.mu.Lock()
+		s.sweeper = clock.AfterFunc(heartbeatSweep, s.sweep)
+		s.mu.Unlock()
+	}
+	return s, nil
+}
+
+// startHeartbeatServer starts a server answering heartbeats on a UDP
+// socket, in real time
+func startHeartbeatServer(detection string) (*heartbeatServer, error) {
+	s, err := newHeartbeatServer(detection, realClock{})
+	if err != nil {
+		return nil, err
+	}
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	s.pc = pc
+	go s.serve()
+	return s, nil
+}
+
+func (s *heartbeatServer) serve() {
+	buf := make([]byte, 64)
+	for {
+		n, addr, err := s.pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if n != 8 {
+			continue
+		}
+		s.touch(binary.LittleEndian.Uint64(buf))
+		s.pc.WriteTo(buf[:n], addr)
+	}
+}
+
+// touch registers a session or records a heartbeat from it
+func (s *heartbeatServer) touch(id uint64) {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := s.sessions[id]
+	if sess == nil {
+		sess = &heartbeatSession{lastSeen: now}
+		s.sessions[id] = sess
+		if s.useTimers {
+			sess.timer = s.clock.AfterFunc(heartbeatTimeout, func() { s.expire(id, sess) })
+		}
+		return
+	}
+	sess.lastSeen = now
+	if s.useTimers {
+		sess.timer.Reset(heartbeatTimeout)
+	}
+}
+
+// expire is a session timer's callback; a heartbeat may have raced it
+func (s *heartbeatServer) expire(id uint64, sess *heartbeatSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions[id] != sess {
+		return
+	}
+	lag := s.clock.Now().Sub(sess.lastSeen) - heartbeatTimeout
+	if lag < 0 {
+		return
+	}
+	delete(s.sessions, id)
+	s.expired = append(s.expired, heartbeatExpiry{id: id, lag: lag})
+}
+
+// sweep expires every session past its deadline, then rearms itself
+func (s *heartbeatServer) sweep() {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	for id, sess := range s.sessions {
+		if lag := now.Sub(sess.lastSeen) - heartbeatTimeout; lag >= 0 {
+			delete(s.sessions, id)
+			s.expired = append(s.expired, heartbeatExpiry{id: id, lag: lag})
+		}
+	}
+	s.sweeper.Reset(heartbeatSweep)
+}
+
+// expiries returns the number of sessions expired so far, how many of
+// them were not meant to go silent, and their mean detection lag
+func (s *heartbeatServer) expiries() (expired, falseExpiries int, meanLag time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var lag time.Duration
+	for _, e := range s.expired {
+		lag += e.lag
+		if e.id%heartbeatSilentEvery != heartbeatSilentEvery-1 {
+			falseExpiries++
+		}
+	}
+	if expired = len(s.expired); expired > 0 {
+		meanLag = lag / time.Duration(expired)
+	}
+	return expired, falseExpiries, meanLag
+}
+
+func (s *heartbeatServer) close() {
+	if s.pc != nil {
+		s.pc.Close()
+	}
+	s.mu.Lock()
+	s.closed = true
+	if s.sweeper != nil {
+		s.sweeper.Stop()
+	}
+	for _, sess := range s.sessions {
+		if sess.timer != nil {
+			sess.timer.Stop()
+		}
+	}
+	s.mu.Unlock()
+}
+
+// heartbeatClient sends heartbeats for many sessions over one socket
+type heartbeatClient struct {
+	conn net.Conn
+	buf  []byte
+}
+
+// round sends one heartbeat for each id, heartbeatWindow at a time, and
+// waits for every reply
+func (c *heartbeatClient) round(ids []uint64) error {
+	for len(ids) > 0 {
+		window := ids[:min(len(ids), heartbeatWindow)]
+		ids = ids[len(window):]
+		for _, id := range window {
+			binary.LittleEndian.PutUint64(c.buf, id)
+			if _, err := c.conn.Write(c.buf[:8]); err != nil {
+				return err
+			}
+		}
+		c.conn.SetReadDeadline(time.Now().Add(time.Second))
+		for range window {
+			if _, err := c.conn.Read(c.buf); err != nil {
+				if errors.Is(err, os.ErrDeadlineExceeded) {
+					return errors.New("heartbeat reply lost")
+				}
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// heartbeatSessionIDs numbers n sessions, splitting off the live ones
+// from every heartbeatSilentEvery-th, which goes silent
+func heartbeatSessionIDs(n int) (all, live []uint64, silent int) {
+	all = make([]uint64, n)
+	for i := range all {
+		all[i] = uint64(i)
+		if i%heartbeatSilentEvery == heartbeatSilentEvery-1 {
+			silent++
+		} else {
+			live = append(live, uint64(i))
+		}
+	}
+	return all, live, silent
+}
+
+var heartbeat struct {
+	server          *heartbeatServer
+	client          *heartbeatClient
+	live            []uint64
+	silent          int
+	bytesPerSession float64
+	nextRound       time.Time
+}
+
+func setupUdpHeartbeat(b *B) error {
+	s, err := startHeartbeatServer(b.StringParam("detection"))
+	if err != nil {
+		return err
+	}
+	heartbeat.server = s
+	conn, err := net.Dial("udp", s.pc.LocalAddr().String())
+	if err != nil {
+		teardownUdpHeartbeat()
+		return err
+	}
+	heartbeat.client = &heartbeatClient{conn: conn, buf: make([]byte, 64)}
+
+	sessions := b.IntParam("sessions")
+	all, live, silent := heartbeatSessionIDs(sessions)
+	heartbeat.live, heartbeat.silent = live, silent
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	if err := heartbeat.client.round(all); err != nil {
+		teardownUdpHeartbeat()
+		return fmt.Errorf("registering sessions: %w", err)
+	}
+	runtime.ReadMemStats(&after)
+	heartbeat.bytesPerSession = float64(int64(after.HeapAlloc)-int64(before.HeapAlloc)) / float64(sessions)
+	heartbeat.nextRound = time.Now().Add(heartbeatInterval)
+	return nil
+}
+
+func teardownUdpHeartbeat() {
+	if heartbeat.client != nil {
+		heartbeat.client.conn.Close()
+	}
+	if heartbeat.server != nil {
+		heartbeat.server.close()
+	}
+	heartbeat.server, heartbeat.client, heartbeat.live = nil, nil, nil
+	heartbeat.silent = 0
+}
+
+// benchUdpHeartbeat runs one heartbeat round and reports detection so far
+func benchUdpHeartbeat(b *B) {
+	b.StopTimer()
+	time.Sleep(time.Until(heartbeat.nextRound))
+	heartbeat.nextRound = time.Now().Add(heartbeatInterval)
+	b.StartTimer()
+
+	start := time.Now()
+	if err := heartbeat.client.round(heartbeat.live); err != nil {
+		b.Fatal(err)
+		return
+	}
+	b.ReportMetric("ns_per_heartbeat", float64(time.Since(start).Nanoseconds())/float64(len(heartbeat.live)))
+
+	b.ReportMetric("bytes_per_session", heartbeat.bytesPerSession)
+	reportHeartbeatExpiries(b, heartbeat.server, heartbeat.silent)
+}
+
+// reportHeartbeatExpiries reports the server's detection of its silent
+// sessions
+func reportHeartbeatExpiries(b *B, s *heartbeatServer, silent int) {
+	expired, falseExpiries, lag := s.expiries()
+	b.ReportMetric("silent", float64(silent))
+	b.ReportMetric("expired", float64(expired))
+	b.ReportMetric("false_expiries", float64(falseExpiries))
+	if expired > 0 {
+		b.ReportMetric("detect_lag_ms", float64(lag)/1e6)
+	}
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "UdpHeartbeat", Category: "udp", Tags: []string{"net"},
+		Iterations: 20,
+		Axes: []Axis{
+			{Name: "sessions", Values: Ints(1000, 10000)},
+			{Name: "detection", Values: Strings("timer", "sweep")},
+		},
+		Setup: setupUdpHeartbeat, Teardown: teardownUdpHeartbeat,
+		Fn: benchUdpHeartbeat,
+	})
+}
@@ -0,0 +1,170 @@
+// Latency Histogram - Go
+//
+// An HDR-style log-linear histogram: values below 2^histSubBits nanoseconds
+// are counted exactly, larger values land in buckets whose width grows with
+// magnitude so the relative error stays below 0.1% across the full range.
+// Recording is allocation-free, so every round trip of a network benchmark
+// can be recorded without disturbing the measurement.
+
+package main
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+const (
+	histSubBits  = 11
+	histSubCount = 1 << histSubBits
+	histHalf     = histSubCount / 2
+	histBuckets  = histSubCount + (64-histSubBits)*histHalf
+)
+
+// Histogram records durations with bounded relative error
+type Histogram struct {
+	counts [histBuckets]uint64
+	total  uint64
+	sum    float64
+	min    int64
+	max    int64
+}
+
+// NewHistogram returns an empty histogram
+func NewHistogram() *Histogram {
+	return &Histogram{min: math.MaxInt64}
+}
+
+// histIndex maps a non-negative value to its bucket
+func histIndex(v int64) int {
+	if v < histSubCount {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - histSubBits
+	return histSubCount + (shift-1)*histHalf + int(v>>shift) - histHalf
+}
+
+// histValue returns the midpoint of the values that map to bucket i
+func histValue(i int) int64 {
+	if i < histSubCount {
+		return int64(i)
+	}
+	j := i - histSubCount
+	shift := j/histHalf + 1
+	low := int64(j%histHalf+histHalf) << shift
+	return low + (int64(1)<<shift)/2
+}
+
+// Record adds one duration
+func (h *Histogram) Record(d time.Duration) {
+	v := int64(d)
+	if v < 0 {
+		v = 0
+	}
+	h.counts[histIndex(v)]++
+	h.total++
+	h.sum += float64(v)
+	if v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+}
+
+// Merge adds every value recorded in other
+func (h *Histogram) Merge(other *Histogram) {
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	h.total += other.total
+	h.sum += other.sum
+	if other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+}
+
+// Count returns the number of recorded values
+func (h *Histogram) Count() uint64 {
+	return h.total
+}
+
+// Min returns the smallest recorded value
+func (h *Histogram) Min() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	return time.Duration(h.min)
+}
+
+// Max returns the largest recorded value
+func (h *Histogram) Max() time.Duration {
+	return time.Duration(h.max)
+}
+
+// Mean returns the exact mean of the recorded values
+func (h *Histogram) Mean() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	return time.Duration(h.sum / float64(h.total))
+}
+
+// Percentile returns the value at percentile p (0-100)
+func (h *Histogram) Percentile(p float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(p / 100 * float64(h.total)))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			v := histValue(i)
+			// Bucket midpoints can overshoot the true extremes
+			if v > h.max {
+				v = h.max
+			}
+			if v < h.min {
+				v = h.min
+			}
+			return time.Duration(v)
+		}
+	}
+	return time.Duration(h.max)
+}
+
+// LatencySummary is the percentile spectrum stored in results
+type LatencySummary struct {
+	Count   uint64  `json:"count"`
+	MinUs   float64 `json:"min_us"`
+	MeanUs  float64 `json:"mean_us"`
+	P50Us   float64 `json:"p50_us"`
+	P90Us   float64 `json:"p90_us"`
+	P99Us   float64 `json:"p99_us"`
+	P999Us  float64 `json:"p99_9_us"`
+	P9999Us float64 `json:"p99_99_us"`
+	MaxUs   float64 `json:"max_us"`
+}
+
+// Summary returns the percentile spectrum up to p99.99
+func (h *Histogram) Summary() *LatencySummary {
+	us := func(d time.Duration) float64 { return float64(d) / 1e3 }
+	return &LatencySummary{
+		Count:   h.total,
+		MinUs:   us(h.Min()),
+		MeanUs:  us(h.Mean()),
+		P50Us:   us(h.Percentile(50)),
+		P90Us:   us(h.Percentile(90)),
+		P99Us:   us(h.Percentile(99)),
+		P999Us:  us(h.Percentile(99.9)),
+		P9999Us: us(h.Percentile(99.99)),
+		MaxUs:   us(h.Max()),
+	}
+}
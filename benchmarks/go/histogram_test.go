@@ -0,0 +1,58 @@
+// Latency Histogram Tests - Go
+//
+// Run with: go test -run Histogram
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistogramIndexRoundTrip(t *testing.T) {
+	for _, v := range []int64{0, 1, 2047, 2048, 2049, 4095, 4096, 1e6, 123456789, 1 << 40, 1<<62 + 12345} {
+		mid := histValue(histIndex(v))
+		diff := mid - v
+		if diff < 0 {
+			diff = -diff
+		}
+		if float64(diff) > float64(v)*0.001 {
+			t.Errorf("value %d maps to midpoint %d (error %d)", v, mid, diff)
+		}
+	}
+}
+
+func TestHistogramPercentiles(t *testing.T) {
+	h := NewHistogram()
+	for i := 1; i <= 10000; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+	cases := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{50, 5000 * time.Microsecond},
+		{99, 9900 * time.Microsecond},
+		{99.99, 9999 * time.Microsecond},
+		{100, 10000 * time.Microsecond},
+	}
+	for _, c := range cases {
+		got := h.Percentile(c.p)
+		if diff := got - c.want; diff > c.want/1000 || -diff > c.want/1000 {
+			t.Errorf("p%v = %v, want %v", c.p, got, c.want)
+		}
+	}
+	if h.Min() != time.Microsecond || h.Max() != 10*time.Millisecond {
+		t.Errorf("min/max = %v/%v", h.Min(), h.Max())
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	a, b := NewHistogram(), NewHistogram()
+	a.Record(time.Millisecond)
+	b.Record(3 * time.Millisecond)
+	a.Merge(b)
+	if a.Count() != 2 || a.Mean() != 2*time.Millisecond || a.Max() != 3*time.Millisecond {
+		t.Errorf("merged count=%d mean=%v max=%v", a.Count(), a.Mean(), a.Max())
+	}
+}
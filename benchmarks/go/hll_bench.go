@@ -0,0 +1,181 @@
+// HyperLogLog Benchmarks - Go
+//
+// Cardinality estimation with HyperLogLog: each value is hashed, the top p
+// bits pick one of 2^p registers and the register keeps the longest run of
+// leading zeros seen in the rest of the hash. Benchmarks cover add
+// throughput over 1B distinct inserts, register-wise merge and the
+// estimate itself, each verifying the estimate stays within three standard
+// errors (1.04/sqrt(m)) of the true count. Part of the probabilistic data
+// structures chapter, with the count-min sketch.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"time"
+)
+
+const hllInserts = 1_000_000_000
+
+// HyperLogLog estimates the number of distinct values added
+type HyperLogLog struct {
+	p         uint8
+	registers []uint8
+}
+
+// NewHyperLogLog returns a sketch with 2^p registers
+func NewHyperLogLog(p uint8) *HyperLogLog {
+	return &HyperLogLog{p: p, registers: make([]uint8, 1<<p)}
+}
+
+// mix64 is the splitmix64 finalizer, turning sequential keys into
+// well-distributed hashes
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// AddHash records a pre-hashed value
+func (h *HyperLogLog) AddHash(hash uint64) {
+	idx := hash >> (64 - h.p)
+	// The sentinel bit bounds the rank when the remaining bits are zero
+	rank := uint8(bits.LeadingZeros64(hash<<h.p|1<<(h.p-1)) + 1)
+	if rank > h.registers[idx] {
+		h.registers[idx] = rank
+	}
+}
+
+// Add records a value
+func (h *HyperLogLog) Add(v uint64) {
+	h.AddHash(mix64(v))
+}
+
+// Merge folds other, which must have the same precision, into h
+func (h *HyperLogLog) Merge(other *HyperLogLog) {
+	for i, r := range other.registers {
+		if r > h.registers[i] {
+			h.registers[i] = r
+		}
+	}
+}
+
+// hllInversePow2 caches 2^-r for every possible register value
+var hllInversePow2 = func() (t [65]float64) {
+	for i := range t {
+		t[i] = math.Ldexp(1, -i)
+	}
+	return t
+}()
+
+// Estimate returns the estimated cardinality, using linear counting while
+// many registers are still empty
+func (h *HyperLogLog) Estimate() float64 {
+	m := float64(len(h.registers))
+	sum, zeros := 0.0, 0
+	for _, r := range h.registers {
+		sum += hllInversePow2[r]
+		if r == 0 {
+			zeros++
+		}
+	}
+	alpha := 0.7213 / (1 + 1.079/m)
+	e := alpha * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		return m * math.Log(m/float64(zeros))
+	}
+	return e
+}
+
+// hllError returns the estimate's error in percent, and an error when it
+// is outside three standard errors
+func hllError(h *HyperLogLog, want float64) (float64, error) {
+	got := h.Estimate()
+	errPct := math.Abs(got-want) / want * 100
+	limit := 3 * 1.04 / math.Sqrt(float64(len(h.registers))) * 100
+	if errPct > limit {
+		return errPct, fmt.Errorf("estimate %.0f for %.0f distinct values is off by %.2f%% (limit %.2f%%)", got, want, errPct, limit)
+	}
+	return errPct, nil
+}
+
+// hllState holds the sketches built and verified in setup for the merge
+// and estimate benchmarks: a and b cover overlapping ranges of
+// hllSetupKeys values
+var hllState struct {
+	a, b   *HyperLogLog
+	merged *HyperLogLog
+}
+
+const hllSetupKeys = 10_000_000
+
+func setupHLLPair(b *B) error {
+	p := uint8(b.IntParam("precision"))
+	hllState.a, hllState.b, hllState.merged = NewHyperLogLog(p), NewHyperLogLog(p), NewHyperLogLog(p)
+	// a holds [0, n), b holds [n/2, 3n/2), so the union has 1.5n values
+	for i := uint64(0); i < hllSetupKeys; i++ {
+		hllState.a.Add(i)
+		hllState.b.Add(i + hllSetupKeys/2)
+	}
+	copy(hllState.merged.registers, hllState.a.registers)
+	hllState.merged.Merge(hllState.b)
+	if _, err := hllError(hllState.a, hllSetupKeys); err != nil {
+		return err
+	}
+	_, err := hllError(hllState.merged, hllSetupKeys*3/2)
+	return err
+}
+
+func teardownHLLPair() {
+	hllState.a, hllState.b, hllState.merged = nil, nil, nil
+}
+
+// hllSink keeps estimates observable so they are not optimized away
+var hllSink float64
+
+func init() {
+	precisions := []Axis{{Name: "precision", Values: Ints(10, 14, 16)}}
+
+	Register(Benchmark{
+		Name: "HLLAdd", Category: "probabilistic", Tags: []string{"cpu", "large"},
+		Iterations: 1, Axes: precisions,
+		Fn: func(b *B) {
+			h := NewHyperLogLog(uint8(b.IntParam("precision")))
+			start := time.Now()
+			for i := uint64(0); i < hllInserts; i++ {
+				h.Add(i)
+			}
+			b.ReportMetric("inserts_per_sec", hllInserts/time.Since(start).Seconds())
+			b.StopTimer()
+			errPct, err := hllError(h, hllInserts)
+			b.ReportMetric("error_pct", errPct)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.StartTimer()
+		},
+	})
+	Register(Benchmark{
+		Name: "HLLMerge", Category: "probabilistic", Tags: []string{"cpu"},
+		Iterations: 10_000, Axes: precisions,
+		Setup: setupHLLPair, Teardown: teardownHLLPair,
+		Fn: func(b *B) {
+			s := &hllState
+			copy(s.merged.registers, s.a.registers)
+			s.merged.Merge(s.b)
+		},
+	})
+	Register(Benchmark{
+		Name: "HLLEstimate", Category: "probabilistic", Tags: []string{"cpu"},
+		Iterations: 10_000, Axes: precisions,
+		Setup: setupHLLPair, Teardown: teardownHLLPair,
+		Fn: func(b *B) {
+			hllSink = hllState.a.Estimate()
+		},
+	})
+}
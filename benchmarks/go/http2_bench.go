@@ -0,0 +1,168 @@
+// Go HTTP/2 Multiplexing Benchmark
+// GET round trips over one HTTP/2 connection to an in-process net/http
+// server, either cleartext (h2c, prior knowledge) or over TLS with ALPN.
+// An iteration issues the streams axis' number of requests at once, so
+// with 1 stream it is the single-stream latency and with more the
+// requests share the connection as concurrent streams:
+//
+//   - the histogram holds every stream's latency, from sending its request
+//     to reading the last byte of its response
+//   - requests_per_sec is the aggregate rate across streams
+//
+// Responses carry the configured request payload size. The TLS case uses
+// the self-signed certificate of the TLS benchmarks and is handshaken once
+// at setup.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var http2Bench struct {
+	server    *http.Server
+	client    *http.Client
+	url       string
+	size      int
+	latencies []time.Duration
+	errs      []error
+}
+
+func setupHttp2Request(b *B) error {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	size := benchConfig.PayloadSizes.Request
+	body := make([]byte, size)
+	var protocols http.Protocols
+	transport := &http.Transport{
+		MaxConnsPerHost:    1,
+		DisableCompression: true,
+		DialContext: func(_ context.Context, network, addr string) (net.Conn, error) {
+			return dialTCP(addr)
+		},
+	}
+	http2Bench.server = &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.Write(body)
+		}),
+		Protocols: &protocols,
+	}
+
+	switch mode := b.StringParam("transport"); mode {
+	case "h2c":
+		protocols.SetUnencryptedHTTP2(true)
+		transport.Protocols = &protocols
+		http2Bench.url = "http://" + ln.Addr().String() + "/"
+		go http2Bench.server.Serve(ln)
+	case "tls":
+		serverCfg, clientCfg, err := tlsConfigs("ecdsa")
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		protocols.SetHTTP2(true)
+		transport.Protocols = &protocols
+		transport.TLSClientConfig = clientCfg
+		http2Bench.server.TLSConfig = serverCfg
+		http2Bench.url = "https://" + ln.Addr().String() + "/"
+		go http2Bench.server.ServeTLS(ln, "", "")
+	default:
+		ln.Close()
+		return fmt.Errorf("unknown transport %q", mode)
+	}
+	http2Bench.client = &http.Client{Transport: transport}
+	http2Bench.size = size
+
+	// Open the connection (and handshake) before timing
+	if _, err := http2Get(); err != nil {
+		teardownHttp2Request()
+		return err
+	}
+	streams := b.IntParam("streams")
+	http2Bench.latencies = make([]time.Duration, streams)
+	http2Bench.errs = make([]error, streams)
+	return nil
+}
+
+func teardownHttp2Request() {
+	if http2Bench.client != nil {
+		http2Bench.client.CloseIdleConnections()
+	}
+	if http2Bench.server != nil {
+		http2Bench.server.Close()
+	}
+	http2Bench.server, http2Bench.client = nil, nil
+	http2Bench.latencies, http2Bench.errs = nil, nil
+}
+
+// http2Get performs one request, checking it went over HTTP/2
+func http2Get() (time.Duration, error) {
+	start := time.Now()
+	resp, err := http2Bench.client.Get(http2Bench.url)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	elapsed := time.Since(start)
+	if err != nil {
+		return 0, err
+	}
+	if resp.ProtoMajor != 2 || resp.StatusCode != http.StatusOK || n != int64(http2Bench.size) {
+		return 0, fmt.Errorf("%s status %d with %d body bytes", resp.Proto, resp.StatusCode, n)
+	}
+	return elapsed, nil
+}
+
+// benchHttp2Request runs the iteration's streams concurrently
+func benchHttp2Request(b *B) {
+	streams := len(http2Bench.latencies)
+	b.SetBytes(int64(streams * http2Bench.size))
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := range streams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			http2Bench.latencies[i], http2Bench.errs[i] = http2Get()
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	b.StopTimer()
+	defer b.StartTimer()
+	hist := b.Histogram()
+	for i, d := range http2Bench.latencies {
+		if err := http2Bench.errs[i]; err != nil {
+			b.Fatal(err)
+			return
+		}
+		hist.Record(d)
+	}
+	b.AddRate("requests_per_sec", float64(streams), elapsed)
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "Http2Request", Category: "http", Tags: []string{"net"},
+		Iterations: 500,
+		Axes: []Axis{
+			{Name: "transport", Values: Strings("h2c", "tls")},
+			{Name: "streams", Values: Ints(1, 16, 100)},
+		},
+		Setup: setupHttp2Request, Teardown: teardownHttp2Request,
+		Fn: benchHttp2Request,
+	})
+}
@@ -0,0 +1,141 @@
+// Go HTTP/1.1 Request Benchmark
+// Request/response round trips through net/http against an in-process
+// handler over one keep-alive connection, so the HTTP layer's cost can be
+// read against TcpReusedRequest and compared with TML's HTTP
+// implementation:
+//
+//   - GET: the handler answers with a body of the configured request
+//     payload size
+//   - POST: the client sends that many bytes and the handler echoes them
+//
+// The headers axis adds that many extra request headers, which the
+// server parses and the handler reads, to expose per-header overhead.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// httpBenchHandler serves the benchmark's GET and POST requests
+type httpBenchHandler struct {
+	body []byte
+}
+
+func (h *httpBenchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Touch every header so parsing can't be skipped lazily
+	headers := 0
+	for _, v := range r.Header {
+		headers += len(v)
+	}
+	w.Header().Set("X-Headers-Seen", strconv.Itoa(headers))
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Length", strconv.Itoa(len(h.body)))
+		w.Write(h.body)
+	case http.MethodPost:
+		w.Header().Set("Content-Length", strconv.FormatInt(r.ContentLength, 10))
+		io.Copy(w, r.Body)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+var httpBench struct {
+	server  *http.Server
+	client  *http.Client
+	url     string
+	payload []byte
+	header  http.Header
+}
+
+func setupHttpRequest(b *B) error {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	size := benchConfig.PayloadSizes.Request
+	httpBench.payload = make([]byte, size)
+	httpBench.server = &http.Server{Handler: &httpBenchHandler{body: make([]byte, size)}}
+	go httpBench.server.Serve(ln)
+
+	httpBench.url = "http://" + ln.Addr().String() + "/echo"
+	httpBench.client = &http.Client{Transport: &http.Transport{
+		MaxIdleConnsPerHost: 1,
+		DisableCompression:  true,
+		DialContext: func(_ context.Context, network, addr string) (net.Conn, error) {
+			return dialTCP(addr)
+		},
+	}}
+	httpBench.header = http.Header{}
+	for i := 0; i < b.IntParam("headers"); i++ {
+		httpBench.header.Set(fmt.Sprintf("X-Bench-%d", i), "tml-benchmarks-header-value")
+	}
+	return nil
+}
+
+func teardownHttpRequest() {
+	if httpBench.client != nil {
+		httpBench.client.CloseIdleConnections()
+	}
+	if httpBench.server != nil {
+		httpBench.server.Close()
+	}
+	httpBench.server, httpBench.client, httpBench.header = nil, nil, nil
+}
+
+// benchHttpRequest performs one request and reads the whole response,
+// returning the connection to the keep-alive pool
+func benchHttpRequest(b *B) {
+	method := b.StringParam("method")
+	var body io.Reader
+	if method == http.MethodPost {
+		body = bytes.NewReader(httpBench.payload)
+	}
+	b.SetBytes(int64(len(httpBench.payload)))
+	start := time.Now()
+	req, err := http.NewRequest(method, httpBench.url, body)
+	if err != nil {
+		b.Fatal(err)
+		return
+	}
+	for k, v := range httpBench.header {
+		req.Header[k] = v
+	}
+	resp, err := httpBench.client.Do(req)
+	if err != nil {
+		b.Fatal(err)
+		return
+	}
+	n, err := io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		b.Fatal(err)
+		return
+	}
+	if resp.StatusCode != http.StatusOK || n != int64(len(httpBench.payload)) {
+		b.Fatal(fmt.Errorf("%s: status %d with %d body bytes", method, resp.StatusCode, n))
+		return
+	}
+	b.Histogram().Record(time.Since(start))
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "HttpRequest", Category: "http", Tags: []string{"net"},
+		Iterations: 5000,
+		Axes: []Axis{
+			{Name: "method", Values: Strings(http.MethodGet, http.MethodPost)},
+			{Name: "headers", Values: Ints(0, 32)},
+		},
+		Setup: setupHttpRequest, Teardown: teardownHttpRequest,
+		Fn: benchHttpRequest,
+	})
+}
@@ -0,0 +1,237 @@
+// InfluxDB Export - Go
+//
+// The influx subcommand converts result files of any suite (migrated as
+// LoadResultSet reads them) into InfluxDB line protocol, one point per
+// benchmark case, so runs can be charted over time in Grafana next to each
+// other. It writes the points to stdout or -o, or with -url POSTs them to
+// an InfluxDB write endpoint: /api/v2/write?org=...&bucket=... (with
+// -token or INFLUX_TOKEN) or a 1.x /write?db=....
+//
+// Each point is tagged with the language, the case name, the benchmark
+// name, the case's parameters, and the host, CPU and commit the run
+// recorded, and carries the time per iteration, the allocation figures,
+// the throughput, the latency percentiles, the GC figures and every
+// reported metric as fields. Its timestamp is the run's, so re-exporting a
+// file overwrites its points rather than duplicating them; files that
+// record no timestamp get the time of the export. Cases that failed are
+// skipped.
+//
+// Run with: go run . influx [-o results.lp] [-measurement benchmark] [lang=]results.json ...
+//        or: go run . influx -url 'http://localhost:8086/api/v2/write?org=tml&bucket=bench' [-token t] go.json tml.json
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// influxPushTimeout bounds a push to the write endpoint
+const influxPushTimeout = 30 * time.Second
+
+// runInflux implements the influx subcommand
+func runInflux(args []string) int {
+	fs := flag.NewFlagSet("influx", flag.ExitOnError)
+	output := fs.String("o", "", "write the line protocol to this file instead of stdout")
+	url := fs.String("url", "", "POST the points to this InfluxDB write endpoint instead of printing them")
+	token := fs.String("token", os.Getenv("INFLUX_TOKEN"), "InfluxDB API token for -url (default $INFLUX_TOKEN)")
+	measurement := fs.String("measurement", "benchmark", "measurement name of the points")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 || (*url != "" && *output != "") {
+		fmt.Fprintln(os.Stderr, "usage: influx [-o results.lp | -url endpoint [-token t]] [-measurement benchmark] [lang=]results.json ...")
+		return 2
+	}
+
+	var lines bytes.Buffer
+	points, skipped := 0, 0
+	for _, arg := range fs.Args() {
+		lang, path, ok := strings.Cut(arg, "=")
+		if !ok {
+			lang, path = "", arg
+		}
+		set, err := LoadResultSet(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		if lang != "" {
+			set.Language = lang
+		}
+		if set.Language == "" {
+			fmt.Fprintf(os.Stderr, "error: %s does not record its language; pass it as lang=%s\n", path, path)
+			return 2
+		}
+		set.Language = strings.ToLower(set.Language)
+		n, s := WriteInfluxLines(&lines, *measurement, set)
+		points, skipped = points+n, skipped+s
+	}
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "skipped %d failed cases\n", skipped)
+	}
+
+	if *url != "" {
+		if err := pushInflux(*url, *token, lines.Bytes()); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Pushed %d points to %s\n", points, *url)
+		return 0
+	}
+	if *output != "" {
+		if err := os.WriteFile(*output, lines.Bytes(), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("%d points written to %s\n", points, *output)
+		return 0
+	}
+	os.Stdout.Write(lines.Bytes())
+	return 0
+}
+
+// WriteInfluxLines writes one line-protocol point per successful result
+// of set and returns how many it wrote and how many failed results it
+// skipped
+func WriteInfluxLines(w io.Writer, measurement string, set ResultSet) (points, skipped int) {
+	ts := time.Now()
+	if t, err := time.Parse(time.RFC3339, set.Metadata.Timestamp); err == nil {
+		ts = t
+	}
+	runTags := map[string]string{
+		"language": set.Language,
+		"host":     set.Metadata.Hostname,
+		"cpu":      set.Metadata.CPUModel,
+		"commit":   set.Metadata.GitCommit,
+	}
+	for _, r := range set.Results {
+		if r.Error != "" {
+			skipped++
+			continue
+		}
+		// Parameters come first, so none can replace the tags below
+		tags := map[string]string{}
+		for k, v := range r.Params {
+			tags[k] = fmt.Sprint(v)
+		}
+		for k, v := range runTags {
+			tags[k] = v
+		}
+		tags["case"] = r.Name
+		tags["benchmark"], _, _ = strings.Cut(r.Name, "/")
+		tags["category"] = r.Category
+
+		fields := strings.Join(influxFields(r), ",")
+		fmt.Fprintf(w, "%s%s %s %d\n", influxEscape(measurement, ", "), influxTags(tags), fields, ts.UnixNano())
+		points++
+	}
+	return points, skipped
+}
+
+// influxFields are the field assignments of a result: the standard figures
+// first, then its metrics by name; a metric may not shadow a standard
+// figure, and values line protocol cannot carry (NaN, infinities) are left
+// out
+func influxFields(r BenchmarkResult) []string {
+	var fields []string
+	seen := map[string]bool{}
+	float := func(key string, v float64) {
+		if seen[key] || math.IsNaN(v) || math.IsInf(v, 0) {
+			return
+		}
+		seen[key] = true
+		fields = append(fields, influxEscape(key, ",= ")+"="+strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	integer := func(key string, v int64) {
+		seen[key] = true
+		fields = append(fields, influxEscape(key, ",= ")+"="+strconv.FormatInt(v, 10)+"i")
+	}
+
+	float("time_us", r.TimeUs)
+	integer("iterations", r.Iterations)
+	integer("allocs_per_op", r.AllocsPerOp)
+	integer("bytes_per_op", r.BytesPerOp)
+	if r.ThroughputMBs > 0 {
+		float("throughput_mbs", r.ThroughputMBs)
+	}
+	if l := r.Latency; l != nil {
+		float("p50_us", l.P50Us)
+		float("p90_us", l.P90Us)
+		float("p99_us", l.P99Us)
+		float("p99_9_us", l.P999Us)
+		float("p99_99_us", l.P9999Us)
+		float("max_us", l.MaxUs)
+	}
+	if gc := r.GC; gc != nil {
+		integer("gc_count", int64(gc.NumGC))
+		float("gc_pause_total_us", gc.PauseTotalUs)
+		float("gc_pause_max_us", gc.PauseMaxUs)
+	}
+	names := make([]string, 0, len(r.Metrics))
+	for name := range r.Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		float(name, r.Metrics[name])
+	}
+	return fields
+}
+
+// influxTags is the tag set of a point, sorted by key as InfluxDB prefers;
+// line protocol has no empty tag values, so empty tags are left out
+func influxTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k, v := range tags {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString("," + influxEscape(k, ",= ") + "=" + influxEscape(tags[k], ",= "))
+	}
+	return b.String()
+}
+
+// influxEscape backslash-escapes the given special characters of a
+// measurement name, tag key, tag value or field key; line protocol has no
+// way to write a newline in them, so those become spaces
+func influxEscape(s, special string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch {
+		case c == '\n' || c == '\r':
+			b.WriteString(`\ `)
+		case strings.ContainsRune(special, c):
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// pushInflux POSTs line protocol to an InfluxDB write endpoint
+func pushInflux(url, token string, lines []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(lines))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+	return postHTTP(req, influxPushTimeout)
+}
@@ -0,0 +1,76 @@
+// InfluxDB Export Tests - Go
+//
+// Run with: go test -run 'InfluxLines|PushInflux'
+
+package main
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInfluxLines(t *testing.T) {
+	set := ResultSet{
+		Language: "go",
+		Metadata: Metadata{Hostname: "bench box", CPUModel: "Xeon, 8 cores", Timestamp: "2026-01-02T03:04:05Z"},
+		Results: []BenchmarkResult{
+			{
+				Name: "TcpRequestSweep/size=64", Category: "tcp", TimeUs: 12.5, Iterations: 1000,
+				Params:  Params{"size": 64, "language": "spoofed"},
+				Latency: &LatencySummary{P50Us: 10, P99Us: 20},
+				Metrics: map[string]float64{"loss pct": 0.5, "time_us": 99, "nan": math.NaN()},
+			},
+			{Name: "Broken", Error: "setup failed"},
+		},
+	}
+	var out strings.Builder
+	points, skipped := WriteInfluxLines(&out, "bench", set)
+	if points != 1 || skipped != 1 {
+		t.Fatalf("%d points, %d skipped; want 1 and 1", points, skipped)
+	}
+
+	line := strings.TrimSuffix(out.String(), "\n")
+	want := `bench,benchmark=TcpRequestSweep,case=TcpRequestSweep/size\=64,category=tcp,cpu=Xeon\,\ 8\ cores,host=bench\ box,language=go,size=64 `
+	if !strings.HasPrefix(line, want) {
+		t.Errorf("line\n  %s\nwant prefix\n  %s", line, want)
+	}
+	if !strings.HasSuffix(line, " 1767323045000000000") {
+		t.Errorf("line %q does not carry the run's timestamp", line)
+	}
+	for _, field := range []string{"time_us=12.5,", "iterations=1000i,", "p50_us=10,", "p99_us=20,", `loss\ pct=0.5`} {
+		if !strings.Contains(line, field) {
+			t.Errorf("line %q lacks field %s", line, field)
+		}
+	}
+	if strings.Contains(line, "time_us=99") || strings.Contains(line, "nan=") {
+		t.Errorf("line %q has a shadowing or non-finite metric", line)
+	}
+}
+
+func TestPushInflux(t *testing.T) {
+	var body, auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body, auth = string(data), r.Header.Get("Authorization")
+		if strings.Contains(body, "bad") {
+			http.Error(w, `{"message":"unable to parse"}`, http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	if err := pushInflux(srv.URL+"/api/v2/write?org=o&bucket=b", "secret", []byte("m v=1i 1\n")); err != nil {
+		t.Fatal(err)
+	}
+	if body != "m v=1i 1\n" || auth != "Token secret" {
+		t.Errorf("server got body %q, authorization %q", body, auth)
+	}
+	if err := pushInflux(srv.URL, "", []byte("bad\n")); err == nil || !strings.Contains(err.Error(), "unable to parse") {
+		t.Errorf("rejected write returned %v, want the server's message", err)
+	}
+}
@@ -0,0 +1,117 @@
+// String Interning Benchmarks - Go
+//
+// Interns 10M symbol strings (100k distinct) with a built-in map, sync.Map
+// and the unique package, then compares equality checks on raw strings
+// against interned handles, matching TML's symbol/atom comparison.
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"unique"
+)
+
+const (
+	internStrings  = 10_000_000
+	internDistinct = 100_000
+)
+
+// internInput holds 10M freshly allocated strings, so equal symbols never
+// share backing memory and raw comparisons must inspect the bytes
+var internInput = sync.OnceValue(func() []string {
+	out := make([]string, internStrings)
+	for i := range out {
+		out[i] = fmt.Sprintf("tml.compiler.symbols.identifier_%06d", (i*7919)%internDistinct)
+	}
+	return out
+})
+
+// internHandles is internInput interned with the unique package
+var internHandles = sync.OnceValue(func() []unique.Handle[string] {
+	in := internInput()
+	out := make([]unique.Handle[string], len(in))
+	for i, s := range in {
+		out[i] = unique.Make(s)
+	}
+	return out
+})
+
+// internSink keeps results observable so loops are not optimized away
+var internSink int
+
+func init() {
+	register := func(name string, fn func(b *B)) {
+		Register(Benchmark{
+			Name: name, Category: "intern", Tags: []string{"cpu", "alloc"},
+			Iterations: 1, Fn: fn,
+		})
+	}
+
+	register("InternMap", func(b *B) {
+		b.StopTimer()
+		in := internInput()
+		b.StartTimer()
+		table := make(map[string]string)
+		for _, s := range in {
+			if _, ok := table[s]; !ok {
+				table[s] = s
+			}
+		}
+		internSink = len(table)
+	})
+	register("InternSyncMap", func(b *B) {
+		b.StopTimer()
+		in := internInput()
+		b.StartTimer()
+		var table sync.Map
+		n := 0
+		for _, s := range in {
+			if _, loaded := table.LoadOrStore(s, s); !loaded {
+				n++
+			}
+		}
+		internSink = n
+	})
+	register("InternUnique", func(b *B) {
+		b.StopTimer()
+		in := internInput()
+		b.StartTimer()
+		n := 0
+		for _, s := range in {
+			if unique.Make(s).Value() != "" {
+				n++
+			}
+		}
+		internSink = n
+	})
+
+	// Equality against a target symbol: raw strings compare bytes, interned
+	// handles compare a single pointer
+	register("InternCompareRaw", func(b *B) {
+		b.StopTimer()
+		in := internInput()
+		target := fmt.Sprintf("tml.compiler.symbols.identifier_%06d", 4242)
+		b.StartTimer()
+		n := 0
+		for _, s := range in {
+			if s == target {
+				n++
+			}
+		}
+		internSink = n
+	})
+	register("InternCompareHandle", func(b *B) {
+		b.StopTimer()
+		handles := internHandles()
+		target := unique.Make(fmt.Sprintf("tml.compiler.symbols.identifier_%06d", 4242))
+		b.StartTimer()
+		n := 0
+		for _, h := range handles {
+			if h == target {
+				n++
+			}
+		}
+		internSink = n
+	})
+}
@@ -0,0 +1,103 @@
+// Iterator Pattern Benchmarks - Go
+//
+// Compares the ways Go code walks a sequence: pull-based range-over-func
+// (iter.Seq, Go 1.23), iter.Pull, channel-based generators and plain
+// callbacks, matching TML's iterator protocol benchmark. Each iteration sums
+// 100M generated elements.
+
+package main
+
+import (
+	"iter"
+)
+
+const iteratorElements = 100_000_000
+
+// rangeSeq yields 0..n-1 as an iter.Seq
+func rangeSeq(n int64) iter.Seq[int64] {
+	return func(yield func(int64) bool) {
+		for i := int64(0); i < n; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+// rangeChan yields 0..n-1 from a generator goroutine over a channel
+func rangeChan(n int64, buffer int) <-chan int64 {
+	ch := make(chan int64, buffer)
+	go func() {
+		for i := int64(0); i < n; i++ {
+			ch <- i
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// rangeCallback calls fn for 0..n-1
+func rangeCallback(n int64, fn func(int64)) {
+	for i := int64(0); i < n; i++ {
+		fn(i)
+	}
+}
+
+// iteratorSink keeps sums observable so loops are not optimized away
+var iteratorSink int64
+
+func init() {
+	register := func(name string, fn func(b *B)) {
+		Register(Benchmark{
+			Name: name, Category: "iterator", Tags: []string{"cpu"},
+			Iterations: 1, Fn: fn,
+		})
+	}
+
+	register("IterLoopBaseline", func(b *B) {
+		sum := int64(0)
+		for i := int64(0); i < iteratorElements; i++ {
+			sum += i
+		}
+		iteratorSink = sum
+	})
+	register("IterSeqRange", func(b *B) {
+		sum := int64(0)
+		for v := range rangeSeq(iteratorElements) {
+			sum += v
+		}
+		iteratorSink = sum
+	})
+	register("IterSeqPull", func(b *B) {
+		next, stop := iter.Pull(rangeSeq(iteratorElements))
+		defer stop()
+		sum := int64(0)
+		for {
+			v, ok := next()
+			if !ok {
+				break
+			}
+			sum += v
+		}
+		iteratorSink = sum
+	})
+	register("IterCallback", func(b *B) {
+		sum := int64(0)
+		rangeCallback(iteratorElements, func(v int64) { sum += v })
+		iteratorSink = sum
+	})
+	register("IterChannelUnbuffered", func(b *B) {
+		sum := int64(0)
+		for v := range rangeChan(iteratorElements, 0) {
+			sum += v
+		}
+		iteratorSink = sum
+	})
+	register("IterChannelBuffered", func(b *B) {
+		sum := int64(0)
+		for v := range rangeChan(iteratorElements, 1024) {
+			sum += v
+		}
+		iteratorSink = sum
+	})
+}
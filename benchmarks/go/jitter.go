@@ -0,0 +1,99 @@
+// Jitter and SLO Statistics - Go
+//
+// The latency histogram gives a request benchmark its percentiles and
+// maximum but forgets the order of the round trips. rttStats keeps what
+// needs it, for the TCP and UDP request benchmarks:
+//
+//   - jitter_us: the mean difference between consecutive round trips
+//     (instantaneous packet delay variation, RFC 5481), which percentiles
+//     cannot show - alternating fast and slow requests have the same
+//     percentiles as a slow drift
+//   - over_<threshold>: how many round trips took longer than each SLO
+//     threshold of the shared configuration (slo: thresholds_us, 1ms and
+//     10ms by default)
+//
+// UDP requests that time out have no round trip and are counted by the
+// UDP accounting instead.
+
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// rttStats accumulates the round trips of one run of a case: warmup and
+// measurement get separate B values, and a new B starts over
+type rttStats struct {
+	b     *B
+	calls int64
+	// rtts is the number of round trips recorded, last the latest one
+	rtts     int64
+	last     time.Duration
+	ipdvSum  time.Duration
+	overSLO  []int64
+	slos     []time.Duration
+	sloNames []string
+}
+
+var tcpRTT, udpRTT rttStats
+
+// begin resets the statistics when b is a new run
+func (s *rttStats) begin(b *B) {
+	if s.b == b {
+		return
+	}
+	slos := benchConfig.SLO.Thresholds
+	*s = rttStats{b: b, slos: slos, overSLO: make([]int64, len(slos)), sloNames: make([]string, len(slos))}
+	for i, t := range slos {
+		s.sloNames[i] = sloMetricName(t)
+	}
+}
+
+// record adds one iteration's round trip, reporting the statistics with
+// the run's last iteration
+func (s *rttStats) record(b *B, rtt time.Duration) {
+	s.begin(b)
+	if s.rtts > 0 {
+		s.ipdvSum += (rtt - s.last).Abs()
+	}
+	s.last = rtt
+	s.rtts++
+	for i, t := range s.slos {
+		if rtt > t {
+			s.overSLO[i]++
+		}
+	}
+	s.done(b)
+}
+
+// miss counts an iteration that had no round trip
+func (s *rttStats) miss(b *B) {
+	s.begin(b)
+	s.done(b)
+}
+
+func (s *rttStats) done(b *B) {
+	if s.calls++; s.calls == b.N {
+		s.report(b)
+	}
+}
+
+// report attaches the statistics so far to the result
+func (s *rttStats) report(b *B) {
+	if s.rtts > 1 {
+		b.ReportMetric("jitter_us", float64(s.ipdvSum)/float64(s.rtts-1)/1e3)
+	}
+	for i, name := range s.sloNames {
+		b.ReportMetric(name, float64(s.overSLO[i]))
+	}
+}
+
+// sloMetricName names the count of round trips over threshold t, e.g.
+// over_1ms or over_250us
+func sloMetricName(t time.Duration) string {
+	if t%time.Millisecond == 0 {
+		return fmt.Sprintf("over_%dms", t/time.Millisecond)
+	}
+	return fmt.Sprintf("over_%dus", t/time.Microsecond)
+}
@@ -0,0 +1,54 @@
+// Jitter and SLO Statistics Tests - Go
+//
+// Run with: go test -run 'RTTStats|SLOMetricName'
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRTTStats(t *testing.T) {
+	saved := benchConfig
+	defer func() { benchConfig = saved }()
+	benchConfig.SLO.Thresholds = []time.Duration{time.Millisecond, 250 * time.Microsecond}
+
+	var s rttStats
+	// A warmup run first, which must not leak into the measured one
+	warmup := &B{N: 2}
+	s.record(warmup, 5*time.Millisecond)
+	s.record(warmup, 5*time.Millisecond)
+
+	b := &B{N: 5}
+	for _, us := range []int{100, 300, 100, 2000} {
+		s.record(b, time.Duration(us)*time.Microsecond)
+	}
+	if b.metrics != nil {
+		t.Fatalf("metrics %v reported before the last iteration", b.metrics)
+	}
+	s.miss(b)
+	// |300-100| + |100-300| + |2000-100| over 3 pairs
+	want := map[string]float64{"jitter_us": 2300.0 / 3, "over_1ms": 1, "over_250us": 2}
+	for name, v := range want {
+		if got, ok := b.metrics[name]; !ok || got != v {
+			t.Errorf("%s = %v (reported %t), want %v", name, got, ok, v)
+		}
+	}
+	if len(b.metrics) != len(want) {
+		t.Errorf("metrics %v, want exactly %v", b.metrics, want)
+	}
+}
+
+func TestSLOMetricName(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		time.Millisecond:        "over_1ms",
+		10 * time.Millisecond:   "over_10ms",
+		250 * time.Microsecond:  "over_250us",
+		1500 * time.Microsecond: "over_1500us",
+	} {
+		if got := sloMetricName(d); got != want {
+			t.Errorf("sloMetricName(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
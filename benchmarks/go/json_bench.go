@@ -1,70 +1,20 @@
+// JSON Benchmarks - Go (encoding/json)
+
 package main
 
 import (
 	"encoding/json"
 	"fmt"
-	"strings"
-	"time"
 )
 
-// BenchmarkResult holds the results of a benchmark
-type BenchmarkResult struct {
-	Name        string
-	TimeUs      float64
-	Iterations  int64
-	ThroughputMBs float64
-}
-
-// RunBenchmark executes a benchmark function and returns timing
-func RunBenchmark(name string, iterations int64, dataSize int64, fn func()) BenchmarkResult {
-	// Warmup
-	warmup := iterations / 10
-	if warmup > 10 {
-		warmup = 10
-	}
-	for i := int64(0); i < warmup; i++ {
-		fn()
-	}
-
-	// Benchmark
-	start := time.Now()
-	for i := int64(0); i < iterations; i++ {
-		fn()
-	}
-	elapsed := time.Since(start)
-
-	totalUs := elapsed.Microseconds()
-	avgUs := float64(totalUs) / float64(iterations)
-	throughput := 0.0
-	if dataSize > 0 {
-		throughput = float64(dataSize*iterations) / (float64(totalUs) / 1e6) / (1024 * 1024)
-	}
-
-	return BenchmarkResult{
-		Name:         name,
-		TimeUs:       avgUs,
-		Iterations:   iterations,
-		ThroughputMBs: throughput,
-	}
-}
-
-// PrintResult prints a benchmark result
-func PrintResult(r BenchmarkResult) {
-	fmt.Printf("%-40s %12.2f us %12d iters", r.Name, r.TimeUs, r.Iterations)
-	if r.ThroughputMBs > 0 {
-		fmt.Printf(" %12.2f MB/s", r.ThroughputMBs)
-	}
-	fmt.Println()
-}
-
 // GenerateSmallJSON creates a small JSON object
 func GenerateSmallJSON() []byte {
 	data := map[string]interface{}{
-		"name":    "John Doe",
-		"age":     30,
-		"active":  true,
-		"email":   "john@example.com",
-		"scores":  []int{95, 87, 92, 88, 91},
+		"name":   "John Doe",
+		"age":    30,
+		"active": true,
+		"email":  "john@example.com",
+		"scores": []int{95, 87, 92, 88, 91},
 		"address": map[string]string{
 			"street": "123 Main St",
 			"city":   "New York",
@@ -116,56 +66,59 @@ func GenerateLargeJSON() []byte {
 	return bytes
 }
 
-func main() {
-	fmt.Println("\n================================================================")
-	fmt.Println("  Go JSON Benchmark (encoding/json)")
-	fmt.Println("================================================================\n")
-
+func init() {
 	// Prepare test data
 	smallJSON := GenerateSmallJSON()
 	mediumJSON := GenerateMediumJSON()
 	largeJSON := GenerateLargeJSON()
 
-	fmt.Printf("%-40s %12s %12s %12s\n", "Test", "Time", "Iterations", "Throughput")
-	fmt.Println(strings.Repeat("-", 80))
-
 	// Small JSON benchmarks
-	result1 := RunBenchmark("Parse Small JSON (parse_fast)", 10000, int64(len(smallJSON)), func() {
-		var obj interface{}
-		json.Unmarshal(smallJSON, &obj)
+	Register(Benchmark{
+		Name: "JsonParseSmall", Category: "json", Tags: []string{"serde", "alloc"},
+		Iterations: 10000, DataSize: int64(len(smallJSON)),
+		Fn: func(b *B) {
+			var obj interface{}
+			json.Unmarshal(smallJSON, &obj)
+		},
 	})
-	PrintResult(result1)
-
-	result2 := RunBenchmark("Parse Small JSON (standard)", 10000, int64(len(smallJSON)), func() {
-		var obj map[string]interface{}
-		json.Unmarshal(smallJSON, &obj)
+	Register(Benchmark{
+		Name: "JsonParseSmallMap", Category: "json", Tags: []string{"serde", "alloc"},
+		Iterations: 10000, DataSize: int64(len(smallJSON)),
+		Fn: func(b *B) {
+			var obj map[string]interface{}
+			json.Unmarshal(smallJSON, &obj)
+		},
 	})
-	PrintResult(result2)
 
 	// Medium JSON benchmarks
-	result3 := RunBenchmark("Parse Medium JSON", 5000, int64(len(mediumJSON)), func() {
-		var obj interface{}
-		json.Unmarshal(mediumJSON, &obj)
+	Register(Benchmark{
+		Name: "JsonParseMedium", Category: "json", Tags: []string{"serde", "alloc"},
+		Iterations: 5000, DataSize: int64(len(mediumJSON)),
+		Fn: func(b *B) {
+			var obj interface{}
+			json.Unmarshal(mediumJSON, &obj)
+		},
 	})
-	PrintResult(result3)
 
-	// Large JSON benchmarks
-	result4 := RunBenchmark("Parse Large JSON (100 objects)", 100, int64(len(largeJSON)), func() {
-		var obj interface{}
-		json.Unmarshal(largeJSON, &obj)
+	// Large JSON benchmarks (100 objects)
+	Register(Benchmark{
+		Name: "JsonParseLarge", Category: "json", Tags: []string{"serde", "alloc"},
+		Iterations: 100, DataSize: int64(len(largeJSON)),
+		Fn: func(b *B) {
+			var obj interface{}
+			json.Unmarshal(largeJSON, &obj)
+		},
 	})
-	PrintResult(result4)
 
 	// Marshaling benchmarks
 	data := map[string]interface{}{
 		"name": "John", "age": 30, "items": []int{1, 2, 3},
 	}
-
-	result5 := RunBenchmark("Marshal Small Object", 10000, 0, func() {
-		json.Marshal(data)
+	Register(Benchmark{
+		Name: "JsonMarshalSmall", Category: "json", Tags: []string{"serde", "alloc"},
+		Iterations: 10000,
+		Fn: func(b *B) {
+			json.Marshal(data)
+		},
 	})
-	PrintResult(result5)
-
-	fmt.Println(strings.Repeat("-", 80))
-	fmt.Println("\n================================================================\n")
 }
@@ -0,0 +1,66 @@
+//go:build easyjson
+
+// Code-Generated JSON Benchmarks - Go (easyjson)
+//
+// The typed JSON benchmarks (json_typed_bench.go) with the codec generated
+// ahead of time by easyjson instead of driven by reflection at run time:
+// the ceiling of statically generated serialization in Go, the counterpart
+// of TML's compile-time approach. JsonParse<Size>Easyjson and
+// JsonMarshal<Size>Easyjson decode and encode the same fixtures into the
+// same structs as JsonParse<Size>Struct and JsonMarshal<Size>Struct.
+//
+// json_typed_easyjson.go holds the generated code. easyjson cannot load
+// package main, so it was generated from a copy of the structs in a
+// scratch package, with its package clause changed to main afterwards:
+//
+//	easyjson -all -no_std_marshalers -build_tags easyjson types.go
+//
+// -no_std_marshalers keeps encoding/json from picking the generated code
+// up through MarshalJSON, which would make the Struct benchmarks measure
+// easyjson too.
+//
+// Run with: go get github.com/mailru/easyjson && go run -tags easyjson . -run Easyjson
+
+package main
+
+import "github.com/mailru/easyjson"
+
+// easyJSONValue is a fixture struct with generated methods
+type easyJSONValue[T any] interface {
+	*T
+	easyjson.MarshalerUnmarshaler
+}
+
+// registerEasyJSON registers the generated Parse and Marshal benchmarks of
+// one fixture, decoded into T
+func registerEasyJSON[T any, P easyJSONValue[T]](size string, fixture []byte, iterations int64) {
+	var value T
+	if err := easyjson.Unmarshal(fixture, P(&value)); err != nil {
+		panic("json fixture " + size + ": " + err.Error())
+	}
+	Register(Benchmark{
+		Name: "JsonParse" + size + "Easyjson", Category: "json", Tags: []string{"serde", "alloc"},
+		Iterations: iterations, DataSize: int64(len(fixture)),
+		Fn: func(b *B) {
+			var obj T
+			if err := easyjson.Unmarshal(fixture, P(&obj)); err != nil {
+				b.Fatal(err)
+			}
+		},
+	})
+	Register(Benchmark{
+		Name: "JsonMarshal" + size + "Easyjson", Category: "json", Tags: []string{"serde", "alloc"},
+		Iterations: iterations, DataSize: int64(len(fixture)),
+		Fn: func(b *B) {
+			if _, err := easyjson.Marshal(P(&value)); err != nil {
+				b.Fatal(err)
+			}
+		},
+	})
+}
+
+func init() {
+	registerEasyJSON[jsonSmall]("Small", GenerateSmallJSON(), 10000)
+	registerEasyJSON[jsonMedium]("Medium", GenerateMediumJSON(), 5000)
+	registerEasyJSON[jsonLarge]("Large", GenerateLargeJSON(), 100)
+}
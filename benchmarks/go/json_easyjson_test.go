@@ -0,0 +1,41 @@
+//go:build easyjson
+
+// Code-Generated JSON Tests - Go
+//
+// Run with: go test -tags easyjson -run EasyJSON
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/mailru/easyjson"
+)
+
+// TestEasyJSONMatchesStd checks that the generated code decodes each
+// fixture as encoding/json does and encodes it back to the same document
+func TestEasyJSONMatchesStd(t *testing.T) {
+	check := func(name string, fixture []byte, got, want easyjson.MarshalerUnmarshaler) {
+		json.Unmarshal(fixture, want)
+		if err := easyjson.Unmarshal(fixture, got); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: decoded as %+v, want %+v", name, got, want)
+		}
+		encoded, err := easyjson.Marshal(got)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var doc, wantDoc interface{}
+		json.Unmarshal(fixture, &wantDoc)
+		if err := json.Unmarshal(encoded, &doc); err != nil || !reflect.DeepEqual(doc, wantDoc) {
+			t.Errorf("%s: encoded as %s, want %s", name, encoded, fixture)
+		}
+	}
+	check("small", GenerateSmallJSON(), &jsonSmall{}, &jsonSmall{})
+	check("medium", GenerateMediumJSON(), &jsonMedium{}, &jsonMedium{})
+	check("large", GenerateLargeJSON(), &jsonLarge{}, &jsonLarge{})
+}
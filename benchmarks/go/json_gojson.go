@@ -0,0 +1,15 @@
+//go:build gojson
+
+// go-json in the JSON library comparison (json_libs.go), a drop-in
+// replacement for encoding/json.
+//
+// Run with: go get github.com/goccy/go-json && go run -tags gojson . -run JsonLib
+
+package main
+
+import gojson "github.com/goccy/go-json"
+
+var _ = addJSONLib("gojson", jsonLib{
+	Marshal:   gojson.Marshal,
+	Unmarshal: gojson.Unmarshal,
+})
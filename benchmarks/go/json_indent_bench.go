@@ -0,0 +1,93 @@
+// Indented JSON Benchmarks - Go (encoding/json)
+//
+// What pretty-printing costs: JsonIndentLarge marshals the large fixture,
+// decoded into its struct (json_typed_bench.go), three ways, by axis
+// output:
+//
+//   - compact: json.Marshal
+//   - indent: json.MarshalIndent with two-space indentation
+//   - encoder: a json.Encoder with the same SetIndent, writing to a reused
+//     bytes.Buffer, which appends the newline Encode always writes
+//
+// Each case reports output_bytes, and against the compact case, which
+// runs first, size_vs_compact_pct and time_vs_compact_pct.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// jsonIndentCompactNs is the compact case's mean time per marshal
+var jsonIndentCompactNs float64
+
+// jsonIndentRun accumulates the marshals and time of one run of a case; a
+// new B starts over
+var jsonIndentRun struct {
+	b       *B
+	n       int64
+	elapsed time.Duration
+}
+
+var jsonIndentBuf bytes.Buffer
+
+func init() {
+	fixture := GenerateLargeJSON()
+	var large jsonLarge
+	if err := decodeJSONStrict(fixture, &large); err != nil {
+		panic("json fixture Large: " + err.Error())
+	}
+	compact, err := json.Marshal(&large)
+	if err != nil {
+		panic("json fixture Large: " + err.Error())
+	}
+
+	Register(Benchmark{
+		Name: "JsonIndentLarge", Category: "json", Tags: []string{"serde", "alloc"},
+		Iterations: 1000,
+		Axes:       []Axis{{Name: "output", Values: Strings("compact", "indent", "encoder")}},
+		Fn: func(b *B) {
+			output := b.StringParam("output")
+			start := time.Now()
+			var out []byte
+			var err error
+			switch output {
+			case "compact":
+				out, err = json.Marshal(&large)
+			case "indent":
+				out, err = json.MarshalIndent(&large, "", "  ")
+			default:
+				jsonIndentBuf.Reset()
+				enc := json.NewEncoder(&jsonIndentBuf)
+				enc.SetIndent("", "  ")
+				err = enc.Encode(&large)
+				out = jsonIndentBuf.Bytes()
+			}
+			elapsed := time.Since(start)
+			if err != nil {
+				b.Fatal(err)
+				return
+			}
+			b.SetBytes(int64(len(out)))
+
+			r := &jsonIndentRun
+			if r.b != b {
+				r.b, r.n, r.elapsed = b, 0, 0
+			}
+			r.n++
+			r.elapsed += elapsed
+			meanNs := float64(r.elapsed.Nanoseconds()) / float64(r.n)
+			b.ReportMetric("output_bytes", float64(len(out)))
+			if output == "compact" {
+				jsonIndentCompactNs = meanNs
+				return
+			}
+			b.ReportMetric("size_vs_compact_pct", percentChange(float64(len(compact)), float64(len(out))))
+			if jsonIndentCompactNs > 0 {
+				b.ReportMetric("time_vs_compact_pct", percentChange(jsonIndentCompactNs, meanNs))
+			}
+		},
+	})
+}
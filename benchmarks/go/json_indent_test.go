@@ -0,0 +1,33 @@
+// Indented JSON Tests - Go
+//
+// Run with: go test -run JsonIndent
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestJsonIndentOutputsAgree(t *testing.T) {
+	var large jsonLarge
+	if err := decodeJSONStrict(GenerateLargeJSON(), &large); err != nil {
+		t.Fatal(err)
+	}
+	compact, _ := json.Marshal(&large)
+	indented, _ := json.MarshalIndent(&large, "", "  ")
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(&large); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), append(indented, '\n')) {
+		t.Error("encoder output differs from MarshalIndent's plus a newline")
+	}
+	var recompacted bytes.Buffer
+	if err := json.Compact(&recompacted, indented); err != nil || !bytes.Equal(recompacted.Bytes(), compact) {
+		t.Errorf("indented output compacts to %d bytes, want the %d of compact, %v", recompacted.Len(), len(compact), err)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build jsoniter
+
+// jsoniter in the JSON library comparison (json_libs.go), configured to
+// behave like encoding/json so both produce the same output.
+//
+// Run with: go get github.com/json-iterator/go && go run -tags jsoniter . -run JsonLib
+
+package main
+
+import jsoniter "github.com/json-iterator/go"
+
+var _ = addJSONLib("jsoniter", jsonLib{
+	Marshal:   jsoniter.ConfigCompatibleWithStandardLibrary.Marshal,
+	Unmarshal: jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal,
+})
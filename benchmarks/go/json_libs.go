@@ -0,0 +1,121 @@
+// JSON Library Comparison - Go
+//
+// encoding/json is the baseline, not the fastest Go can do. JsonLibParse
+// and JsonLibMarshal run the typed and dynamic fixtures through every
+// registered JSON library, so TML's parser is also compared against Go's
+// best case. Only the standard library (std) is built in; the others
+// are third-party modules, each behind a build tag so the suite keeps
+// building without them:
+//
+//   - jsoniter: github.com/json-iterator/go, configured compatible with
+//     encoding/json (json_jsoniter.go)
+//   - sonic: github.com/bytedance/sonic, JIT and SIMD on amd64 and arm64
+//     (json_sonic.go)
+//   - gojson: github.com/goccy/go-json (json_gojson.go)
+//
+// Fetch a library into the module and build with its tag, e.g.:
+//
+//	go get github.com/json-iterator/go && go run -tags jsoniter . -run JsonLib
+//
+// Leave the go.mod and go.sum changes uncommitted: the suite itself has no
+// dependencies.
+
+package main
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// jsonLib is one JSON library
+type jsonLib struct {
+	Marshal   func(v interface{}) ([]byte, error)
+	Unmarshal func(data []byte, v interface{}) error
+}
+
+// jsonLibs are the libraries JsonLibParse and JsonLibMarshal run, by name;
+// the tagged files add theirs during package initialization
+var jsonLibs = map[string]jsonLib{
+	"std": {Marshal: json.Marshal, Unmarshal: json.Unmarshal},
+}
+
+// addJSONLib registers a library; it returns its name so tagged files can
+// register from a package-level variable, ahead of every init function
+func addJSONLib(name string, lib jsonLib) string {
+	jsonLibs[name] = lib
+	return name
+}
+
+// jsonLibNames returns the registered library names in order
+func jsonLibNames() []string {
+	names := make([]string, 0, len(jsonLibs))
+	for name := range jsonLibs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// jsonLibFixture is one fixture with the ways the benchmarks decode it
+type jsonLibFixture struct {
+	data []byte
+	// typed returns a new value of the fixture's struct
+	typed func() interface{}
+}
+
+var jsonLibFixtures = map[string]jsonLibFixture{
+	"small":  {data: GenerateSmallJSON(), typed: func() interface{} { return new(jsonSmall) }},
+	"medium": {data: GenerateMediumJSON(), typed: func() interface{} { return new(jsonMedium) }},
+	"large":  {data: GenerateLargeJSON(), typed: func() interface{} { return new(jsonLarge) }},
+}
+
+// jsonLibCase is what one case's iterations use, resolved at setup
+var jsonLibCase struct {
+	lib  jsonLib
+	data []byte
+	// target returns the value to decode into
+	target func() interface{}
+	// value is the fixture decoded into the target, for marshaling
+	value interface{}
+}
+
+func setupJsonLib(b *B) error {
+	c := &jsonLibCase
+	f := jsonLibFixtures[b.StringParam("fixture")]
+	c.lib, c.data, c.target = jsonLibs[b.StringParam("lib")], f.data, f.typed
+	if b.StringParam("target") == "any" {
+		c.target = func() interface{} { return new(interface{}) }
+	}
+	c.value = c.target()
+	return decodeJSONStrict(c.data, c.value)
+}
+
+func init() {
+	axes := []Axis{
+		{Name: "lib", Values: Strings(jsonLibNames()...)},
+		{Name: "fixture", Values: Strings("small", "medium", "large")},
+		{Name: "target", Values: Strings("struct", "any")},
+	}
+	Register(Benchmark{
+		Name: "JsonLibParse", Category: "json", Tags: []string{"serde", "alloc"},
+		Iterations: 1000, Axes: axes, Setup: setupJsonLib,
+		Fn: func(b *B) {
+			c := &jsonLibCase
+			b.SetBytes(int64(len(c.data)))
+			if err := c.lib.Unmarshal(c.data, c.target()); err != nil {
+				b.Fatal(err)
+			}
+		},
+	})
+	Register(Benchmark{
+		Name: "JsonLibMarshal", Category: "json", Tags: []string{"serde", "alloc"},
+		Iterations: 1000, Axes: axes, Setup: setupJsonLib,
+		Fn: func(b *B) {
+			c := &jsonLibCase
+			b.SetBytes(int64(len(c.data)))
+			if _, err := c.lib.Marshal(c.value); err != nil {
+				b.Fatal(err)
+			}
+		},
+	})
+}
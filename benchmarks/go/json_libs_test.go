@@ -0,0 +1,43 @@
+// JSON Library Comparison Tests - Go
+//
+// Run with: go test -run JSONLib
+//      or: go test -tags 'jsoniter sonic gojson' -run JSONLib
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// TestJSONLibsAgreeWithStd checks that every registered library decodes
+// each fixture to what encoding/json does and encodes it back to the same
+// document
+func TestJSONLibsAgreeWithStd(t *testing.T) {
+	for _, name := range jsonLibNames() {
+		lib := jsonLibs[name]
+		for fixture, f := range jsonLibFixtures {
+			want, got := f.typed(), f.typed()
+			json.Unmarshal(f.data, want)
+			if err := lib.Unmarshal(f.data, got); err != nil {
+				t.Errorf("%s: %s: %v", name, fixture, err)
+				continue
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: %s decoded as %+v, want %+v", name, fixture, got, want)
+			}
+
+			encoded, err := lib.Marshal(got)
+			if err != nil {
+				t.Errorf("%s: %s: %v", name, fixture, err)
+				continue
+			}
+			var doc, wantDoc interface{}
+			json.Unmarshal(f.data, &wantDoc)
+			if err := json.Unmarshal(encoded, &doc); err != nil || !reflect.DeepEqual(doc, wantDoc) {
+				t.Errorf("%s: %s encoded as %s, want %s", name, fixture, encoded, f.data)
+			}
+		}
+	}
+}
@@ -0,0 +1,16 @@
+//go:build sonic
+
+// sonic in the JSON library comparison (json_libs.go), with its default
+// configuration: JIT-compiled codecs and SIMD scanning on amd64 and arm64,
+// a fallback to encoding/json elsewhere.
+//
+// Run with: go get github.com/bytedance/sonic && go run -tags sonic . -run JsonLib
+
+package main
+
+import "github.com/bytedance/sonic"
+
+var _ = addJSONLib("sonic", jsonLib{
+	Marshal:   sonic.ConfigDefault.Marshal,
+	Unmarshal: sonic.ConfigDefault.Unmarshal,
+})
@@ -0,0 +1,179 @@
+// Streaming JSON Benchmarks - Go (encoding/json)
+//
+// Newline-delimited JSON (NDJSON) processed a record at a time with
+// json.Decoder and json.Encoder, the way logs, exports and streaming APIs
+// are handled, rather than as one document held in memory:
+//
+//   - JsonStreamDecode decodes jsonStreamRecords user records into a
+//     reused struct, from a bytes.Reader or from an io.Pipe fed in
+//     jsonStreamChunk writes by another goroutine
+//   - JsonStreamEncode encodes them to a reused bytes.Buffer or into an
+//     io.Pipe drained by another goroutine
+//
+// Both report records_per_s and peak_rss_mb, the process's peak resident
+// set since the case started (Linux only), which stays flat however many
+// records stream through.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	jsonStreamRecords = 10000
+	// jsonStreamChunk is the size of each write feeding the decode pipe
+	jsonStreamChunk = 4096
+)
+
+// jsonStreamUsers are the streamed records, jsonStreamNDJSON their
+// encoding, one per line
+var (
+	jsonStreamUsers  []jsonUser
+	jsonStreamNDJSON []byte
+)
+
+// jsonStreamRun accumulates the records and time of one run of a case; a
+// new B starts over
+var jsonStreamRun struct {
+	b       *B
+	records int64
+	elapsed time.Duration
+}
+
+// reportJSONStream adds an iteration that streamed records in elapsed and
+// reports the run's rate and peak memory so far
+func reportJSONStream(b *B, records int, elapsed time.Duration) {
+	r := &jsonStreamRun
+	if r.b != b {
+		r.b, r.records, r.elapsed = b, 0, 0
+	}
+	r.records += int64(records)
+	r.elapsed += elapsed
+	b.ReportMetric("records_per_s", float64(r.records)/r.elapsed.Seconds())
+	if peak := peakRSS(); peak > 0 {
+		b.ReportMetric("peak_rss_mb", float64(peak)/(1<<20))
+	}
+}
+
+// decodeNDJSON decodes every record of r into a reused user and returns
+// how many there were
+func decodeNDJSON(r io.Reader) (int, error) {
+	dec := json.NewDecoder(r)
+	var user jsonUser
+	n := 0
+	for {
+		err := dec.Decode(&user)
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// encodeNDJSON encodes users to w, one per line
+func encodeNDJSON(w io.Writer, users []jsonUser) error {
+	enc := json.NewEncoder(w)
+	for i := range users {
+		if err := enc.Encode(&users[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// feedPipe writes data to w in chunks and closes it
+func feedPipe(w *io.PipeWriter, data []byte) {
+	for len(data) > 0 {
+		n := min(len(data), jsonStreamChunk)
+		if _, err := w.Write(data[:n]); err != nil {
+			return
+		}
+		data = data[n:]
+	}
+	w.Close()
+}
+
+func setupJsonStream(b *B) error {
+	resetPeakRSS()
+	return nil
+}
+
+var jsonStreamBuf bytes.Buffer
+
+func init() {
+	jsonStreamUsers = make([]jsonUser, jsonStreamRecords)
+	for i := range jsonStreamUsers {
+		jsonStreamUsers[i] = jsonUser{
+			ID: i + 1, Name: fmt.Sprintf("User%d", i), Email: fmt.Sprintf("user%d@example.com", i),
+			Active: i%2 == 0, Age: 20 + i%50,
+		}
+	}
+	var buf bytes.Buffer
+	encodeNDJSON(&buf, jsonStreamUsers)
+	jsonStreamNDJSON = buf.Bytes()
+
+	Register(Benchmark{
+		Name: "JsonStreamDecode", Category: "json", Tags: []string{"serde", "alloc"},
+		Iterations: 50, DataSize: int64(len(jsonStreamNDJSON)),
+		Axes:  []Axis{{Name: "source", Values: Strings("bytes", "pipe")}},
+		Setup: setupJsonStream,
+		Fn: func(b *B) {
+			start := time.Now()
+			var src io.Reader = bytes.NewReader(jsonStreamNDJSON)
+			if b.StringParam("source") == "pipe" {
+				pr, pw := io.Pipe()
+				defer pr.Close()
+				go feedPipe(pw, jsonStreamNDJSON)
+				src = pr
+			}
+			n, err := decodeNDJSON(src)
+			if err == nil && n != jsonStreamRecords {
+				err = fmt.Errorf("decoded %d records, want %d", n, jsonStreamRecords)
+			}
+			if err != nil {
+				b.Fatal(err)
+				return
+			}
+			reportJSONStream(b, n, time.Since(start))
+		},
+	})
+	Register(Benchmark{
+		Name: "JsonStreamEncode", Category: "json", Tags: []string{"serde", "alloc"},
+		Iterations: 50, DataSize: int64(len(jsonStreamNDJSON)),
+		Axes:  []Axis{{Name: "sink", Values: Strings("bytes", "pipe")}},
+		Setup: setupJsonStream,
+		Fn: func(b *B) {
+			start := time.Now()
+			var err error
+			if b.StringParam("sink") == "pipe" {
+				pr, pw := io.Pipe()
+				drained := make(chan int64, 1)
+				go func() {
+					n, _ := io.Copy(io.Discard, pr)
+					drained <- n
+				}()
+				err = encodeNDJSON(pw, jsonStreamUsers)
+				pw.Close()
+				if n := <-drained; err == nil && n != int64(len(jsonStreamNDJSON)) {
+					err = fmt.Errorf("encoded %d bytes, want %d", n, len(jsonStreamNDJSON))
+				}
+			} else {
+				jsonStreamBuf.Reset()
+				err = encodeNDJSON(&jsonStreamBuf, jsonStreamUsers)
+			}
+			if err != nil {
+				b.Fatal(err)
+				return
+			}
+			reportJSONStream(b, jsonStreamRecords, time.Since(start))
+		},
+	})
+}
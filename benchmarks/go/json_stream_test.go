@@ -0,0 +1,39 @@
+// Streaming JSON Tests - Go
+//
+// Run with: go test -run NDJSON
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNDJSONRoundTripsThroughPipe(t *testing.T) {
+	if lines := bytes.Count(jsonStreamNDJSON, []byte("\n")); lines != jsonStreamRecords {
+		t.Fatalf("fixture has %d lines, want %d", lines, jsonStreamRecords)
+	}
+	pr, pw := io.Pipe()
+	go feedPipe(pw, jsonStreamNDJSON)
+	n, err := decodeNDJSON(pr)
+	if err != nil || n != jsonStreamRecords {
+		t.Fatalf("decoded %d records, %v; want %d", n, err, jsonStreamRecords)
+	}
+
+	var buf bytes.Buffer
+	if err := encodeNDJSON(&buf, jsonStreamUsers); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), jsonStreamNDJSON) {
+		t.Error("re-encoding the records changed the stream")
+	}
+}
+
+func TestNDJSONDecodeReportsBadRecord(t *testing.T) {
+	n, err := decodeNDJSON(strings.NewReader("{\"id\": 1}\n{\"id\": \n"))
+	if err == nil || n != 1 {
+		t.Errorf("decoded %d records, %v; want 1 and an error", n, err)
+	}
+}
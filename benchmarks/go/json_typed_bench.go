@@ -0,0 +1,106 @@
+// Typed JSON Benchmarks - Go (encoding/json)
+//
+// The JsonParse benchmarks decode into interface{} and maps, the dynamic
+// path. Real Go code decodes into structs, which skips building maps and
+// boxing every value, so the typed benchmarks below are the fair
+// comparison point for TML's typed decoding. They use the same small,
+// medium and large fixtures, described by the structs here; registration
+// checks that each struct covers every field of its fixture.
+//
+// The same structs have easyjson codecs generated for them
+// (json_typed_easyjson.go, built with -tags easyjson); regenerate those as
+// json_easyjson_bench.go describes when a struct changes.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// jsonAddress is the address of the small fixture
+type jsonAddress struct {
+	Street string `json:"street"`
+	City   string `json:"city"`
+	Zip    string `json:"zip"`
+}
+
+// jsonSmall is the small fixture, one user record
+type jsonSmall struct {
+	Name    string      `json:"name"`
+	Age     int         `json:"age"`
+	Active  bool        `json:"active"`
+	Email   string      `json:"email"`
+	Scores  []int       `json:"scores"`
+	Address jsonAddress `json:"address"`
+}
+
+// jsonUser is one user of the medium and large fixtures; the medium
+// fixture has no ages. The XML benchmarks (xml_bench.go) encode it too.
+type jsonUser struct {
+	ID     int    `json:"id" xml:"id,attr"`
+	Name   string `json:"name" xml:"name"`
+	Email  string `json:"email" xml:"email"`
+	Active bool   `json:"active" xml:"active"`
+	Age    int    `json:"age,omitempty" xml:"age,omitempty"`
+}
+
+// jsonMedium is the medium fixture, a page of users
+type jsonMedium struct {
+	Users    []jsonUser `json:"users"`
+	Metadata struct {
+		Total   int  `json:"total"`
+		Page    int  `json:"page"`
+		PerPage int  `json:"per_page"`
+		HasMore bool `json:"has_more"`
+	} `json:"metadata"`
+}
+
+// jsonLarge is the large fixture, a hundred users
+type jsonLarge struct {
+	Users    []jsonUser `json:"users"`
+	Metadata struct {
+		Count int `json:"count"`
+	} `json:"metadata"`
+}
+
+// decodeJSONStrict decodes data into v, failing on fields v does not have
+func decodeJSONStrict(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// registerTypedJSON registers the typed Parse and Marshal benchmarks of
+// one fixture, decoded into T; Marshal encodes the decoded fixture
+func registerTypedJSON[T any](size string, fixture []byte, iterations int64) {
+	var value T
+	if err := decodeJSONStrict(fixture, &value); err != nil {
+		panic("json fixture " + size + ": " + err.Error())
+	}
+	Register(Benchmark{
+		Name: "JsonParse" + size + "Struct", Category: "json", Tags: []string{"serde", "alloc"},
+		Iterations: iterations, DataSize: int64(len(fixture)),
+		Fn: func(b *B) {
+			var obj T
+			if err := json.Unmarshal(fixture, &obj); err != nil {
+				b.Fatal(err)
+			}
+		},
+	})
+	Register(Benchmark{
+		Name: "JsonMarshal" + size + "Struct", Category: "json", Tags: []string{"serde", "alloc"},
+		Iterations: iterations, DataSize: int64(len(fixture)),
+		Fn: func(b *B) {
+			if _, err := json.Marshal(&value); err != nil {
+				b.Fatal(err)
+			}
+		},
+	})
+}
+
+func init() {
+	registerTypedJSON[jsonSmall]("Small", GenerateSmallJSON(), 10000)
+	registerTypedJSON[jsonMedium]("Medium", GenerateMediumJSON(), 5000)
+	registerTypedJSON[jsonLarge]("Large", GenerateLargeJSON(), 100)
+}
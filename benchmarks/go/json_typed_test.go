@@ -0,0 +1,41 @@
+// Typed JSON Tests - Go
+//
+// Run with: go test -run TypedJSON
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// TestTypedJSONRoundTrips checks that each fixture survives decoding into
+// its struct and encoding again, so the typed benchmarks handle the same
+// data as the dynamic ones
+func TestTypedJSONRoundTrips(t *testing.T) {
+	check := func(name string, fixture []byte, typed interface{}) {
+		if err := decodeJSONStrict(fixture, typed); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		encoded, err := json.Marshal(typed)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var want, got interface{}
+		json.Unmarshal(fixture, &want)
+		json.Unmarshal(encoded, &got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: re-encoded as %s, want %s", name, encoded, fixture)
+		}
+	}
+	check("small", GenerateSmallJSON(), &jsonSmall{})
+	check("medium", GenerateMediumJSON(), &jsonMedium{})
+	check("large", GenerateLargeJSON(), &jsonLarge{})
+}
+
+func TestTypedJSONRejectsUnknownFields(t *testing.T) {
+	if err := decodeJSONStrict([]byte(`{"name": "x", "nickname": "y"}`), &jsonSmall{}); err == nil {
+		t.Error("decoding an unknown field succeeded")
+	}
+}
@@ -0,0 +1,105 @@
+// Leak Detection - Go
+//
+// A case that leaves a goroutine, a socket or a pile of heap objects behind
+// skews every case after it: an echo server still accepting competes for
+// the CPU, an open listener holds its port. So the process is snapshotted
+// before each case's Setup and again after its Teardown - goroutines, open
+// file descriptors and live heap objects, the last two after a forced
+// collection - and whatever the case left behind is attached to its result
+// as leaks and flagged in the table.
+//
+// Shutdown is often asynchronous (a closed listener's accept loop still has
+// to return), so the second snapshot waits up to leakSettleTimeout for the
+// counts to come back down before anything is reported. Heap objects
+// fluctuate with the runtime's own caches and are only reported past
+// leakHeapObjectsSlack. Open descriptors are counted from /proc/self/fd or
+// /dev/fd, and not at all where neither exists.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// leakCheck is whether cases are checked for leaks (-leak-check)
+var leakCheck = true
+
+const (
+	leakSettleTimeout    = 200 * time.Millisecond
+	leakHeapObjectsSlack = 10000
+)
+
+// LeakReport is what a case left behind; zero fields did not grow
+type LeakReport struct {
+	Goroutines  int   `json:"goroutines,omitempty"`
+	FDs         int   `json:"fds,omitempty"`
+	HeapObjects int64 `json:"heap_objects,omitempty"`
+}
+
+// String formats the report for the results table
+func (l *LeakReport) String() string {
+	var parts []string
+	if l.Goroutines > 0 {
+		parts = append(parts, fmt.Sprintf("%d goroutines", l.Goroutines))
+	}
+	if l.FDs > 0 {
+		parts = append(parts, fmt.Sprintf("%d fds", l.FDs))
+	}
+	if l.HeapObjects > 0 {
+		parts = append(parts, fmt.Sprintf("%d heap objects", l.HeapObjects))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// leakSnapshot is the process state leaks are measured against
+type leakSnapshot struct {
+	goroutines  int
+	fds         int // -1 when they cannot be counted
+	heapObjects uint64
+}
+
+func takeLeakSnapshot() leakSnapshot {
+	runtime.GC()
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return leakSnapshot{goroutines: runtime.NumGoroutine(), fds: openFDs(), heapObjects: mem.HeapObjects}
+}
+
+// checkLeaks compares the process against before, giving asynchronous
+// shutdown time to finish, and returns nil when nothing was left behind
+func checkLeaks(before leakSnapshot) *LeakReport {
+	deadline := time.Now().Add(leakSettleTimeout)
+	for {
+		after := takeLeakSnapshot()
+		l := &LeakReport{Goroutines: after.goroutines - before.goroutines}
+		if before.fds >= 0 && after.fds >= 0 {
+			l.FDs = after.fds - before.fds
+		}
+		if grown := int64(after.heapObjects) - int64(before.heapObjects); grown > leakHeapObjectsSlack {
+			l.HeapObjects = grown
+		}
+		if l.Goroutines <= 0 && l.FDs <= 0 && l.HeapObjects <= 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			l.Goroutines, l.FDs = max(l.Goroutines, 0), max(l.FDs, 0)
+			return l
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// openFDs counts the process's open file descriptors, or returns -1
+func openFDs() int {
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		// Reading the directory opens one more, the same in every count
+		if entries, err := os.ReadDir(dir); err == nil {
+			return len(entries)
+		}
+	}
+	return -1
+}
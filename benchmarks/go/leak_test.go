@@ -0,0 +1,51 @@
+// Leak Detection Tests - Go
+//
+// Run with: go test -run Leak
+
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+func TestRunCaseReportsLeaks(t *testing.T) {
+	var ln net.Listener
+	stop := make(chan struct{})
+	defer close(stop)
+	leaky := &Benchmark{Name: "Leaky", Iterations: 1, Fn: func(b *B) {
+		if ln == nil {
+			ln, _ = net.Listen("tcp", "127.0.0.1:0")
+			go func() { <-stop }()
+		}
+	}}
+	r := RunCase(Case{Name: "Leaky", Bench: leaky})
+	if ln == nil {
+		t.Fatal("listener not opened")
+	}
+	defer ln.Close()
+	if r.Leaks == nil || r.Leaks.Goroutines < 1 {
+		t.Errorf("leaks %+v, want the goroutine reported", r.Leaks)
+	}
+	if openFDs() >= 0 && (r.Leaks == nil || r.Leaks.FDs < 1) {
+		t.Errorf("leaks %+v, want the listener's descriptor reported", r.Leaks)
+	}
+
+	clean := &Benchmark{Name: "Clean", Iterations: 10, Fn: func(b *B) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			b.Fatal(err)
+			return
+		}
+		done := make(chan struct{})
+		go func() {
+			l.Accept()
+			close(done)
+		}()
+		l.Close()
+		<-done
+	}}
+	if r := RunCase(Case{Name: "Clean", Bench: clean}); r.Leaks != nil {
+		t.Errorf("clean case reported leaks %s", r.Leaks)
+	}
+}
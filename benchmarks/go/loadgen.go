@@ -0,0 +1,197 @@
+// Load Generator - Go
+//
+// The loadgen subcommand steps an open-loop load (openloop.go) through a
+// series of target rates against an echo server and prints, per step, the
+// rate actually achieved and the corrected latency percentiles: the
+// latency-vs-throughput curve of the server and the path to it. Latency
+// stays flat while the server keeps up, then climbs steeply once a rate
+// exceeds its capacity and requests queue; a step whose achieved rate
+// falls short of the target, or that loses replies, is marked saturated.
+//
+// Without -target the suite's own echo server runs in the process, so
+// client and server share the CPU; point -target at a -mode server
+// instance (remote.go) or any other echo server to measure that instead.
+// Each step uses a fresh connection, so late replies of one step cannot be
+// counted in the next.
+//
+// Run with: go run . loadgen [-proto tcp|udp] [-target host:port] [-rates 1000,2000,5000,10000,20000,50000,100000]
+//                            [-duration 2s] [-size 64] [-o curve.csv]
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loadGenSaturation is the fraction of the target rate a step must reach
+// not to be marked saturated
+const loadGenSaturation = 0.95
+
+// LoadStep is one point of the latency-vs-throughput curve
+type LoadStep struct {
+	TargetRPS   int
+	AchievedRPS float64
+	Sent        int
+	Lost        int
+	// Latency holds the corrected latencies, Uncorrected those from the
+	// actual send times; a gap between them is sender lag, not queueing
+	Latency     *LatencySummary
+	Uncorrected *LatencySummary
+	Error       string
+}
+
+// Saturated reports whether the step did not sustain its target rate
+func (s LoadStep) Saturated() bool {
+	return s.Error != "" || s.Lost > 0 || s.AchievedRPS < loadGenSaturation*float64(s.TargetRPS)
+}
+
+// runLoadGen implements the loadgen subcommand
+func runLoadGen(args []string) int {
+	fs := flag.NewFlagSet("loadgen", flag.ExitOnError)
+	proto := fs.String("proto", "tcp", "tcp or udp")
+	target := fs.String("target", "", "echo server host:port (default: an in-process Go echo server)")
+	rates := fs.String("rates", "1000,2000,5000,10000,20000,50000,100000", "comma-separated target rates in requests per second, one step each")
+	duration := fs.Duration("duration", openLoopDuration, "how long each step sends for")
+	size := fs.Int("size", benchConfig.PayloadSizes.Request, "request payload size in bytes (at least 8, for the sequence number)")
+	output := fs.String("o", "", "also write the curve to this CSV file")
+	fs.Parse(args)
+
+	steps, err := parseRates(*rates)
+	if err == nil && *proto != "tcp" && *proto != "udp" {
+		err = fmt.Errorf("unknown -proto %q (want tcp or udp)", *proto)
+	}
+	if err == nil && (*size < 8 || *size > 65507) {
+		err = fmt.Errorf("-size must be between 8 and 65507 bytes")
+	}
+	if err == nil && *duration <= 0 {
+		err = fmt.Errorf("-duration must be positive")
+	}
+	if err != nil || fs.NArg() > 0 {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+		fmt.Fprintln(os.Stderr, "usage: loadgen [-proto tcp|udp] [-target host:port] [-rates 1000,10000,...] [-duration 2s] [-size 64] [-o curve.csv]")
+		return 2
+	}
+
+	addr := *target
+	if addr == "" {
+		var server io.Closer
+		if *proto == "tcp" {
+			s, err := startTCPEchoServer()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return 1
+			}
+			server, addr = s, s.Addr().String()
+		} else {
+			s, err := startUDPEchoServer()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return 1
+			}
+			server, addr = s, s.Addr()
+		}
+		defer server.Close()
+	}
+
+	fmt.Printf("=== Load Generator: %s echo at %s, %d-byte requests, %s per step ===\n\n", strings.ToUpper(*proto), addr, *size, *duration)
+	for i, rate := range steps {
+		fmt.Fprintf(os.Stderr, "  step %d/%d: %d req/s\n", i+1, len(steps), rate.TargetRPS)
+		steps[i] = runLoadStep(*proto, addr, rate.TargetRPS, *duration, *size)
+	}
+	printLoadCurve(os.Stdout, steps)
+
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err == nil {
+			writeLoadCurveCSV(f, steps)
+			err = f.Close()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: writing %s: %v\n", *output, err)
+			return 1
+		}
+		fmt.Printf("Curve written to %s\n", *output)
+	}
+	return 0
+}
+
+// parseRates parses -rates into the steps to run, in the order given
+func parseRates(s string) ([]LoadStep, error) {
+	var steps []LoadStep
+	for _, field := range splitList(s) {
+		rate, err := strconv.Atoi(field)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("invalid rate %q in -rates", field)
+		}
+		steps = append(steps, LoadStep{TargetRPS: rate})
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("-rates is empty")
+	}
+	return steps, nil
+}
+
+// runLoadStep runs one open-loop step over a new connection to addr
+func runLoadStep(proto, addr string, rate int, duration time.Duration, size int) LoadStep {
+	step := LoadStep{TargetRPS: rate}
+	conn, err := net.Dial(proto, addr)
+	if err != nil {
+		step.Error = err.Error()
+		return step
+	}
+	defer conn.Close()
+	latency := NewHistogram()
+	res, err := openLoop(conn, latency, rate, duration, size, proto == "udp")
+	step.AchievedRPS, step.Sent, step.Lost = res.AchievedRPS, res.Sent, res.Sent-res.Received
+	step.Latency, step.Uncorrected = latency.Summary(), res.Uncorrected.Summary()
+	if err != nil {
+		step.Error = err.Error()
+	}
+	return step
+}
+
+// printLoadCurve prints the steps as a table
+func printLoadCurve(w io.Writer, steps []LoadStep) {
+	fmt.Fprintf(w, "%10s %12s %8s %12s %12s %12s %12s %12s %14s\n", "Target", "Achieved", "Lost", "p50", "p90", "p99", "p99.9", "Max", "Uncorr p99")
+	fmt.Fprintln(w, strings.Repeat("-", 122))
+	for _, s := range steps {
+		if s.Latency == nil {
+			fmt.Fprintf(w, "%10d  error: %s\n", s.TargetRPS, s.Error)
+			continue
+		}
+		l := s.Latency
+		fmt.Fprintf(w, "%10d %12.0f %8d %9.1f us %9.1f us %9.1f us %9.1f us %9.1f us %11.1f us",
+			s.TargetRPS, s.AchievedRPS, s.Lost, l.P50Us, l.P90Us, l.P99Us, l.P999Us, l.MaxUs, s.Uncorrected.P99Us)
+		if s.Saturated() {
+			fmt.Fprint(w, "  SATURATED")
+		}
+		if s.Error != "" {
+			fmt.Fprintf(w, " (%s)", s.Error)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 122))
+}
+
+// writeLoadCurveCSV writes the steps as CSV, one row per step
+func writeLoadCurveCSV(w io.Writer, steps []LoadStep) {
+	fmt.Fprintln(w, "target_rps,achieved_rps,sent,lost,p50_us,p90_us,p99_us,p99_9_us,max_us,uncorrected_p50_us,uncorrected_p99_us,saturated,error")
+	for _, s := range steps {
+		l, u := s.Latency, s.Uncorrected
+		if l == nil {
+			l, u = &LatencySummary{}, &LatencySummary{}
+		}
+		fmt.Fprintf(w, "%d,%.1f,%d,%d,%.2f,%.2f,%.2f,%.2f,%.2f,%.2f,%.2f,%t,%s\n",
+			s.TargetRPS, s.AchievedRPS, s.Sent, s.Lost, l.P50Us, l.P90Us, l.P99Us, l.P999Us, l.MaxUs,
+			u.P50Us, u.P99Us, s.Saturated(), csvField(s.Error))
+	}
+}
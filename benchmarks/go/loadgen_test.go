@@ -0,0 +1,57 @@
+// Load Generator Tests - Go
+//
+// Run with: go test -run 'ParseRates|LoadStep'
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRates(t *testing.T) {
+	steps, err := parseRates("1000, 50000,2000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 3 || steps[0].TargetRPS != 1000 || steps[1].TargetRPS != 50000 || steps[2].TargetRPS != 2000 {
+		t.Errorf("steps %+v, want 1000, 50000, 2000 in order", steps)
+	}
+	for _, bad := range []string{"", "1000,x", "1000,0", "-5"} {
+		if _, err := parseRates(bad); err == nil {
+			t.Errorf("rates %q were accepted", bad)
+		}
+	}
+}
+
+func TestLoadStepSaturated(t *testing.T) {
+	for _, tc := range []struct {
+		step LoadStep
+		want bool
+	}{
+		{LoadStep{TargetRPS: 1000, AchievedRPS: 999}, false},
+		{LoadStep{TargetRPS: 1000, AchievedRPS: 900}, true},
+		{LoadStep{TargetRPS: 1000, AchievedRPS: 1000, Lost: 1}, true},
+		{LoadStep{TargetRPS: 1000, AchievedRPS: 1000, Error: "reset"}, true},
+	} {
+		if got := tc.step.Saturated(); got != tc.want {
+			t.Errorf("%+v saturated = %t, want %t", tc.step, got, tc.want)
+		}
+	}
+}
+
+func TestLoadStepAgainstEchoServer(t *testing.T) {
+	server, err := startTCPEchoServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	step := runLoadStep("tcp", server.Addr().String(), 2000, 100*time.Millisecond, 64)
+	if step.Error != "" {
+		t.Fatal(step.Error)
+	}
+	if step.Sent != 200 || step.Lost != 0 || step.Latency.Count != 200 {
+		t.Errorf("sent %d, lost %d, %d latencies; want 200 sent and answered", step.Sent, step.Lost, step.Latency.Count)
+	}
+}
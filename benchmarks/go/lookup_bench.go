@@ -0,0 +1,256 @@
+// Lookup Scaling Benchmarks - Go
+//
+// Looks up keys in an (unbalanced, randomly built) binary search tree, the
+// built-in map and a sorted slice with binary search, at sizes from 16 to
+// 16M elements. Each iteration performs lookupsPerIter hits, so times are
+// directly comparable across sizes. The scaling subcommand pivots the
+// results into a per-size table and chart to show the crossover points
+// against TML's containers.
+//
+// Run with: go run . scaling [-max-size 16777216]
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const lookupsPerIter = 1_000_000
+
+var (
+	lookupSizes      = Ints(16, 256, 4096, 65536, 1<<20, 1<<24)
+	lookupStructures = Strings("bst", "map", "slice")
+)
+
+// bstNode is a tree node addressed by index into bst.nodes
+type bstNode struct {
+	key         uint64
+	left, right int32
+}
+
+// bst is an unbalanced binary search tree stored in a flat slice; index 0
+// is the nil sentinel
+type bst struct {
+	nodes []bstNode
+}
+
+func newBST(capacity int) *bst {
+	return &bst{nodes: make([]bstNode, 1, capacity+1)}
+}
+
+func (t *bst) insert(key uint64) {
+	t.nodes = append(t.nodes, bstNode{key: key})
+	idx := int32(len(t.nodes) - 1)
+	if idx == 1 {
+		return
+	}
+	cur := int32(1)
+	for {
+		n := &t.nodes[cur]
+		next := &n.right
+		if key < n.key {
+			next = &n.left
+		}
+		if *next == 0 {
+			*next = idx
+			return
+		}
+		cur = *next
+	}
+}
+
+func (t *bst) contains(key uint64) bool {
+	cur := int32(1)
+	if len(t.nodes) == 1 {
+		return false
+	}
+	for cur != 0 {
+		n := &t.nodes[cur]
+		switch {
+		case key == n.key:
+			return true
+		case key < n.key:
+			cur = n.left
+		default:
+			cur = n.right
+		}
+	}
+	return false
+}
+
+func sortedContains(keys []uint64, key uint64) bool {
+	i := sort.Search(len(keys), func(i int) bool { return keys[i] >= key })
+	return i < len(keys) && keys[i] == key
+}
+
+// lookupFixture is the structure under test plus the keys to look up; only
+// the most recent one is kept to bound memory at the 16M size
+type lookupFixture struct {
+	structure string
+	size      int
+	tree      *bst
+	table     map[uint64]struct{}
+	sorted    []uint64
+	probes    []uint64
+}
+
+var currentLookupFixture *lookupFixture
+
+func buildLookupFixture(structure string, size int) *lookupFixture {
+	rng := newRand(int64(size))
+	keys := make([]uint64, size)
+	for i := range keys {
+		keys[i] = rng.Uint64()
+	}
+
+	f := &lookupFixture{structure: structure, size: size}
+	switch structure {
+	case "bst":
+		f.tree = newBST(size)
+		for _, k := range keys {
+			f.tree.insert(k)
+		}
+	case "map":
+		f.table = make(map[uint64]struct{}, size)
+		for _, k := range keys {
+			f.table[k] = struct{}{}
+		}
+	case "slice":
+		f.sorted = append([]uint64(nil), keys...)
+		sort.Slice(f.sorted, func(i, j int) bool { return f.sorted[i] < f.sorted[j] })
+	}
+
+	f.probes = make([]uint64, lookupsPerIter)
+	for i := range f.probes {
+		f.probes[i] = keys[rng.Intn(size)]
+	}
+	return f
+}
+
+// lookupSink keeps results observable so loops are not optimized away
+var lookupSink int
+
+func init() {
+	Register(Benchmark{
+		Name: "LookupScaling", Category: "lookup", Tags: []string{"cpu"},
+		Iterations: 5,
+		Axes: []Axis{
+			{Name: "size", Values: lookupSizes},
+			{Name: "structure", Values: lookupStructures},
+		},
+		Fn: func(b *B) {
+			structure, size := b.StringParam("structure"), b.IntParam("size")
+			f := currentLookupFixture
+			if f == nil || f.structure != structure || f.size != size {
+				b.StopTimer()
+				currentLookupFixture = nil
+				f = buildLookupFixture(structure, size)
+				currentLookupFixture = f
+				b.StartTimer()
+			}
+
+			hits := 0
+			switch structure {
+			case "bst":
+				for _, k := range f.probes {
+					if f.tree.contains(k) {
+						hits++
+					}
+				}
+			case "map":
+				for _, k := range f.probes {
+					if _, ok := f.table[k]; ok {
+						hits++
+					}
+				}
+			case "slice":
+				for _, k := range f.probes {
+					if sortedContains(f.sorted, k) {
+						hits++
+					}
+				}
+			}
+			lookupSink = hits
+		},
+	})
+}
+
+// runScaling implements the scaling subcommand
+func runScaling(args []string) int {
+	fs := flag.NewFlagSet("scaling", flag.ExitOnError)
+	maxSize := fs.Int("max-size", 1<<24, "largest container size to measure")
+	fs.Parse(args)
+
+	cases, err := SelectCases(Filter{Pattern: "^LookupScaling/"})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	// size -> structure -> ns per lookup
+	nsPerLookup := map[int]map[string]float64{}
+	var sizes []int
+	for _, c := range cases {
+		size := c.Params["size"].(int)
+		if size > *maxSize {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "  running %s\n", c.Name)
+		r := RunCase(c)
+		if nsPerLookup[size] == nil {
+			nsPerLookup[size] = map[string]float64{}
+			sizes = append(sizes, size)
+		}
+		nsPerLookup[size][c.Params["structure"].(string)] = r.TimeUs * 1000 / lookupsPerIter
+	}
+	currentLookupFixture = nil
+
+	fmt.Println("=== Lookup Scaling (ns per lookup) ===")
+	fmt.Println()
+	fmt.Printf("%12s", "size")
+	for _, s := range lookupStructures {
+		fmt.Printf(" %10s", s)
+	}
+	fmt.Printf("  %s\n", "fastest")
+	fmt.Println(strings.Repeat("-", 12+11*len(lookupStructures)+10))
+	for _, size := range sizes {
+		fmt.Printf("%12d", size)
+		best, bestNs := "", 0.0
+		for _, v := range lookupStructures {
+			s := v.(string)
+			ns := nsPerLookup[size][s]
+			fmt.Printf(" %10.2f", ns)
+			if best == "" || ns < bestNs {
+				best, bestNs = s, ns
+			}
+		}
+		fmt.Printf("  %s\n", best)
+	}
+
+	// Bar chart, scaled to the slowest measurement overall
+	maxNs := 0.0
+	for _, row := range nsPerLookup {
+		for _, ns := range row {
+			if ns > maxNs {
+				maxNs = ns
+			}
+		}
+	}
+	fmt.Println()
+	for _, size := range sizes {
+		for _, v := range lookupStructures {
+			s := v.(string)
+			ns := nsPerLookup[size][s]
+			bar := 0
+			if maxNs > 0 {
+				bar = int(ns / maxNs * 50)
+			}
+			fmt.Printf("%10d %-5s |%s %.2f\n", size, s, strings.Repeat("#", bar), ns)
+		}
+	}
+	return 0
+}
@@ -1,21 +1,403 @@
 // Algorithm Benchmarks - Go
 //
-// Run with: go run main.go
+// Run with: go run . [-run 'Tcp.*'] [-tags net,serde] [-exclude-tags alloc] [-profile dir] [-trace dir]
+//                   [-gogc 100|off] [-memlimit 1GiB] [-quiet] [-config ../config.yaml]
+//                   [-nodelay=false] [-write-mode single|split|buffered] [-sndbuf 256KiB] [-rcvbuf 256KiB]
+//                   [-o results.json]
+//                   [-raw-samples samples.bin|samples.csv] [-raw-samples-run 'Json.*']
+//                   [-record session.rec] [-record-run 'Tcp.*'] [-soak 1h] [-soak-interval 10s]
+//                   [-leak-check=false] [-mode local|server|client] [-target host:port] [-listen :7007]
+//                   [-echo-addr host:port] [-echo-udp-addr host:port] [-db runs.sqlite]
+//                   [-compare-baseline main [-regression-threshold 10] [-notify-url url]]
+//      or: go run . selftest
+//      or: go run . verify
+//      or: go run . report results.json ...
+//      or: go run . merge [-o combined.json] [lang=]results.json ...
+//      or: go run . influx [-o results.lp | -url endpoint [-token t]] [lang=]results.json ...
+//      or: go run . validate [-schema ../common/results.schema.json] results.json ...
+//      or: go run . compare [-threshold 20] [-notify-url url] [-go-samples go.bin -tml-samples tml.bin] go.json tml.json
+//      or: go run . scaling
+//      or: go run . replay [-addr host:port] [-speed 1] [-run 'Tcp.*'] session.rec
+//      or: go run . orchestrate [-run 'Tcp.*'] [-tml ../tml/bench] [-o combined.json]
+//      or: go run . query -db runs.sqlite [-bench 'Tcp.*'] [-since 2026-01-01] [-machine host] [-by day|commit|machine]
+//      or: go run . loadgen [-proto tcp|udp] [-target host:port] [-rates 1000,10000,100000] [-duration 2s] [-o curve.csv]
+//      or: go run . export-repro [-o repro.tar.gz] [-results go.json,tml.json] TcpRequestSweep
+//      or: go run . bisect -bench TcpRequestSweep -good v0.4.0 [-bad HEAD] [-threshold 10] [-runs 1]
+//
+// Benchmarks tagged large (10GB streams, 100M-row tables, ...) are skipped
+// unless -run, -tags large or -exclude-tags selects them, e.g.
+// go run . -tags large, or go run . -exclude-tags '' for everything.
 
 package main
 
-import "fmt"
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// commands maps subcommand names to their entry points; anything else runs
+// the benchmark suites
+var commands = map[string]func(args []string) int{
+	"selftest":     runSelfTest,
+	"verify":       runVerify,
+	"report":       runReport,
+	"merge":        runMerge,
+	"compare":      runCompare,
+	"scaling":      runScaling,
+	"replay":       runReplay,
+	"orchestrate":  runOrchestrate,
+	"bisect":       runBisect,
+	"validate":     runValidate,
+	"query":        runQuery,
+	"loadgen":      runLoadGen,
+	"influx":       runInflux,
+	"export-repro": runExportRepro,
+}
 
 func main() {
-	fmt.Println("=== Go Algorithm Benchmarks ===")
-	fmt.Println()
-
-	// Correctness tests
-	fmt.Printf("Factorial(10): %d\n", factorialIterative(10))
-	fmt.Printf("Fibonacci(20): %d\n", fibonacciIterative(20))
-	fmt.Printf("GCD(48, 18): %d\n", gcdIterative(48, 18))
-	fmt.Printf("Power(2, 10): %d\n", powerFast(2, 10))
-	fmt.Printf("Primes up to 100: %d\n", countPrimes(100))
-	fmt.Printf("Sum(1..100): %d\n", sumRange(1, 100))
-	fmt.Printf("Collatz steps(27): %d\n", collatzSteps(27))
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			os.Exit(cmd(os.Args[2:]))
+		}
+	}
+	os.Exit(runSuites(os.Args[1:]))
+}
+
+// runSuites runs every benchmark suite and optionally writes a result set
+func runSuites(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	output := fs.String("o", "", "write the result set (with environment metadata) to this JSON file")
+	run := fs.String("run", ".", "run only benchmarks whose name matches this regular expression")
+	tags := fs.String("tags", "", "comma-separated tags; run only benchmarks with at least one of them")
+	excludeTags := fs.String("exclude-tags", "", "comma-separated tags; skip benchmarks with any of them (default: large, unless -run or -tags large is given)")
+	dbPath := fs.String("db", "", "append the run to this SQLite results database, for the query subcommand (needs sqlite3 on PATH)")
+	saveBaseline := fs.String("save-baseline", "", "store this run as the named baseline")
+	compareBaseline := fs.String("compare-baseline", "", "print deltas against the named baseline, exiting 1 on regressions")
+	threshold := fs.Float64("regression-threshold", 10, "percent slowdown flagged as a regression")
+	notifyURL := fs.String("notify-url", "", "POST a JSON summary to this webhook when -compare-baseline finds regressions")
+	profileDir := fs.String("profile", "", "write per-benchmark CPU and heap profiles into this directory")
+	traceDir := fs.String("trace", "", "write a per-benchmark execution trace into this directory")
+	gogc := fs.String("gogc", "", "GC percent for the run, or \"off\" (overrides GOGC)")
+	memLimit := fs.String("memlimit", "", "soft memory limit for the run, e.g. 512MiB or 2GiB (overrides GOMEMLIMIT)")
+	quiet := fs.Bool("quiet", false, "print only the final results table (and baseline deltas)")
+	goldenPath := fs.String("golden", defaultGoldenPath, "shared golden output fixture verified before timing (empty to skip)")
+	configPath := fs.String("config", defaultConfigPath, "shared cross-language configuration (empty for built-in defaults)")
+	rawSamplesPath := fs.String("raw-samples", "", "write every measured iteration's duration to this file (.csv for CSV, otherwise binary)")
+	rawSamplesRun := fs.String("raw-samples-run", ".", "record raw samples only for cases whose name matches this regular expression")
+	recordPath := fs.String("record", "", "record the traffic of TCP client connections to this file, for the replay subcommand")
+	recordRun := fs.String("record-run", ".", "record traffic only for cases whose name matches this regular expression")
+	soak := fs.Duration("soak", 0, "run each selected case continuously for this long, snapshotting it every -soak-interval (default tags: net)")
+	soakInterval := fs.Duration("soak-interval", 10*time.Second, "time between soak snapshots")
+	fs.BoolVar(&leakCheck, "leak-check", leakCheck, "flag goroutines, file descriptors and heap objects a case leaves behind")
+	fs.StringVar(&remoteOptions.Mode, "mode", remoteOptions.Mode, "local, server (serve TCP/UDP echo on -listen for a remote client) or client (run the remote-capable benchmarks against -target)")
+	fs.StringVar(&remoteOptions.Target, "target", remoteOptions.Target, "echo server host:port in client mode")
+	fs.StringVar(&remoteOptions.Listen, "listen", remoteOptions.Listen, "address to serve echo on in server mode")
+	echoAddr := fs.String("echo-addr", "", "run the request benchmarks against this external echo server (e.g. a TML one) instead of a Go one; implies -mode client")
+	fs.StringVar(&remoteOptions.UDPTarget, "echo-udp-addr", "", "UDP echo server in client mode, when not at the TCP address")
+	fs.BoolVar(&tcpOptions.NoDelay, "nodelay", tcpOptions.NoDelay, "set TCP_NODELAY on TCP echo sockets (false enables Nagle's algorithm)")
+	fs.StringVar(&tcpOptions.WriteMode, "write-mode", tcpOptions.WriteMode, "how TCP echo clients write each request: single, split (header then body) or buffered (split writes coalesced)")
+	sndbuf := fs.String("sndbuf", "", "SO_SNDBUF for benchmark TCP sockets, e.g. 64KiB (default: the kernel's)")
+	rcvbuf := fs.String("rcvbuf", "", "SO_RCVBUF for benchmark TCP sockets, e.g. 64KiB (default: the kernel's)")
+	fs.Parse(args)
+
+	var err error
+	if tcpOptions.SendBuffer, err = parseSocketBuffer(*sndbuf); err != nil {
+		fmt.Fprintf(os.Stderr, "error: -sndbuf: %v\n", err)
+		return 2
+	}
+	if tcpOptions.RecvBuffer, err = parseSocketBuffer(*rcvbuf); err != nil {
+		fmt.Fprintf(os.Stderr, "error: -rcvbuf: %v\n", err)
+		return 2
+	}
+	if err := tcpOptions.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 2
+	}
+	if *echoAddr != "" {
+		if remoteOptions.Mode == "server" {
+			fmt.Fprintln(os.Stderr, "error: -echo-addr is for clients, not -mode server")
+			return 2
+		}
+		remoteOptions.Mode, remoteOptions.Target = "client", *echoAddr
+	}
+	if err := remoteOptions.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 2
+	}
+
+	if *configPath = defaultFileFlag(fs, "config"); *configPath != "" {
+		config, err := LoadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 2
+		}
+		config.Apply()
+	}
+
+	if remoteOptions.Mode == "server" {
+		return runEchoServers(remoteOptions.Listen)
+	}
+
+	if err := applyGCSettings(*gogc, *memLimit); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 2
+	}
+
+	if *profileDir != "" {
+		AddMeasureHook(profileHook(*profileDir))
+	}
+	if *traceDir != "" {
+		AddMeasureHook(traceHook(*traceDir))
+	}
+
+	if *soak > 0 && *soakInterval <= 0 {
+		fmt.Fprintln(os.Stderr, "error: -soak-interval must be positive")
+		return 2
+	}
+	filter := Filter{
+		Pattern:     *run,
+		Tags:        splitList(*tags),
+		ExcludeTags: splitList(*excludeTags),
+	}
+	// Soaks are for the server-style benchmarks unless told otherwise
+	if *soak > 0 && len(filter.Tags) == 0 {
+		filter.Tags = []string{"net"}
+	}
+	// Large benchmarks take hours and many GB of memory and disk, so they
+	// run only when asked for: by -run, by -tags large or by -exclude-tags
+	if !flagPassed(fs, "run") && !flagPassed(fs, "exclude-tags") && !slices.Contains(filter.Tags, "large") {
+		filter.ExcludeTags = []string{"large"}
+	}
+	selected, err := SelectCases(filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 2
+	}
+	if remoteOptions.Mode == "client" {
+		var dropped int
+		if selected, dropped = remoteCases(selected); dropped > 0 {
+			fmt.Fprintf(os.Stderr, "client mode: skipping %d cases that cannot run against a remote server\n", dropped)
+		}
+	}
+
+	if *saveBaseline != "" {
+		if err := ValidateBaselineName(*saveBaseline); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 2
+		}
+	}
+	var baseline ResultSet
+	if *compareBaseline != "" {
+		// Load up front so a missing baseline fails before the run
+		if baseline, err = LoadBaseline(*compareBaseline); err != nil {
+			fmt.Fprintf(os.Stderr, "error: loading baseline: %v\n", err)
+			return 2
+		}
+	}
+
+	// Refuse to time implementations that compute the wrong answers
+	if *goldenPath = defaultFileFlag(fs, "golden"); *goldenPath != "" {
+		golden, err := LoadGolden(*goldenPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 2
+		}
+		if failures := golden.Verify(); len(failures) > 0 {
+			for _, f := range failures {
+				fmt.Fprintf(os.Stderr, "golden mismatch: %v\n", f)
+			}
+			return 1
+		}
+	}
+
+	if *rawSamplesPath != "" {
+		if rawSamples, err = openRawSamples(*rawSamplesPath, *rawSamplesRun); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 2
+		}
+		defer func() {
+			if err := rawSamples.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "error: writing %s: %v\n", *rawSamplesPath, err)
+			}
+			rawSamples = nil
+		}()
+	}
+
+	if *recordPath != "" {
+		if traffic, err = openRecorder(*recordPath, *recordRun); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 2
+		}
+		defer func() {
+			if err := traffic.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "error: writing %s: %v\n", *recordPath, err)
+			}
+			traffic = nil
+		}()
+	}
+
+	metadata := CollectMetadata()
+
+	if !*quiet {
+		fmt.Println("=== Go Algorithm Benchmarks ===")
+		fmt.Println()
+		PrintMetadata(metadata)
+		fmt.Println()
+
+		// Correctness tests
+		fmt.Printf("Factorial(10): %d\n", factorialIterative(10))
+		fmt.Printf("Fibonacci(20): %d\n", fibonacciIterative(20))
+		fmt.Printf("GCD(48, 18): %d\n", gcdIterative(48, 18))
+		fmt.Printf("Power(2, 10): %d\n", powerFast(2, 10))
+		fmt.Printf("Primes up to 100: %d\n", countPrimes(100))
+		fmt.Printf("Sum(1..100): %d\n", sumRange(1, 100))
+		fmt.Printf("Collatz steps(27): %d\n", collatzSteps(27))
+		fmt.Println()
+		printTableHeader()
+	}
+
+	var results []BenchmarkResult
+	prog := newProgress(len(selected))
+	category := ""
+	for _, c := range selected {
+		if !*quiet {
+			if c.Bench.Category != category {
+				category = c.Bench.Category
+				fmt.Printf("[%s]\n", category)
+			}
+			prog.begin(c.Name)
+		}
+		var r BenchmarkResult
+		if *soak > 0 {
+			r = RunSoak(c, *soak, *soakInterval, func(s SoakSnapshot) {
+				if !*quiet {
+					prog.note(fmt.Sprintf("  %s %s", c.Name, s))
+				}
+			})
+		} else {
+			r = RunCase(c)
+		}
+		if !*quiet {
+			prog.end()
+			PrintResult(r)
+		}
+		results = append(results, r)
+	}
+	if *quiet {
+		printTable(results)
+	} else {
+		fmt.Println(strings.Repeat("-", 127))
+	}
+
+	set := ResultSet{Language: "go", Metadata: metadata, Results: results}
+	if *output != "" {
+		if err := WriteResultSet(*output, set); err != nil {
+			fmt.Fprintf(os.Stderr, "error: writing %s: %v\n", *output, err)
+			return 1
+		}
+		if !*quiet {
+			fmt.Printf("Results written to %s\n", *output)
+		}
+	}
+	if *dbPath != "" {
+		if err := StoreRun(*dbPath, set); err != nil {
+			fmt.Fprintf(os.Stderr, "error: storing the run: %v\n", err)
+			return 1
+		}
+		if !*quiet {
+			fmt.Printf("Run stored in %s\n", *dbPath)
+		}
+	}
+	if *saveBaseline != "" {
+		if err := SaveBaseline(*saveBaseline, set); err != nil {
+			fmt.Fprintf(os.Stderr, "error: saving baseline: %v\n", err)
+			return 1
+		}
+		if !*quiet {
+			fmt.Printf("Baseline %q saved to %s\n", *saveBaseline, baselinePath(*saveBaseline))
+		}
+	}
+	if *compareBaseline != "" {
+		deltas := CompareResults(baseline.Results, results, *threshold)
+		regressions := PrintDeltas(*compareBaseline, deltas, *threshold)
+		if *notifyURL != "" {
+			changes := make([]NotifyEntry, len(deltas))
+			for i, d := range deltas {
+				changes[i] = NotifyEntry{Name: d.Name, Percent: d.Percent}
+			}
+			n := NewNotification(fmt.Sprintf("baseline %q", *compareBaseline), *threshold, changes)
+			if sent, err := Notify(*notifyURL, n); err != nil {
+				fmt.Fprintf(os.Stderr, "error: notifying: %v\n", err)
+				return 1
+			} else if sent && !*quiet {
+				fmt.Println("Regressions posted to -notify-url")
+			}
+		}
+		if regressions > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// printTableHeader prints the column headings of the results table
+func printTableHeader() {
+	fmt.Printf("%-40s %15s %18s %15s %18s %17s\n", "Benchmark", "Time", "Iterations", "Memory", "Allocs", "Throughput")
+	fmt.Println(strings.Repeat("-", 127))
+}
+
+// printTable prints a complete results table grouped by category
+func printTable(results []BenchmarkResult) {
+	printTableHeader()
+	category := ""
+	for _, r := range results {
+		if r.Category != category {
+			category = r.Category
+			fmt.Printf("[%s]\n", category)
+		}
+		PrintResult(r)
+	}
+	fmt.Println(strings.Repeat("-", 127))
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// defaultFileFlag returns the path of a file flag, or "" when the flag was
+// left at its default and that file does not exist: the defaults are
+// relative to benchmarks/go, so a binary run from anywhere else falls back
+// to its built-in behaviour. A path given explicitly is returned as is, so
+// a missing file is still an error.
+func defaultFileFlag(fs *flag.FlagSet, name string) string {
+	f := fs.Lookup(name)
+	path := f.Value.String()
+	if path == "" || path != f.DefValue {
+		return path
+	}
+	if _, err := os.Stat(path); !flagPassed(fs, name) && os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "note: %s not found, running without -%s\n", path, name)
+		return ""
+	}
+	return path
+}
+
+// flagPassed reports whether the flag was given on the command line
+func flagPassed(fs *flag.FlagSet, name string) bool {
+	passed := false
+	fs.Visit(func(f *flag.Flag) {
+		passed = passed || f.Name == name
+	})
+	return passed
 }
@@ -0,0 +1,49 @@
+// Process Memory - Go
+//
+// Peak resident set size as seen by the OS, which (unlike runtime.MemStats)
+// includes memory the Go runtime has not returned and any non-Go
+// allocations. Only Linux exposes a resettable peak; elsewhere these report
+// zero.
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// resetPeakRSS resets the kernel's high-water mark so the next peakRSS
+// reflects only what happens after this call
+func resetPeakRSS() {
+	if runtime.GOOS == "linux" {
+		os.WriteFile("/proc/self/clear_refs", []byte("5"), 0)
+	}
+}
+
+// peakRSS returns the peak resident set size in bytes, or 0 if unknown
+func peakRSS() int64 {
+	if runtime.GOOS != "linux" {
+		return 0
+	}
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || key != "VmHWM" {
+			continue
+		}
+		kb, err := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "kB")), 10, 64)
+		if err != nil {
+			return 0
+		}
+		return kb * 1024
+	}
+	return 0
+}
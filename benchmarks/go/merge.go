@@ -0,0 +1,114 @@
+// Result Merging - Go
+//
+// Combines result files from the Go, TML, Rust, Python and C++ suites into
+// one dataset keyed by benchmark name and then language, so a single file
+// feeds dashboards and cross-language reports.
+//
+// Each input is a path, or lang=path to set or override the language of a
+// file that does not record one (the Python suite writes bare result
+// arrays):
+//
+// Run with: go run . merge -o combined.json go.json tml.json python=py.json
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// MergedSource describes one input file of a merged dataset
+type MergedSource struct {
+	Language string    `json:"language"`
+	File     string    `json:"file"`
+	Metadata *Metadata `json:"metadata,omitempty"`
+}
+
+// MergedResults is the combined cross-language dataset
+type MergedResults struct {
+	// SchemaVersion is the result schema version of the merged results
+	SchemaVersion int            `json:"schema_version"`
+	Sources       []MergedSource `json:"sources"`
+	// Benchmarks maps benchmark name -> language -> result
+	Benchmarks map[string]map[string]BenchmarkResult `json:"benchmarks"`
+}
+
+// runMerge implements the merge subcommand
+func runMerge(args []string) int {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	output := fs.String("o", "", "write the merged dataset to this file instead of stdout")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: merge [-o combined.json] [lang=]results.json ...")
+		return 2
+	}
+
+	merged := MergedResults{SchemaVersion: resultSchemaVersion, Benchmarks: map[string]map[string]BenchmarkResult{}}
+	for _, arg := range fs.Args() {
+		lang, path, ok := strings.Cut(arg, "=")
+		if !ok {
+			lang, path = "", arg
+		}
+		set, err := LoadResultSet(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		if lang == "" {
+			lang = set.Language
+		}
+		if lang == "" {
+			fmt.Fprintf(os.Stderr, "error: %s does not record its language; pass it as lang=%s\n", path, path)
+			return 2
+		}
+		lang = strings.ToLower(lang)
+		if err := merged.Add(lang, path, set); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+	}
+
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	data = append(data, '\n')
+	if *output == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Merged %d benchmarks from %d files into %s\n", len(merged.Benchmarks), len(merged.Sources), *output)
+	return 0
+}
+
+// Add merges one result set under lang; a benchmark reported twice for the
+// same language is an error rather than a silent overwrite
+func (m *MergedResults) Add(lang, path string, set ResultSet) error {
+	src := MergedSource{Language: lang, File: path}
+	if set.Metadata != (Metadata{}) {
+		md := set.Metadata
+		src.Metadata = &md
+	}
+	m.Sources = append(m.Sources, src)
+	for _, r := range set.Results {
+		byLang := m.Benchmarks[r.Name]
+		if byLang == nil {
+			byLang = map[string]BenchmarkResult{}
+			m.Benchmarks[r.Name] = byLang
+		}
+		if _, dup := byLang[lang]; dup {
+			return fmt.Errorf("%s: %q already has a %s result from an earlier file", path, r.Name, lang)
+		}
+		byLang[lang] = r
+	}
+	return nil
+}
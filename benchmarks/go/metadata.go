@@ -0,0 +1,161 @@
+// Environment Metadata - Go
+//
+// Captures the machine and runtime configuration a result set was produced
+// on, so archived numbers can be compared against TML runs from elsewhere.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// Metadata describes the environment a result set was produced on
+type Metadata struct {
+	GoVersion  string `json:"go_version"`
+	GOOS       string `json:"goos"`
+	GOARCH     string `json:"goarch"`
+	Hostname   string `json:"hostname,omitempty"`
+	CPUModel   string `json:"cpu_model"`
+	NumCPU     int    `json:"num_cpu"`
+	GOMAXPROCS int    `json:"gomaxprocs"`
+	GOGC       string `json:"gogc"`
+	GOMEMLIMIT string `json:"gomemlimit,omitempty"`
+	// TCPNoDelay, TCPWriteMode and the buffer sizes are the TCP benchmark
+	// socket options; a zero buffer size is the kernel's
+	TCPNoDelay    bool   `json:"tcp_nodelay"`
+	TCPWriteMode  string `json:"tcp_write_mode"`
+	TCPSendBuffer int    `json:"tcp_sndbuf,omitempty"`
+	TCPRecvBuffer int    `json:"tcp_rcvbuf,omitempty"`
+	// RemoteTarget is the echo server of a client-mode run (remote.go),
+	// RemoteUDPTarget its UDP echo server when elsewhere
+	RemoteTarget    string `json:"remote_target,omitempty"`
+	RemoteUDPTarget string `json:"remote_udp_target,omitempty"`
+	// GitCommit is the commit the suite was built from, GitDirty whether
+	// the tree had uncommitted changes; empty outside a git checkout
+	GitCommit string `json:"git_commit,omitempty"`
+	GitDirty  bool   `json:"git_dirty,omitempty"`
+	Timestamp string `json:"timestamp"`
+}
+
+// CollectMetadata snapshots the current environment
+func CollectMetadata() Metadata {
+	m := Metadata{
+		GoVersion:     runtime.Version(),
+		GOOS:          runtime.GOOS,
+		GOARCH:        runtime.GOARCH,
+		CPUModel:      cpuModel(),
+		NumCPU:        runtime.NumCPU(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		GOGC:          gcPercent(),
+		GOMEMLIMIT:    memoryLimit(),
+		TCPNoDelay:    tcpOptions.NoDelay,
+		TCPWriteMode:  tcpOptions.WriteMode,
+		TCPSendBuffer: tcpOptions.SendBuffer,
+		TCPRecvBuffer: tcpOptions.RecvBuffer,
+		Timestamp:     time.Now().UTC().Format(time.RFC3339),
+	}
+	m.Hostname, _ = os.Hostname()
+	m.GitCommit, m.GitDirty = gitCommit()
+	if remoteOptions.Mode == "client" {
+		m.RemoteTarget, m.RemoteUDPTarget = remoteOptions.Target, remoteOptions.UDPTarget
+	}
+	return m
+}
+
+// PrintMetadata prints the metadata block shown above the results table
+func PrintMetadata(m Metadata) {
+	fmt.Printf("  Go:         %s (%s/%s)\n", m.GoVersion, m.GOOS, m.GOARCH)
+	if m.Hostname != "" {
+		fmt.Printf("  Host:       %s\n", m.Hostname)
+	}
+	fmt.Printf("  CPU:        %s\n", m.CPUModel)
+	fmt.Printf("  Cores:      %d (GOMAXPROCS=%d)\n", m.NumCPU, m.GOMAXPROCS)
+	fmt.Printf("  GOGC:       %s (GOMEMLIMIT=%s)\n", m.GOGC, m.GOMEMLIMIT)
+	fmt.Printf("  TCP:        nodelay=%t writes=%s sndbuf=%s rcvbuf=%s\n", m.TCPNoDelay, m.TCPWriteMode,
+		socketBufferLabel(m.TCPSendBuffer), socketBufferLabel(m.TCPRecvBuffer))
+	if m.RemoteTarget != "" {
+		fmt.Printf("  Remote:     %s\n", m.RemoteTarget)
+		if m.RemoteUDPTarget != "" {
+			fmt.Printf("  Remote UDP: %s\n", m.RemoteUDPTarget)
+		}
+	}
+	if m.GitCommit != "" {
+		dirty := ""
+		if m.GitDirty {
+			dirty = " (dirty)"
+		}
+		fmt.Printf("  Commit:     %s%s\n", m.GitCommit, dirty)
+	}
+	fmt.Printf("  Timestamp:  %s\n", m.Timestamp)
+}
+
+// gitCommit returns the commit the binary was built from, as stamped by go
+// build, or else the HEAD of the working directory's checkout (go run does
+// not stamp it)
+func gitCommit() (commit string, dirty bool) {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				commit = setting.Value
+			case "vcs.modified":
+				dirty = setting.Value == "true"
+			}
+		}
+		if commit != "" {
+			return commit, dirty
+		}
+	}
+	commit, err := git("", "rev-parse", "HEAD")
+	if err != nil {
+		return "", false
+	}
+	status, err := git("", "status", "--porcelain", "--untracked-files=no")
+	return commit, err == nil && status != ""
+}
+
+// gcPercent reports the effective GC percent ("off" when disabled)
+func gcPercent() string {
+	percent := debug.SetGCPercent(100)
+	debug.SetGCPercent(percent)
+	if percent < 0 {
+		return "off"
+	}
+	return fmt.Sprintf("%d", percent)
+}
+
+// cpuModel returns a human-readable CPU model name, or "unknown"
+func cpuModel() string {
+	switch runtime.GOOS {
+	case "linux":
+		f, err := os.Open("/proc/cpuinfo")
+		if err != nil {
+			break
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			key, value, ok := strings.Cut(scanner.Text(), ":")
+			if ok && strings.TrimSpace(key) == "model name" {
+				return strings.TrimSpace(value)
+			}
+		}
+	case "darwin":
+		out, err := exec.Command("sysctl", "-n", "machdep.cpu.brand_string").Output()
+		if err == nil {
+			return strings.TrimSpace(string(out))
+		}
+	case "windows":
+		if id := os.Getenv("PROCESSOR_IDENTIFIER"); id != "" {
+			return id
+		}
+	}
+	return "unknown"
+}
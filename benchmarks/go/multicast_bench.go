@@ -0,0 +1,183 @@
+// Go UDP Multicast Benchmark
+// The Go reference for TML's multicast support: receivers join a group on
+// the loopback interface, and each iteration one sender multicasts a batch
+// of multicastBatch datagrams to it, as fast as the socket accepts them.
+// Every datagram carries its send time, so each delivery to each receiver
+// is recorded in the latency histogram; the iteration ends when every
+// receiver has the whole batch or multicastDrain after the last send.
+//
+// Metrics: delivered_per_sec (deliveries across all receivers over the
+// iteration) and lost_pct, the share of deliveries that never arrived.
+// A burst to several receivers can overrun their socket buffers, so loss
+// here is kernel queueing rather than a network.
+//
+// Datagram: batch u32 | seq u32 | sent unix ns u64 | padding
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	multicastBatch   = 1000
+	multicastPayload = 64
+	multicastDrain   = 100 * time.Millisecond
+	multicastRcvBuf  = 4 << 20
+)
+
+// multicastGroup is an administratively scoped group, never routed
+var multicastGroup = net.IPv4(239, 77, 15, 46)
+
+// multicastDelivery is one datagram as a receiver got it
+type multicastDelivery struct {
+	batch   uint32
+	latency time.Duration
+}
+
+var multicast struct {
+	receivers []*net.UDPConn
+	sender    *net.UDPConn
+	batch     atomic.Uint32
+	delivered chan multicastDelivery
+	wg        sync.WaitGroup
+	payload   []byte
+}
+
+// loopbackInterface returns the loopback interface to join the group on
+func loopbackInterface() (*net.Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for i := range ifaces {
+		if ifaces[i].Flags&net.FlagLoopback != 0 && ifaces[i].Flags&net.FlagUp != 0 {
+			return &ifaces[i], nil
+		}
+	}
+	return nil, errors.New("no loopback interface is up")
+}
+
+func setupUdpMulticast(b *B) error {
+	lo, err := loopbackInterface()
+	if err != nil {
+		return err
+	}
+	multicast.delivered = make(chan multicastDelivery, multicastBatch*b.IntParam("receivers"))
+	// The first receiver picks the port, the others share it
+	group := &net.UDPAddr{IP: multicastGroup}
+	for i := 0; i < b.IntParam("receivers"); i++ {
+		conn, err := net.ListenMulticastUDP("udp4", lo, group)
+		if err != nil {
+			teardownUdpMulticast()
+			return err
+		}
+		conn.SetReadBuffer(multicastRcvBuf)
+		group.Port = conn.LocalAddr().(*net.UDPAddr).Port
+		multicast.receivers = append(multicast.receivers, conn)
+		multicast.wg.Add(1)
+		go receiveMulticast(conn)
+	}
+	// Sending from the loopback address routes the group out of lo
+	sender, err := net.DialUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}, group)
+	if err != nil {
+		teardownUdpMulticast()
+		return err
+	}
+	multicast.sender = sender
+	multicast.payload = make([]byte, multicastPayload)
+	return nil
+}
+
+// receiveMulticast passes on the current batch's datagrams until conn is
+// closed
+func receiveMulticast(conn *net.UDPConn) {
+	defer multicast.wg.Done()
+	buf := make([]byte, 2*multicastPayload)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+		now := time.Now()
+		batch := binary.LittleEndian.Uint32(buf)
+		if n < 16 || batch != multicast.batch.Load() {
+			continue
+		}
+		sent := time.Unix(0, int64(binary.LittleEndian.Uint64(buf[8:])))
+		// Never block, or Close could not stop the receiver
+		select {
+		case multicast.delivered <- multicastDelivery{batch: batch, latency: now.Sub(sent)}:
+		default:
+		}
+	}
+}
+
+func teardownUdpMulticast() {
+	if multicast.sender != nil {
+		multicast.sender.Close()
+	}
+	for _, conn := range multicast.receivers {
+		conn.Close()
+	}
+	multicast.wg.Wait()
+	multicast.sender, multicast.receivers, multicast.delivered = nil, nil, nil
+}
+
+// benchUdpMulticast sends one batch and collects its deliveries
+func benchUdpMulticast(b *B) {
+	batch := multicast.batch.Add(1)
+	want := multicastBatch * len(multicast.receivers)
+	b.SetBytes(multicastBatch * multicastPayload)
+	start := time.Now()
+	p := multicast.payload
+	binary.LittleEndian.PutUint32(p, batch)
+	for seq := uint32(0); seq < multicastBatch; seq++ {
+		binary.LittleEndian.PutUint32(p[4:], seq)
+		binary.LittleEndian.PutUint64(p[8:], uint64(time.Now().UnixNano()))
+		if _, err := multicast.sender.Write(p); err != nil {
+			b.Fatal(err)
+			return
+		}
+	}
+	drain := time.NewTimer(multicastDrain)
+	defer drain.Stop()
+	got := 0
+collect:
+	for got < want {
+		select {
+		case d := <-multicast.delivered:
+			// A straggler of an earlier batch may have slipped in
+			if d.batch != batch {
+				continue
+			}
+			b.Histogram().Record(d.latency)
+			got++
+		case <-drain.C:
+			break collect
+		}
+	}
+	elapsed := time.Since(start)
+	// Stop the receivers passing on this batch's stragglers
+	multicast.batch.Add(1)
+	b.ReportMetric("delivered_per_sec", float64(got)/elapsed.Seconds())
+	b.ReportMetric("lost_pct", 100*float64(want-got)/float64(want))
+	if got == 0 {
+		b.Fatal(errors.New("no multicast datagram was delivered; is multicast enabled on loopback?"))
+	}
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "UdpMulticast", Category: "udp", Tags: []string{"net"},
+		Iterations: 50,
+		Axes:       []Axis{{Name: "receivers", Values: Ints(1, 4)}},
+		Setup:      setupUdpMulticast, Teardown: teardownUdpMulticast,
+		Fn: benchUdpMulticast,
+	})
+}
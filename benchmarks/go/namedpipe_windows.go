@@ -0,0 +1,254 @@
+// Go Named Pipe Benchmark
+// The Windows counterpart of the unix transport: the echo request round
+// trip over a local named pipe, swept over TcpRequestSweep's payloads so
+// the two give the IPC-versus-loopback comparison that EchoRequest's unix
+// and tcp transports give elsewhere.
+//
+// The suite has no third-party dependencies, so rather than go-winio the
+// pipe is driven through kernel32 directly, in byte mode with synchronous
+// (blocking) handles. The listener keeps one unconnected instance of the
+// pipe waiting, as Windows servers do, so a client never finds the name
+// missing between two accepts. Deadlines are not supported, which the echo
+// round trip does not need.
+//
+// Reads and writes on a synchronous handle are serialized by the kernel, so
+// the concurrent write and read echoRoundTrip uses past tcpInlineWriteMax
+// would deadlock; the sweep stops at that size.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	pipeAccessDuplex       = 0x3
+	pipeUnlimitedInstances = 255
+	pipeBufferSize         = 64 * 1024
+	errorPipeConnected     = syscall.Errno(535)
+	errorPipeBusy          = syscall.Errno(231)
+	namedPipeDialAttempts  = 10
+	namedPipeWaitTimeoutMs = 1000
+)
+
+var (
+	kernel32             = syscall.NewLazyDLL("kernel32.dll")
+	procCreateNamedPipeW = kernel32.NewProc("CreateNamedPipeW")
+	procConnectNamedPipe = kernel32.NewProc("ConnectNamedPipe")
+	procWaitNamedPipeW   = kernel32.NewProc("WaitNamedPipeW")
+)
+
+// namedPipeSeq makes pipe names unique within the process
+var namedPipeSeq atomic.Uint64
+
+// createNamedPipe creates one more instance of the pipe called name
+func createNamedPipe(name string) (syscall.Handle, error) {
+	p, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return syscall.InvalidHandle, err
+	}
+	h, _, err := procCreateNamedPipeW.Call(uintptr(unsafe.Pointer(p)), pipeAccessDuplex,
+		0, // PIPE_TYPE_BYTE | PIPE_READMODE_BYTE | PIPE_WAIT
+		pipeUnlimitedInstances, pipeBufferSize, pipeBufferSize, 0, 0)
+	if syscall.Handle(h) == syscall.InvalidHandle {
+		return syscall.InvalidHandle, os.NewSyscallError("CreateNamedPipe", err)
+	}
+	return syscall.Handle(h), nil
+}
+
+// connectNamedPipe waits for a client to open instance h
+func connectNamedPipe(h syscall.Handle) error {
+	if ok, _, err := procConnectNamedPipe.Call(uintptr(h), 0); ok == 0 && err != errorPipeConnected {
+		return os.NewSyscallError("ConnectNamedPipe", err)
+	}
+	return nil
+}
+
+// openNamedPipe connects a client to the pipe called name, waiting while
+// every instance is busy
+func openNamedPipe(name string) (syscall.Handle, error) {
+	p, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return syscall.InvalidHandle, err
+	}
+	for attempt := 0; ; attempt++ {
+		h, err := syscall.CreateFile(p, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil, syscall.OPEN_EXISTING, 0, 0)
+		if err == nil {
+			return h, nil
+		}
+		if err != errorPipeBusy || attempt == namedPipeDialAttempts {
+			return syscall.InvalidHandle, os.NewSyscallError("CreateFile", err)
+		}
+		procWaitNamedPipeW.Call(uintptr(unsafe.Pointer(p)), namedPipeWaitTimeoutMs)
+	}
+}
+
+// namedPipeTransport is local named pipes with a fresh name per listener
+type namedPipeTransport struct{}
+
+func (namedPipeTransport) Listen() (net.Listener, error) {
+	name := fmt.Sprintf(`\\.\pipe\tml-bench-%d-%d`, os.Getpid(), namedPipeSeq.Add(1))
+	h, err := createNamedPipe(name)
+	if err != nil {
+		return nil, err
+	}
+	return &namedPipeListener{addr: namedPipeAddr(name), next: h}, nil
+}
+
+func (namedPipeTransport) Dial(addr net.Addr) (net.Conn, error) {
+	h, err := openNamedPipe(addr.String())
+	if err != nil {
+		return nil, err
+	}
+	return newNamedPipeConn(h, addr.(namedPipeAddr)), nil
+}
+
+func (namedPipeTransport) RoundTrip(conn net.Conn, req, resp []byte) error {
+	return echoRoundTrip(conn, req, resp)
+}
+
+// namedPipeListener accepts clients on the waiting instance, creating the
+// next one before handing the connection out
+type namedPipeListener struct {
+	addr namedPipeAddr
+
+	mu        sync.Mutex
+	next      syscall.Handle
+	accepting bool
+	closed    bool
+}
+
+func (l *namedPipeListener) Accept() (net.Conn, error) {
+	l.mu.Lock()
+	h, closed := l.next, l.closed
+	l.accepting = !closed
+	l.mu.Unlock()
+	if closed {
+		return nil, net.ErrClosed
+	}
+	err := connectNamedPipe(h)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.accepting = false
+	if l.closed {
+		// Close connected to the instance itself to wake this Accept
+		syscall.CloseHandle(h)
+		return nil, net.ErrClosed
+	}
+	if err != nil {
+		return nil, err
+	}
+	if l.next, err = createNamedPipe(string(l.addr)); err != nil {
+		l.closed = true
+		syscall.CloseHandle(h)
+		return nil, err
+	}
+	return newNamedPipeConn(h, l.addr), nil
+}
+
+// Close stops accepting; ConnectNamedPipe has no cancellation, so a
+// pending Accept is woken by connecting to the waiting instance
+func (l *namedPipeListener) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	accepting := l.accepting
+	l.mu.Unlock()
+	if !accepting {
+		return syscall.CloseHandle(l.next)
+	}
+	if h, err := openNamedPipe(string(l.addr)); err == nil {
+		syscall.CloseHandle(h)
+	}
+	return nil
+}
+
+func (l *namedPipeListener) Addr() net.Addr {
+	return l.addr
+}
+
+// namedPipeAddr is a pipe name
+type namedPipeAddr string
+
+func (namedPipeAddr) Network() string { return "pipe" }
+
+func (a namedPipeAddr) String() string { return string(a) }
+
+// namedPipeConn is one end of a connected pipe instance
+type namedPipeConn struct {
+	h    syscall.Handle
+	addr namedPipeAddr
+	once sync.Once
+}
+
+func newNamedPipeConn(h syscall.Handle, addr namedPipeAddr) *namedPipeConn {
+	return &namedPipeConn{h: h, addr: addr}
+}
+
+func (c *namedPipeConn) Read(p []byte) (int, error) {
+	var n uint32
+	err := syscall.ReadFile(c.h, p, &n, nil)
+	if err == syscall.ERROR_BROKEN_PIPE {
+		// The other end closed
+		return 0, io.EOF
+	}
+	if err != nil {
+		return int(n), os.NewSyscallError("ReadFile", err)
+	}
+	return int(n), nil
+}
+
+func (c *namedPipeConn) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		var n uint32
+		if err := syscall.WriteFile(c.h, p[written:], &n, nil); err != nil {
+			return written, os.NewSyscallError("WriteFile", err)
+		}
+		written += int(n)
+	}
+	return written, nil
+}
+
+func (c *namedPipeConn) Close() error {
+	err := net.ErrClosed
+	c.once.Do(func() { err = syscall.CloseHandle(c.h) })
+	return err
+}
+
+func (c *namedPipeConn) LocalAddr() net.Addr  { return c.addr }
+func (c *namedPipeConn) RemoteAddr() net.Addr { return c.addr }
+
+var errNamedPipeDeadline = errors.New("named pipe: deadlines are not supported")
+
+func (c *namedPipeConn) SetDeadline(time.Time) error      { return errNamedPipeDeadline }
+func (c *namedPipeConn) SetReadDeadline(time.Time) error  { return errNamedPipeDeadline }
+func (c *namedPipeConn) SetWriteDeadline(time.Time) error { return errNamedPipeDeadline }
+
+func setupNamedPipeRequest(b *B) error {
+	return tcpRequest.dial(namedPipeTransport{}, b.IntParam("payload"))
+}
+
+func init() {
+	// The pipe equivalent of TcpRequestSweep, up to tcpInlineWriteMax
+	Register(Benchmark{
+		Name: "NamedPipeRequest", Category: "npipe", Tags: []string{"net"},
+		Iterations: 2000,
+		Axes:       []Axis{{Name: "payload", Values: Ints(64, 1024, 16*1024, tcpInlineWriteMax)}},
+		Setup:      setupNamedPipeRequest, Teardown: tcpRequest.close,
+		Fn: benchEchoRequest,
+	})
+}
@@ -0,0 +1,149 @@
+// Regression Notifications - Go
+//
+// -notify-url makes a scheduled comparison alert by itself: when a run
+// compared against a baseline (-compare-baseline) has regressions above
+// -regression-threshold, or the compare subcommand finds TML more than
+// -threshold slower than Go, a summary is POSTed as JSON to the URL. The
+// summary lists the largest regressions and improvements with their
+// percentages, at most notifyTopN of each; nothing is sent when no
+// threshold is breached.
+//
+// The body carries the summary as plain text in "text", which Slack,
+// Mattermost and Google Chat incoming webhooks display as is, and as
+// structured fields for anything else:
+//
+//	{"text": "...", "source": "baseline main", "threshold_pct": 10, "compared": 42,
+//	 "regressions": [{"name": "TcpDial", "percent": 23.5}], "improvements": [...]}
+//
+// Run with: go run . -compare-baseline main -notify-url https://hooks.example.com/...
+//        or: go run . compare -notify-url https://hooks.example.com/... go.json tml.json
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// notifyTopN is how many regressions and improvements a summary lists
+	notifyTopN = 5
+	// notifyTimeout bounds the webhook POST
+	notifyTimeout = 10 * time.Second
+)
+
+// NotifyEntry is one benchmark of a notification: Percent is its change in
+// time, positive when slower
+type NotifyEntry struct {
+	Name    string  `json:"name"`
+	Percent float64 `json:"percent"`
+}
+
+// Notification is the body POSTed to -notify-url
+type Notification struct {
+	Text         string        `json:"text"`
+	Source       string        `json:"source"`
+	ThresholdPct float64       `json:"threshold_pct"`
+	Compared     int           `json:"compared"`
+	Regressions  []NotifyEntry `json:"regressions"`
+	Improvements []NotifyEntry `json:"improvements"`
+	// total counts of each, beyond the listed ones
+	regressions, improvements int
+}
+
+// NewNotification summarizes the changes of a comparison: those more than
+// threshold percent slower are regressions, those more than threshold
+// percent faster improvements, each listed largest first
+func NewNotification(source string, threshold float64, changes []NotifyEntry) Notification {
+	n := Notification{Source: source, ThresholdPct: threshold, Compared: len(changes)}
+	for _, c := range changes {
+		switch {
+		case c.Percent > threshold:
+			n.Regressions = append(n.Regressions, c)
+		case c.Percent < -threshold:
+			n.Improvements = append(n.Improvements, c)
+		}
+	}
+	n.regressions, n.improvements = len(n.Regressions), len(n.Improvements)
+	n.Regressions = topChanges(n.Regressions)
+	n.Improvements = topChanges(n.Improvements)
+	n.Text = n.summary()
+	return n
+}
+
+// Breached reports whether the comparison has regressions, the condition
+// for notifying
+func (n Notification) Breached() bool {
+	return n.regressions > 0
+}
+
+// topChanges sorts changes by size, largest first, and keeps notifyTopN
+func topChanges(changes []NotifyEntry) []NotifyEntry {
+	sort.SliceStable(changes, func(i, j int) bool {
+		return math.Abs(changes[i].Percent) > math.Abs(changes[j].Percent)
+	})
+	return changes[:min(len(changes), notifyTopN)]
+}
+
+// summary is the plain-text form of the notification
+func (n Notification) summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Benchmark regressions against %s: %d of %d benchmarks more than %.0f%% slower, %d more than %.0f%% faster",
+		n.Source, n.regressions, n.Compared, n.ThresholdPct, n.improvements, n.ThresholdPct)
+	list := func(title string, entries []NotifyEntry, total int) {
+		if len(entries) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n%s:", title)
+		for _, e := range entries {
+			fmt.Fprintf(&b, "\n  %s %+.1f%%", e.Name, e.Percent)
+		}
+		if total > len(entries) {
+			fmt.Fprintf(&b, "\n  ... and %d more", total-len(entries))
+		}
+	}
+	list("Top regressions", n.Regressions, n.regressions)
+	list("Top improvements", n.Improvements, n.improvements)
+	return b.String()
+}
+
+// Notify POSTs n to url when it has regressions; it reports whether it
+// sent anything
+func Notify(url string, n Notification) (bool, error) {
+	if !n.Breached() {
+		return false, nil
+	}
+	body, err := json.Marshal(n)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return true, postHTTP(req, notifyTimeout)
+}
+
+// postHTTP sends req and fails unless the server answers 2xx, quoting the
+// start of the server's error body
+func postHTTP(req *http.Request, timeout time.Duration) error {
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s: %s", req.URL.Redacted(), resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
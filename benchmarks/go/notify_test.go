@@ -0,0 +1,90 @@
+// Regression Notification Tests - Go
+//
+// Run with: go test -run 'Notification|Notify|TMLChanges'
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewNotification(t *testing.T) {
+	var changes []NotifyEntry
+	for i := 1; i <= 8; i++ {
+		changes = append(changes, NotifyEntry{Name: fmt.Sprintf("Slow%d", i), Percent: float64(10 + i*5)})
+	}
+	changes = append(changes,
+		NotifyEntry{Name: "Fast", Percent: -30},
+		NotifyEntry{Name: "Noise", Percent: 9},
+		NotifyEntry{Name: "SlightlyFaster", Percent: -5},
+	)
+	n := NewNotification("baseline \"main\"", 10, changes)
+	if !n.Breached() || n.Compared != len(changes) {
+		t.Fatalf("notification %+v: want breached over %d changes", n, len(changes))
+	}
+	if len(n.Regressions) != notifyTopN || n.Regressions[0].Name != "Slow8" || n.Regressions[notifyTopN-1].Name != "Slow4" {
+		t.Errorf("regressions %v, want the %d largest, largest first", n.Regressions, notifyTopN)
+	}
+	if len(n.Improvements) != 1 || n.Improvements[0].Name != "Fast" {
+		t.Errorf("improvements %v, want only Fast", n.Improvements)
+	}
+	for _, want := range []string{"8 of 11 benchmarks more than 10% slower", "Slow8 +50.0%", "... and 3 more", "Fast -30.0%"} {
+		if !strings.Contains(n.Text, want) {
+			t.Errorf("text %q lacks %q", n.Text, want)
+		}
+	}
+
+	if NewNotification("b", 10, []NotifyEntry{{Name: "Fast", Percent: -50}}).Breached() {
+		t.Error("improvements alone breached the threshold")
+	}
+}
+
+func TestNotify(t *testing.T) {
+	var got []Notification
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n Notification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad payload", http.StatusBadRequest)
+			return
+		}
+		got = append(got, n)
+	}))
+	defer srv.Close()
+
+	if sent, err := Notify(srv.URL, NewNotification("b", 10, []NotifyEntry{{Name: "Ok", Percent: 1}})); sent || err != nil {
+		t.Errorf("a comparison without regressions was sent (%t, %v)", sent, err)
+	}
+	sent, err := Notify(srv.URL, NewNotification("b", 10, []NotifyEntry{{Name: "TcpDial", Percent: 23.5}}))
+	if !sent || err != nil {
+		t.Fatalf("regression not sent: %t, %v", sent, err)
+	}
+	if len(got) != 1 || got[0].Source != "b" || len(got[0].Regressions) != 1 || got[0].Regressions[0].Percent != 23.5 || got[0].Text == "" {
+		t.Errorf("webhook received %+v", got)
+	}
+
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such hook", http.StatusNotFound)
+	})
+	if _, err := Notify(srv.URL, NewNotification("b", 10, []NotifyEntry{{Name: "x", Percent: 50}})); err == nil || !strings.Contains(err.Error(), "no such hook") {
+		t.Errorf("rejected webhook returned %v", err)
+	}
+}
+
+func TestTMLChanges(t *testing.T) {
+	changes := tmlChanges([]Comparison{
+		{Name: "a", Ratio: 1.5, Winner: "go"},
+		{Name: "b", Ratio: 0.5, Winner: "tml"},
+		{Name: "c", Ratio: 2, CI: &RatioCI{Low: 0.9, High: 3}},
+	})
+	want := []float64{50, -100, 0}
+	for i, c := range changes {
+		if c.Percent != want[i] {
+			t.Errorf("%s: %.1f%%, want %.1f%%", c.Name, c.Percent, want[i])
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Open-Loop Load - Go
+//
+// Closed-loop benchmarks send the next request only after the previous
+// reply, so a slow response delays every later request and the queueing it
+// would have caused is never measured (coordinated omission). In open-loop
+// mode requests are sent on a fixed schedule regardless of replies, and
+// latency is measured from each request's *intended* send time, which
+// corrects for any lag of the sender itself. The uncorrected numbers (from
+// the actual send time) are reported alongside as metrics.
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// openLoopDuration is how long each open-loop case sends for
+const openLoopDuration = 2 * time.Second
+
+// openLoopResult is what one open-loop run measured besides the corrected
+// latencies
+type openLoopResult struct {
+	Sent, Received int
+	// AchievedRPS is the rate the requests actually went out at
+	AchievedRPS float64
+	// Uncorrected holds the latencies from the actual send times
+	Uncorrected *Histogram
+}
+
+// openLoop sends rate requests per second over conn for duration, each
+// tagged with its sequence number, while a receiver goroutine matches the
+// echoes and records their corrected latencies into corrected. Datagram
+// transports may lose replies; stream transports must echo every byte in
+// order.
+func openLoop(conn net.Conn, corrected *Histogram, rate int, duration time.Duration, payloadSize int, datagram bool) (openLoopResult, error) {
+	count := max(int(float64(rate)*duration.Seconds()), 1)
+	interval := time.Second / time.Duration(rate)
+	sentAt := make([]atomic.Int64, count)
+
+	uncorrected := NewHistogram()
+	start := time.Now()
+	intended := func(seq int) time.Time { return start.Add(time.Duration(seq) * interval) }
+
+	received := make(chan int, 1)
+	go func() {
+		reply := make([]byte, payloadSize)
+		n := 0
+		for n < count {
+			var err error
+			if datagram {
+				_, err = conn.Read(reply)
+			} else {
+				_, err = io.ReadFull(conn, reply)
+			}
+			if err != nil {
+				break
+			}
+			now := time.Now()
+			seq := int(binary.LittleEndian.Uint64(reply))
+			if seq < 0 || seq >= count {
+				continue
+			}
+			corrected.Record(now.Sub(intended(seq)))
+			uncorrected.Record(now.Sub(time.Unix(0, sentAt[seq].Load())))
+			n++
+		}
+		received <- n
+	}()
+
+	payload := make([]byte, payloadSize)
+	sent := 0
+	var writeErr error
+	for ; sent < count; sent++ {
+		if wait := time.Until(intended(sent)); wait > 0 {
+			time.Sleep(wait)
+		}
+		binary.LittleEndian.PutUint64(payload, uint64(sent))
+		sentAt[sent].Store(time.Now().UnixNano())
+		if _, writeErr = conn.Write(payload); writeErr != nil {
+			break
+		}
+	}
+	sendDone := time.Since(start)
+
+	// Give stragglers a grace period, then unblock the receiver
+	var got int
+	select {
+	case got = <-received:
+	case <-time.After(time.Second):
+		conn.SetReadDeadline(time.Now())
+		got = <-received
+		conn.SetReadDeadline(time.Time{})
+	}
+
+	res := openLoopResult{Sent: sent, Received: got, AchievedRPS: float64(sent) / sendDone.Seconds(), Uncorrected: uncorrected}
+	if writeErr != nil {
+		return res, writeErr
+	}
+	if !datagram && got < count {
+		return res, fmt.Errorf("stream echo returned %d of %d replies", got, count)
+	}
+	return res, nil
+}
+
+// runOpenLoop is an open-loop case: the corrected latencies go to the
+// case's histogram, everything else to its metrics
+func runOpenLoop(b *B, conn net.Conn, rate int, duration time.Duration, payloadSize int, datagram bool) {
+	res, err := openLoop(conn, b.Histogram(), rate, duration, payloadSize, datagram)
+	b.ReportMetric("target_rps", float64(rate))
+	b.ReportMetric("achieved_rps", res.AchievedRPS)
+	b.ReportMetric("lost", float64(res.Sent-res.Received))
+	b.ReportMetric("uncorrected_p50_us", float64(res.Uncorrected.Percentile(50))/1e3)
+	b.ReportMetric("uncorrected_p99_us", float64(res.Uncorrected.Percentile(99))/1e3)
+	b.ReportMetric("uncorrected_p99.99_us", float64(res.Uncorrected.Percentile(99.99))/1e3)
+	if err != nil {
+		b.Fatal(err)
+	}
+}
+
+func init() {
+	rates := []Axis{{Name: "rate", Values: Ints(1000, 10000, 50000)}}
+
+	Register(Benchmark{
+		Name: "TcpOpenLoop", Category: "tcp", Tags: []string{"net", "remote"},
+		Iterations: 1, Axes: rates,
+		Setup: setupTcpReusedRequest, Teardown: tcpRequest.close,
+		Fn: func(b *B) {
+			// Bypass the write mode: open-loop requests are written once
+			conn := tcpRequest.conn.(*tcpRequestConn).Conn
+			runOpenLoop(b, conn, b.IntParam("rate"), openLoopDuration, benchConfig.PayloadSizes.Request, false)
+		},
+	})
+	Register(Benchmark{
+		Name: "UdpOpenLoop", Category: "udp", Tags: []string{"net", "remote"},
+		Iterations: 1, Axes: rates,
+		Setup: setupUdpRequest, Teardown: udpRequest.close,
+		Fn: func(b *B) {
+			runOpenLoop(b, udpRequest.conn, b.IntParam("rate"), openLoopDuration, benchConfig.PayloadSizes.Request, true)
+		},
+	})
+}
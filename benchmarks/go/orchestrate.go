@@ -0,0 +1,218 @@
+// Suite Orchestration - Go
+//
+// Runs the Go and TML suites pair by pair and prints their comparison in
+// one step, instead of running each suite by hand and feeding the result
+// files to compare. For every selected Go benchmark the Go binary and then
+// the TML binary run just that benchmark, back to back, so both sides of a
+// pair see the same machine state; the results are then compared as the
+// compare subcommand does, with confidence intervals from raw samples
+// unless -samples=false.
+//
+// The binaries come from the binaries section of the shared configuration
+// (-go and -tml override it); an empty Go binary is this executable. Both
+// are run with the Go suite's flags (-run, -o, -quiet, -config and
+// -raw-samples) and the same configuration file. Each pair selects its
+// benchmark by name the way compare matches names, ignoring case, spaces,
+// underscores and dashes, and a benchmark the TML suite does not have is
+// reported as unmatched.
+//
+// Run with: go run . orchestrate [-run 'Tcp.*'] [-tml ../tml/bench] [-o combined.json]
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// suiteBinary is one language suite's executable
+type suiteBinary struct {
+	lang string
+	path string
+}
+
+// runOrchestrate implements the orchestrate subcommand
+func runOrchestrate(args []string) int {
+	fs := flag.NewFlagSet("orchestrate", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "shared configuration naming the suite binaries")
+	goPath := fs.String("go", "", "Go suite binary (overrides binaries.go)")
+	tmlPath := fs.String("tml", "", "TML suite binary (overrides binaries.tml)")
+	run := fs.String("run", ".", "run only benchmarks whose name matches this regular expression")
+	threshold := fs.Float64("threshold", 20, "percent faster before a side is flagged as the winner")
+	samples := fs.Bool("samples", true, "record raw samples for bootstrapped confidence intervals")
+	output := fs.String("o", "", "also write both suites' results, merged, to this JSON file")
+	fs.Parse(args)
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 2
+	}
+	config.Apply()
+	configAbs, err := filepath.Abs(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 2
+	}
+	goBin, tmlBin, err := suiteBinaries(config, filepath.Dir(configAbs), *goPath, *tmlPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 2
+	}
+
+	cases, err := SelectCases(Filter{Pattern: *run})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 2
+	}
+	var names []string
+	for _, c := range cases {
+		if len(names) == 0 || names[len(names)-1] != c.Bench.Name {
+			names = append(names, c.Bench.Name)
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "tml-orchestrate-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	sets := map[string]*ResultSet{}
+	rawSamples := map[string]map[string][]time.Duration{}
+	failed := 0
+	for i, name := range names {
+		for _, bin := range []suiteBinary{goBin, tmlBin} {
+			fmt.Fprintf(os.Stderr, "  [%d/%d] %s (%s)\n", i+1, len(names), name, bin.lang)
+			set, samplesByCase, err := runSuiteBenchmark(bin, name, configAbs, filepath.Join(dir, fmt.Sprintf("%s-%d", bin.lang, i)), *samples)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %s %s: %v\n", bin.lang, name, err)
+				failed++
+				continue
+			}
+			if sets[bin.lang] == nil {
+				sets[bin.lang] = &ResultSet{Language: bin.lang, Metadata: set.Metadata}
+				rawSamples[bin.lang] = map[string][]time.Duration{}
+			}
+			sets[bin.lang].Results = append(sets[bin.lang].Results, set.Results...)
+			for k, v := range samplesByCase {
+				rawSamples[bin.lang][k] = v
+			}
+		}
+	}
+	if sets["go"] == nil || sets["tml"] == nil {
+		fmt.Fprintln(os.Stderr, "error: no results to compare")
+		return 1
+	}
+
+	fmt.Println()
+	comparisons, unmatched := CompareGoTML(sets["go"].Results, sets["tml"].Results, *threshold)
+	if *samples {
+		AnnotateSignificance(comparisons, rawSamples["go"], rawSamples["tml"])
+	}
+	PrintComparisons(comparisons, *threshold)
+	if unmatched > 0 {
+		fmt.Printf("%d benchmarks present in only one suite\n", unmatched)
+	}
+
+	if *output != "" {
+		merged := MergedResults{SchemaVersion: resultSchemaVersion, Benchmarks: map[string]map[string]BenchmarkResult{}}
+		for _, bin := range []suiteBinary{goBin, tmlBin} {
+			if err := merged.Add(bin.lang, bin.path, *sets[bin.lang]); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return 1
+			}
+		}
+		data, err := json.MarshalIndent(merged, "", "  ")
+		if err == nil {
+			err = os.WriteFile(*output, append(data, '\n'), 0o644)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// suiteBinaries resolves the Go and TML executables from the flags, or
+// else from the configuration relative to configDir
+func suiteBinaries(config BenchConfig, configDir, goFlag, tmlFlag string) (goBin, tmlBin suiteBinary, err error) {
+	resolve := func(flagPath, configured string) string {
+		if flagPath != "" {
+			return flagPath
+		}
+		if configured != "" && !filepath.IsAbs(configured) {
+			return filepath.Join(configDir, configured)
+		}
+		return configured
+	}
+	goBin = suiteBinary{lang: "go", path: resolve(goFlag, config.Binaries.Go)}
+	tmlBin = suiteBinary{lang: "tml", path: resolve(tmlFlag, config.Binaries.TML)}
+	if goBin.path == "" {
+		if goBin.path, err = os.Executable(); err != nil {
+			return goBin, tmlBin, err
+		}
+	}
+	if tmlBin.path == "" {
+		return goBin, tmlBin, errors.New("no TML binary: set binaries.tml in the configuration or pass -tml")
+	}
+	return goBin, tmlBin, nil
+}
+
+// benchNamePattern matches name, and the cases under it, as compare
+// matches names across suites
+func benchNamePattern(name string) string {
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	for i, r := range normalizeBenchName(name) {
+		if i > 0 {
+			b.WriteString("[ _-]*")
+		}
+		b.WriteString(regexp.QuoteMeta(string(r)))
+	}
+	b.WriteString("(/|$)")
+	return b.String()
+}
+
+// runSuiteBenchmark runs one benchmark of a suite, writing its outputs
+// next to prefix, and loads its results and raw samples
+func runSuiteBenchmark(bin suiteBinary, name, configPath, prefix string, samples bool) (ResultSet, map[string][]time.Duration, error) {
+	resultsPath, samplesPath := prefix+".json", prefix+".bin"
+	args := []string{"-run", benchNamePattern(name), "-o", resultsPath, "-quiet", "-config", configPath}
+	if samples {
+		args = append(args, "-raw-samples", samplesPath)
+	}
+	cmd := exec.Command(bin.path, args...)
+	cmd.Stdout = io.Discard
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return ResultSet{}, nil, err
+	}
+	set, err := LoadResultSet(resultsPath)
+	if err != nil {
+		return ResultSet{}, nil, err
+	}
+	// A suite without the benchmark returns no results, left unmatched
+	if !samples || len(set.Results) == 0 {
+		return set, nil, nil
+	}
+	samplesByCase, err := readRawSamples(samplesPath)
+	if err != nil {
+		return ResultSet{}, nil, fmt.Errorf("raw samples: %w", err)
+	}
+	return set, samplesByCase, nil
+}
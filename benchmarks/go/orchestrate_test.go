@@ -0,0 +1,46 @@
+// Suite Orchestration Tests - Go
+//
+// Run with: go test -run 'BenchNamePattern|SuiteBinaries'
+
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestBenchNamePattern(t *testing.T) {
+	re := regexp.MustCompile(benchNamePattern("TcpReusedRequest"))
+	for name, want := range map[string]bool{
+		"TcpReusedRequest":               true,
+		"tcp_reused_request":             true,
+		"TCP-Reused Request/payload=64":  true,
+		"TcpReusedRequestSweep":          false,
+		"UdpTcpReusedRequest":            false,
+		"TcpReused":                      false,
+		"TcpReusedRequest/conns=8/batch": true,
+	} {
+		if got := re.MatchString(name); got != want {
+			t.Errorf("%q matched %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestSuiteBinaries(t *testing.T) {
+	var config BenchConfig
+	config.Binaries.TML = "tml/bench"
+	goBin, tmlBin, err := suiteBinaries(config, "/bench", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmlBin.path != filepath.Join("/bench", "tml/bench") || goBin.path == "" {
+		t.Errorf("resolved go %q and tml %q", goBin.path, tmlBin.path)
+	}
+	if _, tmlBin, _ = suiteBinaries(config, "/bench", "", "/opt/tml"); tmlBin.path != "/opt/tml" {
+		t.Errorf("-tml did not override the configuration: %q", tmlBin.path)
+	}
+	if _, _, err := suiteBinaries(BenchConfig{}, "/bench", "", ""); err == nil {
+		t.Error("no TML binary configured, want an error")
+	}
+}
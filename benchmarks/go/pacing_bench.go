@@ -0,0 +1,144 @@
+// Go TCP Pacing Benchmark
+// Paces a loopback TCP upload to a target rate with a token-bucket writer,
+// the way TML's pacer is measured: for the configured stream duration
+// (durations.stream_seconds) the client writes as fast as the bucket
+// allows, and the rate is taken from what the in-process server actually
+// drained. Rates are in megabits per second (10^6 bits).
+//
+// Reported per target rate:
+//
+//   - achieved_mbps and rate_error_pct: accuracy, the signed deviation of
+//     the achieved rate from the target
+//   - cpu_pct: process CPU time (client and server) over wall time, the
+//     cost of pacing the stream - sleeping, refilling and writing
+//   - waits: how often the writer had to sleep for tokens
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// pacingBlock is the largest single write the paced client issues
+	pacingBlock = 16 * 1024
+	// pacingBurstWindow sizes the bucket: it holds this long's worth of
+	// tokens, so sleep overshoot is made up without bursting for longer
+	pacingBurstWindow = 10 * time.Millisecond
+)
+
+// pacedWriter is a token-bucket rate limiter in front of a writer. The
+// bucket starts empty, so the first write already waits.
+type pacedWriter struct {
+	w      io.Writer
+	clock  Clock
+	rate   float64 // bytes per second
+	burst  float64
+	tokens float64
+	last   time.Time
+	waits  int
+}
+
+func newPacedWriter(w io.Writer, bytesPerSec float64, clock Clock) *pacedWriter {
+	burst := max(bytesPerSec*pacingBurstWindow.Seconds(), pacingBlock)
+	return &pacedWriter{w: w, clock: clock, rate: bytesPerSec, burst: burst, last: clock.Now()}
+}
+
+// refill adds the tokens earned since the last refill
+func (p *pacedWriter) refill() {
+	now := p.clock.Now()
+	p.tokens = min(p.burst, p.tokens+now.Sub(p.last).Seconds()*p.rate)
+	p.last = now
+}
+
+// Write sends p in pieces no larger than the bucket, sleeping until each
+// piece is covered by tokens
+func (p *pacedWriter) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		n := min(len(b), int(p.burst))
+		p.refill()
+		if short := float64(n) - p.tokens; short > 0 {
+			p.waits++
+			p.clock.Sleep(time.Duration(short / p.rate * float64(time.Second)))
+			p.refill()
+		}
+		m, err := p.w.Write(b[:n])
+		p.tokens -= float64(m)
+		written += m
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
+
+// pacingResult is the outcome of one paced upload
+type pacingResult struct {
+	uploaded int64
+	elapsed  time.Duration
+	cpu      time.Duration
+	waits    int
+}
+
+// runPacedUpload streams to the server's upload sink at mbps for duration
+func (s *tcpStreamServer) runPacedUpload(mbps int, duration time.Duration) (pacingResult, error) {
+	var res pacingResult
+	conn, err := dialTCP(s.ln.Addr().String())
+	if err != nil {
+		return res, err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte{'u'}); err != nil {
+		return res, err
+	}
+
+	cpuStart := processCPUTime()
+	start := time.Now()
+	deadline := start.Add(duration)
+	w := newPacedWriter(conn, float64(mbps)*1e6/8, realClock{})
+	block := make([]byte, pacingBlock)
+	for time.Now().Before(deadline) {
+		if _, err := w.Write(block); err != nil {
+			return res, err
+		}
+	}
+	if err := conn.(interface{ CloseWrite() error }).CloseWrite(); err != nil {
+		return res, err
+	}
+	select {
+	case res.uploaded = <-s.drained:
+	case <-time.After(10 * time.Second):
+		return res, fmt.Errorf("server did not drain the upload")
+	}
+	res.elapsed = time.Since(start)
+	res.cpu = processCPUTime() - cpuStart
+	res.waits = w.waits
+	return res, nil
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "TcpPacing", Category: "tcp", Tags: []string{"net"},
+		Iterations: 1,
+		Axes:       []Axis{{Name: "rate_mbps", Values: Ints(1, 10, 100, 1000)}},
+		Setup:      setupTcpStream, Teardown: teardownTcpStream,
+		Fn: func(b *B) {
+			mbps := b.IntParam("rate_mbps")
+			res, err := tcpStream.runPacedUpload(mbps, benchConfig.Durations.Stream)
+			if err != nil {
+				b.Fatal(err)
+				return
+			}
+			b.SetBytes(res.uploaded)
+			achieved := float64(res.uploaded) * 8 / 1e6 / res.elapsed.Seconds()
+			b.ReportMetric("achieved_mbps", achieved)
+			b.ReportMetric("rate_error_pct", 100*(achieved-float64(mbps))/float64(mbps))
+			b.ReportMetric("cpu_pct", 100*res.cpu.Seconds()/res.elapsed.Seconds())
+			b.ReportMetric("waits", float64(res.waits))
+		},
+	})
+}
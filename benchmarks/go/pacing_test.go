@@ -0,0 +1,29 @@
+// TCP Pacing Tests - Go
+//
+// Run with: go test -run Paced
+
+package main
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestPacedWriterHoldsRate(t *testing.T) {
+	// 1MB at 8MB/s should take about 125ms
+	w := newPacedWriter(io.Discard, 8<<20, realClock{})
+	start := time.Now()
+	block := make([]byte, 64*1024)
+	for i := 0; i < 16; i++ {
+		if n, err := w.Write(block); err != nil || n != len(block) {
+			t.Fatalf("write %d: n %d err %v", i, n, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 110*time.Millisecond || elapsed > 300*time.Millisecond {
+		t.Errorf("1MB at 8MB/s took %v, want about 125ms", elapsed)
+	}
+	if w.waits == 0 {
+		t.Error("writer never waited for tokens")
+	}
+}
@@ -0,0 +1,388 @@
+// Parquet-Lite Encoding Benchmark - Go
+//
+// Writes and reads a simplified Parquet-style file for a 10M-row table:
+// rows are split into row groups, and each column chunk is stored with the
+// encoding that suits it - dictionary + RLE indices for the low-cardinality
+// region strings, RLE for the clustered status codes and plain little-endian
+// values for the amounts. Encode and decode MB/s are measured against the
+// plain in-memory size, and the file size is reported, for the
+// storage-format comparison with TML.
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+const (
+	parquetRows      = 10_000_000
+	parquetGroupRows = 1 << 20
+	parquetMagic     = "TMLPQ1\x00\x00"
+)
+
+// parquetTable is the dataset in its plain in-memory form
+type parquetTable struct {
+	Region []string
+	Status []uint8
+	Amount []float64
+}
+
+// plainSize is the byte size of the table's values without encoding
+func (t *parquetTable) plainSize() int64 {
+	n := int64(len(t.Status) + 8*len(t.Amount))
+	for _, s := range t.Region {
+		n += int64(len(s))
+	}
+	return n
+}
+
+func newParquetTable(rows int) *parquetTable {
+	return &parquetTable{
+		Region: make([]string, rows),
+		Status: make([]uint8, rows),
+		Amount: make([]float64, rows),
+	}
+}
+
+// generateParquetTable builds the dataset; regions and statuses come in
+// runs, as sorted or time-clustered data does in practice
+func generateParquetTable() *parquetTable {
+	regions := make([]string, columnarRegions)
+	for i := range regions {
+		regions[i] = fmt.Sprintf("region-%02d", i)
+	}
+	t := newParquetTable(parquetRows)
+	rng := newRand(1522)
+	var region string
+	var status uint8
+	regionLeft, statusLeft := 0, 0
+	for i := 0; i < parquetRows; i++ {
+		if regionLeft == 0 {
+			region, regionLeft = regions[rng.Intn(len(regions))], 1+rng.Intn(16)
+		}
+		if statusLeft == 0 {
+			status, statusLeft = uint8(rng.Intn(4)), 1+rng.Intn(64)
+		}
+		t.Region[i], t.Status[i] = region, status
+		t.Amount[i] = float64(rng.Intn(100_000)) / 100
+		regionLeft--
+		statusLeft--
+	}
+	return t
+}
+
+// appendRLE appends (run length, value) pairs for values
+func appendRLE(dst []byte, values []uint32) []byte {
+	for i := 0; i < len(values); {
+		j := i + 1
+		for j < len(values) && values[j] == values[i] {
+			j++
+		}
+		dst = binary.AppendUvarint(dst, uint64(j-i))
+		dst = binary.AppendUvarint(dst, uint64(values[i]))
+		i = j
+	}
+	return dst
+}
+
+// decodeRLE expands runs from src into dst, which must have room for
+// exactly the encoded number of values
+func decodeRLE(src []byte, dst []uint32) error {
+	out := 0
+	for len(src) > 0 {
+		run, n := binary.Uvarint(src)
+		if n <= 0 {
+			return errors.New("parquet-lite: bad run length")
+		}
+		src = src[n:]
+		v, n := binary.Uvarint(src)
+		if n <= 0 {
+			return errors.New("parquet-lite: bad run value")
+		}
+		src = src[n:]
+		if run > uint64(len(dst)-out) {
+			return errors.New("parquet-lite: run overflows column")
+		}
+		for end := out + int(run); out < end; out++ {
+			dst[out] = uint32(v)
+		}
+	}
+	if out != len(dst) {
+		return fmt.Errorf("parquet-lite: decoded %d of %d values", out, len(dst))
+	}
+	return nil
+}
+
+// parquetCodec holds the scratch buffers reused across row groups
+type parquetCodec struct {
+	chunk   []byte
+	indices []uint32
+	dict    map[string]uint32
+}
+
+func newParquetCodec() *parquetCodec {
+	return &parquetCodec{
+		chunk:   make([]byte, 0, 8*parquetGroupRows),
+		indices: make([]uint32, parquetGroupRows),
+		dict:    make(map[string]uint32),
+	}
+}
+
+// writeChunk writes one length-prefixed column chunk
+func writeChunk(w *bufio.Writer, chunk []byte) error {
+	var hdr [binary.MaxVarintLen64]byte
+	if _, err := w.Write(hdr[:binary.PutUvarint(hdr[:], uint64(len(chunk)))]); err != nil {
+		return err
+	}
+	_, err := w.Write(chunk)
+	return err
+}
+
+// encodeParquet writes t to path and returns the file size
+func (pc *parquetCodec) encodeParquet(t *parquetTable, path string) (int64, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	w := bufio.NewWriterSize(f, 1<<20)
+	w.WriteString(parquetMagic)
+	var hdr [binary.MaxVarintLen64]byte
+	for start := 0; start < len(t.Status); start += parquetGroupRows {
+		end := min(start+parquetGroupRows, len(t.Status))
+		n := end - start
+		w.Write(hdr[:binary.PutUvarint(hdr[:], uint64(n))])
+
+		// Region: dictionary of distinct strings, then RLE indices into it
+		clear(pc.dict)
+		var dictOrder []string
+		indices := pc.indices[:n]
+		for i, s := range t.Region[start:end] {
+			idx, ok := pc.dict[s]
+			if !ok {
+				idx = uint32(len(dictOrder))
+				pc.dict[s] = idx
+				dictOrder = append(dictOrder, s)
+			}
+			indices[i] = idx
+		}
+		chunk := binary.AppendUvarint(pc.chunk[:0], uint64(len(dictOrder)))
+		for _, s := range dictOrder {
+			chunk = binary.AppendUvarint(chunk, uint64(len(s)))
+			chunk = append(chunk, s...)
+		}
+		chunk = appendRLE(chunk, indices)
+		if err := writeChunk(w, chunk); err != nil {
+			return 0, err
+		}
+
+		// Status: RLE
+		for i, s := range t.Status[start:end] {
+			indices[i] = uint32(s)
+		}
+		chunk = appendRLE(chunk[:0], indices)
+		if err := writeChunk(w, chunk); err != nil {
+			return 0, err
+		}
+
+		// Amount: plain
+		chunk = chunk[:0]
+		for _, a := range t.Amount[start:end] {
+			chunk = binary.LittleEndian.AppendUint64(chunk, math.Float64bits(a))
+		}
+		if err := writeChunk(w, chunk); err != nil {
+			return 0, err
+		}
+		pc.chunk = chunk
+	}
+	w.Write(hdr[:binary.PutUvarint(hdr[:], 0)])
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), f.Close()
+}
+
+// readChunk reads one length-prefixed column chunk into the scratch buffer
+func (pc *parquetCodec) readChunk(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(cap(pc.chunk)) < size {
+		pc.chunk = make([]byte, size)
+	}
+	chunk := pc.chunk[:size]
+	_, err = io.ReadFull(r, chunk)
+	return chunk, err
+}
+
+// decodeParquet reads the file at path into t, which must be sized for
+// every row in the file
+func (pc *parquetCodec) decodeParquet(path string, t *parquetTable) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReaderSize(f, 1<<20)
+	magic := make([]byte, len(parquetMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return err
+	}
+	if string(magic) != parquetMagic {
+		return errors.New("parquet-lite: bad magic")
+	}
+	start := 0
+	for {
+		rows, err := binary.ReadUvarint(r)
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			break
+		}
+		n := int(rows)
+		if n > parquetGroupRows || start+n > len(t.Status) {
+			return fmt.Errorf("parquet-lite: row group of %d rows at row %d does not fit", n, start)
+		}
+		end := start + n
+		indices := pc.indices[:n]
+
+		chunk, err := pc.readChunk(r)
+		if err != nil {
+			return err
+		}
+		dictSize, k := binary.Uvarint(chunk)
+		if k <= 0 {
+			return errors.New("parquet-lite: bad dictionary size")
+		}
+		chunk = chunk[k:]
+		dict := make([]string, dictSize)
+		for i := range dict {
+			l, k := binary.Uvarint(chunk)
+			if k <= 0 || uint64(len(chunk)-k) < l {
+				return errors.New("parquet-lite: bad dictionary entry")
+			}
+			dict[i] = string(chunk[k : k+int(l)])
+			chunk = chunk[k+int(l):]
+		}
+		if err := decodeRLE(chunk, indices); err != nil {
+			return err
+		}
+		for i, idx := range indices {
+			if int(idx) >= len(dict) {
+				return errors.New("parquet-lite: dictionary index out of range")
+			}
+			t.Region[start+i] = dict[idx]
+		}
+
+		if chunk, err = pc.readChunk(r); err != nil {
+			return err
+		}
+		if err := decodeRLE(chunk, indices); err != nil {
+			return err
+		}
+		for i, v := range indices {
+			t.Status[start+i] = uint8(v)
+		}
+
+		if chunk, err = pc.readChunk(r); err != nil {
+			return err
+		}
+		if len(chunk) != 8*n {
+			return fmt.Errorf("parquet-lite: amount chunk is %d bytes, want %d", len(chunk), 8*n)
+		}
+		for i := range t.Amount[start:end] {
+			t.Amount[start+i] = math.Float64frombits(binary.LittleEndian.Uint64(chunk[8*i:]))
+		}
+		start = end
+	}
+	if start != len(t.Status) {
+		return fmt.Errorf("parquet-lite: file holds %d of %d rows", start, len(t.Status))
+	}
+	return nil
+}
+
+// parquetFixture is the source table, the file encoded from it and the
+// table decoding fills, shared by the encode and decode cases
+type parquetFixture struct {
+	dir      string
+	path     string
+	size     int64
+	table    *parquetTable
+	decoded  *parquetTable
+	codec    *parquetCodec
+	fileSize int64
+}
+
+var parquetState parquetFixture
+
+func setupParquet(b *B) error {
+	dir, err := os.MkdirTemp("", "tml-parquet-")
+	if err != nil {
+		return err
+	}
+	s := &parquetState
+	s.dir = dir
+	s.path = filepath.Join(dir, "table.pql")
+	s.table = generateParquetTable()
+	s.size = s.table.plainSize()
+	s.decoded = newParquetTable(parquetRows)
+	s.codec = newParquetCodec()
+	if s.fileSize, err = s.codec.encodeParquet(s.table, s.path); err != nil {
+		return err
+	}
+	// Round-trip once so a broken codec fails setup instead of timing garbage
+	if err := s.codec.decodeParquet(s.path, s.decoded); err != nil {
+		return err
+	}
+	for i := range s.table.Status {
+		if s.table.Region[i] != s.decoded.Region[i] || s.table.Status[i] != s.decoded.Status[i] ||
+			s.table.Amount[i] != s.decoded.Amount[i] {
+			return fmt.Errorf("parquet-lite: row %d does not round-trip", i)
+		}
+	}
+	return nil
+}
+
+func teardownParquet() {
+	if parquetState.dir != "" {
+		os.RemoveAll(parquetState.dir)
+	}
+	parquetState = parquetFixture{}
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "ParquetLite", Category: "columnar", Tags: []string{"cpu", "serde"},
+		Iterations: 5,
+		Axes:       []Axis{{Name: "op", Values: Strings("encode", "decode")}},
+		Setup:      setupParquet, Teardown: teardownParquet,
+		Fn: func(b *B) {
+			s := &parquetState
+			b.SetBytes(s.size)
+			if b.StringParam("op") == "encode" {
+				if _, err := s.codec.encodeParquet(s.table, s.path); err != nil {
+					b.Fatal(err)
+					return
+				}
+			} else if err := s.codec.decodeParquet(s.path, s.decoded); err != nil {
+				b.Fatal(err)
+				return
+			}
+			b.ReportMetric("file_mb", float64(s.fileSize)/(1<<20))
+			b.ReportMetric("compression_ratio", float64(s.size)/float64(s.fileSize))
+		},
+	})
+}
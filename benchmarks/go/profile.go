@@ -0,0 +1,54 @@
+// Profile Capture - Go
+//
+// With -profile dir, every case writes a CPU profile of its measured
+// iterations and a heap profile taken right after them, named after the
+// case so slow benchmarks can be explained against their TML counterparts.
+//
+// Inspect with: go tool pprof -http=: profiles/JsonParseLarge.cpu.pprof
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+)
+
+// caseFileName turns a case name like "TcpEcho/size=64" into a safe file
+// name stem like "TcpEcho_size-64"
+func caseFileName(name string) string {
+	return strings.NewReplacer("/", "_", "=", "-", " ", "_", ":", "-").Replace(name)
+}
+
+// profileHook returns a MeasureHook writing profiles into dir
+func profileHook(dir string) MeasureHook {
+	return func(c Case) (func(), error) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+		stem := filepath.Join(dir, caseFileName(c.Name))
+		cpu, err := os.Create(stem + ".cpu.pprof")
+		if err != nil {
+			return nil, err
+		}
+		if err := pprof.StartCPUProfile(cpu); err != nil {
+			cpu.Close()
+			return nil, err
+		}
+		return func() {
+			pprof.StopCPUProfile()
+			cpu.Close()
+
+			heap, err := os.Create(stem + ".heap.pprof")
+			if err != nil {
+				return
+			}
+			defer heap.Close()
+			// Heap profiles reflect the most recent GC
+			runtime.GC()
+			pprof.WriteHeapProfile(heap)
+		}, nil
+	}
+}
@@ -0,0 +1,68 @@
+// Progress Reporting - Go
+//
+// Long suites (the large-data and open-loop benchmarks run for minutes)
+// would otherwise look hung between result lines. Before each case a
+// progress line with the case count, elapsed time and an ETA extrapolated
+// from the cases finished so far goes to stderr, so redirected stdout
+// stays a clean results table. On a terminal the line is redrawn in place
+// and cleared before the result is printed.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// progress tracks how far through the selected cases a run is
+type progress struct {
+	w     io.Writer
+	tty   bool
+	total int
+	done  int
+	start time.Time
+}
+
+func newProgress(total int) *progress {
+	tty := false
+	if info, err := os.Stderr.Stat(); err == nil {
+		tty = info.Mode()&os.ModeCharDevice != 0
+	}
+	return &progress{w: os.Stderr, tty: tty, total: total, start: time.Now()}
+}
+
+// begin announces the case about to run
+func (p *progress) begin(name string) {
+	elapsed := time.Since(p.start)
+	eta := "?"
+	if p.done > 0 {
+		remaining := elapsed / time.Duration(p.done) * time.Duration(p.total-p.done)
+		eta = remaining.Round(time.Second).String()
+	}
+	line := fmt.Sprintf("[%d/%d] %s  elapsed %s  eta %s",
+		p.done+1, p.total, name, elapsed.Round(time.Second), eta)
+	if p.tty {
+		fmt.Fprintf(p.w, "\r\033[K%s", line)
+	} else {
+		fmt.Fprintln(p.w, line)
+	}
+}
+
+// end marks the current case finished and clears the progress line
+func (p *progress) end() {
+	p.done++
+	if p.tty {
+		fmt.Fprint(p.w, "\r\033[K")
+	}
+}
+
+// note prints a line about the current case, such as a soak snapshot,
+// below its progress line
+func (p *progress) note(line string) {
+	if p.tty {
+		fmt.Fprint(p.w, "\r\033[K")
+	}
+	fmt.Fprintln(p.w, line)
+}
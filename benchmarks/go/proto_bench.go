@@ -0,0 +1,594 @@
+// Protobuf Benchmarks - Go
+//
+// Binary serialization of the JSON fixtures, so serialization can be
+// compared across languages beyond JSON: ProtoParse<Size> and
+// ProtoMarshal<Size> decode and encode the small, medium and large
+// fixtures as the messages of ../common/fixtures.proto, from and into the
+// same structs as the typed JSON benchmarks (json_typed_bench.go).
+//
+// The suite has no third-party dependencies, so as with gRPC
+// (grpc_bench.go) the messages are encoded by hand rather than by
+// protoc-gen-go, the way generated code does it: Marshal computes the
+// size, allocates once and appends each field; Unmarshal walks the tags,
+// validates UTF-8 in strings as proto3 requires and skips unknown fields.
+// Zero scalars are left out as proto3 does; the nested messages (address,
+// metadata) are always written, since the fixtures always have them.
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"unicode/utf8"
+)
+
+// Protobuf wire types
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+var errProtoTruncated = errors.New("protobuf: truncated message")
+
+func protoTag(field, wire int) uint64 {
+	return uint64(field)<<3 | uint64(wire)
+}
+
+// protoVarintSize is the encoded size of v as a varint
+func protoVarintSize(v uint64) int {
+	return (bits.Len64(v|1) + 6) / 7
+}
+
+// protoInt32 is an int32 as protobuf encodes it, sign-extended to 64 bits
+func protoInt32(v int) uint64 {
+	return uint64(int64(int32(v)))
+}
+
+func protoBool(v bool) uint64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+func sizeVarintField(field int, v uint64) int {
+	if v == 0 {
+		return 0
+	}
+	return protoVarintSize(protoTag(field, protoVarint)) + protoVarintSize(v)
+}
+
+func appendVarintField(dst []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return dst
+	}
+	dst = binary.AppendUvarint(dst, protoTag(field, protoVarint))
+	return binary.AppendUvarint(dst, v)
+}
+
+// sizeBytesField is the size of a length-delimited field of n bytes
+func sizeBytesField(field, n int) int {
+	return protoVarintSize(protoTag(field, protoBytes)) + protoVarintSize(uint64(n)) + n
+}
+
+func sizeStringField(field int, s string) int {
+	if s == "" {
+		return 0
+	}
+	return sizeBytesField(field, len(s))
+}
+
+func appendStringField(dst []byte, field int, s string) []byte {
+	if s == "" {
+		return dst
+	}
+	dst = appendBytesHeader(dst, field, len(s))
+	return append(dst, s...)
+}
+
+// appendBytesHeader appends the tag and length of a length-delimited
+// field of n bytes
+func appendBytesHeader(dst []byte, field, n int) []byte {
+	dst = binary.AppendUvarint(dst, protoTag(field, protoBytes))
+	return binary.AppendUvarint(dst, uint64(n))
+}
+
+// protoDecoder reads the fields of one message
+type protoDecoder struct {
+	b []byte
+}
+
+func (d *protoDecoder) more() bool {
+	return len(d.b) > 0
+}
+
+// field reads the next field's tag
+func (d *protoDecoder) field() (num, wire int, err error) {
+	tag, err := d.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	if tag>>3 == 0 || tag>>3 > 1<<29-1 {
+		return 0, 0, fmt.Errorf("protobuf: invalid field number %d", tag>>3)
+	}
+	return int(tag >> 3), int(tag & 7), nil
+}
+
+func (d *protoDecoder) varint() (uint64, error) {
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		return 0, errProtoTruncated
+	}
+	d.b = d.b[n:]
+	return v, nil
+}
+
+// int32 reads a varint as an int32 field does, truncating to 32 bits
+func (d *protoDecoder) int32() (int, error) {
+	v, err := d.varint()
+	return int(int32(v)), err
+}
+
+func (d *protoDecoder) bool() (bool, error) {
+	v, err := d.varint()
+	return v != 0, err
+}
+
+// bytes reads a length-delimited field's contents, aliasing the message
+func (d *protoDecoder) bytes() ([]byte, error) {
+	n, err := d.varint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(d.b)) {
+		return nil, errProtoTruncated
+	}
+	v := d.b[:n]
+	d.b = d.b[n:]
+	return v, nil
+}
+
+func (d *protoDecoder) string() (string, error) {
+	b, err := d.bytes()
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(b) {
+		return "", errors.New("protobuf: string field is not valid UTF-8")
+	}
+	return string(b), nil
+}
+
+// skip skips the contents of a field of an unknown number
+func (d *protoDecoder) skip(wire int) error {
+	var n int
+	switch wire {
+	case protoVarint:
+		_, err := d.varint()
+		return err
+	case protoBytes:
+		_, err := d.bytes()
+		return err
+	case protoFixed64:
+		n = 8
+	case protoFixed32:
+		n = 4
+	default:
+		return fmt.Errorf("protobuf: unsupported wire type %d", wire)
+	}
+	if len(d.b) < n {
+		return errProtoTruncated
+	}
+	d.b = d.b[n:]
+	return nil
+}
+
+// wireError reports a known field arriving with the wrong wire type
+func wireError(msg string, num, wire int) error {
+	return fmt.Errorf("protobuf: %s field %d has wire type %d", msg, num, wire)
+}
+
+// Address
+
+func (a *jsonAddress) protoSize() int {
+	return sizeStringField(1, a.Street) + sizeStringField(2, a.City) + sizeStringField(3, a.Zip)
+}
+
+func (a *jsonAddress) appendProto(dst []byte) []byte {
+	dst = appendStringField(dst, 1, a.Street)
+	dst = appendStringField(dst, 2, a.City)
+	return appendStringField(dst, 3, a.Zip)
+}
+
+func (a *jsonAddress) unmarshalProto(data []byte) error {
+	d := protoDecoder{data}
+	for d.more() {
+		num, wire, err := d.field()
+		if err != nil {
+			return err
+		}
+		switch {
+		case num >= 1 && num <= 3 && wire != protoBytes:
+			return wireError("Address", num, wire)
+		case num == 1:
+			a.Street, err = d.string()
+		case num == 2:
+			a.City, err = d.string()
+		case num == 3:
+			a.Zip, err = d.string()
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Small
+
+// scoresSize is the size of the packed scores
+func (s *jsonSmall) scoresSize() int {
+	n := 0
+	for _, v := range s.Scores {
+		n += protoVarintSize(protoInt32(v))
+	}
+	return n
+}
+
+func (s *jsonSmall) protoSize() int {
+	n := sizeStringField(1, s.Name) + sizeVarintField(2, protoInt32(s.Age)) +
+		sizeVarintField(3, protoBool(s.Active)) + sizeStringField(4, s.Email)
+	if len(s.Scores) > 0 {
+		n += sizeBytesField(5, s.scoresSize())
+	}
+	return n + sizeBytesField(6, s.Address.protoSize())
+}
+
+func (s *jsonSmall) appendProto(dst []byte) []byte {
+	dst = appendStringField(dst, 1, s.Name)
+	dst = appendVarintField(dst, 2, protoInt32(s.Age))
+	dst = appendVarintField(dst, 3, protoBool(s.Active))
+	dst = appendStringField(dst, 4, s.Email)
+	if len(s.Scores) > 0 {
+		dst = appendBytesHeader(dst, 5, s.scoresSize())
+		for _, v := range s.Scores {
+			dst = binary.AppendUvarint(dst, protoInt32(v))
+		}
+	}
+	dst = appendBytesHeader(dst, 6, s.Address.protoSize())
+	return s.Address.appendProto(dst)
+}
+
+func (s *jsonSmall) unmarshalProto(data []byte) error {
+	d := protoDecoder{data}
+	for d.more() {
+		num, wire, err := d.field()
+		if err != nil {
+			return err
+		}
+		switch {
+		case (num == 1 || num == 4 || num == 6) && wire != protoBytes,
+			(num == 2 || num == 3) && wire != protoVarint,
+			num == 5 && wire != protoBytes && wire != protoVarint:
+			return wireError("Small", num, wire)
+		case num == 1:
+			s.Name, err = d.string()
+		case num == 2:
+			s.Age, err = d.int32()
+		case num == 3:
+			s.Active, err = d.bool()
+		case num == 4:
+			s.Email, err = d.string()
+		case num == 5 && wire == protoVarint:
+			// Parsers must accept repeated scalars unpacked too
+			var v int
+			v, err = d.int32()
+			s.Scores = append(s.Scores, v)
+		case num == 5:
+			var packed []byte
+			if packed, err = d.bytes(); err == nil {
+				err = s.unmarshalScores(packed)
+			}
+		case num == 6:
+			var msg []byte
+			if msg, err = d.bytes(); err == nil {
+				err = s.Address.unmarshalProto(msg)
+			}
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unmarshalScores appends packed int32 scores
+func (s *jsonSmall) unmarshalScores(packed []byte) error {
+	n := 0
+	for _, c := range packed {
+		if c < 0x80 {
+			n++
+		}
+	}
+	s.Scores = append(make([]int, 0, len(s.Scores)+n), s.Scores...)
+	d := protoDecoder{packed}
+	for d.more() {
+		v, err := d.int32()
+		if err != nil {
+			return err
+		}
+		s.Scores = append(s.Scores, v)
+	}
+	return nil
+}
+
+// User
+
+func (u *jsonUser) protoSize() int {
+	return sizeVarintField(1, protoInt32(u.ID)) + sizeStringField(2, u.Name) + sizeStringField(3, u.Email) +
+		sizeVarintField(4, protoBool(u.Active)) + sizeVarintField(5, protoInt32(u.Age))
+}
+
+func (u *jsonUser) appendProto(dst []byte) []byte {
+	dst = appendVarintField(dst, 1, protoInt32(u.ID))
+	dst = appendStringField(dst, 2, u.Name)
+	dst = appendStringField(dst, 3, u.Email)
+	dst = appendVarintField(dst, 4, protoBool(u.Active))
+	return appendVarintField(dst, 5, protoInt32(u.Age))
+}
+
+func (u *jsonUser) unmarshalProto(data []byte) error {
+	d := protoDecoder{data}
+	for d.more() {
+		num, wire, err := d.field()
+		if err != nil {
+			return err
+		}
+		switch {
+		case (num == 2 || num == 3) && wire != protoBytes,
+			(num == 1 || num == 4 || num == 5) && wire != protoVarint:
+			return wireError("User", num, wire)
+		case num == 1:
+			u.ID, err = d.int32()
+		case num == 2:
+			u.Name, err = d.string()
+		case num == 3:
+			u.Email, err = d.string()
+		case num == 4:
+			u.Active, err = d.bool()
+		case num == 5:
+			u.Age, err = d.int32()
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sizeUsers is the size of users as repeated field 1
+func sizeUsers(users []jsonUser) int {
+	n := 0
+	for i := range users {
+		n += sizeBytesField(1, users[i].protoSize())
+	}
+	return n
+}
+
+func appendUsers(dst []byte, users []jsonUser) []byte {
+	for i := range users {
+		dst = appendBytesHeader(dst, 1, users[i].protoSize())
+		dst = users[i].appendProto(dst)
+	}
+	return dst
+}
+
+// unmarshalUser appends the User message msg to users
+func unmarshalUser(users []jsonUser, msg []byte) ([]jsonUser, error) {
+	users = append(users, jsonUser{})
+	return users, users[len(users)-1].unmarshalProto(msg)
+}
+
+// Medium
+
+func (m *jsonMedium) metadataSize() int {
+	md := &m.Metadata
+	return sizeVarintField(1, protoInt32(md.Total)) + sizeVarintField(2, protoInt32(md.Page)) +
+		sizeVarintField(3, protoInt32(md.PerPage)) + sizeVarintField(4, protoBool(md.HasMore))
+}
+
+func (m *jsonMedium) protoSize() int {
+	return sizeUsers(m.Users) + sizeBytesField(2, m.metadataSize())
+}
+
+func (m *jsonMedium) appendProto(dst []byte) []byte {
+	dst = appendUsers(dst, m.Users)
+	md := &m.Metadata
+	dst = appendBytesHeader(dst, 2, m.metadataSize())
+	dst = appendVarintField(dst, 1, protoInt32(md.Total))
+	dst = appendVarintField(dst, 2, protoInt32(md.Page))
+	dst = appendVarintField(dst, 3, protoInt32(md.PerPage))
+	return appendVarintField(dst, 4, protoBool(md.HasMore))
+}
+
+func (m *jsonMedium) unmarshalProto(data []byte) error {
+	d := protoDecoder{data}
+	for d.more() {
+		num, wire, err := d.field()
+		if err != nil {
+			return err
+		}
+		if (num == 1 || num == 2) && wire != protoBytes {
+			return wireError("Medium", num, wire)
+		}
+		var msg []byte
+		switch num {
+		case 1:
+			if msg, err = d.bytes(); err == nil {
+				m.Users, err = unmarshalUser(m.Users, msg)
+			}
+		case 2:
+			if msg, err = d.bytes(); err == nil {
+				err = m.unmarshalMetadata(msg)
+			}
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *jsonMedium) unmarshalMetadata(data []byte) error {
+	md := &m.Metadata
+	d := protoDecoder{data}
+	for d.more() {
+		num, wire, err := d.field()
+		if err != nil {
+			return err
+		}
+		if num >= 1 && num <= 4 && wire != protoVarint {
+			return wireError("Medium.Metadata", num, wire)
+		}
+		switch num {
+		case 1:
+			md.Total, err = d.int32()
+		case 2:
+			md.Page, err = d.int32()
+		case 3:
+			md.PerPage, err = d.int32()
+		case 4:
+			md.HasMore, err = d.bool()
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Large
+
+func (l *jsonLarge) protoSize() int {
+	return sizeUsers(l.Users) + sizeBytesField(2, sizeVarintField(1, protoInt32(l.Metadata.Count)))
+}
+
+func (l *jsonLarge) appendProto(dst []byte) []byte {
+	dst = appendUsers(dst, l.Users)
+	count := protoInt32(l.Metadata.Count)
+	dst = appendBytesHeader(dst, 2, sizeVarintField(1, count))
+	return appendVarintField(dst, 1, count)
+}
+
+func (l *jsonLarge) unmarshalProto(data []byte) error {
+	d := protoDecoder{data}
+	for d.more() {
+		num, wire, err := d.field()
+		if err != nil {
+			return err
+		}
+		if (num == 1 || num == 2) && wire != protoBytes {
+			return wireError("Large", num, wire)
+		}
+		var msg []byte
+		switch num {
+		case 1:
+			if msg, err = d.bytes(); err == nil {
+				l.Users, err = unmarshalUser(l.Users, msg)
+			}
+		case 2:
+			if msg, err = d.bytes(); err == nil {
+				err = l.unmarshalMetadata(msg)
+			}
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *jsonLarge) unmarshalMetadata(data []byte) error {
+	d := protoDecoder{data}
+	for d.more() {
+		num, wire, err := d.field()
+		if err != nil {
+			return err
+		}
+		switch {
+		case num == 1 && wire != protoVarint:
+			return wireError("Large.Metadata", num, wire)
+		case num == 1:
+			l.Metadata.Count, err = d.int32()
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// protoMessage is a fixture struct with a hand-written protobuf codec
+type protoMessage[T any] interface {
+	*T
+	protoSize() int
+	appendProto(dst []byte) []byte
+	unmarshalProto(data []byte) error
+}
+
+// marshalProto encodes m the way proto.Marshal does: sized, then
+// appended into one exact allocation
+func marshalProto[T any, P protoMessage[T]](m P) []byte {
+	return m.appendProto(make([]byte, 0, m.protoSize()))
+}
+
+// registerProto registers the Parse and Marshal benchmarks of one
+// fixture: the JSON fixture decoded into T, encoded as its message
+func registerProto[T any, P protoMessage[T]](size string, fixture []byte, iterations int64) {
+	var value T
+	if err := decodeJSONStrict(fixture, &value); err != nil {
+		panic("json fixture " + size + ": " + err.Error())
+	}
+	encoded := marshalProto[T, P](&value)
+	Register(Benchmark{
+		Name: "ProtoParse" + size, Category: "protobuf", Tags: []string{"serde", "alloc"},
+		Iterations: iterations, DataSize: int64(len(encoded)),
+		Fn: func(b *B) {
+			var obj T
+			if err := P(&obj).unmarshalProto(encoded); err != nil {
+				b.Fatal(err)
+			}
+		},
+	})
+	Register(Benchmark{
+		Name: "ProtoMarshal" + size, Category: "protobuf", Tags: []string{"serde", "alloc"},
+		Iterations: iterations, DataSize: int64(len(encoded)),
+		Fn: func(b *B) {
+			marshalProto[T, P](&value)
+		},
+	})
+}
+
+func init() {
+	registerProto[jsonSmall]("Small", GenerateSmallJSON(), 10000)
+	registerProto[jsonMedium]("Medium", GenerateMediumJSON(), 5000)
+	registerProto[jsonLarge]("Large", GenerateLargeJSON(), 100)
+}
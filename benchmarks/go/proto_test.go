@@ -0,0 +1,79 @@
+// Protobuf Tests - Go
+//
+// Run with: go test -run Proto
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestProtoFixturesRoundTrip(t *testing.T) {
+	var small, smallBack jsonSmall
+	var medium, mediumBack jsonMedium
+	var large, largeBack jsonLarge
+	decodeJSONStrict(GenerateSmallJSON(), &small)
+	decodeJSONStrict(GenerateMediumJSON(), &medium)
+	decodeJSONStrict(GenerateLargeJSON(), &large)
+
+	if err := smallBack.unmarshalProto(marshalProto[jsonSmall](&small)); err != nil || !reflect.DeepEqual(smallBack, small) {
+		t.Errorf("small decoded as %+v, %v", smallBack, err)
+	}
+	if err := mediumBack.unmarshalProto(marshalProto[jsonMedium](&medium)); err != nil || !reflect.DeepEqual(mediumBack, medium) {
+		t.Errorf("medium decoded as %+v, %v", mediumBack, err)
+	}
+	if err := largeBack.unmarshalProto(marshalProto[jsonLarge](&large)); err != nil || !reflect.DeepEqual(largeBack, large) {
+		t.Errorf("large decoded as %+v, %v", largeBack, err)
+	}
+}
+
+func TestProtoWireFormat(t *testing.T) {
+	// As protoc-generated code encodes Small{name: "a", age: -1, scores:
+	// [1, 300], address: {zip: "z"}}: zero fields left out, int32 sign
+	// extended to ten bytes, scores packed
+	s := jsonSmall{Name: "a", Age: -1, Scores: []int{1, 300}, Address: jsonAddress{Zip: "z"}}
+	want := []byte{
+		0x0a, 0x01, 'a',
+		0x10, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01,
+		0x2a, 0x03, 0x01, 0xac, 0x02,
+		0x32, 0x03, 0x1a, 0x01, 'z',
+	}
+	if got := marshalProto[jsonSmall](&s); !bytes.Equal(got, want) {
+		t.Errorf("encoded as % x, want % x", got, want)
+	}
+}
+
+func TestProtoParseAcceptsUnpackedAndUnknownFields(t *testing.T) {
+	msg := []byte{
+		0x28, 0x07, // scores: 7, unpacked
+		0x28, 0x08, // scores: 8, unpacked
+		0x78, 0x05, // field 15 varint: unknown
+		0x7a, 0x02, 'x', 'y', // field 15 bytes: unknown
+		0x7d, 0, 0, 0, 0, // field 15 fixed32: unknown
+		0x10, 0x1e, // age: 30
+	}
+	var s jsonSmall
+	if err := s.unmarshalProto(msg); err != nil {
+		t.Fatal(err)
+	}
+	if s.Age != 30 || !reflect.DeepEqual(s.Scores, []int{7, 8}) {
+		t.Errorf("decoded as %+v", s)
+	}
+}
+
+func TestProtoParseRejectsMalformed(t *testing.T) {
+	for name, msg := range map[string][]byte{
+		"truncated string": {0x0a, 0x05, 'a'},
+		"truncated varint": {0x10, 0xff},
+		"wrong wire type":  {0x0d, 0, 0, 0, 0},
+		"invalid UTF-8":    {0x0a, 0x01, 0xff},
+		"field zero":       {0x00, 0x01},
+	} {
+		var s jsonSmall
+		if err := s.unmarshalProto(msg); err == nil {
+			t.Errorf("%s: decoded without error", name)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Streaming Quantiles - Go
+//
+// Constant-memory estimators for streams too long to keep: a uniform
+// reservoir sample (Vitter's Algorithm L, which draws random numbers only
+// when a sample is replaced) and the P² estimator of Jain and Chlamtac,
+// which tracks one quantile with five markers. Soak and open-loop runs can
+// use these where even a histogram per window is too much.
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// Reservoir keeps a uniform random sample of a stream
+type Reservoir struct {
+	sample []float64
+	size   int
+	seen   int64
+	next   int64
+	w      float64
+	rng    *rand.Rand
+}
+
+// NewReservoir returns a reservoir holding up to size values, sampling with
+// newRand(seed)
+func NewReservoir(size int, seed int64) *Reservoir {
+	return &Reservoir{sample: make([]float64, 0, size), size: size, rng: newRand(seed)}
+}
+
+// Add offers one value to the sample
+func (r *Reservoir) Add(v float64) {
+	r.seen++
+	if len(r.sample) < r.size {
+		r.sample = append(r.sample, v)
+		if len(r.sample) == r.size {
+			r.w = math.Exp(math.Log(r.rng.Float64()) / float64(r.size))
+			r.skip()
+		}
+		return
+	}
+	if r.seen == r.next {
+		r.sample[r.rng.Intn(r.size)] = v
+		r.w *= math.Exp(math.Log(r.rng.Float64()) / float64(r.size))
+		r.skip()
+	}
+}
+
+// skip draws the position of the next value that enters the sample
+func (r *Reservoir) skip() {
+	r.next = r.seen + int64(math.Floor(math.Log(r.rng.Float64())/math.Log1p(-r.w))) + 1
+}
+
+// Sample returns the current sample; it is shared, not copied
+func (r *Reservoir) Sample() []float64 {
+	return r.sample
+}
+
+// sampleQuantile returns quantile p (0-1) of values by sorting a copy
+func sampleQuantile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(i, 0)]
+}
+
+// P2Quantile estimates a single quantile of a stream in constant space
+type P2Quantile struct {
+	p     float64
+	count int
+	q     [5]float64 // marker heights
+	n     [5]float64 // marker positions
+	np    [5]float64 // desired marker positions
+	dn    [5]float64 // desired position increments
+}
+
+// NewP2Quantile returns an estimator for quantile p (0-1)
+func NewP2Quantile(p float64) *P2Quantile {
+	return &P2Quantile{
+		p:  p,
+		np: [5]float64{0, 2 * p, 4 * p, 2 + 2*p, 4},
+		dn: [5]float64{0, p / 2, p, (1 + p) / 2, 1},
+		n:  [5]float64{0, 1, 2, 3, 4},
+	}
+}
+
+// Add records one observation
+func (e *P2Quantile) Add(x float64) {
+	if e.count < 5 {
+		e.q[e.count] = x
+		e.count++
+		if e.count == 5 {
+			slices.Sort(e.q[:])
+		}
+		return
+	}
+	e.count++
+
+	var k int
+	switch {
+	case x < e.q[0]:
+		e.q[0] = x
+		k = 0
+	case x >= e.q[4]:
+		e.q[4] = x
+		k = 3
+	default:
+		for k = 0; x >= e.q[k+1]; k++ {
+		}
+	}
+	for i := k + 1; i < 5; i++ {
+		e.n[i]++
+	}
+	for i := range e.np {
+		e.np[i] += e.dn[i]
+	}
+
+	// Move the middle markers toward their desired positions
+	for i := 1; i <= 3; i++ {
+		d := e.np[i] - e.n[i]
+		if (d >= 1 && e.n[i+1]-e.n[i] > 1) || (d <= -1 && e.n[i-1]-e.n[i] < -1) {
+			s := math.Copysign(1, d)
+			q := e.parabolic(i, s)
+			if e.q[i-1] < q && q < e.q[i+1] {
+				e.q[i] = q
+			} else {
+				j := i + int(s)
+				e.q[i] += s * (e.q[j] - e.q[i]) / (e.n[j] - e.n[i])
+			}
+			e.n[i] += s
+		}
+	}
+}
+
+// parabolic is the piecewise-parabolic prediction for marker i moved by s
+func (e *P2Quantile) parabolic(i int, s float64) float64 {
+	q, n := &e.q, &e.n
+	return q[i] + s/(n[i+1]-n[i-1])*
+		((n[i]-n[i-1]+s)*(q[i+1]-q[i])/(n[i+1]-n[i])+
+			(n[i+1]-n[i]-s)*(q[i]-q[i-1])/(n[i]-n[i-1]))
+}
+
+// Quantile returns the current estimate
+func (e *P2Quantile) Quantile() float64 {
+	if e.count < 5 {
+		return sampleQuantile(e.q[:e.count], e.p)
+	}
+	return e.q[2]
+}
@@ -0,0 +1,137 @@
+// Streaming Quantile Benchmarks - Go
+//
+// Feeds 100M exponentially distributed values (a latency-like shape) to
+// reservoir samplers and P² estimators and reports updates/sec together
+// with the relative error of p50, p99 and p99.9 against the exact values.
+// The stream cycles a 1M-value fixture, so its exact quantiles are those of
+// the fixture. Reservoir sampling compares the textbook Algorithm R, one
+// random draw per value, with the skip-based Algorithm L of quantile.go.
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	quantileStream      = 100_000_000
+	quantileFixtureSize = 1_000_000
+	quantileSampleSize  = 10_000
+)
+
+// quantileTargets are the quantiles whose error is reported
+var quantileTargets = []struct {
+	p    float64
+	name string
+}{
+	{0.5, "p50"},
+	{0.99, "p99"},
+	{0.999, "p99.9"},
+}
+
+// quantileFixture is the cycled stream and its exact target quantiles
+var quantileFixture = sync.OnceValues(func() ([]float64, []float64) {
+	rng := newRand(1525)
+	values := make([]float64, quantileFixtureSize)
+	for i := range values {
+		values[i] = rng.ExpFloat64()
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	exact := make([]float64, len(quantileTargets))
+	for i, t := range quantileTargets {
+		exact[i] = sorted[int(math.Ceil(t.p*quantileFixtureSize))-1]
+	}
+	return values, exact
+})
+
+// reservoirR is Algorithm R: every value past the first size draws a
+// random slot, the baseline Algorithm L improves on
+type reservoirR struct {
+	sample []float64
+	size   int
+	seen   int64
+	rng    *rand.Rand
+}
+
+func (r *reservoirR) Add(v float64) {
+	r.seen++
+	if len(r.sample) < r.size {
+		r.sample = append(r.sample, v)
+		return
+	}
+	if j := r.rng.Int63n(r.seen); j < int64(r.size) {
+		r.sample[j] = v
+	}
+}
+
+// reportQuantileErrors reports each estimate's error in percent of exact
+func reportQuantileErrors(b *B, estimate func(i int, p float64) float64) {
+	_, exact := quantileFixture()
+	for i, t := range quantileTargets {
+		b.ReportMetric(t.name+"_err_pct", math.Abs(estimate(i, t.p)-exact[i])/exact[i]*100)
+	}
+}
+
+// feedQuantileStream adds the full stream to add and reports updates/sec
+func feedQuantileStream(b *B, add func(v float64)) {
+	values, _ := quantileFixture()
+	start := time.Now()
+	for n := 0; n < quantileStream; n += len(values) {
+		for _, v := range values {
+			add(v)
+		}
+	}
+	b.ReportMetric("updates_per_sec", quantileStream/time.Since(start).Seconds())
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "QuantileReservoir", Category: "quantile", Tags: []string{"cpu"},
+		Iterations: 3,
+		Axes:       []Axis{{Name: "algorithm", Values: Strings("R", "L")}},
+		Setup: func(b *B) error {
+			quantileFixture()
+			return nil
+		},
+		Fn: func(b *B) {
+			var add func(v float64)
+			var sample func() []float64
+			if b.StringParam("algorithm") == "R" {
+				r := &reservoirR{size: quantileSampleSize, rng: newRand(1)}
+				add, sample = r.Add, func() []float64 { return r.sample }
+			} else {
+				r := NewReservoir(quantileSampleSize, 1)
+				add, sample = r.Add, r.Sample
+			}
+			feedQuantileStream(b, add)
+			reportQuantileErrors(b, func(_ int, p float64) float64 { return sampleQuantile(sample(), p) })
+		},
+	})
+	Register(Benchmark{
+		Name: "QuantileP2", Category: "quantile", Tags: []string{"cpu"},
+		Iterations: 3,
+		Setup: func(b *B) error {
+			quantileFixture()
+			return nil
+		},
+		Fn: func(b *B) {
+			estimators := make([]*P2Quantile, len(quantileTargets))
+			for i, t := range quantileTargets {
+				estimators[i] = NewP2Quantile(t.p)
+			}
+			// One update feeds every tracked quantile, as a latency
+			// monitor would
+			feedQuantileStream(b, func(v float64) {
+				for _, e := range estimators {
+					e.Add(v)
+				}
+			})
+			reportQuantileErrors(b, func(i int, _ float64) float64 { return estimators[i].Quantile() })
+		},
+	})
+}
@@ -0,0 +1,45 @@
+// Streaming Quantile Tests - Go
+//
+// Run with: go test -run 'P2|Reservoir'
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestP2QuantileExponential(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, p := range []float64{0.5, 0.9, 0.99} {
+		e := NewP2Quantile(p)
+		for i := 0; i < 200_000; i++ {
+			e.Add(rng.ExpFloat64())
+		}
+		want := -math.Log(1 - p)
+		if got := e.Quantile(); math.Abs(got-want)/want > 0.02 {
+			t.Errorf("p%v = %v, want %v within 2%%", p*100, got, want)
+		}
+	}
+}
+
+func TestReservoirUniform(t *testing.T) {
+	// Every position of a stream should be equally likely to be sampled
+	const size, stream = 100, 10_000
+	r := NewReservoir(size, 1)
+	for i := 0; i < stream; i++ {
+		r.Add(float64(i))
+	}
+	if len(r.Sample()) != size {
+		t.Fatalf("sample holds %d values, want %d", len(r.Sample()), size)
+	}
+	mean := 0.0
+	for _, v := range r.Sample() {
+		mean += v / size
+	}
+	// The sample mean of a uniform draw from 0..9999 has sd ~ 290
+	if math.Abs(mean-stream/2) > 1500 {
+		t.Errorf("sample mean %v, want about %v", mean, stream/2)
+	}
+}
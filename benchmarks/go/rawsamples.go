@@ -0,0 +1,189 @@
+// Raw Samples - Go
+//
+// With -raw-samples path, the timed duration of every measured iteration
+// is written out for offline statistical analysis (bootstrap confidence
+// intervals, distribution fitting), in the order the iterations ran.
+// -raw-samples-run limits recording to the cases whose name matches; the
+// others run without the extra clock reads.
+//
+// The format follows the file extension: .csv writes "benchmark,iteration,ns"
+// rows, anything else the compact binary layout
+//
+//	"TMLRAW01" then per case: name length u16 | name | count u64 | count x ns u64
+//
+// with integers little endian.
+//
+// Run with: go run . -run Json -raw-samples samples.csv
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const rawSamplesMagic = "TMLRAW01"
+
+// rawSampleWriter streams the samples of each recorded case to a file
+type rawSampleWriter struct {
+	f     *os.File
+	w     *bufio.Writer
+	csv   *csv.Writer
+	match *regexp.Regexp
+}
+
+// rawSamples is the run's writer, or nil when raw samples are off
+var rawSamples *rawSampleWriter
+
+// isCSVPath reports whether path selects the CSV format
+func isCSVPath(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".csv")
+}
+
+// openRawSamples creates the samples file at path, recording cases whose
+// name matches pattern
+func openRawSamples(path, pattern string) (*rawSampleWriter, error) {
+	match, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -raw-samples-run pattern: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &rawSampleWriter{f: f, w: bufio.NewWriter(f), match: match}
+	if isCSVPath(path) {
+		s.csv = csv.NewWriter(s.w)
+		err = s.csv.Write([]string{"benchmark", "iteration", "ns"})
+	} else {
+		_, err = s.w.WriteString(rawSamplesMagic)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// rawSamplesFor returns an empty sample buffer if the case is recorded,
+// or nil
+func rawSamplesFor(c Case) []time.Duration {
+	if rawSamples == nil || !rawSamples.match.MatchString(c.Name) {
+		return nil
+	}
+	return make([]time.Duration, 0, c.Bench.Iterations)
+}
+
+// write appends one case's samples
+func (s *rawSampleWriter) write(name string, samples []time.Duration) error {
+	if s.csv != nil {
+		for i, d := range samples {
+			if err := s.csv.Write([]string{name, strconv.Itoa(i), strconv.FormatInt(d.Nanoseconds(), 10)}); err != nil {
+				return err
+			}
+		}
+		s.csv.Flush()
+		return s.csv.Error()
+	}
+	if len(name) > 0xffff {
+		return fmt.Errorf("case name too long for raw samples: %d bytes", len(name))
+	}
+	var buf [8]byte
+	binary.LittleEndian.PutUint16(buf[:], uint16(len(name)))
+	s.w.Write(buf[:2])
+	s.w.WriteString(name)
+	binary.LittleEndian.PutUint64(buf[:], uint64(len(samples)))
+	s.w.Write(buf[:])
+	for _, d := range samples {
+		binary.LittleEndian.PutUint64(buf[:], uint64(d.Nanoseconds()))
+		if _, err := s.w.Write(buf[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close flushes and closes the file
+func (s *rawSampleWriter) Close() error {
+	if err := s.w.Flush(); err != nil {
+		s.f.Close()
+		return err
+	}
+	return s.f.Close()
+}
+
+// readRawSamples loads a samples file in either format, keyed by case name
+func readRawSamples(path string) (map[string][]time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	if isCSVPath(path) {
+		return readRawSamplesCSV(r)
+	}
+	return readRawSamplesBinary(r)
+}
+
+func readRawSamplesCSV(r io.Reader) (map[string][]time.Duration, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 || strings.Join(records[0], ",") != "benchmark,iteration,ns" {
+		return nil, errors.New("raw samples: missing CSV header")
+	}
+	samples := map[string][]time.Duration{}
+	for i, rec := range records[1:] {
+		ns, err := strconv.ParseInt(rec[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("raw samples: row %d: %w", i+2, err)
+		}
+		samples[rec[0]] = append(samples[rec[0]], time.Duration(ns))
+	}
+	return samples, nil
+}
+
+func readRawSamplesBinary(r io.Reader) (map[string][]time.Duration, error) {
+	magic := make([]byte, len(rawSamplesMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != rawSamplesMagic {
+		return nil, errors.New("raw samples: not a raw samples file")
+	}
+	samples := map[string][]time.Duration{}
+	var buf [8]byte
+	for {
+		if _, err := io.ReadFull(r, buf[:2]); err != nil {
+			if err == io.EOF {
+				return samples, nil
+			}
+			return nil, err
+		}
+		name := make([]byte, binary.LittleEndian.Uint16(buf[:]))
+		if _, err := io.ReadFull(r, name); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, err
+		}
+		count := binary.LittleEndian.Uint64(buf[:])
+		var durations []time.Duration
+		for i := uint64(0); i < count; i++ {
+			if _, err := io.ReadFull(r, buf[:]); err != nil {
+				return nil, fmt.Errorf("raw samples: %s truncated: %w", name, err)
+			}
+			durations = append(durations, time.Duration(binary.LittleEndian.Uint64(buf[:])))
+		}
+		samples[string(name)] = append(samples[string(name)], durations...)
+	}
+}
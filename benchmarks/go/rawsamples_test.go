@@ -0,0 +1,73 @@
+// Raw Samples Tests - Go
+//
+// Run with: go test -run RawSamples
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRawSamplesRoundTrip(t *testing.T) {
+	samples := []time.Duration{1500, 2 * time.Millisecond, 0, 42}
+	for _, name := range []string{"samples.bin", "samples.csv"} {
+		path := filepath.Join(t.TempDir(), name)
+		w, err := openRawSamples(path, ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.write("Json/size=small,x", samples); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.write("Other", samples[:1]); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readRawSamples(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(got) != 2 || len(got["Other"]) != 1 || len(got["Json/size=small,x"]) != len(samples) {
+			t.Fatalf("%s: read %v", name, got)
+		}
+		for i, d := range samples {
+			if got["Json/size=small,x"][i] != d {
+				t.Errorf("%s: sample %d = %v, want %v", name, i, got["Json/size=small,x"][i], d)
+			}
+		}
+	}
+}
+
+func TestRawSamplesRecordsMatchingCases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "samples.bin")
+	w, err := openRawSamples(path, "^Recorded$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawSamples = w
+	defer func() { rawSamples = nil }()
+
+	sleep := func(b *B) { time.Sleep(time.Millisecond) }
+	RunCase(Case{Name: "Recorded", Bench: &Benchmark{Name: "Recorded", Iterations: 5, Fn: sleep}})
+	RunCase(Case{Name: "Skipped", Bench: &Benchmark{Name: "Skipped", Iterations: 5, Fn: sleep}})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readRawSamples(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || len(got["Recorded"]) != 5 {
+		t.Fatalf("recorded %v, want 5 samples of Recorded only", got)
+	}
+	for i, d := range got["Recorded"] {
+		if d < time.Millisecond {
+			t.Errorf("sample %d = %v, shorter than the iteration's sleep", i, d)
+		}
+	}
+}
@@ -0,0 +1,483 @@
+// Traffic Recording and Replay - Go
+//
+// With -record path, every TCP connection a matching case opens through
+// dialTCP is recorded from the client's side: when it was opened, each
+// write with its bytes and each read with its size, all timestamped from
+// the start of the case. -record-run limits recording to the cases whose
+// name matches, as -raw-samples-run does; the recording adds a copy per
+// write to the recorded run's own numbers.
+//
+// The replay subcommand plays a recording back as a client load profile:
+// each connection is dialed and its writes sent on the recorded schedule
+// (scaled by -speed), and each read waits for the recorded number of
+// bytes. Replies are timed from the write before them, so a replayed
+// session reports the latency the server shows under that traffic. It
+// replays against the TCP echo server, which answers echo sessions
+// byte-for-byte, or against any server given with -addr - the TML suite's
+// among them, since both suites read and write the same format:
+//
+//	"TMLREC01" then per session:
+//	  name length u16 | name | truncated u8 | connection count u32
+//	  per connection: opened ns u64 | event count u64
+//	  per event:      kind u8 (0 write, 1 read) | at ns u64 | length u32 | bytes (writes only)
+//
+// with integers little endian. A session is truncated, its remaining
+// traffic dropped, once it holds recordMaxBytes.
+//
+// Run with: go run . -run TcpReusedRequest -record session.rec
+//      and: go run . replay [-addr host:port] [-speed 2] [-run 'Tcp.*'] session.rec
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const (
+	recordMagic = "TMLREC01"
+	// recordMaxBytes caps one session's recorded payload and event headers
+	recordMaxBytes = 64 << 20
+	// replayReadTimeout bounds each replayed read, so a server answering
+	// differently from the recorded one fails instead of hanging
+	replayReadTimeout = 5 * time.Second
+
+	recordWrite = 0
+	recordRead  = 1
+)
+
+// recordEvent is one write or read on a recorded connection
+type recordEvent struct {
+	kind byte
+	at   time.Duration
+	n    int
+	data []byte // writes only
+}
+
+// recordedConn is the traffic of one connection
+type recordedConn struct {
+	opened time.Duration
+	events []recordEvent
+}
+
+// recordedSession is the traffic of one case
+type recordedSession struct {
+	name      string
+	truncated bool
+	conns     []*recordedConn
+}
+
+// recorder writes the sessions of the recorded cases to a file
+type recorder struct {
+	f     *os.File
+	w     *bufio.Writer
+	match *regexp.Regexp
+	err   error
+
+	mu      sync.Mutex
+	session *recordingSession
+}
+
+// recordingSession is the case being recorded
+type recordingSession struct {
+	start time.Time
+
+	mu   sync.Mutex
+	rec  recordedSession
+	size int
+}
+
+// traffic is the run's recorder, or nil when recording is off
+var traffic *recorder
+
+// openRecorder creates the recording file at path, recording cases whose
+// name matches pattern
+func openRecorder(path, pattern string) (*recorder, error) {
+	match, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -record-run pattern: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &recorder{f: f, w: bufio.NewWriter(f), match: match}
+	if _, err := r.w.WriteString(recordMagic); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// startRecording begins recording c if it is recorded, returning the
+// function that ends it
+func startRecording(c Case) func() {
+	r := traffic
+	if r == nil || !r.match.MatchString(c.Name) {
+		return func() {}
+	}
+	s := &recordingSession{start: time.Now(), rec: recordedSession{name: c.Name}}
+	r.mu.Lock()
+	r.session = s
+	r.mu.Unlock()
+	return func() {
+		r.mu.Lock()
+		r.session = nil
+		r.mu.Unlock()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err := writeRecordedSession(r.w, &s.rec); err != nil && r.err == nil {
+			r.err = err
+		}
+	}
+}
+
+// recordConn returns conn, wrapped to record its traffic if a case is
+// being recorded
+func recordConn(conn net.Conn) net.Conn {
+	r := traffic
+	if r == nil {
+		return conn
+	}
+	r.mu.Lock()
+	s := r.session
+	r.mu.Unlock()
+	if s == nil {
+		return conn
+	}
+	rc := &recordedConn{opened: time.Since(s.start)}
+	s.mu.Lock()
+	s.rec.conns = append(s.rec.conns, rc)
+	s.mu.Unlock()
+	return &trafficConn{Conn: conn, session: s, rec: rc}
+}
+
+// Close flushes and closes the file, returning the first error of the run
+func (r *recorder) Close() error {
+	err := r.err
+	if ferr := r.w.Flush(); err == nil {
+		err = ferr
+	}
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// trafficConn records the traffic of a client connection
+type trafficConn struct {
+	net.Conn
+	session *recordingSession
+	rec     *recordedConn
+}
+
+// add appends an event unless the session is full
+func (c *trafficConn) add(ev recordEvent) {
+	s := c.session
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rec.truncated {
+		return
+	}
+	if s.size += 13 + len(ev.data); s.size > recordMaxBytes {
+		s.rec.truncated = true
+		return
+	}
+	c.rec.events = append(c.rec.events, ev)
+}
+
+func (c *trafficConn) Write(p []byte) (int, error) {
+	at := time.Since(c.session.start)
+	n, err := c.Conn.Write(p)
+	if n > 0 {
+		c.add(recordEvent{kind: recordWrite, at: at, n: n, data: append([]byte(nil), p[:n]...)})
+	}
+	return n, err
+}
+
+func (c *trafficConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.add(recordEvent{kind: recordRead, at: time.Since(c.session.start), n: n})
+	}
+	return n, err
+}
+
+// CloseWrite half-closes the connection, as *net.TCPConn does
+func (c *trafficConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return errors.New("record: connection does not support CloseWrite")
+}
+
+func writeRecordedSession(w *bufio.Writer, s *recordedSession) error {
+	if len(s.name) > 0xffff {
+		return fmt.Errorf("case name too long for a recording: %d bytes", len(s.name))
+	}
+	var buf [8]byte
+	binary.LittleEndian.PutUint16(buf[:], uint16(len(s.name)))
+	w.Write(buf[:2])
+	w.WriteString(s.name)
+	truncated := byte(0)
+	if s.truncated {
+		truncated = 1
+	}
+	w.WriteByte(truncated)
+	binary.LittleEndian.PutUint32(buf[:], uint32(len(s.conns)))
+	w.Write(buf[:4])
+	for _, c := range s.conns {
+		binary.LittleEndian.PutUint64(buf[:], uint64(c.opened))
+		w.Write(buf[:])
+		binary.LittleEndian.PutUint64(buf[:], uint64(len(c.events)))
+		w.Write(buf[:])
+		for _, ev := range c.events {
+			w.WriteByte(ev.kind)
+			binary.LittleEndian.PutUint64(buf[:], uint64(ev.at))
+			w.Write(buf[:])
+			binary.LittleEndian.PutUint32(buf[:], uint32(ev.n))
+			w.Write(buf[:4])
+			if _, err := w.Write(ev.data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readRecording loads every session of a recording file
+func readRecording(path string) ([]*recordedSession, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	magic := make([]byte, len(recordMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != recordMagic {
+		return nil, errors.New("record: not a recording file")
+	}
+	var sessions []*recordedSession
+	for {
+		s, err := readRecordedSession(r)
+		if err == io.EOF {
+			return sessions, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("record: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+}
+
+func readRecordedSession(r *bufio.Reader) (*recordedSession, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[:2]); err != nil {
+		return nil, err
+	}
+	name := make([]byte, binary.LittleEndian.Uint16(buf[:]))
+	if _, err := io.ReadFull(r, name); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	s := &recordedSession{name: string(name)}
+	if _, err := io.ReadFull(r, buf[:5]); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	s.truncated = buf[0] != 0
+	conns := binary.LittleEndian.Uint32(buf[1:])
+	for i := uint32(0); i < conns; i++ {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		c := &recordedConn{opened: time.Duration(binary.LittleEndian.Uint64(buf[:]))}
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		events := binary.LittleEndian.Uint64(buf[:])
+		for j := uint64(0); j < events; j++ {
+			var hdr [13]byte
+			if _, err := io.ReadFull(r, hdr[:]); err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			ev := recordEvent{
+				kind: hdr[0],
+				at:   time.Duration(binary.LittleEndian.Uint64(hdr[1:])),
+				n:    int(binary.LittleEndian.Uint32(hdr[9:])),
+			}
+			switch ev.kind {
+			case recordWrite:
+				ev.data = make([]byte, ev.n)
+				if _, err := io.ReadFull(r, ev.data); err != nil {
+					return nil, unexpectedEOF(err)
+				}
+			case recordRead:
+			default:
+				return nil, fmt.Errorf("%s: unknown event kind %d", s.name, ev.kind)
+			}
+			c.events = append(c.events, ev)
+		}
+		s.conns = append(s.conns, c)
+	}
+	return s, nil
+}
+
+// unexpectedEOF reports a file ending inside a session as truncated
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// replayStats is what one replayed connection observed
+type replayStats struct {
+	hist           *Histogram
+	sent, received int64
+	maxLag         time.Duration
+}
+
+// replayConn replays one recorded connection against addr, its schedule
+// relative to start and divided by speed
+func replayConn(c *recordedConn, addr string, start time.Time, speed float64) (replayStats, error) {
+	st := replayStats{hist: NewHistogram()}
+	due := func(at time.Duration) time.Time { return start.Add(time.Duration(float64(at) / speed)) }
+	time.Sleep(time.Until(due(c.opened)))
+	conn, err := dialTCP(addr)
+	if err != nil {
+		return st, err
+	}
+	defer conn.Close()
+
+	var buf []byte
+	var lastWrite, lastRead time.Time
+	awaiting := false
+	for _, ev := range c.events {
+		if ev.kind == recordRead {
+			if cap(buf) < ev.n {
+				buf = make([]byte, ev.n)
+			}
+			conn.SetReadDeadline(time.Now().Add(replayReadTimeout))
+			if _, err := io.ReadFull(conn, buf[:ev.n]); err != nil {
+				return st, fmt.Errorf("reading a %d-byte reply (does the server answer as the recorded one did?): %w", ev.n, err)
+			}
+			st.received += int64(ev.n)
+			lastRead = time.Now()
+			continue
+		}
+		// A reply is complete once the next request goes out
+		if awaiting && !lastRead.Before(lastWrite) {
+			st.hist.Record(lastRead.Sub(lastWrite))
+		}
+		if wait := time.Until(due(ev.at)); wait > 0 {
+			time.Sleep(wait)
+		} else {
+			st.maxLag = max(st.maxLag, -wait)
+		}
+		if _, err := conn.Write(ev.data); err != nil {
+			return st, err
+		}
+		st.sent += int64(ev.n)
+		lastWrite, awaiting = time.Now(), true
+	}
+	if awaiting && !lastRead.Before(lastWrite) {
+		st.hist.Record(lastRead.Sub(lastWrite))
+	}
+	return st, nil
+}
+
+// replaySession replays every connection of s concurrently
+func replaySession(b *B, s *recordedSession, addr string, speed float64) {
+	start := time.Now()
+	stats := make([]replayStats, len(s.conns))
+	errs := make([]error, len(s.conns))
+	var wg sync.WaitGroup
+	for i, c := range s.conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stats[i], errs[i] = replayConn(c, addr, start, speed)
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		b.Fatal(err)
+		return
+	}
+	var sent, received int64
+	var maxLag time.Duration
+	for _, st := range stats {
+		b.Histogram().Merge(st.hist)
+		sent += st.sent
+		received += st.received
+		maxLag = max(maxLag, st.maxLag)
+	}
+	b.SetBytes(sent + received)
+	b.ReportMetric("connections", float64(len(s.conns)))
+	b.ReportMetric("bytes_sent", float64(sent))
+	b.ReportMetric("bytes_received", float64(received))
+	b.ReportMetric("max_lag_ms", float64(maxLag)/1e6)
+	if s.truncated {
+		b.ReportMetric("truncated", 1)
+	}
+}
+
+// runReplay is the replay subcommand
+func runReplay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	addr := fs.String("addr", "", "replay against this server instead of the local TCP echo server")
+	speed := fs.Float64("speed", 1, "replay this many times faster than recorded")
+	run := fs.String("run", ".", "replay only sessions whose name matches this regular expression")
+	fs.Parse(args)
+	if fs.NArg() != 1 || *speed <= 0 {
+		fmt.Fprintln(os.Stderr, "usage: replay [-addr host:port] [-speed 1] [-run pattern] recording")
+		return 2
+	}
+	match, err := regexp.Compile(*run)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: invalid -run pattern: %v\n", err)
+		return 2
+	}
+	sessions, err := readRecording(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	target := *addr
+	if target == "" {
+		ln, err := startTCPEchoServer()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		defer ln.Close()
+		target = ln.Addr().String()
+	}
+
+	failed := 0
+	for _, s := range sessions {
+		if !match.MatchString(s.name) {
+			continue
+		}
+		r := RunBenchmark("Replay/"+s.name, 1, 0, func(b *B) {
+			replaySession(b, s, target, *speed)
+		})
+		PrintResult(r)
+		if r.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+// Traffic Recording Tests - Go
+//
+// Run with: go test -run Record
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordAndReplayEchoSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.rec")
+	r, err := openRecorder(path, "Echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	traffic = r
+	defer func() { traffic = nil }()
+
+	ln, err := startEchoServer(tcpTransport{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	skipped := startRecording(Case{Name: "Other"})
+	conn, err := dialTCP(ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	skipped()
+
+	stop := startRecording(Case{Name: "Echo"})
+	conn, err = dialTCP(ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range []string{"hello", "recorded world"} {
+		reply := make([]byte, len(req))
+		if err := echoRoundTrip(conn, []byte(req), reply); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn.Close()
+	stop()
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := readRecording(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].name != "Echo" || len(sessions[0].conns) != 1 {
+		t.Fatalf("recorded %d sessions, want the Echo one with one connection", len(sessions))
+	}
+	var sent, read bytes.Buffer
+	received := 0
+	for _, ev := range sessions[0].conns[0].events {
+		switch ev.kind {
+		case recordWrite:
+			sent.Write(ev.data)
+		case recordRead:
+			received += ev.n
+		}
+	}
+	read.WriteString("hellorecorded world")
+	if !bytes.Equal(sent.Bytes(), read.Bytes()) || received != read.Len() {
+		t.Errorf("recorded writes %q and %d read bytes, want %q both ways", sent.String(), received, read.String())
+	}
+
+	res := RunBenchmark("Replay", 1, 0, func(b *B) {
+		replaySession(b, sessions[0], ln.Addr().String(), 1)
+	})
+	if res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	if res.Latency == nil || res.Metrics["bytes_received"] != float64(read.Len()) {
+		t.Errorf("replay latency %v, received %v bytes", res.Latency, res.Metrics["bytes_received"])
+	}
+}
+
+func TestReadRecordingRejectsTruncatedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.rec")
+	r, err := openRecorder(path, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A session header claiming a connection that never follows
+	r.w.Write([]byte{4, 0, 'E', 'c', 'h', 'o', 0, 1, 0, 0, 0})
+	r.Close()
+	if _, err := readRecording(path); err == nil || !bytes.Contains([]byte(err.Error()), []byte(io.ErrUnexpectedEOF.Error())) {
+		t.Errorf("got %v, want an unexpected EOF", err)
+	}
+}
@@ -0,0 +1,175 @@
+// Benchmark Registry - Go
+//
+// Every benchmark registers itself from an init function in its suite file;
+// the runner selects from the registry with a regular expression over names,
+// mirroring `go test -bench` semantics.
+//
+// A benchmark may declare parameter axes (payload size, concurrency, codec,
+// ...). The runner expands the full matrix into cases named like go test
+// sub-benchmarks, e.g. "TcpEcho/size=1024/conns=8", and the pattern is
+// matched against those expanded names.
+//
+// Benchmarks also declare tags (net, cpu, alloc, serde) so whole categories
+// can be included or excluded with -tags and -exclude-tags. The remote tag
+// marks the benchmarks that can run against a remote echo server
+// (remote.go). The large tag marks those over data sets of many GB, which
+// a plain run skips; opt in with -tags large, -exclude-tags '' or a -run
+// pattern naming them.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Benchmark is a registered benchmark
+type Benchmark struct {
+	Name       string
+	Category   string
+	Iterations int64
+	DataSize   int64
+	Tags       []string
+	Axes       []Axis
+	// Setup, if set, runs once per case before the warmup, e.g. to start a
+	// server; Teardown runs after the measured iterations, or right away
+	// if Setup fails, so it must cope with a partly built fixture
+	Setup    func(b *B) error
+	Teardown func()
+	Fn       func(b *B)
+}
+
+// Axis is one parameter dimension of a benchmark matrix
+type Axis struct {
+	Name   string
+	Values []interface{}
+}
+
+// Params holds the parameter values of one matrix case
+type Params map[string]interface{}
+
+// Case is one runnable point of a benchmark's parameter matrix
+type Case struct {
+	Bench  *Benchmark
+	Name   string
+	Params Params
+}
+
+var registry []Benchmark
+
+// Register adds a benchmark to the registry
+func Register(b Benchmark) {
+	for _, existing := range registry {
+		if existing.Name == b.Name {
+			panic(fmt.Sprintf("benchmark %q registered twice", b.Name))
+		}
+	}
+	for _, axis := range b.Axes {
+		if len(axis.Values) == 0 {
+			panic(fmt.Sprintf("benchmark %q: axis %q has no values", b.Name, axis.Name))
+		}
+	}
+	registry = append(registry, b)
+}
+
+// Ints builds axis values from integers
+func Ints(values ...int) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+// Strings builds axis values from strings
+func Strings(values ...string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+// Cases expands a benchmark's axes into the cartesian product of cases; a
+// benchmark without axes yields a single case
+func (b *Benchmark) Cases() []Case {
+	cases := []Case{{Bench: b, Name: b.Name}}
+	for _, axis := range b.Axes {
+		var next []Case
+		for _, c := range cases {
+			for _, v := range axis.Values {
+				params := Params{}
+				for k, pv := range c.Params {
+					params[k] = pv
+				}
+				params[axis.Name] = v
+				next = append(next, Case{
+					Bench:  b,
+					Name:   fmt.Sprintf("%s/%s=%v", c.Name, axis.Name, v),
+					Params: params,
+				})
+			}
+		}
+		cases = next
+	}
+	return cases
+}
+
+// HasTag reports whether the benchmark declares tag
+func (b *Benchmark) HasTag(tag string) bool {
+	for _, t := range b.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter selects benchmark cases by name pattern and tags
+type Filter struct {
+	// Pattern is matched against expanded case names
+	Pattern string
+	// Tags, when non-empty, keeps only benchmarks with at least one of them
+	Tags []string
+	// ExcludeTags drops benchmarks with any of them
+	ExcludeTags []string
+}
+
+// matchesTags applies the tag part of the filter to a benchmark
+func (f Filter) matchesTags(b *Benchmark) bool {
+	for _, tag := range f.ExcludeTags {
+		if b.HasTag(tag) {
+			return false
+		}
+	}
+	if len(f.Tags) == 0 {
+		return true
+	}
+	for _, tag := range f.Tags {
+		if b.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectCases returns the expanded cases accepted by the filter, in
+// registration order
+func SelectCases(f Filter) ([]Case, error) {
+	re, err := regexp.Compile(f.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -run pattern: %w", err)
+	}
+	var selected []Case
+	for i := range registry {
+		if !f.matchesTags(&registry[i]) {
+			continue
+		}
+		for _, c := range registry[i].Cases() {
+			if re.MatchString(c.Name) {
+				selected = append(selected, c)
+			}
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,36 @@
+// Registry Tests - Go
+//
+// Run with: go test -run Cases
+
+package main
+
+import "testing"
+
+func TestCasesWithoutAxes(t *testing.T) {
+	b := &Benchmark{Name: "Plain"}
+	cases := b.Cases()
+	if len(cases) != 1 || cases[0].Name != "Plain" || cases[0].Params != nil {
+		t.Fatalf("unexpected cases: %+v", cases)
+	}
+}
+
+func TestCasesMatrix(t *testing.T) {
+	b := &Benchmark{
+		Name: "Echo",
+		Axes: []Axis{
+			{Name: "size", Values: Ints(64, 1024)},
+			{Name: "codec", Values: Strings("json", "cbor", "xml")},
+		},
+	}
+	cases := b.Cases()
+	if len(cases) != 6 {
+		t.Fatalf("got %d cases, want 6", len(cases))
+	}
+	if cases[0].Name != "Echo/size=64/codec=json" {
+		t.Errorf("first case = %q", cases[0].Name)
+	}
+	last := cases[len(cases)-1]
+	if last.Name != "Echo/size=1024/codec=xml" || last.Params["size"] != 1024 || last.Params["codec"] != "xml" {
+		t.Errorf("last case = %+v", last)
+	}
+}
@@ -0,0 +1,243 @@
+// Go TCP Relay Benchmark
+// The reused-connection echo round trip of TcpReusedRequest, relayed:
+// client -> relay -> ... -> echo server and back, through a chain of
+// in-process TCP relays that accept a connection, dial the next hop and
+// copy both directions with io.Copy - the simplest proxy, and the pattern
+// a TML proxy is compared against. The hops axis sets the number of
+// relays, 0 being the direct round trip.
+//
+// Besides the round-trip latency, per_hop_us reports what each relay adds
+// to the mean round trip over a direct one. Setup measures the direct mean
+// itself, on a connection straight to the same echo server, so the metric
+// does not depend on which other cases ran.
+
+package main
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// relayBaselineRounds is the number of direct round trips Setup averages,
+// after as many again to warm up
+const relayBaselineRounds = 1000
+
+// tcpRelay forwards every connection accepted from its listener to
+// upstream. Like echoServer, Close closes everything still open and
+// returns once all of its goroutines have.
+type tcpRelay struct {
+	net.Listener
+	upstream string
+	wg       sync.WaitGroup
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+}
+
+// startTCPRelay starts a relay to upstream on a loopback port, returning
+// once its accept loop is running
+func startTCPRelay(upstream string) (*tcpRelay, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	r := &tcpRelay{Listener: tcpListener{ln}, upstream: upstream, conns: map[net.Conn]struct{}{}}
+	ready := make(chan struct{})
+	r.wg.Add(1)
+	go r.serve(ready)
+	<-ready
+	return r, nil
+}
+
+// serve is the accept loop, which signals ready before its first Accept
+func (r *tcpRelay) serve(ready chan<- struct{}) {
+	defer r.wg.Done()
+	close(ready)
+	for {
+		down, err := r.Accept()
+		if err != nil {
+			return
+		}
+		// The upstream leg is the relay's own, not a benchmark client
+		// connection, so it is neither tracked nor recorded
+		up, err := net.Dial("tcp", r.upstream)
+		if err != nil {
+			down.Close()
+			continue
+		}
+		configureTCPConn(up)
+		if !r.track(down, up) {
+			return
+		}
+		r.wg.Add(1)
+		go r.relay(down, up)
+	}
+}
+
+// track registers the connections of a relayed session, unless the relay
+// is closing
+func (r *tcpRelay) track(conns ...net.Conn) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		for _, c := range conns {
+			c.Close()
+		}
+		return false
+	}
+	for _, c := range conns {
+		r.conns[c] = struct{}{}
+	}
+	return true
+}
+
+// relay copies both directions of a session until each has ended,
+// passing each end on as a half-close, then closes both connections
+func (r *tcpRelay) relay(down, up net.Conn) {
+	defer r.wg.Done()
+	upstreamDone := make(chan struct{})
+	go func() {
+		io.Copy(up, down)
+		closeWrite(up)
+		close(upstreamDone)
+	}()
+	io.Copy(down, up)
+	closeWrite(down)
+	<-upstreamDone
+
+	r.mu.Lock()
+	delete(r.conns, down)
+	delete(r.conns, up)
+	r.mu.Unlock()
+	down.Close()
+	up.Close()
+}
+
+// closeWrite half-closes conn where it supports that
+func closeWrite(conn net.Conn) {
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		cw.CloseWrite()
+	}
+}
+
+// Close stops the relay and waits for all of its goroutines to finish
+func (r *tcpRelay) Close() error {
+	err := r.Listener.Close()
+	r.mu.Lock()
+	r.closed = true
+	for c := range r.conns {
+		c.Close()
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+	return err
+}
+
+// relayChain is the echo server and the relays in front of it, closed
+// front to back
+type relayChain []io.Closer
+
+func (c relayChain) Close() error {
+	var first error
+	for i := len(c) - 1; i >= 0; i-- {
+		if err := c[i].Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// relayBaseline is the direct mean round trip of the current case and the
+// measured run it is reported with
+var relayBaseline struct {
+	directUs float64
+	b        *B
+	calls    int64
+}
+
+// measureDirectRoundTrip returns the mean round trip, in microseconds, of a
+// connection straight to addr
+func measureDirectRoundTrip(t Transport, addr net.Addr, size int) (float64, error) {
+	conn, err := t.Dial(addr)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	payload, reply := make([]byte, size), make([]byte, size)
+	var start time.Time
+	for i := range 2 * relayBaselineRounds {
+		if i == relayBaselineRounds {
+			start = time.Now()
+		}
+		if err := t.RoundTrip(conn, payload, reply); err != nil {
+			return 0, err
+		}
+	}
+	return float64(time.Since(start).Microseconds()) / relayBaselineRounds, nil
+}
+
+func setupTcpRelayRequest(b *B) error {
+	c := &tcpRequest
+	echo, err := startEchoServer(tcpTransport{})
+	if err != nil {
+		return err
+	}
+	chain := relayChain{echo}
+	c.server, c.transport = chain, tcpTransport{}
+	addr := echo.Addr()
+	size := benchConfig.PayloadSizes.Request
+	relayBaseline.directUs, relayBaseline.b = 0, nil
+	if b.IntParam("hops") > 0 {
+		if relayBaseline.directUs, err = measureDirectRoundTrip(c.transport, addr, size); err != nil {
+			c.close()
+			return err
+		}
+	}
+	for range b.IntParam("hops") {
+		r, err := startTCPRelay(addr.String())
+		if err != nil {
+			c.close()
+			return err
+		}
+		chain = append(chain, r)
+		c.server = chain
+		addr = r.Addr()
+	}
+	if c.conn, err = c.transport.Dial(addr); err != nil {
+		c.close()
+		return err
+	}
+	c.payload, c.reply = make([]byte, size), make([]byte, size)
+	return nil
+}
+
+// benchRelayRequest performs one round trip through the case's relays,
+// reporting per_hop_us with the run's last iteration
+func benchRelayRequest(b *B) {
+	benchEchoRequest(b)
+	hops := b.IntParam("hops")
+	if b.err != nil || hops == 0 {
+		return
+	}
+	r := &relayBaseline
+	if r.b != b {
+		r.b, r.calls = b, 0
+	}
+	if r.calls++; r.calls == b.N {
+		mean := float64(b.Histogram().Mean()) / 1e3
+		b.ReportMetric("per_hop_us", (mean-r.directUs)/float64(hops))
+	}
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "TcpRelayRequest", Category: "tcp", Tags: []string{"net"},
+		Iterations: 10000,
+		Axes:       []Axis{{Name: "hops", Values: Ints(0, 1, 2)}},
+		Setup:      setupTcpRelayRequest, Teardown: tcpRequest.close,
+		Fn: benchRelayRequest,
+	})
+}
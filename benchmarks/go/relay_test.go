@@ -0,0 +1,84 @@
+// TCP Relay Tests - Go
+//
+// Run with: go test -run Relay
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRelayChainEchoes(t *testing.T) {
+	echo, err := startEchoServer(tcpTransport{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := relayChain{echo}
+	defer chain.Close()
+	addr := echo.Addr()
+	for range 2 {
+		r, err := startTCPRelay(addr.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		chain = append(chain, r)
+		addr = r.Addr()
+	}
+
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	req := bytes.Repeat([]byte("relay"), 1000)
+	resp := make([]byte, len(req))
+	if err := echoRoundTrip(conn, req, resp); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(resp, req) {
+		t.Error("echo through two relays differs from the request")
+	}
+
+	// A half-close travels through the chain, so the echo server hangs up
+	// and the client reads EOF
+	conn.(*net.TCPConn).CloseWrite()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if n, err := io.Copy(io.Discard, conn); err != nil || n != 0 {
+		t.Errorf("after half-close read %d bytes, %v; want EOF", n, err)
+	}
+}
+
+func TestRelayCloseEndsSessions(t *testing.T) {
+	echo, err := startEchoServer(tcpTransport{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	r, err := startTCPRelay(echo.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("tcp", r.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := echoRoundTrip(conn, []byte("x"), make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		r.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return with a session open")
+	}
+}
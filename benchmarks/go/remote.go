@@ -0,0 +1,147 @@
+// Remote Mode - Go
+//
+// The request benchmarks normally run client and echo server in one
+// process over loopback. To measure a real network path instead, run the
+// suite as an echo server on one machine and as a client on another:
+//
+//   - server: -mode server serves TCP and UDP echo on the -listen address
+//     (both protocols on the same port) until interrupted; no benchmarks
+//     run
+//   - client: -mode client -target host:port runs the benchmarks tagged
+//     remote against the server at target, over TCP and UDP as each
+//     benchmark requires, instead of starting their own servers
+//
+// The server need not be this suite: -echo-addr points the client at any
+// echo server, such as the TML suite's, for mixed-language round trips,
+// and -echo-udp-addr at its UDP echo when that is on another port. An echo
+// server only has to send back every byte (TCP) or datagram (UDP) it gets.
+//
+// In client mode tcpTransport's listener and the UDP echo server stand in
+// for the remote server: they have its address and nothing behind them, so
+// the benchmarks themselves are unchanged. Numbers that come from the
+// server side of the process - TcpIdleConns' bytes_per_conn - only count
+// the client then. The target is recorded in the result metadata.
+//
+// Run with: go run . -mode server [-listen :7007]
+//      then: go run . -mode client -target server-host:7007 [-o remote.json]
+//        or: go run . -echo-addr 127.0.0.1:9000 [-echo-udp-addr 127.0.0.1:9001]
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+)
+
+var remoteModes = []string{"local", "server", "client"}
+
+// RemoteOptions choose where the request benchmarks' echo server runs
+type RemoteOptions struct {
+	Mode string
+	// Target is the echo server a client connects to
+	Target string
+	// UDPTarget is the client's UDP echo server, when not at Target
+	UDPTarget string
+	// Listen is the address a server serves on
+	Listen string
+}
+
+// remoteOptions holds the settings of the current run
+var remoteOptions = RemoteOptions{Mode: "local", Listen: ":7007"}
+
+// Validate checks the mode and that client mode has a target
+func (o RemoteOptions) Validate() error {
+	if !slices.Contains(remoteModes, o.Mode) {
+		return fmt.Errorf("unknown mode %q (want one of %v)", o.Mode, remoteModes)
+	}
+	if o.Mode == "client" && o.Target == "" {
+		return errors.New("client mode needs -target host:port")
+	}
+	if o.Mode != "client" && o.UDPTarget != "" {
+		return errors.New("-echo-udp-addr needs client mode (-echo-addr or -mode client)")
+	}
+	return nil
+}
+
+// udpTarget is the UDP echo server a client sends to
+func (o RemoteOptions) udpTarget() string {
+	if o.UDPTarget != "" {
+		return o.UDPTarget
+	}
+	return o.Target
+}
+
+// remoteCases keeps the cases that can run against a remote server
+func remoteCases(cases []Case) (kept []Case, dropped int) {
+	for _, c := range cases {
+		if c.Bench.HasTag("remote") {
+			kept = append(kept, c)
+		} else {
+			dropped++
+		}
+	}
+	return kept, dropped
+}
+
+// runEchoServers implements server mode: TCP and UDP echo on addr until
+// the process is interrupted
+func runEchoServers(addr string) int {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	// Serve UDP on the port TCP got, so one address names both
+	pc, err := net.ListenPacket("udp", ln.Addr().String())
+	if err != nil {
+		ln.Close()
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	tcp := serveEcho(tcpListener{ln})
+	udp := serveUDPEcho(pc)
+	fmt.Printf("Serving TCP and UDP echo on %s; interrupt to stop\n", ln.Addr())
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	tcp.Close()
+	udp.Close()
+	return 0
+}
+
+// remoteListener stands for the remote echo server in client mode: its
+// address is the target, and Accept waits for Close, as nothing connects
+type remoteListener struct {
+	addr   net.Addr
+	closed chan struct{}
+	once   sync.Once
+}
+
+func listenRemote() (net.Listener, error) {
+	addr, err := net.ResolveTCPAddr("tcp", remoteOptions.Target)
+	if err != nil {
+		return nil, err
+	}
+	return &remoteListener{addr: addr, closed: make(chan struct{})}, nil
+}
+
+func (l *remoteListener) Accept() (net.Conn, error) {
+	<-l.closed
+	return nil, net.ErrClosed
+}
+
+func (l *remoteListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *remoteListener) Addr() net.Addr {
+	return l.addr
+}
@@ -0,0 +1,92 @@
+// Remote Mode Tests - Go
+//
+// Run with: go test -run Remote
+
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+func TestRemoteClientUsesTarget(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := net.ListenPacket("udp", ln.Addr().String())
+	if err != nil {
+		ln.Close()
+		t.Fatal(err)
+	}
+	tcp, udp := serveEcho(ln), serveUDPEcho(pc)
+	defer tcp.Close()
+	defer udp.Close()
+
+	saved := remoteOptions
+	defer func() { remoteOptions = saved }()
+	remoteOptions = RemoteOptions{Mode: "client", Target: ln.Addr().String()}
+
+	var c requestClient
+	if err := c.dial(tcpTransport{}, 64); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.conn.RemoteAddr().String(); got != ln.Addr().String() {
+		t.Errorf("TCP client connected to %s, want the target %s", got, ln.Addr())
+	}
+	if err := c.transport.RoundTrip(c.conn, c.payload, c.reply); err != nil {
+		t.Errorf("TCP round trip: %v", err)
+	}
+	c.close()
+
+	if err := c.dialUDP(64); err != nil {
+		t.Fatal(err)
+	}
+	defer c.close()
+	a := newUDPAccounting(udpRequestTimeout)
+	if answered, err := a.roundTrip(c.conn, c.payload, c.reply); !answered || err != nil {
+		t.Errorf("UDP round trip against the target: answered %t, %v", answered, err)
+	}
+}
+
+func TestRemoteCases(t *testing.T) {
+	cases := []Case{
+		{Name: "a", Bench: &Benchmark{Tags: []string{"net", "remote"}}},
+		{Name: "b", Bench: &Benchmark{Tags: []string{"net"}}},
+	}
+	kept, dropped := remoteCases(cases)
+	if len(kept) != 1 || kept[0].Name != "a" || dropped != 1 {
+		t.Errorf("kept %v, dropped %d; want [a], 1", kept, dropped)
+	}
+}
+
+func TestRemoteSeparateUDPTarget(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	udp := serveUDPEcho(pc)
+	defer udp.Close()
+
+	saved := remoteOptions
+	defer func() { remoteOptions = saved }()
+	// Nothing listens at the TCP target; UDP must not go there
+	remoteOptions = RemoteOptions{Mode: "client", Target: "127.0.0.1:9", UDPTarget: pc.LocalAddr().String()}
+	if err := remoteOptions.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	var c requestClient
+	if err := c.dialUDP(64); err != nil {
+		t.Fatal(err)
+	}
+	defer c.close()
+	a := newUDPAccounting(udpRequestTimeout)
+	if answered, err := a.roundTrip(c.conn, c.payload, c.reply); !answered || err != nil {
+		t.Errorf("UDP round trip against the UDP target: answered %t, %v", answered, err)
+	}
+
+	if err := (RemoteOptions{Mode: "local", UDPTarget: "127.0.0.1:9"}).Validate(); err == nil {
+		t.Error("a UDP target outside client mode was accepted")
+	}
+}
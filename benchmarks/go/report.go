@@ -0,0 +1,178 @@
+// Markdown Report - Go
+//
+// Converts one or more result files (Go result sets or the flat format of
+// the TML/C++ suites) into Markdown tables, one per category, with the
+// delta of every other language against TML.
+//
+// Run with: go run . report [-o report.md] go.json tml.json ...
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// reportSource is one loaded result file and its column label
+type reportSource struct {
+	Label   string
+	Results map[string]BenchmarkResult
+}
+
+// runReport implements the report subcommand
+func runReport(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	output := fs.String("o", "", "write the Markdown report to this file instead of stdout")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: report [-o report.md] results.json ...")
+		return 2
+	}
+
+	var sets []ResultSet
+	for _, path := range fs.Args() {
+		set, err := LoadResultSet(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		sets = append(sets, set)
+	}
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	WriteMarkdownReport(out, sets)
+	return 0
+}
+
+// WriteMarkdownReport renders result sets as per-category Markdown tables
+func WriteMarkdownReport(w io.Writer, sets []ResultSet) {
+	sources, tml := reportSources(sets)
+
+	// category -> benchmark names, in first-seen order
+	categories := map[string][]string{}
+	seen := map[string]bool{}
+	for _, set := range sets {
+		for _, r := range set.Results {
+			category := r.Category
+			if category == "" {
+				category = "uncategorized"
+			}
+			key := category + "\x00" + r.Name
+			if !seen[key] {
+				seen[key] = true
+				categories[category] = append(categories[category], r.Name)
+			}
+		}
+	}
+	names := make([]string, 0, len(categories))
+	for category := range categories {
+		names = append(names, category)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "# Benchmark Report")
+	fmt.Fprintln(w)
+	for _, set := range sets {
+		if set.Metadata.GoVersion != "" {
+			m := set.Metadata
+			commit := ""
+			if m.GitCommit != "" {
+				commit = fmt.Sprintf(", commit %.12s", m.GitCommit)
+			}
+			fmt.Fprintf(w, "- **%s**: %s on %s/%s, %s (%d cores)%s, %s\n",
+				set.Language, m.GoVersion, m.GOOS, m.GOARCH, m.CPUModel, m.NumCPU, commit, m.Timestamp)
+		}
+	}
+
+	for _, category := range names {
+		fmt.Fprintf(w, "\n## %s\n\n", category)
+
+		header := []string{"Benchmark"}
+		for _, src := range sources {
+			header = append(header, src.Label+" (us)")
+		}
+		if tml != nil {
+			for _, src := range sources {
+				if src != tml {
+					header = append(header, src.Label+" vs TML")
+				}
+			}
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(header, " | "))
+		fmt.Fprintf(w, "|%s\n", strings.Repeat("---|", len(header)))
+
+		for _, name := range categories[category] {
+			row := []string{name}
+			for _, src := range sources {
+				row = append(row, formatReportTime(src.Results, name))
+			}
+			if tml != nil {
+				for _, src := range sources {
+					if src != tml {
+						row = append(row, formatDelta(src.Results, tml.Results, name))
+					}
+				}
+			}
+			fmt.Fprintf(w, "| %s |\n", strings.Join(row, " | "))
+		}
+	}
+}
+
+// reportSources labels each result set by language, disambiguating repeats,
+// and returns the TML source if one is present
+func reportSources(sets []ResultSet) ([]*reportSource, *reportSource) {
+	var sources []*reportSource
+	var tml *reportSource
+	counts := map[string]int{}
+	for _, set := range sets {
+		label := set.Language
+		if label == "" {
+			label = "unknown"
+		}
+		counts[label]++
+		if counts[label] > 1 {
+			label = fmt.Sprintf("%s #%d", label, counts[label])
+		}
+		src := &reportSource{Label: label, Results: map[string]BenchmarkResult{}}
+		for _, r := range set.Results {
+			src.Results[r.Name] = r
+		}
+		sources = append(sources, src)
+		if tml == nil && strings.EqualFold(set.Language, "tml") {
+			tml = src
+		}
+	}
+	return sources, tml
+}
+
+func formatReportTime(results map[string]BenchmarkResult, name string) string {
+	r, ok := results[name]
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.3f", r.TimeUs)
+}
+
+// formatDelta shows how much slower (+) or faster (-) a result is than TML
+func formatDelta(results, tml map[string]BenchmarkResult, name string) string {
+	r, ok := results[name]
+	t, tok := tml[name]
+	if !ok || !tok || t.TimeUs <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", (r.TimeUs-t.TimeUs)/t.TimeUs*100)
+}
@@ -0,0 +1,247 @@
+// Reproducibility Bundles - Go
+//
+// export-repro packages what it takes to rerun one benchmark elsewhere
+// into a single .tar.gz, so a discrepancy between a TML and a Go run can be
+// handed over and reproduced as it was measured:
+//
+//   - config.yaml and golden.json: the shared configuration (with the RNG
+//     seed every generated input and corpus derives from) and the golden
+//     fixture, as the run used them
+//   - suite/: the Go suite's source, which is one package, with go.mod
+//   - metadata.json: the environment of the exporting machine, including
+//     the commit and whether the tree was dirty
+//   - results/: the benchmark's results from each -results file, with that
+//     run's metadata, e.g. the Go and TML runs that disagree
+//   - run.sh and README.md: the command that reruns exactly the
+//     benchmark's cases, and what the bundle holds
+//
+// Every input of the Go benchmarks is generated from the seed at setup, so
+// no corpus files need copying; a benchmark's fixed seed is in its source.
+//
+// Run with: go run . export-repro [-o repro.tar.gz] [-results go.json,tml.json] TcpRequestSweep
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// reproFile is one file of a bundle, by its path inside the bundle
+type reproFile struct {
+	name string
+	mode int64
+	data []byte
+}
+
+// runExportRepro implements the export-repro subcommand
+func runExportRepro(args []string) int {
+	fs := flag.NewFlagSet("export-repro", flag.ExitOnError)
+	output := fs.String("o", "", "bundle to write (default repro-<benchmark>.tar.gz)")
+	configPath := fs.String("config", defaultConfigPath, "shared configuration to bundle (empty for built-in defaults)")
+	goldenPath := fs.String("golden", defaultGoldenPath, "golden output fixture to bundle (empty to skip)")
+	results := fs.String("results", "", "comma-separated result files whose results for the benchmark to include")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: export-repro [-o repro.tar.gz] [-config ../config.yaml] [-golden ../common/golden.json] [-results go.json,tml.json] benchmark")
+		return 2
+	}
+	name := fs.Arg(0)
+
+	*goldenPath = defaultFileFlag(fs, "golden")
+	config := defaultConfig()
+	if *configPath = defaultFileFlag(fs, "config"); *configPath != "" {
+		var err error
+		if config, err = LoadConfig(*configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 2
+		}
+		config.Apply()
+	}
+	pattern := benchNamePattern(name)
+	cases, err := SelectCases(Filter{Pattern: pattern})
+	if err == nil && len(cases) == 0 {
+		err = fmt.Errorf("no benchmark named %s", name)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 2
+	}
+
+	bench := cases[0].Bench.Name
+	files, err := reproBundle(pattern, cases, config, *configPath, *goldenPath, splitList(*results))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	path := *output
+	if path == "" {
+		path = "repro-" + bench + ".tar.gz"
+	}
+	if err := writeReproArchive(path, "repro-"+bench, files); err != nil {
+		fmt.Fprintf(os.Stderr, "error: writing %s: %v\n", path, err)
+		return 1
+	}
+	fmt.Printf("%d cases of %s bundled into %s\n", len(cases), bench, path)
+	return 0
+}
+
+// reproBundle collects the files of the bundle for the cases of one
+// benchmark; pattern matches its results in the other suites' files too
+func reproBundle(pattern string, cases []Case, config BenchConfig, configPath, goldenPath string, resultPaths []string) ([]reproFile, error) {
+	var files []reproFile
+	add := func(path string, data []byte) {
+		files = append(files, reproFile{name: path, mode: 0o644, data: data})
+	}
+
+	configFlag, goldenFlag := `""`, `""`
+	if configPath != "" {
+		data, err := os.ReadFile(configPath)
+		if err != nil {
+			return nil, err
+		}
+		add("config.yaml", data)
+		configFlag = "../config.yaml"
+	}
+	if goldenPath != "" {
+		data, err := os.ReadFile(goldenPath)
+		if err != nil {
+			return nil, err
+		}
+		add("golden.json", data)
+		goldenFlag = "../golden.json"
+	}
+
+	metadata := CollectMetadata()
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	add("metadata.json", append(data, '\n'))
+
+	re := regexp.MustCompile(pattern)
+	var resultNotes []string
+	for _, path := range resultPaths {
+		set, err := LoadResultSet(path)
+		if err != nil {
+			return nil, err
+		}
+		var kept []BenchmarkResult
+		for _, r := range set.Results {
+			if re.MatchString(r.Name) {
+				kept = append(kept, r)
+			}
+		}
+		set.Results = kept
+		set.SchemaVersion = resultSchemaVersion
+		data, err := json.MarshalIndent(set, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		file := "results/" + filepath.Base(path)
+		add(file, append(data, '\n'))
+		resultNotes = append(resultNotes, fmt.Sprintf("- %s: %d results from %s (%s)", file, len(kept), path, set.Language))
+	}
+
+	sources, err := filepath.Glob("*.go")
+	if err != nil {
+		return nil, err
+	}
+	sources = append(sources, "go.mod")
+	sort.Strings(sources)
+	for _, src := range sources {
+		if strings.HasSuffix(src, "_test.go") {
+			continue
+		}
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return nil, err
+		}
+		add("suite/"+src, data)
+	}
+
+	bench := cases[0].Bench.Name
+	run := "^" + regexp.QuoteMeta(bench) + "(/|$)"
+	command := fmt.Sprintf("go run . -run %s -config %s -golden %s -o ../rerun.json", shellQuote(run), configFlag, goldenFlag)
+	files = append(files, reproFile{name: "run.sh", mode: 0o755, data: []byte(
+		"#!/bin/sh\n# Reruns " + bench + " as bundled; extra arguments are passed to the suite\n" +
+			"cd \"$(dirname \"$0\")/suite\" && exec " + command + " \"$@\"\n")})
+	add("README.md", []byte(reproReadme(cases, config, metadata, command, resultNotes)))
+	return files, nil
+}
+
+// reproReadme describes a bundle
+func reproReadme(cases []Case, config BenchConfig, m Metadata, command string, resultNotes []string) string {
+	var b strings.Builder
+	bench := cases[0].Bench
+	fmt.Fprintf(&b, "# Reproducing %s\n\n", bench.Name)
+	fmt.Fprintf(&b, "Exported %s from commit %s", m.Timestamp, orUnknown(m.GitCommit))
+	if m.GitDirty {
+		b.WriteString(" with uncommitted changes (included in suite/)")
+	}
+	fmt.Fprintf(&b, " on %s (%s, %s/%s, %s).\n\n", orUnknown(m.Hostname), m.CPUModel, m.GOOS, m.GOARCH, m.GoVersion)
+
+	fmt.Fprintf(&b, "Category %s, tags %s, %d iterations per case, configuration seed %d.\n\n",
+		bench.Category, strings.Join(bench.Tags, ", "), bench.Iterations, config.Seed)
+	b.WriteString("Cases:\n\n")
+	for _, c := range cases {
+		fmt.Fprintf(&b, "- %s\n", c.Name)
+	}
+
+	b.WriteString("\nRerun them with `./run.sh`, which runs, from suite/:\n\n")
+	fmt.Fprintf(&b, "    %s\n\n", command)
+	fmt.Fprintf(&b, "and writes rerun.json. The export ran with GOGC=%s and GOMEMLIMIT=%s; pass\n", m.GOGC, orUnknown(m.GOMEMLIMIT))
+	b.WriteString("-gogc and -memlimit to match the settings in a reported run's metadata.\n")
+	if len(resultNotes) > 0 {
+		b.WriteString("\nReported results, each with its run's metadata:\n\n")
+		b.WriteString(strings.Join(resultNotes, "\n"))
+		b.WriteString("\n\nCompare a rerun with them using `go run . compare`.\n")
+	}
+	return b.String()
+}
+
+// writeReproArchive writes files into a gzipped tar under dir
+func writeReproArchive(path, dir string, files []reproFile) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	now := time.Now().Truncate(time.Second)
+	for _, f := range files {
+		hdr := &tar.Header{Name: dir + "/" + f.name, Mode: f.mode, Size: int64(len(f.data)), ModTime: now}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
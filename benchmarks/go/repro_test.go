@@ -0,0 +1,92 @@
+// Reproducibility Bundle Tests - Go
+//
+// Run with: go test -run ReproBundle
+
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReproBundle(t *testing.T) {
+	pattern := benchNamePattern("tcp_dial")
+	cases, err := SelectCases(Filter{Pattern: pattern})
+	if err != nil || len(cases) != 1 {
+		t.Fatalf("cases %v, %v; want TcpDial", cases, err)
+	}
+	dir := t.TempDir()
+	resultsPath := filepath.Join(dir, "tml.json")
+	if err := WriteResultSet(resultsPath, ResultSet{Language: "tml", Results: []BenchmarkResult{
+		{Name: "tcp dial", TimeUs: 40}, {Name: "TcpDialer", TimeUs: 1}, {Name: "Sort", TimeUs: 2},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := reproBundle(pattern, cases, defaultConfig(), defaultConfigPath, defaultGoldenPath, []string{resultsPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "repro.tar.gz")
+	if err := writeReproArchive(path, "repro-TcpDial", files); err != nil {
+		t.Fatal(err)
+	}
+
+	bundle := readTarGz(t, path)
+	for _, name := range []string{"config.yaml", "golden.json", "metadata.json", "README.md", "suite/go.mod", "suite/main.go", "suite/tcp_dial_bench.go"} {
+		if _, ok := bundle["repro-TcpDial/"+name]; !ok {
+			t.Errorf("bundle lacks %s", name)
+		}
+	}
+	for name := range bundle {
+		if strings.HasSuffix(name, "_test.go") {
+			t.Errorf("bundle has test file %s", name)
+		}
+	}
+	if run := bundle["repro-TcpDial/run.sh"]; !strings.Contains(run, "-run '^TcpDial(/|$)' -config ../config.yaml -golden ../golden.json") {
+		t.Errorf("run.sh does not rerun just TcpDial with the bundled inputs:\n%s", run)
+	}
+	var set ResultSet
+	if err := json.Unmarshal([]byte(bundle["repro-TcpDial/results/tml.json"]), &set); err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Results) != 1 || set.Results[0].Name != "tcp dial" || set.Language != "tml" {
+		t.Errorf("bundled TML results %+v, want only tcp dial", set)
+	}
+}
+
+// readTarGz returns the files of a gzipped tar by name
+func readTarGz(t *testing.T, path string) map[string]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[hdr.Name] = string(data)
+	}
+}
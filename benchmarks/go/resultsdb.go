@@ -0,0 +1,442 @@
+// Results Database - Go
+//
+// -db appends every run to a SQLite file - one row in runs for the
+// environment metadata, one row in results per case - so the history of a
+// machine or branch accumulates in one place, and the query subcommand
+// answers longitudinal questions over it: how a benchmark moved across
+// commits, which days were noisy, how two machines compare. The file is
+// plain SQLite, so anything the subcommand does not cover is one sqlite3
+// session away; each result row keeps its full JSON in the result column.
+//
+// The suite has no third-party dependencies, so rather than link a SQLite
+// driver it drives the sqlite3 command-line shell, which must be on PATH
+// (3.33 or newer, for -json output).
+//
+// query filters by benchmark (regular expression on the case name), date
+// range (-since/-until, dates or RFC 3339 times, -until inclusive),
+// machine (hostname), commit (prefix) and language, and prints per-case
+// aggregates: run count, mean, median, min, max, standard deviation and
+// the change from the first to the last run. -by additionally splits them
+// by day, commit or machine.
+//
+// Run with: go run . -db runs.sqlite [-run 'Tcp.*']
+//      then: go run . query -db runs.sqlite [-bench 'Tcp.*'] [-since 2026-01-01] [-until 2026-02-01]
+//                     [-machine host] [-commit abc123] [-language go] [-by day|commit|machine] [-csv]
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resultsDBSchema creates the tables of a results database; it is applied
+// on every store, so an empty or new file becomes a database
+const resultsDBSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id INTEGER PRIMARY KEY,
+	timestamp TEXT NOT NULL,
+	language TEXT NOT NULL,
+	hostname TEXT NOT NULL DEFAULT '',
+	cpu_model TEXT NOT NULL DEFAULT '',
+	goos TEXT NOT NULL DEFAULT '',
+	goarch TEXT NOT NULL DEFAULT '',
+	go_version TEXT NOT NULL DEFAULT '',
+	git_commit TEXT NOT NULL DEFAULT '',
+	git_dirty INTEGER NOT NULL DEFAULT 0,
+	remote_target TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS results (
+	run_id INTEGER NOT NULL REFERENCES runs(id),
+	name TEXT NOT NULL,
+	category TEXT NOT NULL DEFAULT '',
+	time_us REAL,
+	iterations INTEGER,
+	throughput_mbs REAL,
+	allocs_per_op INTEGER,
+	bytes_per_op INTEGER,
+	p50_us REAL,
+	p99_us REAL,
+	error TEXT NOT NULL DEFAULT '',
+	result TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS runs_timestamp ON runs(timestamp);
+CREATE INDEX IF NOT EXISTS results_name ON results(name);
+`
+
+// StoreRun appends a result set to the database at path, creating it if
+// needed
+func StoreRun(path string, set ResultSet) error {
+	m := set.Metadata
+	if m.Timestamp == "" {
+		m.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	}
+	var sql strings.Builder
+	sql.WriteString(resultsDBSchema)
+	sql.WriteString("BEGIN IMMEDIATE;\n")
+	fmt.Fprintf(&sql, "INSERT INTO runs (timestamp, language, hostname, cpu_model, goos, goarch, go_version, git_commit, git_dirty, remote_target) VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %d, %s);\n",
+		sqlText(m.Timestamp), sqlText(set.Language), sqlText(m.Hostname), sqlText(m.CPUModel), sqlText(m.GOOS),
+		sqlText(m.GOARCH), sqlText(m.GoVersion), sqlText(m.GitCommit), sqlBool(m.GitDirty), sqlText(m.RemoteTarget))
+	for _, r := range set.Results {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		p50, p99 := "NULL", "NULL"
+		if r.Latency != nil {
+			p50, p99 = sqlReal(r.Latency.P50Us), sqlReal(r.Latency.P99Us)
+		}
+		// Inside the transaction the newest run is the one just inserted
+		fmt.Fprintf(&sql, "INSERT INTO results SELECT max(id), %s, %s, %s, %d, %s, %d, %d, %s, %s, %s, %s FROM runs;\n",
+			sqlText(r.Name), sqlText(r.Category), sqlReal(r.TimeUs), r.Iterations, sqlReal(r.ThroughputMBs),
+			r.AllocsPerOp, r.BytesPerOp, p50, p99, sqlText(r.Error), sqlText(string(data)))
+	}
+	sql.WriteString("COMMIT;\n")
+	_, err := sqlite(path, sql.String())
+	return err
+}
+
+// DBQuery selects the results a query aggregates; empty fields do not
+// filter
+type DBQuery struct {
+	// Bench is a regular expression on the case name
+	Bench string
+	// Since and Until bound the run timestamps, as RFC 3339 times
+	Since, Until string
+	Machine      string
+	// Commit is a prefix of the commit hash
+	Commit   string
+	Language string
+}
+
+// where is the SQL condition for the query's filters other than Bench
+func (q DBQuery) where() string {
+	conds := []string{"results.error = ''"}
+	if q.Since != "" {
+		conds = append(conds, "runs.timestamp >= "+sqlText(q.Since))
+	}
+	if q.Until != "" {
+		conds = append(conds, "runs.timestamp < "+sqlText(q.Until))
+	}
+	if q.Machine != "" {
+		conds = append(conds, "runs.hostname = "+sqlText(q.Machine))
+	}
+	if q.Commit != "" {
+		conds = append(conds, fmt.Sprintf("substr(runs.git_commit, 1, %d) = %s", len(q.Commit), sqlText(q.Commit)))
+	}
+	if q.Language != "" {
+		conds = append(conds, "runs.language = "+sqlText(q.Language))
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// dbRow is one stored result as the query reads it back
+type dbRow struct {
+	Name      string  `json:"name"`
+	TimeUs    float64 `json:"time_us"`
+	Timestamp string  `json:"timestamp"`
+	Hostname  string  `json:"hostname"`
+	GitCommit string  `json:"git_commit"`
+}
+
+// QueryRuns returns the stored results matching q, oldest run first
+func QueryRuns(path string, q DBQuery) ([]dbRow, error) {
+	pattern, err := regexp.Compile(q.Bench)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -bench pattern: %w", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	out, err := sqlite(path, "SELECT results.name, results.time_us, runs.timestamp, runs.hostname, runs.git_commit"+
+		" FROM results JOIN runs ON runs.id = results.run_id WHERE "+q.where()+
+		" ORDER BY runs.timestamp, runs.id;\n", "-json", "-readonly")
+	if err != nil {
+		return nil, err
+	}
+	var all []dbRow
+	// sqlite3 prints nothing at all for an empty result
+	if len(strings.TrimSpace(string(out))) > 0 {
+		if err := json.Unmarshal(out, &all); err != nil {
+			return nil, fmt.Errorf("reading sqlite3 output: %w", err)
+		}
+	}
+	var rows []dbRow
+	for _, r := range all {
+		if pattern.MatchString(r.Name) {
+			rows = append(rows, r)
+		}
+	}
+	return rows, nil
+}
+
+// DBAggregate summarizes the runs of one case, or of one case within one
+// group when the query is split
+type DBAggregate struct {
+	Name  string
+	Group string
+	Runs  int
+	// MeanUs, MedianUs, MinUs, MaxUs and StddevUs are over the runs' times
+	// per iteration
+	MeanUs, MedianUs, MinUs, MaxUs, StddevUs float64
+	// ChangePct is the last run's time relative to the first's
+	ChangePct float64
+	First     string
+	Last      string
+}
+
+// dbGroupings are the -by values and the key each takes from a row
+var dbGroupings = map[string]func(dbRow) string{
+	"": func(dbRow) string { return "" },
+	"day": func(r dbRow) string {
+		day, _, _ := strings.Cut(r.Timestamp, "T")
+		return day
+	},
+	"commit":  func(r dbRow) string { return shortCommit(r.GitCommit) },
+	"machine": func(r dbRow) string { return r.Hostname },
+}
+
+// AggregateRuns groups rows (oldest first) by case name and the -by key
+func AggregateRuns(rows []dbRow, by string) []DBAggregate {
+	key := dbGroupings[by]
+	type group struct {
+		name, key string
+		rows      []dbRow
+	}
+	var order []*group
+	groups := map[[2]string]*group{}
+	for _, r := range rows {
+		id := [2]string{r.Name, key(r)}
+		g, ok := groups[id]
+		if !ok {
+			g = &group{name: id[0], key: id[1]}
+			groups[id] = g
+			order = append(order, g)
+		}
+		g.rows = append(g.rows, r)
+	}
+
+	aggs := make([]DBAggregate, 0, len(order))
+	for _, g := range order {
+		times := make([]float64, len(g.rows))
+		sum := 0.0
+		for i, r := range g.rows {
+			times[i] = r.TimeUs
+			sum += r.TimeUs
+		}
+		n := float64(len(times))
+		a := DBAggregate{
+			Name: g.name, Group: g.key, Runs: len(times), MeanUs: sum / n,
+			ChangePct: percentChange(times[0], times[len(times)-1]),
+			First:     g.rows[0].Timestamp, Last: g.rows[len(g.rows)-1].Timestamp,
+		}
+		if len(times) > 1 {
+			squares := 0.0
+			for _, t := range times {
+				squares += (t - a.MeanUs) * (t - a.MeanUs)
+			}
+			a.StddevUs = math.Sqrt(squares / (n - 1))
+		}
+		sort.Float64s(times)
+		a.MinUs, a.MaxUs = times[0], times[len(times)-1]
+		if mid := len(times) / 2; len(times)%2 == 1 {
+			a.MedianUs = times[mid]
+		} else {
+			a.MedianUs = (times[mid-1] + times[mid]) / 2
+		}
+		aggs = append(aggs, a)
+	}
+	sort.SliceStable(aggs, func(i, j int) bool {
+		if aggs[i].Name != aggs[j].Name {
+			return aggs[i].Name < aggs[j].Name
+		}
+		return aggs[i].Group < aggs[j].Group
+	})
+	return aggs
+}
+
+// runQuery implements the query subcommand
+func runQuery(args []string) int {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	dbPath := fs.String("db", "", "results database written by -db")
+	bench := fs.String("bench", ".", "only cases whose name matches this regular expression")
+	since := fs.String("since", "", "only runs at or after this date (2006-01-02) or RFC 3339 time")
+	until := fs.String("until", "", "only runs before this RFC 3339 time, or up to and including this date")
+	machine := fs.String("machine", "", "only runs on this hostname")
+	commit := fs.String("commit", "", "only runs of commits starting with this hash prefix")
+	language := fs.String("language", "", "only runs of this language suite")
+	by := fs.String("by", "", "also split the aggregates by day, commit or machine")
+	csv := fs.Bool("csv", false, "print CSV instead of a table")
+	fs.Parse(args)
+	if *dbPath == "" || fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "usage: query -db runs.sqlite [-bench regexp] [-since date] [-until date] [-machine host] [-commit prefix] [-language go] [-by day|commit|machine] [-csv]")
+		return 2
+	}
+	if _, ok := dbGroupings[*by]; !ok {
+		fmt.Fprintf(os.Stderr, "error: unknown -by %q (want day, commit or machine)\n", *by)
+		return 2
+	}
+
+	q := DBQuery{Bench: *bench, Machine: *machine, Commit: *commit, Language: *language}
+	var err error
+	if q.Since, err = parseQueryTime(*since, false); err != nil {
+		fmt.Fprintf(os.Stderr, "error: -since: %v\n", err)
+		return 2
+	}
+	if q.Until, err = parseQueryTime(*until, true); err != nil {
+		fmt.Fprintf(os.Stderr, "error: -until: %v\n", err)
+		return 2
+	}
+
+	rows, err := QueryRuns(*dbPath, q)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	aggs := AggregateRuns(rows, *by)
+	if *csv {
+		writeAggregatesCSV(os.Stdout, aggs, *by)
+	} else {
+		writeAggregatesTable(os.Stdout, aggs, *by)
+	}
+	return 0
+}
+
+// parseQueryTime turns a -since or -until value into an RFC 3339 UTC time
+// comparable with stored timestamps; a bare date used as an upper bound
+// means the end of that day
+func parseQueryTime(s string, endOfDay bool) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC().Format(time.RFC3339), nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return "", fmt.Errorf("%q is neither a date (2006-01-02) nor an RFC 3339 time", s)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t.Format(time.RFC3339), nil
+}
+
+func writeAggregatesTable(w io.Writer, aggs []DBAggregate, by string) {
+	if len(aggs) == 0 {
+		fmt.Fprintln(w, "no matching results")
+		return
+	}
+	group := ""
+	if by != "" {
+		group = fmt.Sprintf(" %-12s", by)
+	}
+	fmt.Fprintf(w, "%-40s%s %5s %12s %12s %12s %12s %10s %9s\n", "Benchmark", group, "Runs", "Mean", "Median", "Min", "Max", "Stddev", "Change")
+	fmt.Fprintln(w, strings.Repeat("-", 120+len(group)))
+	for _, a := range aggs {
+		if by != "" {
+			group = fmt.Sprintf(" %-12s", a.Group)
+		}
+		fmt.Fprintf(w, "%-40s%s %5d %9.2f us %9.2f us %9.2f us %9.2f us %7.2f us %+8.1f%%\n",
+			a.Name, group, a.Runs, a.MeanUs, a.MedianUs, a.MinUs, a.MaxUs, a.StddevUs, a.ChangePct)
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 120+len(group)))
+	fmt.Fprintf(w, "%d cases, %s to %s\n", len(aggs), earliest(aggs), latest(aggs))
+}
+
+func writeAggregatesCSV(w io.Writer, aggs []DBAggregate, by string) {
+	header := "name,runs,mean_us,median_us,min_us,max_us,stddev_us,change_pct,first,last"
+	if by != "" {
+		header = "name," + by + strings.TrimPrefix(header, "name")
+	}
+	fmt.Fprintln(w, header)
+	for _, a := range aggs {
+		name := csvField(a.Name)
+		if by != "" {
+			name += "," + csvField(a.Group)
+		}
+		fmt.Fprintf(w, "%s,%d,%g,%g,%g,%g,%g,%g,%s,%s\n",
+			name, a.Runs, a.MeanUs, a.MedianUs, a.MinUs, a.MaxUs, a.StddevUs, a.ChangePct, a.First, a.Last)
+	}
+}
+
+func earliest(aggs []DBAggregate) string {
+	first := aggs[0].First
+	for _, a := range aggs {
+		first = min(first, a.First)
+	}
+	return first
+}
+
+func latest(aggs []DBAggregate) string {
+	last := aggs[0].Last
+	for _, a := range aggs {
+		last = max(last, a.Last)
+	}
+	return last
+}
+
+// csvField quotes a CSV field when it needs it
+func csvField(s string) string {
+	if strings.ContainsAny(s, ",\"\n") {
+		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	}
+	return s
+}
+
+// shortCommit abbreviates a commit hash the way git log --oneline does
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+// sqlite runs a SQL script through the sqlite3 shell against the database
+// at path and returns what it printed
+func sqlite(path, script string, options ...string) ([]byte, error) {
+	args := append([]string{"-bail", "-batch"}, options...)
+	cmd := exec.Command("sqlite3", append(args, path)...)
+	cmd.Stdin = strings.NewReader(script)
+	out, err := cmd.Output()
+	if err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) && len(exit.Stderr) > 0 {
+			return nil, fmt.Errorf("sqlite3 %s: %s", path, strings.TrimSpace(string(exit.Stderr)))
+		}
+		return nil, fmt.Errorf("sqlite3 %s: %w", path, err)
+	}
+	return out, nil
+}
+
+// sqlText is s as a SQL string literal
+func sqlText(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// sqlReal is f as a SQL number; SQLite has no NaN or infinities, so those
+// are stored as NULL
+func sqlReal(f float64) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "NULL"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func sqlBool(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+// Results Database Tests - Go
+//
+// Run with: go test -run 'AggregateRuns|ParseQueryTime|ResultsDB'
+
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestAggregateRuns(t *testing.T) {
+	rows := []dbRow{
+		{Name: "b", TimeUs: 10, Timestamp: "2026-01-01T10:00:00Z", GitCommit: "aaa"},
+		{Name: "a", TimeUs: 4, Timestamp: "2026-01-01T10:00:00Z", GitCommit: "aaa"},
+		{Name: "b", TimeUs: 30, Timestamp: "2026-01-02T10:00:00Z", GitCommit: "bbb"},
+		{Name: "b", TimeUs: 20, Timestamp: "2026-01-02T11:00:00Z", GitCommit: "bbb"},
+	}
+	aggs := AggregateRuns(rows, "")
+	if len(aggs) != 2 || aggs[0].Name != "a" || aggs[1].Name != "b" {
+		t.Fatalf("aggregates %+v, want a then b", aggs)
+	}
+	b := aggs[1]
+	if b.Runs != 3 || b.MeanUs != 20 || b.MedianUs != 20 || b.MinUs != 10 || b.MaxUs != 30 || b.StddevUs != 10 {
+		t.Errorf("b = %+v, want 3 runs, mean/median 20, min 10, max 30, stddev 10", b)
+	}
+	if b.ChangePct != 100 || b.First != rows[0].Timestamp || b.Last != rows[3].Timestamp {
+		t.Errorf("b change %.1f%% from %s to %s, want +100%% over all three runs", b.ChangePct, b.First, b.Last)
+	}
+
+	byDay := AggregateRuns(rows, "day")
+	if len(byDay) != 3 || byDay[1].Group != "2026-01-01" || byDay[2].Group != "2026-01-02" || byDay[2].MedianUs != 25 {
+		t.Errorf("by day %+v, want b split into 2026-01-01 and 2026-01-02 (median 25)", byDay)
+	}
+}
+
+func TestParseQueryTime(t *testing.T) {
+	for _, tc := range []struct {
+		in       string
+		endOfDay bool
+		want     string
+	}{
+		{"", false, ""},
+		{"2026-03-01", false, "2026-03-01T00:00:00Z"},
+		{"2026-03-01", true, "2026-03-02T00:00:00Z"},
+		{"2026-03-01T12:00:00+02:00", true, "2026-03-01T10:00:00Z"},
+	} {
+		got, err := parseQueryTime(tc.in, tc.endOfDay)
+		if err != nil || got != tc.want {
+			t.Errorf("parseQueryTime(%q, %t) = %q, %v; want %q", tc.in, tc.endOfDay, got, err, tc.want)
+		}
+	}
+	if _, err := parseQueryTime("yesterday", false); err == nil {
+		t.Error("an unparsable time was accepted")
+	}
+}
+
+func TestResultsDBStoreAndQuery(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 is not on PATH")
+	}
+	path := filepath.Join(t.TempDir(), "runs.sqlite")
+	run := func(host, commit, timestamp string, us float64) ResultSet {
+		return ResultSet{
+			Language: "go",
+			Metadata: Metadata{Hostname: host, GitCommit: commit, Timestamp: timestamp, CPUModel: "it's quoted"},
+			Results: []BenchmarkResult{
+				{Name: "TcpDial", TimeUs: us, Iterations: 10},
+				{Name: "Sort", TimeUs: 1, Iterations: 10},
+				{Name: "Broken", Error: "setup failed"},
+			},
+		}
+	}
+	for _, set := range []ResultSet{
+		run("alpha", "abc123", "2026-01-01T00:00:00Z", 10),
+		run("alpha", "def456", "2026-01-05T00:00:00Z", 12),
+		run("beta", "def456", "2026-01-06T00:00:00Z", 30),
+	} {
+		if err := StoreRun(path, set); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		q    DBQuery
+		want []float64
+	}{
+		{DBQuery{Bench: "Dial"}, []float64{10, 12, 30}},
+		{DBQuery{Bench: "Dial", Machine: "alpha"}, []float64{10, 12}},
+		{DBQuery{Bench: "Dial", Commit: "def"}, []float64{12, 30}},
+		{DBQuery{Bench: "Dial", Since: "2026-01-02T00:00:00Z", Until: "2026-01-06T00:00:00Z"}, []float64{12}},
+		{DBQuery{Bench: "Dial", Language: "tml"}, nil},
+		{DBQuery{Bench: "Broken"}, nil},
+	} {
+		rows, err := QueryRuns(path, tc.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []float64
+		for _, r := range rows {
+			got = append(got, r.TimeUs)
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("%+v: times %v, want %v", tc.q, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%+v: times %v, want %v", tc.q, got, tc.want)
+				break
+			}
+		}
+	}
+}
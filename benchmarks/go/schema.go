@@ -0,0 +1,151 @@
+// Result Schema Versioning - Go
+//
+// Result files carry a schema_version field so saved baselines and result
+// sets from older harness builds keep loading as the format evolves.
+// LoadResultSet detects the version of a document and runs it through the
+// chain of migrations up to resultSchemaVersion before decoding it.
+//
+// Version history:
+//
+//	1: unversioned files - the Go result set before versioning, the flat
+//	   per-category format of the TML and C++ suites (total_ns/per_op_ns)
+//	   and the bare result array of the Python suite (throughput_mb_s)
+//	2: schema_version added; timings always in time_us, category set on
+//	   every result
+//	3: metadata records the TCP echo socket options (tcp_nodelay,
+//	   tcp_write_mode); older Go runs used Go's defaults
+//
+// To change the format, bump resultSchemaVersion and register a migration
+// from the previous version in resultMigrations.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// resultSchemaVersion is the result file format version written by this
+// build
+const resultSchemaVersion = 3
+
+// resultMigrations maps each version to the migration that upgrades a
+// document of that version to the next one
+var resultMigrations = map[int]func(data []byte) ([]byte, error){
+	1: migrateResultsV1,
+	2: migrateResultsV2,
+}
+
+// resultSchemaVersionOf reports the schema version of a result document;
+// documents without one are version 1
+func resultSchemaVersionOf(data []byte) (int, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return 1, nil
+	}
+	var doc struct {
+		SchemaVersion *int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return 0, err
+	}
+	if doc.SchemaVersion == nil {
+		return 1, nil
+	}
+	if *doc.SchemaVersion < 1 {
+		return 0, fmt.Errorf("invalid schema_version %d", *doc.SchemaVersion)
+	}
+	return *doc.SchemaVersion, nil
+}
+
+// migrateResultSet upgrades a result document to resultSchemaVersion
+func migrateResultSet(data []byte) ([]byte, error) {
+	version, err := resultSchemaVersionOf(data)
+	if err != nil {
+		return nil, err
+	}
+	if version > resultSchemaVersion {
+		return nil, fmt.Errorf("schema_version %d is newer than this harness supports (%d); update the harness",
+			version, resultSchemaVersion)
+	}
+	for ; version < resultSchemaVersion; version++ {
+		migrate, ok := resultMigrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration from schema_version %d", version)
+		}
+		if data, err = migrate(data); err != nil {
+			return nil, fmt.Errorf("migrating from schema_version %d: %w", version, err)
+		}
+	}
+	return data, nil
+}
+
+// resultEntryV1 is one version 1 result in any of the suites' formats
+type resultEntryV1 struct {
+	BenchmarkResult
+	TotalNs int64 `json:"total_ns"`
+	PerOpNs int64 `json:"per_op_ns"`
+	// ThroughputMBs2 is the spelling used by the Python suite
+	ThroughputMBs2 float64 `json:"throughput_mb_s"`
+}
+
+// resultFileV1 is the union of the Go result set and the flat per-category
+// format written by the TML and C++ suites (common/bench.tml, bench.hpp)
+type resultFileV1 struct {
+	Language string          `json:"language"`
+	Category string          `json:"category"`
+	Metadata Metadata        `json:"metadata"`
+	Results  []resultEntryV1 `json:"results"`
+}
+
+// migrateResultsV1 normalizes the version 1 formats into a version 2 result
+// set: timings become microseconds per iteration and the file-level
+// category moves onto each result. A bare array's language is left empty
+// for the caller to fill in.
+func migrateResultsV1(data []byte) ([]byte, error) {
+	var file resultFileV1
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(data, &file.Results)
+	} else {
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	set := ResultSet{SchemaVersion: 2, Language: file.Language, Metadata: file.Metadata}
+	for _, raw := range file.Results {
+		r := raw.BenchmarkResult
+		if r.Category == "" {
+			r.Category = file.Category
+		}
+		if r.ThroughputMBs == 0 {
+			r.ThroughputMBs = raw.ThroughputMBs2
+		}
+		if r.TimeUs == 0 {
+			if raw.TotalNs > 0 && r.Iterations > 0 {
+				r.TimeUs = float64(raw.TotalNs) / float64(r.Iterations) / 1000
+			} else {
+				r.TimeUs = float64(raw.PerOpNs) / 1000
+			}
+		}
+		set.Results = append(set.Results, r)
+	}
+	return json.Marshal(set)
+}
+
+// migrateResultsV2 fills in the TCP options older Go runs always used:
+// TCP_NODELAY on, one write per request
+func migrateResultsV2(data []byte) ([]byte, error) {
+	var set ResultSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	if set.Language == "go" {
+		set.Metadata.TCPNoDelay = true
+		set.Metadata.TCPWriteMode = "single"
+	}
+	set.SchemaVersion = 3
+	return json.Marshal(set)
+}
@@ -0,0 +1,86 @@
+// Result Schema Tests - Go
+//
+// Run with: go test -run Schema
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func loadResultDoc(t *testing.T, doc string) (ResultSet, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "results.json")
+	if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadResultSet(path)
+}
+
+func TestSchemaMigratesVersion1Formats(t *testing.T) {
+	cases := map[string]string{
+		"go":     `{"language": "go", "results": [{"name": "Fib", "category": "math", "time_us": 2.5, "iterations": 10}]}`,
+		"flat":   `{"language": "tml", "category": "math", "results": [{"name": "Fib", "iterations": 10, "total_ns": 25000}]}`,
+		"per-op": `{"language": "cpp", "category": "math", "results": [{"name": "Fib", "iterations": 10, "per_op_ns": 2500}]}`,
+		"python": `[{"name": "Fib", "category": "math", "time_us": 2.5, "iterations": 10, "throughput_mb_s": 4}]`,
+	}
+	for name, doc := range cases {
+		set, err := loadResultDoc(t, doc)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if set.SchemaVersion != resultSchemaVersion || len(set.Results) != 1 {
+			t.Fatalf("%s: loaded %+v", name, set)
+		}
+		r := set.Results[0]
+		if r.Name != "Fib" || r.Category != "math" || r.TimeUs != 2.5 {
+			t.Errorf("%s: migrated result %+v", name, r)
+		}
+		if name == "python" && r.ThroughputMBs != 4 {
+			t.Errorf("python: throughput %v, want 4", r.ThroughputMBs)
+		}
+	}
+}
+
+func TestSchemaRoundTripsCurrentVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.json")
+	want := BenchmarkResult{Name: "Fib", Category: "math", TimeUs: 2.5, Iterations: 10}
+	if err := WriteResultSet(path, ResultSet{Language: "go", Results: []BenchmarkResult{want}}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), fmt.Sprintf(`"schema_version": %d`, resultSchemaVersion)) {
+		t.Errorf("written file lacks schema_version:\n%s", data)
+	}
+	set, err := LoadResultSet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Language != "go" || len(set.Results) != 1 || set.Results[0].TimeUs != want.TimeUs {
+		t.Errorf("round trip loaded %+v", set)
+	}
+}
+
+func TestSchemaMigratesVersion2TCPOptions(t *testing.T) {
+	set, err := loadResultDoc(t, `{"schema_version": 2, "language": "go", "metadata": {"go_version": "go1.23"}, "results": []}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := set.Metadata; !m.TCPNoDelay || m.TCPWriteMode != "single" || m.GoVersion != "go1.23" {
+		t.Errorf("migrated metadata %+v, want Go's TCP defaults", m)
+	}
+}
+
+func TestSchemaRejectsNewerVersion(t *testing.T) {
+	_, err := loadResultDoc(t, `{"schema_version": 99, "language": "go", "results": []}`)
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("loading a newer schema: %v, want an error", err)
+	}
+}
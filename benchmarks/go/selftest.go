@@ -0,0 +1,156 @@
+// Harness Self-Test - Go
+//
+// Benchmarks operations of known duration and checks that the harness
+// reports them accurately, catching clock or accounting bugs before any
+// comparison numbers are published.
+//
+// Run with: go run . selftest [-tolerance 0.10]
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+)
+
+// selfTestCheck is a single harness accuracy check
+type selfTestCheck struct {
+	Name string
+	Run  func(tolerance float64) (detail string, ok bool)
+}
+
+var selfTestChecks = []selfTestCheck{
+	{"Clock resolution", checkClockResolution},
+	{"Iteration accounting", checkIterationAccounting},
+	{"Busy-wait 100us", func(tol float64) (string, bool) { return checkBusyWait(100*time.Microsecond, tol) }},
+	{"Busy-wait 1ms", func(tol float64) (string, bool) { return checkBusyWait(time.Millisecond, tol) }},
+	{"Sleep 2ms", func(tol float64) (string, bool) { return checkSleep(2*time.Millisecond, tol) }},
+	{"Sleep 10ms", func(tol float64) (string, bool) { return checkSleep(10*time.Millisecond, tol) }},
+	{"Throughput accounting", checkThroughput},
+	{"StopTimer exclusion", checkStopTimer},
+	{"ResetTimer", checkResetTimer},
+}
+
+// runSelfTest implements the selftest subcommand
+func runSelfTest(args []string) int {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	tolerance := fs.Float64("tolerance", 0.10, "allowed relative error for timed checks")
+	fs.Parse(args)
+
+	fmt.Println("=== Go Harness Self-Test ===")
+	fmt.Println()
+
+	failed := 0
+	for _, check := range selfTestChecks {
+		detail, ok := check.Run(*tolerance)
+		status := "PASS"
+		if !ok {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("  [%s] %-24s %s\n", status, check.Name, detail)
+	}
+
+	fmt.Println()
+	if failed > 0 {
+		fmt.Printf("%d of %d checks failed\n", failed, len(selfTestChecks))
+		return 1
+	}
+	fmt.Printf("All %d checks passed\n", len(selfTestChecks))
+	return 0
+}
+
+// withinTolerance reports whether measured is within tol of expected
+func withinTolerance(measured, expected, tol float64) bool {
+	return measured >= expected*(1-tol) && measured <= expected*(1+tol)
+}
+
+// busyWait spins until d has elapsed, without yielding to the scheduler
+func busyWait(d time.Duration) {
+	start := time.Now()
+	for time.Since(start) < d {
+	}
+}
+
+func checkClockResolution(tol float64) (string, bool) {
+	smallest := time.Duration(1<<63 - 1)
+	for i := 0; i < 1000; i++ {
+		start := time.Now()
+		var d time.Duration
+		for d == 0 {
+			d = time.Since(start)
+		}
+		if d < smallest {
+			smallest = d
+		}
+	}
+	return fmt.Sprintf("smallest tick %v", smallest), smallest < time.Microsecond
+}
+
+func checkIterationAccounting(tol float64) (string, bool) {
+	const iterations = 1000
+	calls := 0
+	r := RunBenchmark("selftest", iterations, 0, func(b *B) { calls++ })
+	// RunBenchmark warms up with min(iterations/10, 10) extra calls
+	expected := iterations + 10
+	return fmt.Sprintf("%d calls (expected %d), reported %d iters", calls, expected, r.Iterations),
+		calls == expected && r.Iterations == iterations
+}
+
+func checkBusyWait(d time.Duration, tol float64) (string, bool) {
+	r := RunBenchmark("selftest", 50, 0, func(b *B) { busyWait(d) })
+	expected := float64(d.Microseconds())
+	return fmt.Sprintf("reported %.2f us (expected %.2f us)", r.TimeUs, expected),
+		withinTolerance(r.TimeUs, expected, tol)
+}
+
+func checkSleep(d time.Duration, tol float64) (string, bool) {
+	r := RunBenchmark("selftest", 20, 0, func(b *B) { time.Sleep(d) })
+	expected := float64(d.Microseconds())
+	// Sleeps never return early but routinely overshoot by timer slack, so
+	// only the lower bound is strict and the upper bound allows one extra
+	// millisecond on top of the tolerance.
+	ok := r.TimeUs >= expected && r.TimeUs <= expected*(1+tol)+1000
+	return fmt.Sprintf("reported %.2f us (expected >= %.2f us)", r.TimeUs, expected), ok
+}
+
+func checkThroughput(tol float64) (string, bool) {
+	const dataSize = 1 << 20
+	d := time.Millisecond
+	r := RunBenchmark("selftest", 20, dataSize, func(b *B) { busyWait(d) })
+	expected := float64(dataSize) / (1024 * 1024) / d.Seconds()
+	return fmt.Sprintf("reported %.2f MB/s (expected %.2f MB/s)", r.ThroughputMBs, expected),
+		withinTolerance(r.ThroughputMBs, expected, tol)
+}
+
+func checkStopTimer(tol float64) (string, bool) {
+	d := 200 * time.Microsecond
+	r := RunBenchmark("selftest", 50, 0, func(b *B) {
+		b.StopTimer()
+		busyWait(time.Millisecond) // excluded setup
+		b.StartTimer()
+		busyWait(d)
+	})
+	expected := float64(d.Microseconds())
+	return fmt.Sprintf("reported %.2f us (expected %.2f us)", r.TimeUs, expected),
+		withinTolerance(r.TimeUs, expected, tol)
+}
+
+func checkResetTimer(tol float64) (string, bool) {
+	const iterations = 50
+	d := 200 * time.Microsecond
+	first := true
+	r := RunBenchmark("selftest", iterations, 0, func(b *B) {
+		// b.N distinguishes the measured run from the warmup
+		if first && b.N == iterations {
+			busyWait(20 * time.Millisecond) // excluded one-time setup
+			b.ResetTimer()
+			first = false
+		}
+		busyWait(d)
+	})
+	expected := float64(d.Microseconds())
+	return fmt.Sprintf("reported %.2f us (expected %.2f us)", r.TimeUs, expected),
+		withinTolerance(r.TimeUs, expected, tol)
+}
@@ -0,0 +1,193 @@
+// Go Zero-Copy File Serving Benchmark
+// Serves a file over loopback TCP the two ways a file server can, to
+// compare against TML's zero-copy I/O:
+//
+//   - sendfile: io.Copy from the *os.File into the *net.TCPConn, which Go
+//     turns into sendfile(2) on Linux, the BSDs, macOS and Solaris (and
+//     TransmitFile on Windows), so the data never enters user space
+//   - read_write: a manual loop reading the file into a user-space buffer
+//     and writing it to the connection, the copy sendfile avoids
+//
+// The file is written at setup and is in the page cache by the time the
+// measured iterations run, so the cases compare the copy, not the disk.
+// Each iteration connects, has the server send the whole file and hang up,
+// and drains it on the client. Reported per case:
+//
+//   - cpu_pct: process CPU time (server and client) over wall time
+//   - vs_read_write_pct: the sendfile case's throughput relative to the
+//     read_write case, when that ran first
+//
+// Where the platform has no zero-copy path io.Copy falls back to a
+// user-space copy, and the two cases measure the same thing.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	fileServeSize = 256 << 20
+	// fileServeBuffer is the read_write loop's buffer, the size io.Copy
+	// would use without a zero-copy path
+	fileServeBuffer = 32 * 1024
+)
+
+// fileServeFixture is the served file and the server sending it
+type fileServeFixture struct {
+	dir  string
+	path string
+	ln   net.Listener
+	// zeroCopy selects io.Copy over the read/write loop
+	zeroCopy bool
+	// errs delivers the server's send errors
+	errs chan error
+	// run is the B the totals below belong to: warmup and measurement get
+	// separate ones
+	run              *B
+	received         int64
+	elapsed, cpuTime time.Duration
+	// readWriteMBs is the measured throughput of the read_write case,
+	// which sendfile reports relative to
+	readWriteMBs float64
+}
+
+var fileServe fileServeFixture
+
+// serveFile sends the file to conn in the fixture's mode and hangs up
+func (s *fileServeFixture) serveFile(conn net.Conn) error {
+	defer conn.Close()
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if s.zeroCopy {
+		_, err = io.Copy(conn, f)
+		return err
+	}
+	buf := make([]byte, fileServeBuffer)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			if _, werr := conn.Write(buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// fetch downloads the file once and returns the bytes received
+func (s *fileServeFixture) fetch() (int64, error) {
+	conn, err := dialTCP(s.ln.Addr().String())
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	n, err := io.Copy(io.Discard, conn)
+	if err != nil {
+		return n, err
+	}
+	select {
+	case err := <-s.errs:
+		return n, fmt.Errorf("server: %w", err)
+	default:
+	}
+	if n != fileServeSize {
+		return n, fmt.Errorf("received %d bytes, want %d", n, fileServeSize)
+	}
+	return n, nil
+}
+
+func setupFileServe(b *B) error {
+	s := &fileServe
+	dir, err := os.MkdirTemp("", "tml-fileserve-")
+	if err != nil {
+		return err
+	}
+	s.dir = dir
+	s.path = filepath.Join(dir, "served.bin")
+	s.zeroCopy = b.StringParam("method") == "sendfile"
+	s.errs = make(chan error, 1)
+	if err := writeTransferFile(s.path, fileServeSize); err != nil {
+		return err
+	}
+	if s.ln, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+		return err
+	}
+	go func() {
+		for {
+			conn, err := s.ln.Accept()
+			if err != nil {
+				return
+			}
+			configureTCPConn(conn)
+			go func() {
+				if err := s.serveFile(conn); err != nil {
+					select {
+					case s.errs <- err:
+					default:
+					}
+				}
+			}()
+		}
+	}()
+	return nil
+}
+
+func teardownFileServe() {
+	s := &fileServe
+	if s.ln != nil {
+		s.ln.Close()
+	}
+	if s.dir != "" {
+		os.RemoveAll(s.dir)
+	}
+	// Keep the read_write throughput for the sendfile case that follows
+	*s = fileServeFixture{readWriteMBs: s.readWriteMBs}
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "FileServe", Category: "transfer", Tags: []string{"net"},
+		Iterations: 5, DataSize: fileServeSize,
+		Axes:  []Axis{{Name: "method", Values: Strings("read_write", "sendfile")}},
+		Setup: setupFileServe, Teardown: teardownFileServe,
+		Fn: func(b *B) {
+			s := &fileServe
+			if s.run != b {
+				s.run, s.received, s.elapsed, s.cpuTime = b, 0, 0, 0
+			}
+			cpuStart := processCPUTime()
+			start := time.Now()
+			n, err := s.fetch()
+			if err != nil {
+				b.Fatal(err)
+				return
+			}
+			s.elapsed += time.Since(start)
+			s.cpuTime += processCPUTime() - cpuStart
+			s.received += n
+
+			// Report the run so far; the last iteration's figures cover all
+			mbs := float64(s.received) / s.elapsed.Seconds() / (1024 * 1024)
+			b.ReportMetric("cpu_pct", 100*s.cpuTime.Seconds()/s.elapsed.Seconds())
+			if !s.zeroCopy {
+				s.readWriteMBs = mbs
+			} else if s.readWriteMBs > 0 {
+				b.ReportMetric("vs_read_write_pct", percentChange(s.readWriteMBs, mbs))
+			}
+		},
+	})
+}
@@ -0,0 +1,215 @@
+// Sliding Window Statistics Benchmarks - Go
+//
+// Rolling mean and max over a streaming sequence, one iteration per value
+// added, so the reported time is the cost of a single window slide:
+//
+//   - naive recomputes both over the whole window, O(w) per slide
+//   - deque keeps a running sum and a monotonic queue of max candidates,
+//     amortized O(1)
+//   - segment tree keeps sum and max over a circular window, O(log w)
+//
+// Each runs at window sizes from 10 to 1M; the window is filled in setup.
+
+package main
+
+import (
+	"math"
+	"sync"
+)
+
+// slidingWindow is a fixed-size window over a stream
+type slidingWindow interface {
+	// add appends v, evicting the oldest value once the window is full
+	add(v float64)
+	// stats returns the mean and max of the values in the window
+	stats() (mean, max float64)
+}
+
+// naiveWindow recomputes every statistic from the raw window
+type naiveWindow struct {
+	buf []float64
+	pos int
+	n   int
+}
+
+func newNaiveWindow(size int) *naiveWindow {
+	return &naiveWindow{buf: make([]float64, size)}
+}
+
+func (w *naiveWindow) add(v float64) {
+	w.buf[w.pos] = v
+	w.pos = (w.pos + 1) % len(w.buf)
+	if w.n < len(w.buf) {
+		w.n++
+	}
+}
+
+func (w *naiveWindow) stats() (float64, float64) {
+	sum, max := 0.0, math.Inf(-1)
+	for _, x := range w.buf[:w.n] {
+		sum += x
+		if x > max {
+			max = x
+		}
+	}
+	return sum / float64(w.n), max
+}
+
+// dequeEntry is a max candidate and the stream position it was added at
+type dequeEntry struct {
+	seq int
+	v   float64
+}
+
+// dequeWindow keeps candidates for the max in decreasing order: a value
+// can never be the max once a larger, newer one has arrived
+type dequeWindow struct {
+	size  int
+	vals  []float64
+	seq   int
+	sum   float64
+	queue []dequeEntry // ring buffer
+	head  int
+	count int
+}
+
+func newDequeWindow(size int) *dequeWindow {
+	return &dequeWindow{size: size, vals: make([]float64, size), queue: make([]dequeEntry, size)}
+}
+
+func (w *dequeWindow) add(v float64) {
+	slot := w.seq % w.size
+	if w.seq >= w.size {
+		w.sum -= w.vals[slot]
+	}
+	w.vals[slot] = v
+	w.sum += v
+
+	for w.count > 0 && w.queue[(w.head+w.count-1)%w.size].v <= v {
+		w.count--
+	}
+	if w.count > 0 && w.queue[w.head].seq <= w.seq-w.size {
+		w.head = (w.head + 1) % w.size
+		w.count--
+	}
+	w.queue[(w.head+w.count)%w.size] = dequeEntry{seq: w.seq, v: v}
+	w.count++
+	w.seq++
+}
+
+func (w *dequeWindow) stats() (float64, float64) {
+	return w.sum / float64(min(w.seq, w.size)), w.queue[w.head].v
+}
+
+// segTreeWindow stores the window in the leaves of a binary tree whose
+// inner nodes hold the sum and max of their subtree
+type segTreeWindow struct {
+	size   int
+	leaves int
+	sum    []float64
+	max    []float64
+	seq    int
+}
+
+func newSegTreeWindow(size int) *segTreeWindow {
+	leaves := 1
+	for leaves < size {
+		leaves <<= 1
+	}
+	t := &segTreeWindow{size: size, leaves: leaves, sum: make([]float64, 2*leaves), max: make([]float64, 2*leaves)}
+	for i := range t.max {
+		t.max[i] = math.Inf(-1)
+	}
+	return t
+}
+
+func (t *segTreeWindow) add(v float64) {
+	i := t.leaves + t.seq%t.size
+	t.sum[i], t.max[i] = v, v
+	for i > 1 {
+		i >>= 1
+		t.sum[i] = t.sum[2*i] + t.sum[2*i+1]
+		t.max[i] = math.Max(t.max[2*i], t.max[2*i+1])
+	}
+	t.seq++
+}
+
+func (t *segTreeWindow) stats() (float64, float64) {
+	return t.sum[1] / float64(min(t.seq, t.size)), t.max[1]
+}
+
+// slidingStreamLen is the length of the pre-generated stream; positions
+// wrap around it
+const slidingStreamLen = 1 << 22
+
+// slidingStream is a random walk, so the max changes both by new peaks
+// and by old peaks leaving the window
+var slidingStream = sync.OnceValue(func() []float64 {
+	rng := newRand(1524)
+	s := make([]float64, slidingStreamLen)
+	v := 0.0
+	for i := range s {
+		v += rng.NormFloat64()
+		s[i] = v
+	}
+	return s
+})
+
+// slidingState is the window under test and the next stream position
+var slidingState struct {
+	window slidingWindow
+	pos    int
+}
+
+// slidingSink keeps results observable so slides are not optimized away
+var slidingSink float64
+
+// setupSliding returns a Setup that builds a window with newWindow and
+// fills it from the stream
+func setupSliding(newWindow func(size int) slidingWindow) func(b *B) error {
+	return func(b *B) error {
+		size := b.IntParam("window")
+		stream := slidingStream()
+		w := newWindow(size)
+		for i := 0; i < size; i++ {
+			w.add(stream[i%slidingStreamLen])
+		}
+		slidingState.window, slidingState.pos = w, size
+		return nil
+	}
+}
+
+func teardownSliding() {
+	slidingState.window = nil
+}
+
+func benchSliding(b *B) {
+	s := &slidingState
+	s.window.add(slidingStream()[s.pos%slidingStreamLen])
+	s.pos++
+	mean, max := s.window.stats()
+	slidingSink = mean + max
+}
+
+func init() {
+	windows := []Axis{{Name: "window", Values: Ints(10, 1000, 100_000, 1_000_000)}}
+
+	Register(Benchmark{
+		Name: "SlidingWindowNaive", Category: "sliding", Tags: []string{"cpu"},
+		Iterations: 1000, Axes: windows,
+		Setup:    setupSliding(func(size int) slidingWindow { return newNaiveWindow(size) }),
+		Teardown: teardownSliding, Fn: benchSliding,
+	})
+	Register(Benchmark{
+		Name: "SlidingWindowDeque", Category: "sliding", Tags: []string{"cpu"},
+		Iterations: 1_000_000, Axes: windows,
+		Setup:    setupSliding(func(size int) slidingWindow { return newDequeWindow(size) }),
+		Teardown: teardownSliding, Fn: benchSliding,
+	})
+	Register(Benchmark{
+		Name: "SlidingWindowSegmentTree", Category: "sliding", Tags: []string{"cpu"},
+		Iterations: 1_000_000, Axes: windows,
+		Setup:    setupSliding(func(size int) slidingWindow { return newSegTreeWindow(size) }),
+		Teardown: teardownSliding, Fn: benchSliding,
+	})
+}
@@ -0,0 +1,30 @@
+// Sliding Window Tests - Go
+//
+// Run with: go test -run Sliding
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSlidingWindowsAgree(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, size := range []int{1, 2, 7, 64} {
+		naive, deque, tree := newNaiveWindow(size), newDequeWindow(size), newSegTreeWindow(size)
+		for i := 0; i < 1000; i++ {
+			v := float64(rng.Intn(100))
+			naive.add(v)
+			wantMean, wantMax := naive.stats()
+			for name, w := range map[string]slidingWindow{"deque": deque, "segtree": tree} {
+				w.add(v)
+				mean, max := w.stats()
+				if math.Abs(mean-wantMean) > 1e-9 || max != wantMax {
+					t.Fatalf("%s size %d step %d: mean/max = %v/%v, want %v/%v", name, size, i, mean, max, wantMean, wantMax)
+				}
+			}
+		}
+	}
+}
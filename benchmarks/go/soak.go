@@ -0,0 +1,190 @@
+// Soak Mode - Go
+//
+// With -soak, each selected case runs continuously for the given duration
+// instead of for its iteration count, and every -soak-interval a snapshot
+// is taken of the interval just finished: iterations per second,
+// throughput, latency percentiles, live heap (after a forced collection,
+// outside the timed region) and goroutine count. A case that is stable
+// draws flat lines; one that leaks or degrades drifts, which is what TML's
+// published soak stability graphs compare against.
+//
+// Soak runs default to the server-style benchmarks (tag net) unless -tags
+// says otherwise. The result of a case covers the whole soak, with the
+// snapshots attached and the drift from the first to the last snapshot in
+// its metrics: heap_growth_pct, goroutine_growth and ops_drift_pct. The
+// first snapshot includes any warm-up effects, so soak for many intervals.
+// Measure hooks, raw samples and traffic recording do not apply to soaks.
+//
+// Run with: go run . -soak 1h [-soak-interval 10s] [-run 'TcpReusedRequest'] [-o soak.json]
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// SoakSnapshot is one interval of a soak
+type SoakSnapshot struct {
+	// ElapsedS is when the interval ended, in seconds since the soak began
+	ElapsedS      float64 `json:"elapsed_s"`
+	OpsPerSec     float64 `json:"ops_per_sec"`
+	ThroughputMBs float64 `json:"throughput_mbs,omitempty"`
+	P50Us         float64 `json:"p50_us,omitempty"`
+	P99Us         float64 `json:"p99_us,omitempty"`
+	MaxUs         float64 `json:"max_us,omitempty"`
+	HeapBytes     uint64  `json:"heap_bytes"`
+	Goroutines    int     `json:"goroutines"`
+}
+
+// String formats the snapshot as one progress line
+func (s SoakSnapshot) String() string {
+	line := fmt.Sprintf("%8.0fs %12.1f ops/s", s.ElapsedS, s.OpsPerSec)
+	if s.ThroughputMBs > 0 {
+		line += fmt.Sprintf(" %10.2f MB/s", s.ThroughputMBs)
+	}
+	if s.P99Us > 0 {
+		line += fmt.Sprintf("  p50 %.2f us  p99 %.2f us  max %.2f us", s.P50Us, s.P99Us, s.MaxUs)
+	}
+	return line + fmt.Sprintf("  heap %.1f MiB  goroutines %d", float64(s.HeapBytes)/(1<<20), s.Goroutines)
+}
+
+// RunSoak runs one case for duration, taking a snapshot every interval and
+// passing it to report as it is taken, and reports what it leaked
+func RunSoak(c Case, duration, interval time.Duration, report func(SoakSnapshot)) BenchmarkResult {
+	if !leakCheck {
+		return runSoak(c, duration, interval, report)
+	}
+	before := takeLeakSnapshot()
+	r := runSoak(c, duration, interval, report)
+	r.Leaks = checkLeaks(before)
+	return r
+}
+
+// runSoak is RunSoak without the leak check
+func runSoak(c Case, duration, interval time.Duration, report func(SoakSnapshot)) BenchmarkResult {
+	bench := c.Bench
+	resetTrackedTCPConns()
+	defer resetTrackedTCPConns()
+
+	if bench.Setup != nil {
+		if err := bench.Setup(&B{params: c.Params}); err != nil {
+			// Release whatever the failed Setup got as far as creating
+			if bench.Teardown != nil {
+				bench.Teardown()
+			}
+			return BenchmarkResult{
+				Name: c.Name, Category: bench.Category,
+				Params: c.Params, Error: fmt.Sprintf("setup: %v", err),
+			}
+		}
+	}
+	if bench.Teardown != nil {
+		defer bench.Teardown()
+	}
+
+	var snapshots []SoakSnapshot
+	total := NewHistogram()
+	var iterations int64
+	b := &B{N: bench.Iterations, params: c.Params, hist: NewHistogram()}
+	start := time.Now()
+	next := start.Add(interval)
+	var intervalIters int64
+	var intervalTime time.Duration
+	b.StartTimer()
+	for b.err == nil {
+		before := b.timed()
+		bench.Fn(b)
+		intervalTime += b.timed() - before
+		intervalIters++
+		iterations++
+		now := time.Now()
+		if now.Before(next) && now.Sub(start) < duration {
+			continue
+		}
+
+		b.StopTimer()
+		s := soakSnapshot(b, intervalIters, intervalTime, now.Sub(start))
+		snapshots = append(snapshots, s)
+		report(s)
+		if b.hist.Count() > 0 {
+			total.Merge(b.hist)
+			b.hist = NewHistogram()
+		}
+		intervalIters, intervalTime = 0, 0
+		if now.Sub(start) >= duration {
+			break
+		}
+		next = next.Add(interval)
+		b.StartTimer()
+	}
+	b.StopTimer()
+
+	dataSize := bench.DataSize
+	if b.bytes > 0 {
+		dataSize = b.bytes
+	}
+	r := newResult(c.Name, iterations, dataSize, b.elapsed)
+	r.Category = bench.Category
+	r.Params = c.Params
+	if iterations > 0 {
+		r.AllocsPerOp = int64(b.allocs) / iterations
+		r.BytesPerOp = int64(b.allocBytes) / iterations
+		gc := b.gc
+		r.GC = &gc
+	}
+	if total.Count() > 0 {
+		r.Latency = total.Summary()
+	}
+	r.Metrics = b.resultMetrics()
+	if len(snapshots) > 1 {
+		if r.Metrics == nil {
+			r.Metrics = map[string]float64{}
+		}
+		first, last := snapshots[0], snapshots[len(snapshots)-1]
+		r.Metrics["heap_growth_pct"] = percentChange(float64(first.HeapBytes), float64(last.HeapBytes))
+		r.Metrics["goroutine_growth"] = float64(last.Goroutines - first.Goroutines)
+		r.Metrics["ops_drift_pct"] = percentChange(first.OpsPerSec, last.OpsPerSec)
+	}
+	r.Soak = snapshots
+	r.TCPInfo = collectTCPInfo()
+	if b.err != nil {
+		r.Error = b.err.Error()
+	}
+	return r
+}
+
+// soakSnapshot summarizes the interval of iters iterations that took
+// timed, ending elapsed into the soak
+func soakSnapshot(b *B, iters int64, timed, elapsed time.Duration) SoakSnapshot {
+	// Collect first, so the heap is what is live rather than what garbage
+	// has piled up since the last cycle
+	runtime.GC()
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	s := SoakSnapshot{
+		ElapsedS:   elapsed.Seconds(),
+		HeapBytes:  mem.HeapAlloc,
+		Goroutines: runtime.NumGoroutine(),
+	}
+	if timed > 0 {
+		s.OpsPerSec = float64(iters) / timed.Seconds()
+		s.ThroughputMBs = float64(b.bytes*iters) / timed.Seconds() / (1024 * 1024)
+	}
+	if b.hist != nil && b.hist.Count() > 0 {
+		s.P50Us = float64(b.hist.Percentile(50)) / 1e3
+		s.P99Us = float64(b.hist.Percentile(99)) / 1e3
+		s.MaxUs = float64(b.hist.Max()) / 1e3
+	}
+	return s
+}
+
+// percentChange is the change from before to after in percent, or 0 when
+// before is 0
+func percentChange(before, after float64) float64 {
+	if before == 0 {
+		return 0
+	}
+	return (after - before) / before * 100
+}
@@ -0,0 +1,48 @@
+// Soak Tests - Go
+//
+// Run with: go test -run Soak
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunSoakSnapshots(t *testing.T) {
+	leaked := make(chan struct{})
+	defer close(leaked)
+	calls := 0
+	bench := &Benchmark{Name: "Leaky", Iterations: 1, Fn: func(b *B) {
+		// Every iteration leaves a goroutine behind
+		go func() { <-leaked }()
+		calls++
+		b.SetBytes(1024)
+		b.Histogram().Record(time.Millisecond)
+		time.Sleep(time.Millisecond)
+	}}
+	var reported []SoakSnapshot
+	r := RunSoak(Case{Name: "Leaky", Bench: bench}, 100*time.Millisecond, 25*time.Millisecond, func(s SoakSnapshot) {
+		reported = append(reported, s)
+	})
+	if r.Error != "" {
+		t.Fatal(r.Error)
+	}
+	if len(r.Soak) < 3 || len(reported) != len(r.Soak) {
+		t.Fatalf("%d snapshots, %d reported; want at least 3, all reported", len(r.Soak), len(reported))
+	}
+	if r.Iterations != int64(calls) {
+		t.Errorf("%d iterations, want %d", r.Iterations, calls)
+	}
+	if r.Latency == nil || r.Latency.Count != uint64(calls) {
+		t.Errorf("latency %+v, want %d samples across all snapshots", r.Latency, calls)
+	}
+	if r.Metrics["goroutine_growth"] <= 0 {
+		t.Errorf("goroutine_growth %v, want the leak to show", r.Metrics["goroutine_growth"])
+	}
+	for i, s := range r.Soak {
+		if s.OpsPerSec <= 0 || s.ThroughputMBs <= 0 || s.P99Us <= 0 {
+			t.Errorf("snapshot %d incomplete: %+v", i, s)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+// Effective Socket Buffers - Go
+//
+// The SO_SNDBUF and SO_RCVBUF sizes the kernel actually gave a socket,
+// which differ from the requested ones: Linux doubles a request for its
+// bookkeeping and caps it at net.core.wmem_max and rmem_max, and
+// autotuning grows unset buffers as the connection runs. Platforms
+// without getsockopt report nothing (sockbuf_other.go).
+
+package main
+
+import (
+	"net"
+	"syscall"
+)
+
+// socketBuffers reads the current send and receive buffer sizes of a TCP
+// connection
+func socketBuffers(conn net.Conn) (send, recv int, ok bool) {
+	tc := tcpConnOf(conn)
+	if tc == nil {
+		return 0, 0, false
+	}
+	raw, err := tc.SyscallConn()
+	if err != nil {
+		return 0, 0, false
+	}
+	var sendErr, recvErr error
+	err = raw.Control(func(fd uintptr) {
+		send, sendErr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_SNDBUF)
+		recv, recvErr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+	})
+	if err != nil || sendErr != nil || recvErr != nil {
+		return 0, 0, false
+	}
+	return send, recv, true
+}
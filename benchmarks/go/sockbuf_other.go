@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "net"
+
+// socketBuffers is unknown on this platform
+func socketBuffers(conn net.Conn) (send, recv int, ok bool) {
+	return 0, 0, false
+}
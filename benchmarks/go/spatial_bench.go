@@ -0,0 +1,481 @@
+// Spatial Index Benchmarks - Go
+//
+// Inserts 1M small bounding boxes into a spatial index, then runs window
+// (range) queries and 10-nearest-neighbor queries against it, completing
+// the spatial category next to the geo benchmarks. Two indexes:
+//
+//   - rtree: a Guttman R-tree with quadratic split, adapting to any data
+//     distribution
+//   - grid: a uniform grid registering each box in every cell it overlaps,
+//     cheaper to build but tuned to one density
+//
+// Range queries cover a 10x10 window (about 100 boxes); nearest-neighbor
+// queries measure distance from a point to the closest edge of each box.
+
+package main
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+)
+
+const (
+	spatialBoxes      = 1_000_000
+	spatialWorld      = 1000.0
+	spatialMaxBoxSize = 2.0
+	spatialWindow     = 10.0
+	spatialNeighbors  = 10
+	rtreeMaxEntries   = 16
+	rtreeMinEntries   = 6
+	gridCellSize      = 4.0
+)
+
+// bbox is an axis-aligned bounding box
+type bbox struct {
+	MinX, MinY, MaxX, MaxY float64
+}
+
+func (r bbox) area() float64 {
+	return (r.MaxX - r.MinX) * (r.MaxY - r.MinY)
+}
+
+func (r bbox) union(o bbox) bbox {
+	return bbox{math.Min(r.MinX, o.MinX), math.Min(r.MinY, o.MinY), math.Max(r.MaxX, o.MaxX), math.Max(r.MaxY, o.MaxY)}
+}
+
+func (r bbox) intersects(o bbox) bool {
+	return r.MinX <= o.MaxX && o.MinX <= r.MaxX && r.MinY <= o.MaxY && o.MinY <= r.MaxY
+}
+
+// dist2 returns the squared distance from (x, y) to the nearest point of r
+func (r bbox) dist2(x, y float64) float64 {
+	dx := math.Max(0, math.Max(r.MinX-x, x-r.MaxX))
+	dy := math.Max(0, math.Max(r.MinY-y, y-r.MaxY))
+	return dx*dx + dy*dy
+}
+
+// spatialIndex is the interface both indexes are benchmarked through
+type spatialIndex interface {
+	Insert(box bbox, id int)
+	// Search appends the ids of boxes intersecting query to dst
+	Search(query bbox, dst []int) []int
+	// Nearest appends the ids of the k boxes closest to (x, y) to dst
+	Nearest(x, y float64, k int, dst []int) []int
+}
+
+// rtreeEntry is a child node (inner nodes) or a stored box (leaves)
+type rtreeEntry struct {
+	box   bbox
+	child *rtreeNode
+	id    int
+}
+
+type rtreeNode struct {
+	leaf    bool
+	entries []rtreeEntry
+}
+
+func (n *rtreeNode) bounds() bbox {
+	b := n.entries[0].box
+	for _, e := range n.entries[1:] {
+		b = b.union(e.box)
+	}
+	return b
+}
+
+// RTree is a dynamic R-tree over rectangles
+type RTree struct {
+	root *rtreeNode
+}
+
+// NewRTree returns an empty tree
+func NewRTree() *RTree {
+	return &RTree{root: &rtreeNode{leaf: true}}
+}
+
+// Insert adds a box, growing the tree at the root when a split reaches it
+func (t *RTree) Insert(box bbox, id int) {
+	if sibling := t.insert(t.root, rtreeEntry{box: box, id: id}); sibling != nil {
+		old := t.root
+		t.root = &rtreeNode{entries: []rtreeEntry{
+			{box: old.bounds(), child: old},
+			{box: sibling.bounds(), child: sibling},
+		}}
+	}
+}
+
+// insert places e in the subtree under n and returns the new sibling of n
+// if n had to split
+func (t *RTree) insert(n *rtreeNode, e rtreeEntry) *rtreeNode {
+	if n.leaf {
+		n.entries = append(n.entries, e)
+	} else {
+		i := chooseSubtree(n, e.box)
+		child := n.entries[i].child
+		sibling := t.insert(child, e)
+		if sibling != nil {
+			n.entries[i].box = child.bounds()
+			n.entries = append(n.entries, rtreeEntry{box: sibling.bounds(), child: sibling})
+		} else {
+			n.entries[i].box = n.entries[i].box.union(e.box)
+		}
+	}
+	if len(n.entries) > rtreeMaxEntries {
+		return splitQuadratic(n)
+	}
+	return nil
+}
+
+// chooseSubtree picks the child needing the least enlargement, breaking
+// ties by smaller area
+func chooseSubtree(n *rtreeNode, box bbox) int {
+	best, bestGrow, bestArea := 0, math.Inf(1), math.Inf(1)
+	for i, e := range n.entries {
+		area := e.box.area()
+		grow := e.box.union(box).area() - area
+		if grow < bestGrow || (grow == bestGrow && area < bestArea) {
+			best, bestGrow, bestArea = i, grow, area
+		}
+	}
+	return best
+}
+
+// splitQuadratic moves part of n's entries into a new sibling using
+// Guttman's quadratic split
+func splitQuadratic(n *rtreeNode) *rtreeNode {
+	entries := n.entries
+	// Seeds: the pair that would waste the most area together
+	seedA, seedB, worst := 0, 1, math.Inf(-1)
+	for i := range entries {
+		for j := i + 1; j < len(entries); j++ {
+			d := entries[i].box.union(entries[j].box).area() - entries[i].box.area() - entries[j].box.area()
+			if d > worst {
+				seedA, seedB, worst = i, j, d
+			}
+		}
+	}
+	a := []rtreeEntry{entries[seedA]}
+	b := []rtreeEntry{entries[seedB]}
+	boxA, boxB := entries[seedA].box, entries[seedB].box
+	rest := make([]rtreeEntry, 0, len(entries)-2)
+	for i, e := range entries {
+		if i != seedA && i != seedB {
+			rest = append(rest, e)
+		}
+	}
+	for len(rest) > 0 {
+		// Give a group everything left if it needs it to reach the minimum
+		if len(a)+len(rest) == rtreeMinEntries {
+			a = append(a, rest...)
+			break
+		}
+		if len(b)+len(rest) == rtreeMinEntries {
+			b = append(b, rest...)
+			break
+		}
+		// Next: the entry with the strongest preference for one group
+		pick, pickDiff := 0, math.Inf(-1)
+		for i, e := range rest {
+			dA := boxA.union(e.box).area() - boxA.area()
+			dB := boxB.union(e.box).area() - boxB.area()
+			if diff := math.Abs(dA - dB); diff > pickDiff {
+				pick, pickDiff = i, diff
+			}
+		}
+		e := rest[pick]
+		rest[pick] = rest[len(rest)-1]
+		rest = rest[:len(rest)-1]
+		dA := boxA.union(e.box).area() - boxA.area()
+		dB := boxB.union(e.box).area() - boxB.area()
+		if dA < dB || (dA == dB && len(a) <= len(b)) {
+			a, boxA = append(a, e), boxA.union(e.box)
+		} else {
+			b, boxB = append(b, e), boxB.union(e.box)
+		}
+	}
+	n.entries = a
+	return &rtreeNode{leaf: n.leaf, entries: b}
+}
+
+// Search appends the ids of boxes intersecting query
+func (t *RTree) Search(query bbox, dst []int) []int {
+	return searchNode(t.root, query, dst)
+}
+
+func searchNode(n *rtreeNode, query bbox, dst []int) []int {
+	for i := range n.entries {
+		e := &n.entries[i]
+		if !e.box.intersects(query) {
+			continue
+		}
+		if n.leaf {
+			dst = append(dst, e.id)
+		} else {
+			dst = searchNode(e.child, query, dst)
+		}
+	}
+	return dst
+}
+
+// rtreeItem is a queue entry of the best-first nearest-neighbor search
+type rtreeItem struct {
+	dist2 float64
+	node  *rtreeNode
+	id    int
+}
+
+type rtreeQueue []rtreeItem
+
+func (q rtreeQueue) Len() int            { return len(q) }
+func (q rtreeQueue) Less(i, j int) bool  { return q[i].dist2 < q[j].dist2 }
+func (q rtreeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *rtreeQueue) Push(x interface{}) { *q = append(*q, x.(rtreeItem)) }
+func (q *rtreeQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// Nearest appends the ids of the k boxes closest to (x, y), nearest first,
+// expanding nodes in order of their distance
+func (t *RTree) Nearest(x, y float64, k int, dst []int) []int {
+	q := rtreeQueue{{node: t.root}}
+	found := 0
+	for q.Len() > 0 && found < k {
+		item := heap.Pop(&q).(rtreeItem)
+		if item.node == nil {
+			dst = append(dst, item.id)
+			found++
+			continue
+		}
+		for _, e := range item.node.entries {
+			next := rtreeItem{dist2: e.box.dist2(x, y), node: e.child, id: e.id}
+			heap.Push(&q, next)
+		}
+	}
+	return dst
+}
+
+// GridIndex is a uniform grid of cells listing the boxes overlapping them
+type GridIndex struct {
+	cols, rows int
+	cellSize   float64
+	cells      [][]int32
+	boxes      []bbox
+	// stamp dedupes boxes spanning several cells within one query
+	stamp []uint32
+	query uint32
+}
+
+// NewGridIndex returns an empty grid covering [0, world) on both axes
+func NewGridIndex(world, cellSize float64) *GridIndex {
+	n := int(math.Ceil(world / cellSize))
+	return &GridIndex{cols: n, rows: n, cellSize: cellSize, cells: make([][]int32, n*n)}
+}
+
+// cellRange returns the clamped cell coordinates a box spans
+func (g *GridIndex) cellRange(r bbox) (x0, y0, x1, y1 int) {
+	clamp := func(v float64, n int) int {
+		return max(0, min(n-1, int(v/g.cellSize)))
+	}
+	return clamp(r.MinX, g.cols), clamp(r.MinY, g.rows), clamp(r.MaxX, g.cols), clamp(r.MaxY, g.rows)
+}
+
+// Insert registers a box in every cell it overlaps; ids must be dense
+func (g *GridIndex) Insert(box bbox, id int) {
+	for len(g.boxes) <= id {
+		g.boxes = append(g.boxes, bbox{})
+		g.stamp = append(g.stamp, 0)
+	}
+	g.boxes[id] = box
+	x0, y0, x1, y1 := g.cellRange(box)
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			c := y*g.cols + x
+			g.cells[c] = append(g.cells[c], int32(id))
+		}
+	}
+}
+
+// Search appends the ids of boxes intersecting query
+func (g *GridIndex) Search(query bbox, dst []int) []int {
+	g.query++
+	x0, y0, x1, y1 := g.cellRange(query)
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			for _, id := range g.cells[y*g.cols+x] {
+				if g.stamp[id] != g.query && g.boxes[id].intersects(query) {
+					g.stamp[id] = g.query
+					dst = append(dst, int(id))
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// gridCandidate is a box found by the ring search and its distance
+type gridCandidate struct {
+	dist2 float64
+	id    int
+}
+
+// gridBest is a max-heap keeping the k nearest candidates so far
+type gridBest []gridCandidate
+
+func (h gridBest) Len() int            { return len(h) }
+func (h gridBest) Less(i, j int) bool  { return h[i].dist2 > h[j].dist2 }
+func (h gridBest) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *gridBest) Push(x interface{}) { *h = append(*h, x.(gridCandidate)) }
+func (h *gridBest) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// Nearest appends the ids of the k boxes closest to (x, y), nearest first,
+// searching rings of cells outward until no unvisited cell can be closer
+// than the k-th candidate
+func (g *GridIndex) Nearest(x, y float64, k int, dst []int) []int {
+	g.query++
+	cx := max(0, min(g.cols-1, int(x/g.cellSize)))
+	cy := max(0, min(g.rows-1, int(y/g.cellSize)))
+	best := make(gridBest, 0, k+1)
+	visit := func(col, row int) {
+		if col < 0 || row < 0 || col >= g.cols || row >= g.rows {
+			return
+		}
+		for _, id := range g.cells[row*g.cols+col] {
+			if g.stamp[id] == g.query {
+				continue
+			}
+			g.stamp[id] = g.query
+			d := g.boxes[id].dist2(x, y)
+			if len(best) < k {
+				heap.Push(&best, gridCandidate{d, int(id)})
+			} else if d < best[0].dist2 {
+				best[0] = gridCandidate{d, int(id)}
+				heap.Fix(&best, 0)
+			}
+		}
+	}
+	maxRing := max(g.cols, g.rows)
+	for ring := 0; ring <= maxRing; ring++ {
+		if ring == 0 {
+			visit(cx, cy)
+		} else {
+			for i := -ring; i <= ring; i++ {
+				visit(cx+i, cy-ring)
+				visit(cx+i, cy+ring)
+			}
+			for i := -ring + 1; i < ring; i++ {
+				visit(cx-ring, cy+i)
+				visit(cx+ring, cy+i)
+			}
+		}
+		// Cells beyond this ring are at least ring cells away
+		reach := float64(ring) * g.cellSize
+		if len(best) == k && best[0].dist2 <= reach*reach {
+			break
+		}
+	}
+	start := len(dst)
+	for best.Len() > 0 {
+		dst = append(dst, heap.Pop(&best).(gridCandidate).id)
+	}
+	// The max-heap yields farthest first
+	for i, j := start, len(dst)-1; i < j; i, j = i+1, j-1 {
+		dst[i], dst[j] = dst[j], dst[i]
+	}
+	return dst
+}
+
+// spatialBoxSet generates the benchmark boxes: uniform positions, sizes up
+// to spatialMaxBoxSize
+func spatialBoxSet(n int, seed int64) []bbox {
+	rng := newRand(seed)
+	boxes := make([]bbox, n)
+	for i := range boxes {
+		x := rng.Float64() * (spatialWorld - spatialMaxBoxSize)
+		y := rng.Float64() * (spatialWorld - spatialMaxBoxSize)
+		boxes[i] = bbox{x, y, x + rng.Float64()*spatialMaxBoxSize, y + rng.Float64()*spatialMaxBoxSize}
+	}
+	return boxes
+}
+
+func newSpatialIndex(kind string) spatialIndex {
+	if kind == "grid" {
+		return NewGridIndex(spatialWorld, gridCellSize)
+	}
+	return NewRTree()
+}
+
+// spatialState holds the boxes, the index built from them for the query
+// benchmarks and the reused result buffer
+var spatialState struct {
+	boxes   []bbox
+	index   spatialIndex
+	results []int
+	rng     *rand.Rand
+}
+
+func setupSpatialBoxes(b *B) error {
+	spatialState.boxes = spatialBoxSet(spatialBoxes, 1529)
+	return nil
+}
+
+func setupSpatialIndex(b *B) error {
+	setupSpatialBoxes(b)
+	idx := newSpatialIndex(b.StringParam("index"))
+	for i, box := range spatialState.boxes {
+		idx.Insert(box, i)
+	}
+	spatialState.index = idx
+	spatialState.rng = newRand(15292)
+	return nil
+}
+
+func teardownSpatial() {
+	spatialState.boxes, spatialState.index, spatialState.results = nil, nil, nil
+}
+
+func init() {
+	indexes := []Axis{{Name: "index", Values: Strings("rtree", "grid")}}
+
+	Register(Benchmark{
+		Name: "SpatialInsert", Category: "geo", Tags: []string{"cpu", "alloc"},
+		Iterations: 3, Axes: indexes,
+		Setup: setupSpatialBoxes, Teardown: teardownSpatial,
+		Fn: func(b *B) {
+			idx := newSpatialIndex(b.StringParam("index"))
+			for i, box := range spatialState.boxes {
+				idx.Insert(box, i)
+			}
+			spatialState.index = idx
+		},
+	})
+	Register(Benchmark{
+		Name: "SpatialRangeQuery", Category: "geo", Tags: []string{"cpu"},
+		Iterations: 100_000, Axes: indexes,
+		Setup: setupSpatialIndex, Teardown: teardownSpatial,
+		Fn: func(b *B) {
+			s := &spatialState
+			x := s.rng.Float64() * (spatialWorld - spatialWindow)
+			y := s.rng.Float64() * (spatialWorld - spatialWindow)
+			s.results = s.index.Search(bbox{x, y, x + spatialWindow, y + spatialWindow}, s.results[:0])
+		},
+	})
+	Register(Benchmark{
+		Name: "SpatialNearest", Category: "geo", Tags: []string{"cpu"},
+		Iterations: 100_000, Axes: indexes,
+		Setup: setupSpatialIndex, Teardown: teardownSpatial,
+		Fn: func(b *B) {
+			s := &spatialState
+			x, y := s.rng.Float64()*spatialWorld, s.rng.Float64()*spatialWorld
+			s.results = s.index.Nearest(x, y, spatialNeighbors, s.results[:0])
+		},
+	})
+}
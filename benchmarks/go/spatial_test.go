@@ -0,0 +1,55 @@
+// Spatial Index Tests - Go
+//
+// Run with: go test -run Spatial
+
+package main
+
+import (
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestSpatialIndexesMatchBruteForce(t *testing.T) {
+	boxes := spatialBoxSet(20_000, 1)
+	rng := newRand(2)
+	for _, kind := range []string{"rtree", "grid"} {
+		idx := newSpatialIndex(kind)
+		for i, box := range boxes {
+			idx.Insert(box, i)
+		}
+		for q := 0; q < 200; q++ {
+			x, y := rng.Float64()*spatialWorld, rng.Float64()*spatialWorld
+			query := bbox{x, y, x + 40, y + 40}
+
+			got := idx.Search(query, nil)
+			var want []int
+			for i, box := range boxes {
+				if box.intersects(query) {
+					want = append(want, i)
+				}
+			}
+			sort.Ints(got)
+			if !slices.Equal(got, want) {
+				t.Fatalf("%s: search %v found %d boxes, want %d", kind, query, len(got), len(want))
+			}
+
+			// Compare distances rather than ids, since ties may order either way
+			near := idx.Nearest(x, y, 5, nil)
+			dists := make([]float64, len(boxes))
+			for i, box := range boxes {
+				dists[i] = box.dist2(x, y)
+			}
+			sorted := slices.Clone(dists)
+			slices.Sort(sorted)
+			if len(near) != 5 {
+				t.Fatalf("%s: nearest returned %d ids", kind, len(near))
+			}
+			for i, id := range near {
+				if dists[id] != sorted[i] {
+					t.Fatalf("%s: neighbor %d of (%.1f, %.1f) at dist2 %v, want %v", kind, i, x, y, dists[id], sorted[i])
+				}
+			}
+		}
+	}
+}
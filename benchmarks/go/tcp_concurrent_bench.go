@@ -0,0 +1,123 @@
+// Go TCP Concurrent Request Benchmark
+// Runs the echo round trip over 1, 8, 64 and 256 simultaneous client
+// connections, each driven by its own goroutine, against one echo server.
+// An iteration is tcpConcurrentRounds round trips on every connection, so
+// the reported throughput is the aggregate across connections, while the
+// latency histogram holds every individual round trip - how long one
+// client waits as the server shares itself among more of them.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const tcpConcurrentRounds = 100
+
+// tcpConcurrentClient is one connection and the latencies of its current
+// iteration
+type tcpConcurrentClient struct {
+	conn      net.Conn
+	payload   []byte
+	reply     []byte
+	latencies []time.Duration
+	err       error
+}
+
+// roundTrips performs the iteration's round trips, stopping at the first
+// error
+func (c *tcpConcurrentClient) roundTrips() {
+	c.latencies = c.latencies[:0]
+	for i := 0; i < tcpConcurrentRounds; i++ {
+		start := time.Now()
+		if c.err = (tcpTransport{}).RoundTrip(c.conn, c.payload, c.reply); c.err != nil {
+			return
+		}
+		c.latencies = append(c.latencies, time.Since(start))
+	}
+}
+
+var tcpConcurrent struct {
+	server  net.Listener
+	clients []*tcpConcurrentClient
+}
+
+func setupTcpConcurrentRequest(b *B) error {
+	ln, err := startTCPEchoServer()
+	if err != nil {
+		return err
+	}
+	tcpConcurrent.server = ln
+	size := benchConfig.PayloadSizes.Request
+	for i := 0; i < b.IntParam("conns"); i++ {
+		conn, err := tcpTransport{}.Dial(ln.Addr())
+		if err != nil {
+			teardownTcpConcurrentRequest()
+			return fmt.Errorf("connection %d: %w", i, err)
+		}
+		tcpConcurrent.clients = append(tcpConcurrent.clients, &tcpConcurrentClient{
+			conn:      conn,
+			payload:   make([]byte, size),
+			reply:     make([]byte, size),
+			latencies: make([]time.Duration, 0, tcpConcurrentRounds),
+		})
+	}
+	return nil
+}
+
+func teardownTcpConcurrentRequest() {
+	for _, c := range tcpConcurrent.clients {
+		c.conn.Close()
+	}
+	if tcpConcurrent.server != nil {
+		tcpConcurrent.server.Close()
+	}
+	tcpConcurrent.server, tcpConcurrent.clients = nil, nil
+}
+
+// benchTcpConcurrentRequest runs every connection's round trips at once
+func benchTcpConcurrentRequest(b *B) {
+	clients := tcpConcurrent.clients
+	requests := len(clients) * tcpConcurrentRounds
+	b.SetBytes(int64(requests * benchConfig.PayloadSizes.Request))
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, c := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.roundTrips()
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	// Fold the per-connection samples in outside the measurement
+	b.StopTimer()
+	defer b.StartTimer()
+	hist := b.Histogram()
+	for _, c := range clients {
+		if c.err != nil {
+			b.Fatal(c.err)
+			return
+		}
+		for _, d := range c.latencies {
+			hist.Record(d)
+		}
+	}
+	b.AddRate("requests_per_sec", float64(requests), elapsed)
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "TcpConcurrentRequest", Category: "tcp", Tags: []string{"net", "remote"},
+		Iterations: 20,
+		Axes:       []Axis{{Name: "conns", Values: Ints(1, 8, 64, 256)}},
+		Setup:      setupTcpConcurrentRequest, Teardown: teardownTcpConcurrentRequest,
+		Fn: benchTcpConcurrentRequest,
+	})
+}
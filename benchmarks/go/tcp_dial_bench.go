@@ -0,0 +1,59 @@
+// Go TCP Dial Benchmark
+// Connection establishment, which TcpBind (listener setup only) and the
+// request benchmarks (one connection dialed in setup) leave out: each
+// iteration dials the echo server, already listening since setup, and
+// closes the connection again. The histogram records the net.Dial call
+// alone - the loopback three-way handshake plus socket creation - while the
+// iteration time also covers the close.
+//
+// The client closes first, so every iteration leaves a client port in
+// TIME_WAIT; the iteration count stays well below the ephemeral port range.
+
+package main
+
+import (
+	"net"
+	"time"
+)
+
+var tcpDial struct {
+	server net.Listener
+	addr   string
+}
+
+func setupTcpDial(b *B) error {
+	ln, err := startTCPEchoServer()
+	if err != nil {
+		return err
+	}
+	tcpDial.server, tcpDial.addr = ln, ln.Addr().String()
+	return nil
+}
+
+func teardownTcpDial() {
+	if tcpDial.server != nil {
+		tcpDial.server.Close()
+	}
+	tcpDial.server = nil
+}
+
+// benchTcpDial connects to the listening server and hangs up
+func benchTcpDial(b *B) {
+	start := time.Now()
+	conn, err := net.Dial("tcp", tcpDial.addr)
+	if err != nil {
+		b.Fatal(err)
+		return
+	}
+	b.Histogram().Record(time.Since(start))
+	conn.Close()
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "TcpDial", Category: "tcp", Tags: []string{"net", "remote"},
+		Iterations: 2000,
+		Setup:      setupTcpDial, Teardown: teardownTcpDial,
+		Fn: benchTcpDial,
+	})
+}
@@ -0,0 +1,93 @@
+// Go TCP Idle Connections Benchmark (C10K)
+// Connection-scaling constants: setup opens up to 10,000 connections to
+// the echo server, sends one byte over each so the server is holding all
+// of them, and leaves them idle; the iterations then time the echo round
+// trip on one more, active connection. With the idle axis at 0 as the
+// reference, the latency shows what the idle set costs the active client -
+// the runtime poller and scheduler carrying thousands of parked
+// goroutines.
+//
+// Metrics: bytes_per_conn, the heap and goroutine stack held per idle
+// connection, both ends included (client conn, server conn and the
+// server's goroutine with its copy buffer), after a forced collection.
+// Kernel socket memory is not counted.
+//
+// 10,000 connections take 20,000 descriptors in one process; setup fails
+// with the open file limit named if it is too low.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+)
+
+var tcpIdle struct {
+	conns        []net.Conn
+	bytesPerConn float64
+}
+
+// heapAndStacks is the memory the runtime holds for live objects and
+// goroutine stacks, after a collection
+func heapAndStacks() uint64 {
+	runtime.GC()
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return mem.HeapAlloc + mem.StackInuse
+}
+
+func setupTcpIdleConns(b *B) error {
+	if err := tcpRequest.dial(tcpTransport{}, benchConfig.PayloadSizes.Request); err != nil {
+		return err
+	}
+	idle := b.IntParam("idle")
+	addr := tcpRequest.server.(net.Listener).Addr().String()
+	before := heapAndStacks()
+	ping := []byte{0}
+	for i := 0; i < idle; i++ {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			tcpIdle.conns = append(tcpIdle.conns, conn)
+			if _, err = conn.Write(ping); err == nil {
+				_, err = io.ReadFull(conn, ping)
+			}
+		}
+		if err != nil {
+			teardownTcpIdleConns()
+			return fmt.Errorf("idle connection %d: %w (is the open file limit high enough?)", i, err)
+		}
+	}
+	tcpIdle.bytesPerConn = 0
+	if idle > 0 {
+		tcpIdle.bytesPerConn = float64(int64(heapAndStacks()-before)) / float64(idle)
+	}
+	return nil
+}
+
+func teardownTcpIdleConns() {
+	for _, conn := range tcpIdle.conns {
+		conn.Close()
+	}
+	tcpIdle.conns = nil
+	tcpRequest.close()
+}
+
+// benchTcpIdleConns is the echo round trip on the active connection
+func benchTcpIdleConns(b *B) {
+	benchEchoRequest(b)
+	if len(tcpIdle.conns) > 0 {
+		b.ReportMetric("bytes_per_conn", tcpIdle.bytesPerConn)
+	}
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "TcpIdleConns", Category: "tcp", Tags: []string{"net", "remote"},
+		Iterations: 10000,
+		Axes:       []Axis{{Name: "idle", Values: Ints(0, 1000, 10000)}},
+		Setup:      setupTcpIdleConns, Teardown: teardownTcpIdleConns,
+		Fn: benchTcpIdleConns,
+	})
+}
@@ -0,0 +1,252 @@
+// Go TCP Streaming Throughput Benchmark
+// Pushes a continuous byte stream over a single loopback TCP connection
+// for the configured duration (durations.stream_seconds in config.yaml)
+// and reports the bandwidth in each direction, complementing the
+// request/response latency benchmarks:
+//
+//   - upload: client to server; timed until the server has drained
+//     everything the client wrote
+//   - download: server to client; timed by the client's reads
+//   - both: both directions at once on the same connection
+//
+// The server is in-process, so the upload byte count is taken from what
+// it actually received rather than from what the client handed the kernel.
+//
+// TcpStreamBuffers sweeps the upload across SO_SNDBUF/SO_RCVBUF sizes, set
+// on both ends in place of -sndbuf and -rcvbuf, to show how much buffer
+// the stream needs: each case reports the sizes the kernel granted
+// (sndbuf_kb at the client, rcvbuf_kb at the server, where getsockopt is
+// available) and its throughput relative to the kernel's default buffers
+// (vs_default_pct, when the default case ran first).
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// tcpStreamBlock is the size of each write on either side
+const tcpStreamBlock = 256 * 1024
+
+// tcpStreamServer sinks and/or sources a stream per connection, as chosen
+// by the first byte the client sends: 'u'pload, 'd'ownload or 'b'oth
+type tcpStreamServer struct {
+	ln net.Listener
+	// buffer, when set, is the SO_SNDBUF and SO_RCVBUF of both ends
+	buffer int
+	// drained delivers the byte count of each finished upload
+	drained chan int64
+	// recvBuf is the receive buffer the last upload ended with, or 0;
+	// written before drained is sent
+	recvBuf int
+}
+
+func startTCPStreamServer(buffer int) (*tcpStreamServer, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &tcpStreamServer{ln: ln, buffer: buffer, drained: make(chan int64, 1)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			configureTCPConn(conn)
+			if buffer > 0 {
+				setTCPBuffers(conn.(*net.TCPConn), buffer, buffer)
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s, nil
+}
+
+func (s *tcpStreamServer) serve(conn net.Conn) {
+	defer conn.Close()
+	var mode [1]byte
+	if _, err := io.ReadFull(conn, mode[:]); err != nil {
+		return
+	}
+	sink := func() {
+		n, _ := io.Copy(io.Discard, conn)
+		_, s.recvBuf, _ = socketBuffers(conn)
+		s.drained <- n
+	}
+	switch mode[0] {
+	case 'u':
+		sink()
+	case 'b':
+		go sink()
+		fallthrough
+	case 'd':
+		// Source until the client hangs up
+		block := make([]byte, tcpStreamBlock)
+		for {
+			if _, err := conn.Write(block); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// tcpStreamResult is the bandwidth achieved in one streaming run
+type tcpStreamResult struct {
+	uploaded, downloaded   int64
+	uploadDur, downloadDur time.Duration
+	// sendBuf and recvBuf are the buffers the upload's client and server
+	// ended with, 0 where unknown
+	sendBuf, recvBuf int
+}
+
+// runTCPStream drives one connection in mode for duration
+func (s *tcpStreamServer) runTCPStream(mode byte, duration time.Duration) (tcpStreamResult, error) {
+	var res tcpStreamResult
+	conn, err := dialTCP(s.ln.Addr().String())
+	if err != nil {
+		return res, err
+	}
+	defer conn.Close()
+	if s.buffer > 0 {
+		if err := setTCPBuffers(tcpConnOf(conn), s.buffer, s.buffer); err != nil {
+			return res, err
+		}
+	}
+	if _, err := conn.Write([]byte{mode}); err != nil {
+		return res, err
+	}
+	start := time.Now()
+	deadline := start.Add(duration)
+
+	uploadErr := make(chan error, 1)
+	if mode == 'u' || mode == 'b' {
+		go func() {
+			block := make([]byte, tcpStreamBlock)
+			for time.Now().Before(deadline) {
+				if _, err := conn.Write(block); err != nil {
+					uploadErr <- err
+					return
+				}
+			}
+			res.sendBuf, _, _ = socketBuffers(conn)
+			uploadErr <- conn.(interface{ CloseWrite() error }).CloseWrite()
+		}()
+	}
+	if mode == 'd' || mode == 'b' {
+		conn.SetReadDeadline(deadline)
+		block := make([]byte, tcpStreamBlock)
+		for {
+			n, err := conn.Read(block)
+			res.downloaded += int64(n)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
+			}
+			if err != nil {
+				return res, err
+			}
+		}
+		res.downloadDur = time.Since(start)
+	}
+	if mode == 'u' || mode == 'b' {
+		if err := <-uploadErr; err != nil {
+			return res, err
+		}
+		select {
+		case res.uploaded = <-s.drained:
+			res.recvBuf = s.recvBuf
+		case <-time.After(duration + 10*time.Second):
+			return res, fmt.Errorf("server did not drain the upload")
+		}
+		res.uploadDur = time.Since(start)
+	}
+	return res, nil
+}
+
+func (s *tcpStreamServer) close() {
+	s.ln.Close()
+}
+
+var tcpStream *tcpStreamServer
+
+// tcpStreamDefaultMBs is the upload bandwidth of TcpStreamBuffers' default
+// case, which the other cases report relative to
+var tcpStreamDefaultMBs float64
+
+func setupTcpStream(b *B) error {
+	var err error
+	tcpStream, err = startTCPStreamServer(0)
+	return err
+}
+
+func setupTcpStreamBuffers(b *B) error {
+	buffer, err := parseSocketBuffer(b.StringParam("buffer"))
+	if err != nil {
+		return err
+	}
+	tcpStream, err = startTCPStreamServer(buffer)
+	return err
+}
+
+func teardownTcpStream() {
+	if tcpStream != nil {
+		tcpStream.close()
+		tcpStream = nil
+	}
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "TcpStream", Category: "tcp", Tags: []string{"net"},
+		Iterations: 1,
+		Axes:       []Axis{{Name: "direction", Values: Strings("upload", "download", "both")}},
+		Setup:      setupTcpStream, Teardown: teardownTcpStream,
+		Fn: func(b *B) {
+			res, err := tcpStream.runTCPStream(b.StringParam("direction")[0], benchConfig.Durations.Stream)
+			if err != nil {
+				b.Fatal(err)
+				return
+			}
+			b.SetBytes(res.uploaded + res.downloaded)
+			if res.uploadDur > 0 {
+				b.ReportMetric("upload_mb_s", float64(res.uploaded)/res.uploadDur.Seconds()/(1024*1024))
+			}
+			if res.downloadDur > 0 {
+				b.ReportMetric("download_mb_s", float64(res.downloaded)/res.downloadDur.Seconds()/(1024*1024))
+			}
+		},
+	})
+
+	Register(Benchmark{
+		Name: "TcpStreamBuffers", Category: "tcp", Tags: []string{"net"},
+		Iterations: 1,
+		Axes:       []Axis{{Name: "buffer", Values: Strings("default", "16KiB", "64KiB", "256KiB", "1MiB", "4MiB")}},
+		Setup:      setupTcpStreamBuffers, Teardown: teardownTcpStream,
+		Fn: func(b *B) {
+			res, err := tcpStream.runTCPStream('u', benchConfig.Durations.Stream)
+			if err != nil {
+				b.Fatal(err)
+				return
+			}
+			b.SetBytes(res.uploaded)
+			mbs := float64(res.uploaded) / res.uploadDur.Seconds() / (1024 * 1024)
+			b.ReportMetric("upload_mb_s", mbs)
+			if tcpStream.buffer == 0 {
+				tcpStreamDefaultMBs = mbs
+			} else if tcpStreamDefaultMBs > 0 {
+				b.ReportMetric("vs_default_pct", percentChange(tcpStreamDefaultMBs, mbs))
+			}
+			if res.sendBuf > 0 {
+				b.ReportMetric("sndbuf_kb", float64(res.sendBuf)/1024)
+			}
+			if res.recvBuf > 0 {
+				b.ReportMetric("rcvbuf_kb", float64(res.recvBuf)/1024)
+			}
+		},
+	})
+}
@@ -0,0 +1,344 @@
+// Go TCP/UDP Request Benchmarks
+// Round-trip latency of a small request (64 bytes unless config.yaml says
+// otherwise) against a local echo server, over
+// a reused TCP connection and over UDP datagrams. Every round trip is
+// recorded in a latency histogram so results carry the full percentile
+// spectrum, not just the average. TcpRequestSweep repeats the TCP round
+// trip at payloads from 64B to 1MB, where TML comparisons diverge most,
+// and EchoRequest runs it over every transport registered in transport.go.
+//
+// UDP requests carry a sequence number and wait at most udpRequestTimeout
+// for their echo, so a dropped datagram costs one timeout instead of
+// hanging the run. Replies are accounted separately: timeouts (reported as
+// loss_pct, the effective loss rate), late replies to requests already
+// given up on, and duplicates; only matching replies enter the histogram.
+// udpMaxConsecutiveTimeouts in a row fail the case, since the server is
+// then gone rather than dropping.
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+const (
+	udpRequestTimeout         = 250 * time.Millisecond
+	udpMaxConsecutiveTimeouts = 20
+)
+
+// startTCPEchoServer listens on loopback at the configured port and
+// echoes every accepted connection until the server is closed
+func startTCPEchoServer() (*echoServer, error) {
+	return startEchoServer(tcpTransport{})
+}
+
+// udpEchoServer echoes every datagram back to its sender; like echoServer,
+// Close returns only once its goroutine has stopped. In client mode
+// (remote.go) it stands for the remote server and has no socket.
+type udpEchoServer struct {
+	net.PacketConn
+	addr string
+	done chan struct{}
+}
+
+// startUDPEchoServer echoes every datagram arriving at the configured port
+// back to its sender until the server is closed, returning once its read
+// loop is running
+func startUDPEchoServer() (*udpEchoServer, error) {
+	if remoteOptions.Mode == "client" {
+		done := make(chan struct{})
+		close(done)
+		return &udpEchoServer{addr: remoteOptions.udpTarget(), done: done}, nil
+	}
+	pc, err := net.ListenPacket("udp", fmt.Sprintf("127.0.0.1:%d", benchConfig.Ports.UDPEcho))
+	if err != nil {
+		return nil, err
+	}
+	return serveUDPEcho(pc), nil
+}
+
+// serveUDPEcho starts echoing the datagrams arriving at pc, returning once
+// its read loop is running
+func serveUDPEcho(pc net.PacketConn) *udpEchoServer {
+	s := &udpEchoServer{PacketConn: pc, addr: pc.LocalAddr().String(), done: make(chan struct{})}
+	ready := make(chan struct{})
+	go func() {
+		defer close(s.done)
+		close(ready)
+		buf := make([]byte, 64*1024)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			pc.WriteTo(buf[:n], addr)
+		}
+	}()
+	<-ready
+	return s
+}
+
+// Addr is the address clients send their datagrams to
+func (s *udpEchoServer) Addr() string {
+	return s.addr
+}
+
+// Close closes the socket and waits for the read loop to return
+func (s *udpEchoServer) Close() error {
+	var err error
+	if s.PacketConn != nil {
+		err = s.PacketConn.Close()
+	}
+	<-s.done
+	return err
+}
+
+// requestClient is the per-case state shared by setup, iterations and
+// teardown of a request benchmark
+type requestClient struct {
+	server io.Closer
+	// transport is nil for UDP
+	transport Transport
+	conn      net.Conn
+	payload   []byte
+	reply     []byte
+}
+
+func (c *requestClient) close() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	if c.server != nil {
+		c.server.Close()
+	}
+	*c = requestClient{}
+}
+
+var tcpRequest, udpRequest requestClient
+
+// udpAccounting tracks what became of each UDP request of a case
+type udpAccounting struct {
+	timeout time.Duration
+	seq     uint64 // of the latest request
+	// timedOut are the requests given up on whose reply has not shown up
+	timedOut map[uint64]bool
+
+	sent, answered, timeouts, late, duplicates int
+	consecutiveTimeouts                        int
+
+	// b is the run the counts belong to and calls its iterations so far,
+	// as in rttStats
+	b     *B
+	calls int64
+}
+
+var udpStats udpAccounting
+
+func newUDPAccounting(timeout time.Duration) udpAccounting {
+	return udpAccounting{timeout: timeout, timedOut: map[uint64]bool{}}
+}
+
+// roundTrip sends payload, tagged with the next sequence number, and reads
+// replies until its own arrives or the timeout passes. It reports whether
+// the request was answered; a timeout is not an error.
+func (a *udpAccounting) roundTrip(conn net.Conn, payload, reply []byte) (bool, error) {
+	a.seq++
+	a.sent++
+	binary.LittleEndian.PutUint64(payload, a.seq)
+	if _, err := conn.Write(payload); err != nil {
+		return false, err
+	}
+	conn.SetReadDeadline(time.Now().Add(a.timeout))
+	for {
+		n, err := conn.Read(reply)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			a.timeouts++
+			a.consecutiveTimeouts++
+			a.timedOut[a.seq] = true
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if n < 8 {
+			continue
+		}
+		switch seq := binary.LittleEndian.Uint64(reply); {
+		case seq == a.seq:
+			a.answered++
+			a.consecutiveTimeouts = 0
+			return true, nil
+		case a.timedOut[seq]:
+			delete(a.timedOut, seq)
+			a.late++
+		case seq < a.seq:
+			a.duplicates++
+		}
+	}
+}
+
+// begin starts the counts over when b is a new run, so the warmup's round
+// trips stay out of the measured ones. The sequence numbers carry on, so a
+// late warmup reply is still told apart from a duplicate.
+func (a *udpAccounting) begin(b *B) {
+	if a.b == b {
+		return
+	}
+	a.b, a.calls = b, 0
+	a.sent, a.answered, a.timeouts, a.late, a.duplicates = 0, 0, 0, 0, 0
+	a.consecutiveTimeouts = 0
+}
+
+// report attaches the counts to the result
+func (a *udpAccounting) report(b *B) {
+	b.ReportMetric("sent", float64(a.sent))
+	b.ReportMetric("timeouts", float64(a.timeouts))
+	b.ReportMetric("late", float64(a.late))
+	b.ReportMetric("duplicates", float64(a.duplicates))
+	b.ReportMetric("loss_pct", a.lossPct())
+}
+
+// lossPct is the share of requests that timed out
+func (a *udpAccounting) lossPct() float64 {
+	if a.sent == 0 {
+		return 0
+	}
+	return 100 * float64(a.timeouts) / float64(a.sent)
+}
+
+var tcpSweepPayloads = Ints(64, 1024, 16*1024, 64*1024, 1<<20)
+
+func setupTcpReusedRequest(b *B) error {
+	return tcpRequest.dial(tcpTransport{}, benchConfig.PayloadSizes.Request)
+}
+
+func setupTcpRequestSweep(b *B) error {
+	return tcpRequest.dial(tcpTransport{}, b.IntParam("payload"))
+}
+
+func setupEchoRequest(b *B) error {
+	return tcpRequest.dial(transports[b.StringParam("transport")], benchConfig.PayloadSizes.Request)
+}
+
+// dial starts an echo server on t and connects the reused client for
+// requests of size bytes
+func (c *requestClient) dial(t Transport, size int) error {
+	ln, err := startEchoServer(t)
+	if err != nil {
+		return err
+	}
+	c.server, c.transport = ln, t
+	conn, err := t.Dial(ln.Addr())
+	if err != nil {
+		c.close()
+		return err
+	}
+	c.conn = conn
+	c.payload = make([]byte, size)
+	c.reply = make([]byte, size)
+	return nil
+}
+
+// benchEchoRequest performs one request/response round trip over the
+// connection opened in setup
+func benchEchoRequest(b *B) {
+	c := &tcpRequest
+	b.SetBytes(int64(len(c.payload)))
+	start := time.Now()
+	if err := c.transport.RoundTrip(c.conn, c.payload, c.reply); err != nil {
+		b.Fatal(err)
+		return
+	}
+	rtt := time.Since(start)
+	b.Histogram().Record(rtt)
+	tcpRTT.record(b, rtt)
+}
+
+func setupUdpRequest(b *B) error {
+	return udpRequest.dialUDP(benchConfig.PayloadSizes.Request)
+}
+
+// dialUDP starts the UDP echo server and connects the client for
+// datagrams of size bytes, resetting the UDP accounting
+func (c *requestClient) dialUDP(size int) error {
+	pc, err := startUDPEchoServer()
+	if err != nil {
+		return err
+	}
+	c.server = pc
+	conn, err := net.Dial("udp", pc.Addr())
+	if err != nil {
+		c.close()
+		return err
+	}
+	c.conn = conn
+	c.payload = make([]byte, size)
+	c.reply = make([]byte, 64*1024)
+	udpStats = newUDPAccounting(udpRequestTimeout)
+	return nil
+}
+
+// benchUdpRequest sends one datagram and waits for its echo, reporting
+// the run's accounting with its last iteration
+func benchUdpRequest(b *B) {
+	c, a := &udpRequest, &udpStats
+	a.begin(b)
+	b.SetBytes(int64(len(c.payload)))
+	start := time.Now()
+	answered, err := a.roundTrip(c.conn, c.payload, c.reply)
+	if err != nil {
+		b.Fatal(err)
+		return
+	}
+	if answered {
+		rtt := time.Since(start)
+		b.Histogram().Record(rtt)
+		udpRTT.record(b, rtt)
+	} else {
+		udpRTT.miss(b)
+	}
+	if a.consecutiveTimeouts >= udpMaxConsecutiveTimeouts {
+		a.report(b)
+		udpRTT.report(b)
+		b.Fatal(fmt.Errorf("%d UDP requests in a row timed out", a.consecutiveTimeouts))
+		return
+	}
+	if a.calls++; a.calls == b.N {
+		a.report(b)
+	}
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "TcpReusedRequest", Category: "tcp", Tags: []string{"net", "remote"},
+		Iterations: 10000,
+		Setup:      setupTcpReusedRequest, Teardown: tcpRequest.close,
+		Fn: benchEchoRequest,
+	})
+	Register(Benchmark{
+		Name: "TcpRequestSweep", Category: "tcp", Tags: []string{"net", "remote"},
+		Iterations: 2000,
+		Axes:       []Axis{{Name: "payload", Values: tcpSweepPayloads}},
+		Setup:      setupTcpRequestSweep, Teardown: tcpRequest.close,
+		Fn: benchEchoRequest,
+	})
+	Register(Benchmark{
+		Name: "EchoRequest", Category: "transport", Tags: []string{"net"},
+		Iterations: 10000,
+		Axes:       []Axis{{Name: "transport", Values: Strings(transportNames()...)}},
+		Setup:      setupEchoRequest, Teardown: tcpRequest.close,
+		Fn: benchEchoRequest,
+	})
+	Register(Benchmark{
+		Name: "UdpRequest", Category: "udp", Tags: []string{"net", "remote"},
+		Iterations: 10000,
+		Setup:      setupUdpRequest, Teardown: udpRequest.close,
+		Fn: benchUdpRequest,
+	})
+}
@@ -0,0 +1,96 @@
+// TCP Socket Statistics - Go
+//
+// Every client connection opened with dialTCP is tracked for the case
+// being run, and right after the measured iterations the kernel's TCP_INFO
+// is read from those still open: smoothed round-trip time and its
+// variance, retransmitted segments and the congestion window. The summary
+// is attached to the result as tcp_info, so an anomaly in a TML-vs-Go
+// latency comparison can be traced to kernel-level effects - a
+// retransmission, a collapsed window - rather than to either language.
+//
+// TCP_INFO is Linux only (tcpinfo_linux.go); elsewhere, and for cases that
+// close their connections within each iteration, there is no tcp_info.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"sync"
+)
+
+// TCPInfoStats summarizes the kernel statistics of a case's connections
+type TCPInfoStats struct {
+	Connections int     `json:"connections"`
+	RTTUs       float64 `json:"rtt_us"`
+	RTTVarUs    float64 `json:"rttvar_us"`
+	MaxRTTUs    float64 `json:"max_rtt_us"`
+	// Retransmits is the total of segments retransmitted over the
+	// connections' lifetimes
+	Retransmits uint64  `json:"retransmits"`
+	SndCwnd     float64 `json:"snd_cwnd"`
+}
+
+// tcpInfo is the subset of TCP_INFO read from one connection
+type tcpInfo struct {
+	rttUs, rttVarUs uint32
+	totalRetrans    uint32
+	sndCwnd         uint32
+}
+
+// trackedTCPConns are the client connections of the current case
+var trackedTCPConns struct {
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+// trackTCPConn adds a connection to the current case
+func trackTCPConn(conn net.Conn) {
+	trackedTCPConns.mu.Lock()
+	trackedTCPConns.conns = append(trackedTCPConns.conns, conn)
+	trackedTCPConns.mu.Unlock()
+}
+
+// resetTrackedTCPConns forgets the previous case's connections
+func resetTrackedTCPConns() {
+	trackedTCPConns.mu.Lock()
+	trackedTCPConns.conns = nil
+	trackedTCPConns.mu.Unlock()
+}
+
+// collectTCPInfo summarizes the tracked connections that are still open,
+// or returns nil if there are none or TCP_INFO is unavailable
+func collectTCPInfo() *TCPInfoStats {
+	trackedTCPConns.mu.Lock()
+	conns := trackedTCPConns.conns
+	trackedTCPConns.mu.Unlock()
+
+	var stats TCPInfoStats
+	var cwnd float64
+	for _, conn := range conns {
+		info, ok := readTCPInfo(conn)
+		if !ok {
+			continue
+		}
+		stats.Connections++
+		stats.RTTUs += float64(info.rttUs)
+		stats.RTTVarUs += float64(info.rttVarUs)
+		stats.MaxRTTUs = max(stats.MaxRTTUs, float64(info.rttUs))
+		stats.Retransmits += uint64(info.totalRetrans)
+		cwnd += float64(info.sndCwnd)
+	}
+	if stats.Connections == 0 {
+		return nil
+	}
+	n := float64(stats.Connections)
+	stats.RTTUs /= n
+	stats.RTTVarUs /= n
+	stats.SndCwnd = cwnd / n
+	return &stats
+}
+
+// String formats the summary for the results table
+func (s *TCPInfoStats) String() string {
+	return fmt.Sprintf("%d conns, rtt %.0f us (var %.0f, max %.0f), retransmits %d, cwnd %.1f",
+		s.Connections, s.RTTUs, s.RTTVarUs, s.MaxRTTUs, s.Retransmits, s.SndCwnd)
+}
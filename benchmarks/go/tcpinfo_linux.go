@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+// readTCPInfo reads TCP_INFO from an open TCP connection
+func readTCPInfo(conn net.Conn) (tcpInfo, bool) {
+	tc, ok := conn.(*net.TCPConn)
+	if !ok {
+		return tcpInfo{}, false
+	}
+	raw, err := tc.SyscallConn()
+	if err != nil {
+		return tcpInfo{}, false
+	}
+	var ti syscall.TCPInfo
+	var errno syscall.Errno
+	err = raw.Control(func(fd uintptr) {
+		size := uint32(unsafe.Sizeof(ti))
+		_, _, errno = syscall.Syscall6(syscall.SYS_GETSOCKOPT, fd, syscall.IPPROTO_TCP, syscall.TCP_INFO,
+			uintptr(unsafe.Pointer(&ti)), uintptr(unsafe.Pointer(&size)), 0)
+	})
+	if err != nil || errno != 0 {
+		return tcpInfo{}, false
+	}
+	return tcpInfo{rttUs: ti.Rtt, rttVarUs: ti.Rttvar, totalRetrans: ti.Total_retrans, sndCwnd: ti.Snd_cwnd}, true
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "net"
+
+// readTCPInfo is unavailable on this platform
+func readTCPInfo(conn net.Conn) (tcpInfo, bool) {
+	return tcpInfo{}, false
+}
@@ -0,0 +1,175 @@
+// TCP Socket Options - Go
+//
+// Run-wide settings for the TCP echo benchmarks, so the effect of Nagle's
+// algorithm and of write coalescing can be quantified:
+//
+//   - nodelay: TCP_NODELAY on both ends of every echo connection. Go
+//     enables it by default; -nodelay=false turns Nagle's algorithm on.
+//   - write mode: how request/response clients send each request - in one
+//     write (single), as a small header write followed by the body (split,
+//     the pattern Nagle and delayed ACKs penalize), or as the split writes
+//     coalesced by a bufio.Writer and flushed once (buffered).
+//   - socket buffers: SO_SNDBUF and SO_RCVBUF on every benchmark TCP
+//     socket (-sndbuf, -rcvbuf); unset, the kernel sizes and autotunes
+//     them. The TcpStreamBuffers benchmark sweeps both across sizes.
+//
+// The settings are recorded in the result metadata. The open-loop
+// benchmarks always write each request once.
+//
+// Run with: go run . -run Tcp -nodelay=false -write-mode split
+//        or: go run . -run TcpStream -sndbuf 256KiB -rcvbuf 256KiB
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net"
+	"slices"
+)
+
+// tcpSplitHeader is the size of the header write in split mode
+const tcpSplitHeader = 8
+
+var tcpWriteModes = []string{"single", "split", "buffered"}
+
+// TCPOptions are the socket settings applied to the TCP echo benchmarks
+type TCPOptions struct {
+	NoDelay   bool
+	WriteMode string
+	// SendBuffer and RecvBuffer are the SO_SNDBUF and SO_RCVBUF sizes in
+	// bytes; 0 keeps the kernel's
+	SendBuffer int
+	RecvBuffer int
+}
+
+// tcpOptions holds the settings of the current run
+var tcpOptions = TCPOptions{NoDelay: true, WriteMode: "single"}
+
+// Validate checks that the write mode is known and the buffer sizes are
+// usable
+func (o TCPOptions) Validate() error {
+	if !slices.Contains(tcpWriteModes, o.WriteMode) {
+		return fmt.Errorf("unknown write mode %q (want one of %v)", o.WriteMode, tcpWriteModes)
+	}
+	if o.SendBuffer < 0 || o.RecvBuffer < 0 {
+		return fmt.Errorf("socket buffer sizes must not be negative")
+	}
+	return nil
+}
+
+// configureTCPConn applies the run's socket options to conn
+func configureTCPConn(conn net.Conn) error {
+	tc, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+	if err := tc.SetNoDelay(tcpOptions.NoDelay); err != nil {
+		return err
+	}
+	return setTCPBuffers(tc, tcpOptions.SendBuffer, tcpOptions.RecvBuffer)
+}
+
+// parseSocketBuffer parses a -sndbuf or -rcvbuf size like "64KiB"; empty
+// or "default" means the kernel's
+func parseSocketBuffer(s string) (int, error) {
+	if s == "" || s == "default" {
+		return 0, nil
+	}
+	n, err := parseByteSize(s)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxInt32 {
+		return 0, fmt.Errorf("size %q too large for a socket buffer", s)
+	}
+	return int(n), nil
+}
+
+// socketBufferLabel describes a buffer size option for display
+func socketBufferLabel(n int) string {
+	if n == 0 {
+		return "default"
+	}
+	return formatByteSize(int64(n))
+}
+
+// setTCPBuffers sets the SO_SNDBUF and SO_RCVBUF of conn, leaving those
+// given as 0 alone. Set on a connected socket, a size still applies to its
+// later traffic; Linux doubles it for bookkeeping and caps it at
+// net.core.wmem_max and rmem_max, and an explicit size disables autotuning.
+func setTCPBuffers(conn *net.TCPConn, send, recv int) error {
+	if send > 0 {
+		if err := conn.SetWriteBuffer(send); err != nil {
+			return err
+		}
+	}
+	if recv > 0 {
+		return conn.SetReadBuffer(recv)
+	}
+	return nil
+}
+
+// tcpConnOf returns the TCP connection under conn, which dialTCP may have
+// wrapped for recording
+func tcpConnOf(conn net.Conn) *net.TCPConn {
+	if tc, ok := conn.(*trafficConn); ok {
+		conn = tc.Conn
+	}
+	tc, _ := conn.(*net.TCPConn)
+	return tc
+}
+
+// dialTCP connects to addr with the run's socket options applied
+func dialTCP(addr string) (net.Conn, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := configureTCPConn(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	trackTCPConn(conn)
+	return recordConn(conn), nil
+}
+
+// tcpRequestConn is a client connection whose every Write sends one
+// request in the run's write mode
+type tcpRequestConn struct {
+	net.Conn
+	buf *bufio.Writer
+}
+
+func newTCPRequestConn(conn net.Conn) *tcpRequestConn {
+	c := &tcpRequestConn{Conn: conn}
+	if tcpOptions.WriteMode == "buffered" {
+		c.buf = bufio.NewWriter(conn)
+	}
+	return c
+}
+
+// Write sends p as one request
+func (c *tcpRequestConn) Write(p []byte) (int, error) {
+	switch tcpOptions.WriteMode {
+	case "split":
+		h := min(tcpSplitHeader, len(p))
+		n, err := c.Conn.Write(p[:h])
+		if err != nil || h == len(p) {
+			return n, err
+		}
+		m, err := c.Conn.Write(p[h:])
+		return n + m, err
+	case "buffered":
+		h := min(tcpSplitHeader, len(p))
+		c.buf.Write(p[:h])
+		c.buf.Write(p[h:])
+		if err := c.buf.Flush(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	default:
+		return c.Conn.Write(p)
+	}
+}
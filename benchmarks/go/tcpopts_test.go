@@ -0,0 +1,65 @@
+// TCP Socket Options Tests - Go
+//
+// Run with: go test -run 'SocketBuffer|TCPBuffers'
+
+package main
+
+import (
+	"net"
+	"runtime"
+	"testing"
+)
+
+func TestParseSocketBuffer(t *testing.T) {
+	cases := []struct {
+		in    string
+		want  int
+		label string
+	}{
+		{"", 0, "default"},
+		{"default", 0, "default"},
+		{"65536", 64 << 10, "64KiB"},
+		{"1000", 1000, "1000B"},
+		{"4MiB", 4 << 20, "4MiB"},
+	}
+	for _, c := range cases {
+		got, err := parseSocketBuffer(c.in)
+		if err != nil || got != c.want {
+			t.Errorf("parseSocketBuffer(%q) = %d, %v; want %d", c.in, got, err, c.want)
+		}
+		if label := socketBufferLabel(got); label != c.label {
+			t.Errorf("socketBufferLabel(%d) = %q, want %q", got, label, c.label)
+		}
+	}
+	for _, bad := range []string{"off", "4GiB", "-1", "64KB"} {
+		if _, err := parseSocketBuffer(bad); err == nil {
+			t.Errorf("parseSocketBuffer(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestSetTCPBuffers(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := setTCPBuffers(tcpConnOf(conn), 64<<10, 32<<10); err != nil {
+		t.Fatal(err)
+	}
+	send, recv, ok := socketBuffers(conn)
+	if !ok {
+		t.Skipf("socket buffer sizes are not readable on %s", runtime.GOOS)
+	}
+	// Kernels may round or (Linux) double the request, but never grant less
+	// than these small sizes
+	if send < 64<<10 || recv < 32<<10 {
+		t.Errorf("granted sndbuf %d, rcvbuf %d; want at least %d and %d", send, recv, 64<<10, 32<<10)
+	}
+}
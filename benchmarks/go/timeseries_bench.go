@@ -0,0 +1,138 @@
+// Time Series Downsampling Benchmark - Go
+//
+// Ingests 100M timestamped points and rolls them up into per-minute
+// min/max/avg, as a telemetry pipeline does before storage. Two approaches:
+// a map keyed by minute, which handles any time range, and a slice of
+// buckets indexed by minute offset from a known start, which trades that
+// flexibility for no hashing. Mirrors TML's telemetry pipeline benchmark.
+
+package main
+
+import "unsafe"
+
+const (
+	tsPoints   = 100_000_000
+	tsBucketNs = int64(60e9)
+)
+
+// tsPoint is one telemetry sample
+type tsPoint struct {
+	TimestampNs int64
+	Value       float64
+}
+
+// tsRollup is the per-minute aggregate
+type tsRollup struct {
+	Min, Max, Sum float64
+	Count         int64
+}
+
+func (r *tsRollup) add(v float64) {
+	if r.Count == 0 || v < r.Min {
+		r.Min = v
+	}
+	if r.Count == 0 || v > r.Max {
+		r.Max = v
+	}
+	r.Sum += v
+	r.Count++
+}
+
+// Avg returns the mean of the bucket's values
+func (r *tsRollup) Avg() float64 {
+	return r.Sum / float64(r.Count)
+}
+
+// tsFixture is the point stream generated in setup
+var tsFixture []tsPoint
+
+// setupTimeSeries generates points roughly every 10ms with jitter, so a
+// minute holds about 6000 of them and some arrive slightly out of order
+func setupTimeSeries(b *B) error {
+	rng := newRand(1523)
+	points := make([]tsPoint, tsPoints)
+	ts := int64(1_700_000_000) * 1e9
+	value := 50.0
+	for i := range points {
+		ts += 10e6
+		value += rng.NormFloat64()
+		points[i] = tsPoint{TimestampNs: ts + rng.Int63n(20e6) - 10e6, Value: value}
+	}
+	tsFixture = points
+	return nil
+}
+
+func teardownTimeSeries() {
+	tsFixture = nil
+}
+
+// rollupMap aggregates into a map keyed by bucket start
+func rollupMap(points []tsPoint) map[int64]*tsRollup {
+	buckets := make(map[int64]*tsRollup)
+	for i := range points {
+		p := &points[i]
+		key := p.TimestampNs - p.TimestampNs%tsBucketNs
+		r := buckets[key]
+		if r == nil {
+			r = &tsRollup{}
+			buckets[key] = r
+		}
+		r.add(p.Value)
+	}
+	return buckets
+}
+
+// rollupBucketed aggregates into a slice indexed by minute offset from
+// start; points outside [start, end) are dropped and counted
+func rollupBucketed(points []tsPoint, start, end int64) ([]tsRollup, int) {
+	start -= start % tsBucketNs
+	buckets := make([]tsRollup, (end-start+tsBucketNs-1)/tsBucketNs)
+	dropped := 0
+	for i := range points {
+		p := &points[i]
+		if p.TimestampNs < start || p.TimestampNs >= end {
+			dropped++
+			continue
+		}
+		buckets[(p.TimestampNs-start)/tsBucketNs].add(p.Value)
+	}
+	return buckets, dropped
+}
+
+// tsSink keeps rollups observable so aggregation is not optimized away
+var tsSink float64
+
+func init() {
+	Register(Benchmark{
+		Name: "TimeSeriesRollup", Category: "timeseries", Tags: []string{"cpu", "alloc", "large"},
+		Iterations: 3, DataSize: tsPoints * int64(unsafe.Sizeof(tsPoint{})),
+		Axes:  []Axis{{Name: "approach", Values: Strings("map", "bucketed")}},
+		Setup: setupTimeSeries, Teardown: teardownTimeSeries,
+		Fn: func(b *B) {
+			checksum := 0.0
+			buckets := 0
+			if b.StringParam("approach") == "map" {
+				m := rollupMap(tsFixture)
+				for _, r := range m {
+					checksum += r.Avg()
+				}
+				buckets = len(m)
+			} else {
+				// The ingest window is known up front, as for a fixed
+				// retention period
+				start := tsFixture[0].TimestampNs - 1e9
+				end := tsFixture[len(tsFixture)-1].TimestampNs + 1e9
+				s, dropped := rollupBucketed(tsFixture, start, end)
+				for i := range s {
+					if s[i].Count > 0 {
+						checksum += s[i].Avg()
+						buckets++
+					}
+				}
+				b.ReportMetric("dropped", float64(dropped))
+			}
+			tsSink = checksum
+			b.ReportMetric("buckets", float64(buckets))
+		},
+	})
+}
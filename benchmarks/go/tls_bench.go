@@ -0,0 +1,224 @@
+// Go TLS Benchmarks
+// crypto/tls variants of the TCP benchmarks, against an in-process echo
+// server with a self-signed certificate generated at setup:
+//
+//   - TlsHandshake: connect, complete a full handshake and close, for an
+//     ECDSA P-256 and an RSA-2048 server key
+//   - TlsResumedHandshake: the same, resuming a session from a ticket the
+//     server issued on a priming connection made at setup, so the
+//     certificate signature and verification are skipped
+//   - TlsReusedRequest: the request/response echo round trip over one
+//     established TLS connection, so it can be read against
+//     TcpReusedRequest for the cost of encryption
+//
+// All use TLS 1.3 with Go's default cipher suite preferences. The client
+// trusts only the generated certificate; key generation is not timed.
+
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"time"
+)
+
+// selfSignedCert returns a certificate for 127.0.0.1 with a key of the
+// given type ("ecdsa" or "rsa") and a pool trusting it
+func selfSignedCert(keyType string) (tls.Certificate, *x509.CertPool, error) {
+	var key crypto.Signer
+	var err error
+	switch keyType {
+	case "ecdsa":
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "rsa":
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		err = fmt.Errorf("unknown key type %q", keyType)
+	}
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "tml-benchmarks"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool, nil
+}
+
+// tlsConfigs builds matching server and client configurations around a
+// fresh self-signed certificate
+func tlsConfigs(keyType string) (server, client *tls.Config, err error) {
+	cert, pool, err := selfSignedCert(keyType)
+	if err != nil {
+		return nil, nil, err
+	}
+	server = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS13}
+	client = &tls.Config{RootCAs: pool, ServerName: "127.0.0.1", MinVersion: tls.VersionTLS13}
+	return server, client, nil
+}
+
+// dialTLS connects to addr with the run's socket options and completes the
+// handshake
+func dialTLS(addr string, cfg *tls.Config) (*tls.Conn, error) {
+	conn, err := dialTCP(addr)
+	if err != nil {
+		return nil, err
+	}
+	tc := tls.Client(conn, cfg)
+	if err := tc.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tc, nil
+}
+
+var tlsBench struct {
+	server net.Listener
+	client *tls.Config
+}
+
+func setupTlsHandshake(b *B) error {
+	serverCfg, clientCfg, err := tlsConfigs(b.StringParam("key"))
+	if err != nil {
+		return err
+	}
+	ln, err := startEchoServer(tlsTransport{server: serverCfg, client: clientCfg})
+	if err != nil {
+		return err
+	}
+	tlsBench.server, tlsBench.client = ln, clientCfg
+	return nil
+}
+
+func teardownTlsHandshake() {
+	if tlsBench.server != nil {
+		tlsBench.server.Close()
+	}
+	tlsBench.server, tlsBench.client = nil, nil
+}
+
+// benchTlsHandshake opens, handshakes and closes one connection
+func benchTlsHandshake(b *B) {
+	start := time.Now()
+	conn, err := dialTLS(tlsBench.server.Addr().String(), tlsBench.client)
+	if err != nil {
+		b.Fatal(err)
+		return
+	}
+	conn.Close()
+	b.Histogram().Record(time.Since(start))
+}
+
+func setupTlsResumedHandshake(b *B) error {
+	if err := setupTlsHandshake(b); err != nil {
+		return err
+	}
+	tlsBench.client.ClientSessionCache = tls.NewLRUClientSessionCache(1)
+	// TLS 1.3 tickets arrive after the handshake, so read an echo to
+	// receive one before closing the priming connection
+	conn, err := dialTLS(tlsBench.server.Addr().String(), tlsBench.client)
+	if err != nil {
+		teardownTlsHandshake()
+		return err
+	}
+	defer conn.Close()
+	var probe [1]byte
+	if _, err := conn.Write(probe[:]); err == nil {
+		_, err = io.ReadFull(conn, probe[:])
+	}
+	if err != nil {
+		teardownTlsHandshake()
+		return fmt.Errorf("priming session ticket: %w", err)
+	}
+	return nil
+}
+
+// benchTlsResumedHandshake opens, resumes and closes one connection
+func benchTlsResumedHandshake(b *B) {
+	start := time.Now()
+	conn, err := dialTLS(tlsBench.server.Addr().String(), tlsBench.client)
+	if err != nil {
+		b.Fatal(err)
+		return
+	}
+	resumed := conn.ConnectionState().DidResume
+	conn.Close()
+	if !resumed {
+		b.Fatal(fmt.Errorf("handshake did not resume the session"))
+		return
+	}
+	b.Histogram().Record(time.Since(start))
+}
+
+var tlsRequest requestClient
+
+func setupTlsReusedRequest(b *B) error {
+	serverCfg, clientCfg, err := tlsConfigs("ecdsa")
+	if err != nil {
+		return err
+	}
+	return tlsRequest.dial(tlsTransport{server: serverCfg, client: clientCfg}, benchConfig.PayloadSizes.Request)
+}
+
+// benchTlsReusedRequest performs one encrypted round trip over the
+// connection opened in setup
+func benchTlsReusedRequest(b *B) {
+	c := &tlsRequest
+	b.SetBytes(int64(len(c.payload)))
+	start := time.Now()
+	if err := c.transport.RoundTrip(c.conn, c.payload, c.reply); err != nil {
+		b.Fatal(err)
+		return
+	}
+	b.Histogram().Record(time.Since(start))
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "TlsHandshake", Category: "tls", Tags: []string{"net"},
+		Iterations: 500,
+		Axes:       []Axis{{Name: "key", Values: Strings("ecdsa", "rsa")}},
+		Setup:      setupTlsHandshake, Teardown: teardownTlsHandshake,
+		Fn: benchTlsHandshake,
+	})
+	Register(Benchmark{
+		Name: "TlsResumedHandshake", Category: "tls", Tags: []string{"net"},
+		Iterations: 500,
+		Axes:       []Axis{{Name: "key", Values: Strings("ecdsa", "rsa")}},
+		Setup:      setupTlsResumedHandshake, Teardown: teardownTlsHandshake,
+		Fn: benchTlsResumedHandshake,
+	})
+	Register(Benchmark{
+		Name: "TlsReusedRequest", Category: "tls", Tags: []string{"net"},
+		Iterations: 10000,
+		Setup:      setupTlsReusedRequest, Teardown: tlsRequest.close,
+		Fn: benchTlsReusedRequest,
+	})
+}
@@ -0,0 +1,37 @@
+// Execution Trace Capture - Go
+//
+// With -trace dir, every case records a runtime execution trace of its
+// measured iterations, showing goroutine scheduling, netpoller wakeups and
+// GC phases. Traces grow quickly, so narrow the run with -run to the case
+// being analyzed, e.g. one of the concurrent TCP benchmarks.
+//
+// Inspect with: go tool trace traces/TcpBindConcurrent_goroutines-1000.trace
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime/trace"
+)
+
+// traceHook returns a MeasureHook writing execution traces into dir
+func traceHook(dir string) MeasureHook {
+	return func(c Case) (func(), error) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+		f, err := os.Create(filepath.Join(dir, caseFileName(c.Name)+".trace"))
+		if err != nil {
+			return nil, err
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return func() {
+			trace.Stop()
+			f.Close()
+		}, nil
+	}
+}
@@ -0,0 +1,107 @@
+// Chunked File Transfer Tests - Go
+//
+// Run with: go test -run Transfer
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTransferDeliversFile(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	if err := writeTransferFile(src, 10_000_123); err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error, 1)
+	ln, err := startTransferReceiver(dst, errs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	stats, err := newTransferSender(ln.Addr().String(), 64*1024).send(src, transferOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (10_000_123 + 64*1024 - 1) / (64 * 1024); stats.chunks != want || stats.retransmits != 0 {
+		t.Errorf("stats %+v, want %d chunks and no retransmits", stats, want)
+	}
+	want, _ := os.ReadFile(src)
+	got, _ := os.ReadFile(dst)
+	if !bytes.Equal(got, want) {
+		t.Errorf("received %d bytes differing from the %d sent", len(got), len(want))
+	}
+}
+
+func TestTransferRejectsCorruptChunk(t *testing.T) {
+	client, server := net.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- receiveFile(server, filepath.Join(t.TempDir(), "dst")) }()
+
+	var hello [transferHelloSize]byte
+	copy(hello[:], transferMagic)
+	binary.LittleEndian.PutUint64(hello[4:], 4)
+	client.Write(hello[:])
+	var offset [8]byte
+	if _, err := io.ReadFull(client, offset[:]); err != nil || binary.LittleEndian.Uint64(offset[:]) != 0 {
+		t.Fatalf("start offset %x, err %v; want 0", offset, err)
+	}
+
+	s := &transferSender{}
+	frame := make([]byte, transferHeaderSize+4)
+	copy(frame[transferHeaderSize:], "data")
+	acked, ok, err := s.sendFrame(client, frame, 0, 12345)
+	if err != nil || ok || acked != 0 {
+		t.Fatalf("corrupt chunk: acked %d ok %v err %v, want a rejection", acked, ok, err)
+	}
+	sum := crc32.Checksum([]byte("data"), castagnoli)
+	if acked, ok, err = s.sendFrame(client, frame, 0, sum); err != nil || !ok || acked != 4 {
+		t.Fatalf("retransmitted chunk: acked %d ok %v err %v", acked, ok, err)
+	}
+	if acked, ok, err = s.sendFrame(client, frame[:transferHeaderSize], 4, sum); err != nil || !ok || acked != 4 {
+		t.Fatalf("final frame: acked %d ok %v err %v", acked, ok, err)
+	}
+	if err := <-done; err != nil && err != io.EOF {
+		t.Errorf("receiver: %v", err)
+	}
+}
+
+func TestTransferResumesInterrupted(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	if err := writeTransferFile(src, 1_000_003); err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error, 1)
+	ln, err := startTransferReceiver(dst, errs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	sender := newTransferSender(ln.Addr().String(), 64*1024)
+	if _, err := sender.send(src, transferOptions{stopAfter: 500_000}); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := sender.send(src, transferOptions{resume: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := uint64(8 * 64 * 1024); stats.resumedAt != want {
+		t.Errorf("resumed at %d, want %d", stats.resumedAt, want)
+	}
+	want, _ := hashFile(src)
+	got, _ := hashFile(dst)
+	if !bytes.Equal(got, want) {
+		t.Errorf("resumed file hash %x, want %x", got, want)
+	}
+}
@@ -0,0 +1,346 @@
+// Network Transports - Go
+//
+// The echo request benchmarks run over a Transport rather than a fixed
+// socket type, so a new transport - an in-memory pipe, a Unix socket, a
+// wrapper injecting faults - is benchmarked by adding it to transports
+// instead of duplicating the echo server and client code. EchoRequest runs
+// the round trip over every registered transport.
+//
+// Still missing: there is no io_uring or other kernel-bypass transport,
+// and the TCP and TLS request benchmarks (TcpReusedRequest,
+// TcpRequestSweep, TlsReusedRequest, ...) are fixed to tcpTransport and
+// tlsTransport rather than running over every registered transport.
+//
+// Registered transports:
+//
+//   - pipe: net.Pipe, synchronous in-memory connections with no kernel
+//     involvement at all - the floor set by the runtime and scheduler, so
+//     the syscall share of the tcp numbers can be separated out
+//   - tcp: loopback TCP with the run's socket options and write mode
+//   - tcp-faults: tcp with every client write cut into faultMaxWrite-byte
+//     pieces, the short writes of a congested or misbehaving link, to see
+//     how the harness and the numbers hold up against them
+//   - unix: unix domain stream sockets (uds_bench.go)
+
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+)
+
+// Transport is a connection-oriented transport the echo benchmarks run
+// over
+type Transport interface {
+	// Listen opens a listener for an echo server
+	Listen() (net.Listener, error)
+	// Dial connects a client to addr, the address of a listener from Listen
+	Dial(addr net.Addr) (net.Conn, error)
+	// RoundTrip sends req on a client connection and reads its echo, as
+	// long as req, into resp
+	RoundTrip(conn net.Conn, req, resp []byte) error
+}
+
+const faultMaxWrite = 16
+
+// transports are the transports EchoRequest runs over, by name
+var transports = map[string]Transport{
+	"pipe":       pipeTransport{},
+	"tcp":        tcpTransport{},
+	"tcp-faults": faultTransport{inner: tcpTransport{}, maxWrite: faultMaxWrite},
+	"unix":       udsTransport{},
+}
+
+// transportNames returns the registered transport names in order
+func transportNames() []string {
+	names := make([]string, 0, len(transports))
+	for name := range transports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// echoServer echoes every connection accepted from its listener. Close
+// stops it deterministically: the listener and every connection still
+// open are closed, and Close returns only once the accept loop and all
+// connection goroutines have, so nothing of one case's server is still
+// running when the next case starts.
+type echoServer struct {
+	net.Listener
+	wg sync.WaitGroup
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+}
+
+// startEchoServer starts an echo server on a listener from t, returning
+// once its accept loop is running
+func startEchoServer(t Transport) (*echoServer, error) {
+	ln, err := t.Listen()
+	if err != nil {
+		return nil, err
+	}
+	return serveEcho(ln), nil
+}
+
+// serveEcho starts an echo server on ln, returning once its accept loop is
+// running
+func serveEcho(ln net.Listener) *echoServer {
+	s := &echoServer{Listener: ln, conns: map[net.Conn]struct{}{}}
+	ready := make(chan struct{})
+	s.wg.Add(1)
+	go s.serve(ready)
+	<-ready
+	return s
+}
+
+// serve is the accept loop, which signals ready before its first Accept
+func (s *echoServer) serve(ready chan<- struct{}) {
+	defer s.wg.Done()
+	close(ready)
+	for {
+		conn, err := s.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go func() {
+			defer s.wg.Done()
+			io.Copy(conn, conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+// Close stops the server and waits for all of its goroutines to finish
+func (s *echoServer) Close() error {
+	err := s.Listener.Close()
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+// tcpInlineWriteMax is the largest request written before reading the
+// echo; larger ones are written concurrently, since the echo server could
+// otherwise stall on full socket buffers while the client is still writing
+const tcpInlineWriteMax = 64 * 1024
+
+// echoRoundTrip is the RoundTrip of stream transports: write the request,
+// then read the echo
+func echoRoundTrip(conn net.Conn, req, resp []byte) error {
+	if len(req) <= tcpInlineWriteMax {
+		if _, err := conn.Write(req); err != nil {
+			return err
+		}
+		_, err := io.ReadFull(conn, resp)
+		return err
+	}
+	written := make(chan error, 1)
+	go func() {
+		_, err := conn.Write(req)
+		written <- err
+	}()
+	_, readErr := io.ReadFull(conn, resp)
+	if err := <-written; err != nil {
+		return err
+	}
+	return readErr
+}
+
+// tcpTransport is loopback TCP at the configured echo port, with the run's
+// socket options on both ends and client writes in the run's write mode
+type tcpTransport struct{}
+
+func (tcpTransport) Listen() (net.Listener, error) {
+	if remoteOptions.Mode == "client" {
+		return listenRemote()
+	}
+	ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", benchConfig.Ports.TCPEcho))
+	if err != nil {
+		return nil, err
+	}
+	return tcpListener{ln}, nil
+}
+
+func (tcpTransport) Dial(addr net.Addr) (net.Conn, error) {
+	conn, err := dialTCP(addr.String())
+	if err != nil {
+		return nil, err
+	}
+	return newTCPRequestConn(conn), nil
+}
+
+func (tcpTransport) RoundTrip(conn net.Conn, req, resp []byte) error {
+	return echoRoundTrip(conn, req, resp)
+}
+
+// tcpListener applies the run's socket options to accepted connections;
+// one that cannot be configured is closed and fails Accept, rather than
+// serving a benchmark with the kernel's settings
+type tcpListener struct {
+	net.Listener
+}
+
+func (l tcpListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if err := configureTCPConn(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// pipeTransport connects clients to its listeners with net.Pipe
+type pipeTransport struct{}
+
+func (pipeTransport) Listen() (net.Listener, error) {
+	return &pipeListener{conns: make(chan net.Conn), done: make(chan struct{})}, nil
+}
+
+func (pipeTransport) Dial(addr net.Addr) (net.Conn, error) {
+	a, ok := addr.(pipeAddr)
+	if !ok {
+		return nil, fmt.Errorf("pipe transport cannot dial %s address %s", addr.Network(), addr)
+	}
+	client, server := net.Pipe()
+	select {
+	case a.l.conns <- server:
+		return client, nil
+	case <-a.l.done:
+		client.Close()
+		server.Close()
+		return nil, errors.New("pipe listener closed")
+	}
+}
+
+func (pipeTransport) RoundTrip(conn net.Conn, req, resp []byte) error {
+	return echoRoundTrip(conn, req, resp)
+}
+
+// pipeListener hands the server ends of dialed pipes to Accept
+type pipeListener struct {
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr{l}
+}
+
+// pipeAddr identifies a pipeListener to Dial
+type pipeAddr struct {
+	l *pipeListener
+}
+
+func (pipeAddr) Network() string { return "pipe" }
+
+func (a pipeAddr) String() string { return fmt.Sprintf("pipe:%p", a.l) }
+
+// tlsTransport is tcpTransport with TLS on top, handshaken when dialing
+type tlsTransport struct {
+	server, client *tls.Config
+}
+
+func (t tlsTransport) Listen() (net.Listener, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	return tls.NewListener(tcpListener{ln}, t.server), nil
+}
+
+func (t tlsTransport) Dial(addr net.Addr) (net.Conn, error) {
+	conn, err := dialTLS(addr.String(), t.client)
+	if err != nil {
+		return nil, err
+	}
+	return newTCPRequestConn(conn), nil
+}
+
+func (tlsTransport) RoundTrip(conn net.Conn, req, resp []byte) error {
+	return echoRoundTrip(conn, req, resp)
+}
+
+// faultTransport wraps another transport's client connections so every
+// write is cut into pieces of at most maxWrite bytes
+type faultTransport struct {
+	inner    Transport
+	maxWrite int
+}
+
+func (t faultTransport) Listen() (net.Listener, error) {
+	return t.inner.Listen()
+}
+
+func (t faultTransport) Dial(addr net.Addr) (net.Conn, error) {
+	conn, err := t.inner.Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &faultConn{Conn: conn, t: t}, nil
+}
+
+func (t faultTransport) RoundTrip(conn net.Conn, req, resp []byte) error {
+	return t.inner.RoundTrip(conn, req, resp)
+}
+
+// faultConn is a client connection of faultTransport
+type faultConn struct {
+	net.Conn
+	t faultTransport
+}
+
+func (c *faultConn) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), c.t.maxWrite)
+		m, err := c.Conn.Write(p[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
@@ -0,0 +1,103 @@
+// Transport Tests - Go
+//
+// Run with: go test -run Transport
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestTransportsEcho(t *testing.T) {
+	for _, name := range transportNames() {
+		tr := transports[name]
+		ln, err := startEchoServer(tr)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		conn, err := tr.Dial(ln.Addr())
+		if err != nil {
+			ln.Close()
+			t.Fatalf("%s: %v", name, err)
+		}
+		// Small requests are written inline, large ones concurrently
+		for _, size := range []int{1, 64, tcpInlineWriteMax + 1} {
+			req := bytes.Repeat([]byte{byte(size)}, size)
+			resp := make([]byte, size)
+			if err := tr.RoundTrip(conn, req, resp); err != nil {
+				t.Errorf("%s: %d bytes: %v", name, size, err)
+			} else if !bytes.Equal(resp, req) {
+				t.Errorf("%s: %d bytes echoed wrongly", name, size)
+			}
+		}
+		conn.Close()
+		ln.Close()
+	}
+}
+
+func TestFaultConnCutsWrites(t *testing.T) {
+	var sizes []int
+	w := &faultConn{Conn: &recordingConn{sizes: &sizes}, t: faultTransport{maxWrite: 16}}
+	if n, err := w.Write(make([]byte, 40)); n != 40 || err != nil {
+		t.Fatalf("wrote %d: %v", n, err)
+	}
+	if len(sizes) != 3 || sizes[0] != 16 || sizes[1] != 16 || sizes[2] != 8 {
+		t.Errorf("writes %v, want [16 16 8]", sizes)
+	}
+}
+
+// recordingConn records the size of every write
+type recordingConn struct {
+	net.Conn
+	sizes *[]int
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	*c.sizes = append(*c.sizes, len(p))
+	return len(p), nil
+}
+
+func TestEchoServerCloseWaits(t *testing.T) {
+	before := runtime.NumGoroutine()
+	ln, err := startTCPEchoServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		ln.Close()
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// A round trip makes sure the server holds the connection
+	reply := make([]byte, 1)
+	if err := echoRoundTrip(conn, []byte{1}, reply); err != nil {
+		t.Fatal(err)
+	}
+	ln.Close()
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("%d goroutines after Close, %d before the server started", after, before)
+	}
+	// The server's end of the idle connection was closed too
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(reply); err != io.EOF {
+		t.Errorf("read after Close: %v, want EOF", err)
+	}
+}
+
+func TestUDPEchoServerCloseWaits(t *testing.T) {
+	before := runtime.NumGoroutine()
+	pc, err := startUDPEchoServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc.Close()
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("%d goroutines after Close, %d before the server started", after, before)
+	}
+}
@@ -0,0 +1,105 @@
+// UDP Request Tests - Go
+//
+// Run with: go test -run 'UDPAccounting|IPv4Fragments'
+
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// startFaultyUDPEchoServer echoes datagrams, except that it drops
+// requests 3 and 6, answers request 4 twice and holds back its reply to
+// request 6 until request 7 arrives
+func startFaultyUDPEchoServer(t *testing.T) net.PacketConn {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		buf := make([]byte, 64)
+		var held []byte
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			switch binary.LittleEndian.Uint64(buf) {
+			case 3:
+				continue
+			case 4:
+				pc.WriteTo(buf[:n], addr)
+			case 6:
+				held = append([]byte(nil), buf[:n]...)
+				continue
+			case 7:
+				pc.WriteTo(held, addr)
+			}
+			pc.WriteTo(buf[:n], addr)
+		}
+	}()
+	return pc
+}
+
+func TestUDPAccountingCountsFaults(t *testing.T) {
+	pc := startFaultyUDPEchoServer(t)
+	defer pc.Close()
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	a := newUDPAccounting(50 * time.Millisecond)
+	payload, reply := make([]byte, 16), make([]byte, 64)
+	for i := 1; i <= 8; i++ {
+		answered, err := a.roundTrip(conn, payload, reply)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		if want := i != 3 && i != 6; answered != want {
+			t.Errorf("request %d answered %v, want %v", i, answered, want)
+		}
+	}
+	if a.sent != 8 || a.answered != 6 || a.timeouts != 2 || a.late != 1 || a.duplicates != 1 {
+		t.Errorf("sent %d answered %d timeouts %d late %d duplicates %d, want 8 6 2 1 1",
+			a.sent, a.answered, a.timeouts, a.late, a.duplicates)
+	}
+	if loss := a.lossPct(); loss != 25 {
+		t.Errorf("loss %.1f%%, want 25%%", loss)
+	}
+}
+
+func TestIPv4Fragments(t *testing.T) {
+	for _, tc := range []struct{ payload, mtu, want int }{
+		{1472, 1500, 1},
+		{1473, 1500, 2},
+		// 1480 bytes of UDP datagram per fragment
+		{2952, 1500, 2},
+		{2953, 1500, 3},
+		{udpMaxPayload, 1500, 45},
+		{udpMaxPayload, 65536, 1},
+	} {
+		if got := ipv4Fragments(tc.payload, tc.mtu); got != tc.want {
+			t.Errorf("%dB at MTU %d: %d fragments, want %d", tc.payload, tc.mtu, got, tc.want)
+		}
+	}
+}
+
+func TestUdpRequestExcludesWarmup(t *testing.T) {
+	bench := &Benchmark{
+		Name: "UdpRequest", Iterations: 20,
+		Setup: setupUdpRequest, Teardown: udpRequest.close,
+		Fn: benchUdpRequest,
+	}
+	r := RunCase(Case{Name: "UdpRequest", Bench: bench})
+	if r.Error != "" {
+		t.Fatal(r.Error)
+	}
+	if sent := r.Metrics["sent"]; sent != 20 {
+		t.Errorf("sent = %v, want the 20 measured requests only", sent)
+	}
+}
@@ -0,0 +1,94 @@
+// Go UDP Payload Sweep Benchmark
+// The UdpRequest round trip at datagram payloads from 64B to the 65507-byte
+// UDP maximum, the axis along which IP fragmentation changes the picture:
+// a datagram larger than the path MTU is split into fragments that all
+// have to arrive, so latency steps up and one lost fragment loses the
+// whole datagram (counted by the UDP accounting as a timeout).
+//
+// The path MTU is taken from the interface the client socket sends
+// through, so it needs no privileges or platform-specific socket options;
+// on loopback that is the interface's own, typically 64KB, and nothing in
+// the sweep fragments - point the run at a real interface to see the step.
+// Per payload the case reports path_mtu, the largest unfragmented payload
+// (max_unfragmented) and the fragments each datagram becomes.
+
+package main
+
+import (
+	"errors"
+	"net"
+)
+
+const (
+	// ipv4HeaderSize and udpHeaderSize are the headers without options
+	ipv4HeaderSize = 20
+	udpHeaderSize  = 8
+	udpMaxPayload  = 65507
+)
+
+var udpSweepPayloads = Ints(64, 512, 1024, 1472, 1473, 4096, 8192, 16384, 32768, udpMaxPayload)
+
+// interfaceMTU returns the MTU of the interface that owns addr
+func interfaceMTU(addr net.Addr) (int, error) {
+	udp, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return 0, errors.New("not a UDP address")
+	}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return 0, err
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.Equal(udp.IP) {
+				return iface.MTU, nil
+			}
+		}
+	}
+	return 0, errors.New("no interface owns " + udp.IP.String())
+}
+
+// ipv4Fragments returns how many IPv4 fragments a UDP datagram with
+// payload bytes becomes at mtu
+func ipv4Fragments(payload, mtu int) int {
+	if udpHeaderSize+payload <= mtu-ipv4HeaderSize {
+		return 1
+	}
+	// Every fragment but the last carries a multiple of 8 bytes
+	perFragment := (mtu - ipv4HeaderSize) &^ 7
+	return (udpHeaderSize + payload + perFragment - 1) / perFragment
+}
+
+var udpSweepMTU int
+
+func setupUdpPayloadSweep(b *B) error {
+	if err := udpRequest.dialUDP(b.IntParam("payload")); err != nil {
+		return err
+	}
+	mtu, err := interfaceMTU(udpRequest.conn.LocalAddr())
+	if err != nil {
+		udpRequest.close()
+		return err
+	}
+	udpSweepMTU = mtu
+	return nil
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "UdpPayloadSweep", Category: "udp", Tags: []string{"net", "remote"},
+		Iterations: 2000,
+		Axes:       []Axis{{Name: "payload", Values: udpSweepPayloads}},
+		Setup:      setupUdpPayloadSweep, Teardown: udpRequest.close,
+		Fn: func(b *B) {
+			benchUdpRequest(b)
+			b.ReportMetric("path_mtu", float64(udpSweepMTU))
+			b.ReportMetric("max_unfragmented", float64(min(udpSweepMTU-ipv4HeaderSize-udpHeaderSize, udpMaxPayload)))
+			b.ReportMetric("fragments", float64(ipv4Fragments(len(udpRequest.payload), udpSweepMTU)))
+		},
+	})
+}
@@ -0,0 +1,119 @@
+// Go Unix Domain Socket Benchmarks
+// UDS equivalents of the TCP bind benchmarks, plus the unix transport that
+// EchoRequest runs its round trip over (see transport.go). Set against the
+// tcp numbers they separate the cost of the kernel's TCP/IP stack from
+// that of local IPC as such.
+//
+// Sockets are created in a temporary directory, since a unix socket is a
+// file; closing the listener unlinks it.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// udsTransport is stream unix sockets in a temporary directory
+type udsTransport struct{}
+
+func (udsTransport) Listen() (net.Listener, error) {
+	dir, err := os.MkdirTemp("", "tml-uds-")
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", filepath.Join(dir, "echo.sock"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return udsListener{Listener: ln, dir: dir}, nil
+}
+
+func (udsTransport) Dial(addr net.Addr) (net.Conn, error) {
+	return net.Dial("unix", addr.String())
+}
+
+func (udsTransport) RoundTrip(conn net.Conn, req, resp []byte) error {
+	return echoRoundTrip(conn, req, resp)
+}
+
+// udsListener removes its directory when closed
+type udsListener struct {
+	net.Listener
+	dir string
+}
+
+func (l udsListener) Close() error {
+	err := l.Listener.Close()
+	os.RemoveAll(l.dir)
+	return err
+}
+
+var udsBindDir string
+
+func setupUdsBind(b *B) error {
+	dir, err := os.MkdirTemp("", "tml-uds-")
+	udsBindDir = dir
+	return err
+}
+
+func teardownUdsBind() {
+	if udsBindDir != "" {
+		os.RemoveAll(udsBindDir)
+		udsBindDir = ""
+	}
+}
+
+// bindUnix creates and closes one listener at name in the bind directory
+func bindUnix(name string) error {
+	listener, err := net.Listen("unix", filepath.Join(udsBindDir, name))
+	if err != nil {
+		return err
+	}
+	return listener.Close()
+}
+
+func init() {
+	// Listener bind overhead, as TcpBind
+	Register(Benchmark{
+		Name: "UdsBind", Category: "uds", Tags: []string{"net"},
+		Iterations: 50,
+		Setup:      setupUdsBind, Teardown: teardownUdsBind,
+		Fn: func(b *B) {
+			if err := bindUnix("bind.sock"); err != nil {
+				b.Fatal(err)
+			}
+		},
+	})
+
+	// A batch of concurrent binds, one per goroutine, as TcpBindConcurrent
+	Register(Benchmark{
+		Name: "UdsBindConcurrent", Category: "uds", Tags: []string{"net"},
+		Iterations: 5,
+		Axes:       []Axis{{Name: "goroutines", Values: Ints(10, 100, 1000)}},
+		Setup:      setupUdsBind, Teardown: teardownUdsBind,
+		Fn: func(b *B) {
+			n := b.IntParam("goroutines")
+			errs := make(chan error, n)
+			var wg sync.WaitGroup
+			for i := 0; i < n; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := bindUnix(fmt.Sprintf("bind-%d.sock", i)); err != nil {
+						errs <- err
+					}
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			if err := <-errs; err != nil {
+				b.Fatal(err)
+			}
+		},
+	})
+}
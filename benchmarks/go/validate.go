@@ -0,0 +1,289 @@
+// Result Validation - Go
+//
+// benchmarks/common/results.schema.json is the JSON Schema of a result set
+// at the current schema version, shared by every language suite. The
+// validate subcommand checks result files against it before they are
+// merged or compared, so a suite writing a misspelled field, a negative
+// time or a string where a number belongs is caught with the offending
+// path rather than showing up as a silently missing benchmark.
+//
+// A file is checked the way LoadResultSet reads it: its schema_version is
+// detected, older formats (such as the TML suite's flat per-category
+// files) are migrated, and the migrated document is what must conform. A
+// file at the current version is checked exactly as written.
+//
+// Only the parts of JSON Schema the results schema uses are implemented:
+// type, const, enum, required, properties, additionalProperties, items,
+// minimum, minLength and local $ref.
+//
+// Run with: go run . validate [-schema ../common/results.schema.json] results.json ...
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// defaultResultSchemaPath locates the results schema from benchmarks/go
+const defaultResultSchemaPath = "../common/results.schema.json"
+
+// runValidate implements the validate subcommand
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	schemaPath := fs.String("schema", defaultResultSchemaPath, "JSON Schema of a result set")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: validate [-schema ../common/results.schema.json] results.json ...")
+		return 2
+	}
+
+	schema, err := LoadSchema(*schemaPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 2
+	}
+	invalid := 0
+	for _, path := range fs.Args() {
+		problems := ValidateResultFile(path, schema)
+		if len(problems) == 0 {
+			fmt.Printf("%s: ok\n", path)
+			continue
+		}
+		invalid++
+		fmt.Printf("%s: %d problems\n", path, len(problems))
+		for _, p := range problems {
+			fmt.Printf("  %s\n", p)
+		}
+	}
+	if invalid > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Schema is a JSON Schema document, decoded generically
+type Schema struct {
+	root map[string]any
+}
+
+// LoadSchema reads the JSON Schema at path
+func LoadSchema(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var root map[string]any
+	if err := decodeJSONNumbers(data, &root); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &Schema{root: root}, nil
+}
+
+// ValidateResultFile checks the result file at path, migrated to the
+// current schema version, and returns every violation found
+func ValidateResultFile(path string, schema *Schema) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	if data, err = migrateResultSet(data); err != nil {
+		return []string{err.Error()}
+	}
+	var doc any
+	if err := decodeJSONNumbers(data, &doc); err != nil {
+		return []string{err.Error()}
+	}
+	return schema.Validate(doc)
+}
+
+// decodeJSONNumbers decodes data keeping numbers as json.Number, so
+// integers can be told from other numbers
+func decodeJSONNumbers(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// Validate checks doc against the schema and returns every violation,
+// each prefixed with the JSON pointer of the offending value
+func (s *Schema) Validate(doc any) []string {
+	var problems []string
+	s.check(s.root, doc, "", &problems)
+	return problems
+}
+
+func (s *Schema) check(schema map[string]any, v any, path string, problems *[]string) {
+	fail := func(format string, args ...any) {
+		at := path
+		if at == "" {
+			at = "/"
+		}
+		*problems = append(*problems, at+": "+fmt.Sprintf(format, args...))
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		target, err := s.resolve(ref)
+		if err != nil {
+			fail("%v", err)
+			return
+		}
+		s.check(target, v, path, problems)
+		return
+	}
+	if types, ok := schema["type"]; ok && !matchesType(types, v) {
+		fail("is %s, want %v", jsonTypeName(v), types)
+		return
+	}
+	if want, ok := schema["const"]; ok && !jsonEqual(want, v) {
+		fail("is %v, want %v", v, want)
+	}
+	if options, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, o := range options {
+			found = found || jsonEqual(o, v)
+		}
+		if !found {
+			fail("is %v, want one of %v", v, options)
+		}
+	}
+
+	switch v := v.(type) {
+	case json.Number:
+		if minimum, ok := schema["minimum"].(json.Number); ok {
+			if n, _ := v.Float64(); n < mustFloat(minimum) {
+				fail("is %s, below the minimum %s", v, minimum)
+			}
+		}
+	case string:
+		if minLength, ok := schema["minLength"].(json.Number); ok {
+			if n, _ := minLength.Int64(); int64(len(v)) < n {
+				fail("is shorter than %d characters", n)
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				s.check(items, item, fmt.Sprintf("%s/%d", path, i), problems)
+			}
+		}
+	case map[string]any:
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if _, ok := v[name.(string)]; !ok {
+					fail("missing required field %q", name)
+				}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := path + "/" + escapePointer(k)
+			if sub, ok := properties[k].(map[string]any); ok {
+				s.check(sub, v[k], child, problems)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					fail("unknown field %q", k)
+				}
+			case map[string]any:
+				s.check(extra, v[k], child, problems)
+			}
+		}
+	}
+}
+
+// resolve follows a local reference such as "#/$defs/result"
+func (s *Schema) resolve(ref string) (map[string]any, error) {
+	pointer, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, fmt.Errorf("unsupported $ref %q: only local references are supported", ref)
+	}
+	var node any = s.root
+	for _, part := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		node = m[strings.NewReplacer("~1", "/", "~0", "~").Replace(part)]
+	}
+	target, ok := node.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unresolvable $ref %q", ref)
+	}
+	return target, nil
+}
+
+// matchesType reports whether v is of the schema type, or one of the
+// types when given a list
+func matchesType(types any, v any) bool {
+	switch t := types.(type) {
+	case string:
+		name := jsonTypeName(v)
+		return name == t || (t == "number" && name == "integer")
+	case []any:
+		for _, one := range t {
+			if matchesType(one, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jsonTypeName is the JSON Schema type of a decoded value
+func jsonTypeName(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// jsonEqual compares decoded values, numbers by value
+func jsonEqual(a, b any) bool {
+	an, aok := a.(json.Number)
+	bn, bok := b.(json.Number)
+	if aok && bok {
+		return mustFloat(an) == mustFloat(bn)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func mustFloat(n json.Number) float64 {
+	f, _ := n.Float64()
+	return f
+}
+
+// escapePointer escapes a key for use in a JSON pointer
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
@@ -0,0 +1,82 @@
+// Result Validation Tests - Go
+//
+// Run with: go test -run Validate
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func validateDoc(t *testing.T, doc string) []string {
+	t.Helper()
+	schema, err := LoadSchema(defaultResultSchemaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "results.json")
+	if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return ValidateResultFile(path, schema)
+}
+
+func TestValidateAcceptsSuiteOutputs(t *testing.T) {
+	schema, err := LoadSchema(defaultResultSchemaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newResult("TcpReusedRequest", 100, 64, 1000)
+	r.Params = Params{"payload": 64, "transport": "tcp"}
+	r.GC = &GCStats{NumGC: 1}
+	r.Latency = NewHistogram().Summary()
+	r.Metrics = map[string]float64{"loss_pct": 0.5}
+	r.Soak = []SoakSnapshot{{ElapsedS: 10, OpsPerSec: 1000, HeapBytes: 1 << 20, Goroutines: 4}}
+	r.Leaks = &LeakReport{Goroutines: 1}
+	path := filepath.Join(t.TempDir(), "go.json")
+	if err := WriteResultSet(path, ResultSet{Language: "go", Metadata: CollectMetadata(), Results: []BenchmarkResult{r}}); err != nil {
+		t.Fatal(err)
+	}
+	if problems := ValidateResultFile(path, schema); len(problems) > 0 {
+		t.Errorf("Go result set: %v", problems)
+	}
+
+	// The TML suite's flat per-category format, migrated
+	tml := `{"language": "tml", "category": "math", "results": [{"name": "Fib", "iterations": 10, "total_ns": 25000, "per_op_ns": 2500, "ops_per_sec": 400000}]}`
+	if problems := validateDoc(t, tml); len(problems) > 0 {
+		t.Errorf("TML result file: %v", problems)
+	}
+}
+
+func TestValidateReportsViolations(t *testing.T) {
+	doc := `{"schema_version": 3, "language": "tml", "results": [
+		{"name": "", "time_us": -1, "iterations": 1.5, "time_ns": 3},
+		{"name": "Fib", "time_us": 2, "iterations": 10, "metrics": {"ops": "fast"}}
+	]}`
+	problems := validateDoc(t, doc)
+	for _, want := range []string{
+		"/results/0/name: is shorter than 1 characters",
+		"/results/0/time_us: is -1, below the minimum 0",
+		"/results/0/iterations: is number, want integer",
+		`/results/0: unknown field "time_ns"`,
+		"/results/1/metrics/ops: is string, want number",
+	} {
+		found := false
+		for _, p := range problems {
+			found = found || p == want
+		}
+		if !found {
+			t.Errorf("missing problem %q in:\n%s", want, strings.Join(problems, "\n"))
+		}
+	}
+	if len(problems) != 5 {
+		t.Errorf("%d problems, want 5:\n%s", len(problems), strings.Join(problems, "\n"))
+	}
+
+	if problems := validateDoc(t, `{"schema_version": 99, "language": "go", "results": []}`); len(problems) != 1 {
+		t.Errorf("future schema version: %v", problems)
+	}
+}
@@ -0,0 +1,211 @@
+// Varint Encoding Benchmarks - Go
+//
+// Encodes and decodes 100M integers as LEB128 varints, the primitive under
+// protobuf and the TML wire format:
+//
+//   - uvarint: unsigned values with a spread of magnitudes, so every
+//     encoded length from 1 to 9 bytes occurs
+//   - zigzag: signed values, zigzag-mapped so small negatives stay short
+//   - delta: increasing timestamps stored as zigzag varints of the
+//     difference to the previous value, as time series and posting lists are
+//
+// The fixture holds 10M values and each case runs over it ten times:
+// 100M resident values would need about 2.5GB (the values, a worst-case
+// encode buffer and the decode target), and the per-value cost no longer
+// changes once the fixture is far larger than the caches.
+//
+// Throughput is measured against the raw 8-byte integers.
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// varintValues is the fixture size; ten iterations make the 100M values
+const varintValues = 10_000_000
+
+// zigzagEncode maps signed to unsigned so small magnitudes stay small:
+// 0, -1, 1, -2 become 0, 1, 2, 3
+func zigzagEncode(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+// zigzagDecode inverts zigzagEncode
+func zigzagDecode(u uint64) int64 {
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+var errVarintTruncated = errors.New("varint: truncated or overlong value")
+
+// appendUvarints encodes values as unsigned varints
+func appendUvarints(dst []byte, values []int64) []byte {
+	for _, v := range values {
+		dst = binary.AppendUvarint(dst, uint64(v))
+	}
+	return dst
+}
+
+// decodeUvarints fills values from src and returns the bytes consumed
+func decodeUvarints(src []byte, values []int64) (int, error) {
+	off := 0
+	for i := range values {
+		u, n := binary.Uvarint(src[off:])
+		if n <= 0 {
+			return off, errVarintTruncated
+		}
+		values[i] = int64(u)
+		off += n
+	}
+	return off, nil
+}
+
+// appendZigzagVarints encodes values as zigzag varints
+func appendZigzagVarints(dst []byte, values []int64) []byte {
+	for _, v := range values {
+		dst = binary.AppendUvarint(dst, zigzagEncode(v))
+	}
+	return dst
+}
+
+// decodeZigzagVarints fills values from src and returns the bytes consumed
+func decodeZigzagVarints(src []byte, values []int64) (int, error) {
+	off := 0
+	for i := range values {
+		u, n := binary.Uvarint(src[off:])
+		if n <= 0 {
+			return off, errVarintTruncated
+		}
+		values[i] = zigzagDecode(u)
+		off += n
+	}
+	return off, nil
+}
+
+// appendDeltaVarints encodes each value as the zigzag varint of its
+// difference to the previous one, starting from zero
+func appendDeltaVarints(dst []byte, values []int64) []byte {
+	prev := int64(0)
+	for _, v := range values {
+		dst = binary.AppendUvarint(dst, zigzagEncode(v-prev))
+		prev = v
+	}
+	return dst
+}
+
+// decodeDeltaVarints inverts appendDeltaVarints
+func decodeDeltaVarints(src []byte, values []int64) (int, error) {
+	off, prev := 0, int64(0)
+	for i := range values {
+		u, n := binary.Uvarint(src[off:])
+		if n <= 0 {
+			return off, errVarintTruncated
+		}
+		prev += zigzagDecode(u)
+		values[i] = prev
+		off += n
+	}
+	return off, nil
+}
+
+// varintCodecs pairs each encoding axis value with its functions
+var varintCodecs = map[string]struct {
+	encode func(dst []byte, values []int64) []byte
+	decode func(src []byte, values []int64) (int, error)
+}{
+	"uvarint": {appendUvarints, decodeUvarints},
+	"zigzag":  {appendZigzagVarints, decodeZigzagVarints},
+	"delta":   {appendDeltaVarints, decodeDeltaVarints},
+}
+
+// varintFixture generates the values for an encoding
+func varintFixture(encoding string, n int, seed int64) []int64 {
+	rng := newRand(seed)
+	values := make([]int64, n)
+	switch encoding {
+	case "uvarint":
+		// Uniform bit length, so each varint length is equally common
+		for i := range values {
+			values[i] = int64(rng.Uint64() >> (1 + rng.Intn(63)))
+		}
+	case "zigzag":
+		for i := range values {
+			v := int64(rng.Uint64() >> (1 + rng.Intn(63)))
+			if rng.Intn(2) == 0 {
+				v = -v
+			}
+			values[i] = v
+		}
+	case "delta":
+		// Nanosecond timestamps at roughly 1ms intervals with jitter
+		ts := int64(1_700_000_000) * 1e9
+		for i := range values {
+			ts += 1e6 + rng.Int63n(2e5) - 1e5
+			values[i] = ts
+		}
+	}
+	return values
+}
+
+// varintState holds the values, their encoding and the decode target
+var varintState struct {
+	values  []int64
+	encoded []byte
+	decoded []int64
+}
+
+var varintSink int
+
+func setupVarint(b *B) error {
+	s := &varintState
+	encoding := b.StringParam("encoding")
+	codec := varintCodecs[encoding]
+	s.values = varintFixture(encoding, varintValues, 1531)
+	s.encoded = codec.encode(make([]byte, 0, binary.MaxVarintLen64*varintValues), s.values)
+	s.decoded = make([]int64, varintValues)
+	if _, err := codec.decode(s.encoded, s.decoded); err != nil {
+		return err
+	}
+	for i, v := range s.values {
+		if s.decoded[i] != v {
+			return fmt.Errorf("%s: value %d decoded as %d, want %d", encoding, i, s.decoded[i], v)
+		}
+	}
+	return nil
+}
+
+func teardownVarint() {
+	varintState.values, varintState.encoded, varintState.decoded = nil, nil, nil
+}
+
+func init() {
+	encodings := []Axis{{Name: "encoding", Values: Strings("uvarint", "zigzag", "delta")}}
+
+	Register(Benchmark{
+		Name: "VarintEncode", Category: "varint", Tags: []string{"cpu", "serde"},
+		Iterations: 10, DataSize: 8 * varintValues, Axes: encodings,
+		Setup: setupVarint, Teardown: teardownVarint,
+		Fn: func(b *B) {
+			s := &varintState
+			s.encoded = varintCodecs[b.StringParam("encoding")].encode(s.encoded[:0], s.values)
+			varintSink = len(s.encoded)
+			b.ReportMetric("bytes_per_value", float64(len(s.encoded))/varintValues)
+		},
+	})
+	Register(Benchmark{
+		Name: "VarintDecode", Category: "varint", Tags: []string{"cpu", "serde"},
+		Iterations: 10, DataSize: 8 * varintValues, Axes: encodings,
+		Setup: setupVarint, Teardown: teardownVarint,
+		Fn: func(b *B) {
+			s := &varintState
+			n, err := varintCodecs[b.StringParam("encoding")].decode(s.encoded, s.decoded)
+			if err != nil {
+				b.Fatal(err)
+				return
+			}
+			varintSink = n
+		},
+	})
+}
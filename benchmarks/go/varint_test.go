@@ -0,0 +1,46 @@
+// Varint Tests - Go
+//
+// Run with: go test -run 'Varint|Zigzag'
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestZigzag(t *testing.T) {
+	for v, want := range map[int64]uint64{0: 0, -1: 1, 1: 2, -2: 3, math.MaxInt64: math.MaxUint64 - 1, math.MinInt64: math.MaxUint64} {
+		if got := zigzagEncode(v); got != want {
+			t.Errorf("zigzagEncode(%d) = %d, want %d", v, got, want)
+		}
+		if got := zigzagDecode(want); got != v {
+			t.Errorf("zigzagDecode(%d) = %d, want %d", want, got, v)
+		}
+	}
+}
+
+func TestVarintCodecsRoundTrip(t *testing.T) {
+	edges := []int64{0, 1, -1, 127, 128, math.MaxInt64, math.MinInt64, 0}
+	for name, codec := range varintCodecs {
+		values := append(varintFixture(name, 1000, 1), edges...)
+		if name == "uvarint" {
+			// Negative values take the full 10 bytes but must still round-trip
+			values = append(values, -5)
+		}
+		encoded := codec.encode(nil, values)
+		decoded := make([]int64, len(values))
+		n, err := codec.decode(encoded, decoded)
+		if err != nil || n != len(encoded) {
+			t.Fatalf("%s: decoded %d of %d bytes: %v", name, n, len(encoded), err)
+		}
+		for i := range values {
+			if decoded[i] != values[i] {
+				t.Fatalf("%s: value %d decoded as %d, want %d", name, i, decoded[i], values[i])
+			}
+		}
+		if _, err := codec.decode(encoded[:len(encoded)-1], decoded); err == nil {
+			t.Errorf("%s: truncated input decoded without error", name)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Correctness Verification - Go
+//
+// Asserts known results for every algorithm and checks that each network
+// echo path returns exactly the bytes it was sent, so a broken
+// implementation fails loudly instead of producing fast numbers. The
+// shared golden fixture (see golden.go) is checked too. Exits non-zero on
+// any mismatch, for use as a CI gate before benchmarking.
+//
+// Run with: go run . verify [-golden ../common/golden.json]
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// verifyCheck is a single correctness assertion
+type verifyCheck struct {
+	Name string
+	Run  func() error
+}
+
+var verifyChecks = []verifyCheck{
+	{"Factorial(10)", func() error {
+		return expectInt32s(3628800, factorialRecursive(10), factorialIterative(10))
+	}},
+	{"Fibonacci(20)", func() error {
+		return expectInt32s(6765, fibonacciRecursive(20), fibonacciIterative(20))
+	}},
+	{"GCD(48, 18)", func() error { return expectInt32s(6, gcdRecursive(48, 18), gcdIterative(48, 18)) }},
+	{"Power(2, 10)", func() error { return expectInt32s(1024, powerNaive(2, 10), powerFast(2, 10)) }},
+	{"Primes up to 100", func() error { return expectInt32s(25, countPrimes(100)) }},
+	{"Primes up to 1000", func() error { return expectInt32s(168, countPrimes(1000)) }},
+	{"Sum(1..100)", func() error { return expectInt32s(5050, sumRange(1, 100)) }},
+	{"Collatz steps(27)", func() error { return expectInt32s(111, collatzSteps(27)) }},
+	{"TCP echo 64B", func() error { return verifyTCPEcho(64) }},
+	{"TCP echo 1MiB", func() error { return verifyTCPEcho(1 << 20) }},
+	{"UDP echo 64B", func() error { return verifyUDPEcho(64) }},
+	{"UDP echo 1400B", func() error { return verifyUDPEcho(1400) }},
+}
+
+// runVerify implements the verify subcommand
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	goldenPath := fs.String("golden", defaultGoldenPath, "shared golden output fixture (empty to skip)")
+	fs.Parse(args)
+
+	checks := verifyChecks
+	if *goldenPath = defaultFileFlag(fs, "golden"); *goldenPath != "" {
+		golden, err := LoadGolden(*goldenPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 2
+		}
+		checks = append(checks[:len(checks):len(checks)], golden.Checks()...)
+	}
+
+	fmt.Println("=== Go Correctness Verification ===")
+	fmt.Println()
+
+	failed := 0
+	for _, check := range checks {
+		if err := check.Run(); err != nil {
+			fmt.Printf("  [FAIL] %-20s %v\n", check.Name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("  [PASS] %s\n", check.Name)
+	}
+
+	fmt.Println()
+	if failed > 0 {
+		fmt.Printf("%d of %d checks failed\n", failed, len(checks))
+		return 1
+	}
+	fmt.Printf("All %d checks passed\n", len(checks))
+	return 0
+}
+
+// expectInt32s checks that every implementation returned want
+func expectInt32s(want int32, got ...int32) error {
+	for i, g := range got {
+		if g != want {
+			return fmt.Errorf("implementation %d returned %d, want %d", i+1, g, want)
+		}
+	}
+	return nil
+}
+
+// echoPattern returns a payload whose bytes depend on their offset, so
+// reordered or truncated echoes are detected; the golden fixture defines
+// the same pattern for every language
+func echoPattern(n int) []byte {
+	p := make([]byte, n)
+	for i := range p {
+		p[i] = byte(i*31 + i>>8)
+	}
+	return p
+}
+
+func verifyTCPEcho(size int) error {
+	payload := echoPattern(size)
+	reply, err := tcpEchoRoundTrip(payload)
+	if err != nil {
+		return err
+	}
+	return compareEcho(payload, reply)
+}
+
+// tcpEchoRoundTrip sends payload through a fresh TCP echo server and
+// returns what came back
+func tcpEchoRoundTrip(payload []byte) ([]byte, error) {
+	ln, err := startTCPEchoServer()
+	if err != nil {
+		return nil, err
+	}
+	defer ln.Close()
+	conn, err := dialTCP(ln.Addr().String())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// Write concurrently: a payload larger than the socket buffers would
+	// otherwise deadlock against the unread echo
+	writeErr := make(chan error, 1)
+	go func() {
+		_, err := conn.Write(payload)
+		writeErr <- err
+	}()
+	reply := make([]byte, len(payload))
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, err
+	}
+	return reply, <-writeErr
+}
+
+func verifyUDPEcho(size int) error {
+	pc, err := startUDPEchoServer()
+	if err != nil {
+		return err
+	}
+	defer pc.Close()
+	conn, err := net.Dial("udp", pc.Addr())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	payload := echoPattern(size)
+	reply := make([]byte, 64*1024)
+	// Loopback rarely drops datagrams, but retry rather than fail on one
+	for attempt := 0; attempt < 3; attempt++ {
+		if _, err := conn.Write(payload); err != nil {
+			return err
+		}
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := conn.Read(reply)
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return compareEcho(payload, reply[:n])
+	}
+	return fmt.Errorf("no echo after 3 attempts")
+}
+
+// compareEcho reports the first difference between a payload and its echo
+func compareEcho(sent, got []byte) error {
+	if bytes.Equal(sent, got) {
+		return nil
+	}
+	if len(sent) != len(got) {
+		return fmt.Errorf("echoed %d bytes, sent %d", len(got), len(sent))
+	}
+	for i := range sent {
+		if sent[i] != got[i] {
+			return fmt.Errorf("echo differs at byte %d", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Go Virtual Time Benchmarks
+// The timer-driven structures of the network benchmarks run on a
+// virtualClock (clock.go) instead of the wall clock: no sleeps and no
+// sockets, timers firing at exactly their deadline, so what is left is the
+// data-structure cost and every run reports the same detection numbers.
+//
+//   - SessionExpiry: the heartbeat server's session tracking as in
+//     UdpHeartbeat, heartbeats delivered by calling it directly; one
+//     iteration is a round over every live session followed by advancing
+//     the clock heartbeatInterval, which fires the expiry timers or sweeps
+//   - RateLimiter: the pacer's token bucket as in TcpPacing, writing to
+//     io.Discard for one virtual second per iteration
+
+package main
+
+import (
+	"io"
+	"time"
+)
+
+// rateLimiterWindow is the virtual time each RateLimiter iteration paces
+const rateLimiterWindow = time.Second
+
+var virtualTime struct {
+	clock  *virtualClock
+	server *heartbeatServer
+	live   []uint64
+	silent int
+}
+
+func setupSessionExpiry(b *B) error {
+	clock := newVirtualClock()
+	s, err := newHeartbeatServer(b.StringParam("detection"), clock)
+	if err != nil {
+		return err
+	}
+	all, live, silent := heartbeatSessionIDs(b.IntParam("sessions"))
+	for _, id := range all {
+		s.touch(id)
+	}
+	virtualTime.clock, virtualTime.server = clock, s
+	virtualTime.live, virtualTime.silent = live, silent
+	return nil
+}
+
+func teardownSessionExpiry() {
+	if virtualTime.server != nil {
+		virtualTime.server.close()
+	}
+	virtualTime.clock, virtualTime.server, virtualTime.live = nil, nil, nil
+}
+
+// benchSessionExpiry runs one heartbeat round, then lets heartbeatInterval
+// of virtual time pass
+func benchSessionExpiry(b *B) {
+	s := virtualTime.server
+	start := time.Now()
+	for _, id := range virtualTime.live {
+		s.touch(id)
+	}
+	virtualTime.clock.Advance(heartbeatInterval)
+	b.ReportMetric("ns_per_heartbeat", float64(time.Since(start).Nanoseconds())/float64(len(virtualTime.live)))
+	reportHeartbeatExpiries(b, s, virtualTime.silent)
+}
+
+// benchRateLimiter paces writes of pacingBlock for rateLimiterWindow of
+// virtual time
+func benchRateLimiter(b *B) {
+	mbps := b.IntParam("rate_mbps")
+	clock := newVirtualClock()
+	w := newPacedWriter(io.Discard, float64(mbps)*1e6/8, clock)
+	block := make([]byte, pacingBlock)
+	deadline := clock.Now().Add(rateLimiterWindow)
+	start := time.Now()
+	writes := 0
+	var written int64
+	for clock.Now().Before(deadline) {
+		n, _ := w.Write(block)
+		written += int64(n)
+		writes++
+	}
+	// No SetBytes: the bytes are virtual, a real-time rate would mean nothing
+	b.ReportMetric("ns_per_write", float64(time.Since(start).Nanoseconds())/float64(writes))
+	achieved := float64(written) * 8 / 1e6 / clock.Now().Sub(virtualEpoch).Seconds()
+	b.ReportMetric("achieved_mbps", achieved)
+	b.ReportMetric("rate_error_pct", 100*(achieved-float64(mbps))/float64(mbps))
+	b.ReportMetric("waits", float64(w.waits))
+}
+
+func init() {
+	Register(Benchmark{
+		Name: "SessionExpiry", Category: "timers", Tags: []string{"cpu"},
+		Iterations: 50,
+		Axes: []Axis{
+			{Name: "sessions", Values: Ints(1000, 10000, 100000)},
+			{Name: "detection", Values: Strings("timer", "sweep")},
+		},
+		Setup: setupSessionExpiry, Teardown: teardownSessionExpiry,
+		Fn: benchSessionExpiry,
+	})
+	Register(Benchmark{
+		Name: "RateLimiter", Category: "timers", Tags: []string{"cpu"},
+		Iterations: 20,
+		Axes:       []Axis{{Name: "rate_mbps", Values: Ints(1, 10, 100, 1000)}},
+		Fn:         benchRateLimiter,
+	})
+}
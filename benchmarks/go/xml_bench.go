@@ -0,0 +1,65 @@
+// XML Benchmarks - Go (encoding/xml)
+//
+// The user list of the large JSON fixture as an XML document, a verbose
+// text format alongside JSON:
+//
+//	<users count="100"><user id="1"><name>User0</name>...</user>...</users>
+//
+// XmlParseUsers unmarshals the document into the same users as the typed
+// JSON benchmarks (json_typed_bench.go) and XmlMarshalUsers marshals them
+// back. Both report xml_vs_json_pct, how much larger the document is than
+// the JSON fixture.
+
+package main
+
+import "encoding/xml"
+
+// xmlUserList is the user list document
+type xmlUserList struct {
+	XMLName xml.Name   `xml:"users"`
+	Count   int        `xml:"count,attr"`
+	Users   []jsonUser `xml:"user"`
+}
+
+// xmlUserListOf converts the large fixture to a user list document
+func xmlUserListOf(l *jsonLarge) xmlUserList {
+	return xmlUserList{Count: l.Metadata.Count, Users: l.Users}
+}
+
+func init() {
+	fixture := GenerateLargeJSON()
+	var large jsonLarge
+	if err := decodeJSONStrict(fixture, &large); err != nil {
+		panic("json fixture Large: " + err.Error())
+	}
+	users := xmlUserListOf(&large)
+	encoded, err := xml.Marshal(&users)
+	if err != nil {
+		panic("xml user list: " + err.Error())
+	}
+	sizeVsJSON := percentChange(float64(len(fixture)), float64(len(encoded)))
+
+	Register(Benchmark{
+		Name: "XmlParseUsers", Category: "xml", Tags: []string{"serde", "alloc"},
+		Iterations: 100, DataSize: int64(len(encoded)),
+		Fn: func(b *B) {
+			var list xmlUserList
+			if err := xml.Unmarshal(encoded, &list); err != nil {
+				b.Fatal(err)
+				return
+			}
+			b.ReportMetric("xml_vs_json_pct", sizeVsJSON)
+		},
+	})
+	Register(Benchmark{
+		Name: "XmlMarshalUsers", Category: "xml", Tags: []string{"serde", "alloc"},
+		Iterations: 100, DataSize: int64(len(encoded)),
+		Fn: func(b *B) {
+			if _, err := xml.Marshal(&users); err != nil {
+				b.Fatal(err)
+				return
+			}
+			b.ReportMetric("xml_vs_json_pct", sizeVsJSON)
+		},
+	})
+}
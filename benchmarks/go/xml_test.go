@@ -0,0 +1,46 @@
+// XML Tests - Go
+//
+// Run with: go test -run Xml
+
+package main
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestXmlUserListRoundTrip(t *testing.T) {
+	var large jsonLarge
+	if err := decodeJSONStrict(GenerateLargeJSON(), &large); err != nil {
+		t.Fatal(err)
+	}
+	users := xmlUserListOf(&large)
+	data, err := xml.Marshal(&users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back xmlUserList
+	if err := xml.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	back.XMLName = xml.Name{}
+	if !reflect.DeepEqual(back, users) {
+		t.Errorf("decoded as %+v", back)
+	}
+}
+
+func TestXmlUserShape(t *testing.T) {
+	users := xmlUserList{Count: 2, Users: []jsonUser{
+		{ID: 1, Name: "A & B", Email: "a@example.com", Active: true, Age: 30},
+		{ID: 2, Name: "C", Email: "c@example.com"},
+	}}
+	want := `<users count="2">` +
+		`<user id="1"><name>A &amp; B</name><email>a@example.com</email><active>true</active><age>30</age></user>` +
+		`<user id="2"><name>C</name><email>c@example.com</email><active>false</active></user>` +
+		`</users>`
+	data, err := xml.Marshal(&users)
+	if err != nil || string(data) != want {
+		t.Errorf("encoded as %s, %v", data, err)
+	}
+}
@@ -81,8 +81,8 @@ echo ------------------------------------------------------------
 where go >nul 2>&1
 if %errorlevel%==0 (
     pushd "%~dp0go"
-    echo Running main.go...
-    go run main.go algorithms_test.go
+    echo Running harness...
+    go run .
     echo.
     echo Running Go benchmarks (short)...
     go test -bench=. -benchtime=100ms